@@ -13,7 +13,7 @@ var addSchoolIDCmd = &cobra.Command{
 	Use:   "add-school-id",
 	Short: "Add school_id column to students table",
 	Run: func(cmd *cobra.Command, args []string) {
-		configs.ConnectDatabase()
+		configs.ConnectDatabase(appConfig.Database)
 
 		// Add school_id column to students table
 		sqlQuery := `
@@ -1,19 +1,31 @@
 package cmd
 
 import (
+	"context"
 	"easy-attend-service/configs"
 	"easy-attend-service/controller"
 	"easy-attend-service/middlewares"
+	"easy-attend-service/repositories"
+	"easy-attend-service/services"
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/jwt"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/metrics"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/requestid"
+	"easy-attend-service/utils/tracing"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
+	"slices"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 var rootCmd = &cobra.Command{
@@ -28,57 +40,100 @@ var serveCmd = &cobra.Command{
 	Long:  "Start the HTTP server to serve the API endpoints",
 	Args:  NotReqArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Load environment variables
-		err := godotenv.Load()
-		if err != nil {
-			log.Println("No .env file found, using system environment variables")
+		cfg := appConfig
+		if cfg.JWT.Secret == "" {
+			log.Fatal("JWT secret is not set (JWT_SECRET, --jwt-secret or jwt.secret in the config file)")
 		}
 
-		// Initialize logger
-		logger.InitLogger()
-		logger.LogInfo("Starting Easy Attend Service", nil)
+		// Initialize logger, token signing and the default language
+		logger.InitLogger(cfg.Log)
+		jwt.Init(cfg.JWT)
+		i18n.Init(cfg.I18n)
+		logger.LogInfo(cmd.Context(), "Starting Easy Attend Service", nil)
+
+		// Tracing first so startup queries are traced too
+		shutdownTracing, err := tracing.Init(cfg.Tracing)
+		if err != nil {
+			log.Fatal("Failed to initialize tracing:", err)
+		}
 
 		// Connect to database
-		configs.ConnectDatabase()
+		configs.ConnectDatabase(cfg.Database)
+		if err := configs.DB.Use(tracing.GormPlugin{}); err != nil {
+			log.Fatal("Failed to install the tracing plugin:", err)
+		}
+		if sqlDB, err := configs.DB.DB(); err == nil {
+			if err := metrics.RegisterDB(sqlDB); err != nil {
+				log.Fatal("Failed to register database metrics:", err)
+			}
+		}
 
-		// Setup Gin mode
-		ginMode := os.Getenv("GIN_MODE")
-		if ginMode == "" {
-			ginMode = "debug"
+		// Schema changes run through `migrate up`; replicas only migrate on start when opted in
+		if cfg.Server.MigrateOnStart {
+			if err := modelUp(0); err != nil {
+				log.Fatal("Failed to migrate database:", err)
+			}
 		}
-		gin.SetMode(ginMode)
+
+		// Start the outbox dispatcher that publishes side effects
+		dispatcher := newOutboxDispatcher(cfg.Outbox)
+		dispatcher.Start()
+
+		// Setup Gin mode
+		gin.SetMode(cfg.Server.GinMode)
 
 		// Create Gin router
 		r := gin.Default()
 
-		// Add CORS middleware with more permissive settings for development
-		r.Use(cors.New(cors.Config{
-			AllowAllOrigins:  true, // Allow all origins for development
-			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD"},
-			AllowHeaders:     []string{"*"}, // Allow all headers
-			ExposeHeaders:    []string{"Content-Length", "Authorization"},
-			AllowCredentials: false, // Set to false when using AllowAllOrigins
-			MaxAge:           12 * time.Hour,
-		}))
-
-		// Add logging middleware
+		// Add CORS middleware
+		r.Use(cors.New(corsConfig(cfg.CORS)))
+
+		// Server span per request; it runs first so the logs below carry its trace ID
+		r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(untracedPath)))
+
+		// X-Request-ID in and out, before anything logs
+		r.Use(middlewares.RequestIDMiddleware())
+
+		// Add logging and metrics middleware
 		r.Use(middlewares.LoggingMiddleware())
+		r.Use(middlewares.MetricsMiddleware())
 
 		// Setup routes
-		setupRoutes(r)
+		stopRoutes := setupRoutes(r, cfg, controller.WorkerCheck("outbox_dispatcher", dispatcher))
 
-		// Get port from environment
-		port := os.Getenv("PORT")
-		if port == "" {
-			port = "8080"
+		// Serve until SIGINT/SIGTERM, then drain and stop workers before the DB pool
+		flushTraces := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				log.Println("Failed to flush traces:", err)
+			}
+		}
+		if err := serve(newHTTPServer(cfg.Server, r), cfg.Server, stopRoutes, dispatcher.Stop, flushTraces); err != nil {
+			log.Fatal(err)
 		}
-
-		log.Printf("Server starting on port %s", port)
-		log.Fatal(r.Run(":" + port))
 	},
 }
 
+// appConfig is loaded and validated before any command runs
+var appConfig *configs.Config
+
 func init() {
+	// Let commands with their own PersistentPreRunE (migrate) keep the config loading below
+	cobra.EnableTraverseRunHooks = true
+	configs.BindFlags(rootCmd.PersistentFlags())
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		cfg, err := configs.Load(cmd.Flags())
+		if err != nil {
+			// A bad setting is not a usage mistake; main prints the error once
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			return err
+		}
+		appConfig = cfg
+		return nil
+	}
+
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(Migrate())
 }
@@ -95,18 +150,123 @@ func NotReqArgs(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func setupRoutes(r *gin.Engine) {
+// newOutboxDispatcher builds the dispatcher with the log, webhook and notification consumers
+func newOutboxDispatcher(cfg configs.OutboxConfig) *outbox.Dispatcher {
+	config := outbox.DefaultDispatcherConfig()
+	config.PollInterval = time.Duration(cfg.PollInterval)
+	config.BatchSize = cfg.BatchSize
+	config.MaxAttempts = cfg.MaxAttempts
+
+	consumers := []outbox.Consumer{outbox.NewLogConsumer(configs.DB)}
+	if cfg.WebhookURL != "" {
+		consumers = append(consumers, outbox.NewWebhookConsumer(cfg.WebhookURL, cfg.WebhookSecret))
+	}
+	consumers = append(consumers, outbox.Notifications)
+
+	return outbox.NewDispatcher(configs.DB, config, consumers...)
+}
+
+// untracedPath keeps probe and scrape traffic out of the traces
+func untracedPath(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/health", "/livez", "/readyz", "/metrics":
+		return false
+	}
+	return true
+}
+
+// corsConfig allows every origin when the list contains "*"
+func corsConfig(cfg configs.CORSConfig) cors.Config {
+	config := cors.Config{
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD"},
+		AllowHeaders:     []string{"*"}, // Allow all headers
+		ExposeHeaders:    []string{"Content-Length", "Authorization", "Idempotent-Replayed", "ETag", "Last-Modified", requestid.Header},
+		AllowCredentials: false, // Must stay false while every origin is allowed
+		MaxAge:           time.Duration(cfg.MaxAge),
+	}
+	if slices.Contains(cfg.AllowOrigins, "*") {
+		config.AllowAllOrigins = true
+	} else {
+		config.AllowOrigins = cfg.AllowOrigins
+	}
+	return config
+}
+
+// setupRoutes registers every route and returns a func that stops the background
+// workers the routes own (rate limiter and idempotency key cleanup). workerChecks join the readiness probe.
+func setupRoutes(r *gin.Engine, cfg *configs.Config, workerChecks ...controller.HealthCheck) (stop func()) {
+	// Initialize repositories and the services built on them
+	attendanceRepo := repositories.NewAttendanceRepository(configs.DB)
+	studentRepo := repositories.NewStudentRepository(configs.DB)
+	classroomRepo := repositories.NewClassroomRepository(configs.DB)
+	teacherRepo := repositories.NewTeacherRepository(configs.DB)
+	schoolRepo := repositories.NewSchoolRepository(configs.DB)
+	academicYearRepo := repositories.NewAcademicYearRepository(configs.DB)
+	logRepo := repositories.NewLogRepository(configs.DB)
+	genderRepo := repositories.NewGenderRepository(configs.DB)
+	prefixRepo := repositories.NewPrefixRepository(configs.DB)
+	classroomMemberRepo := repositories.NewClassroomMemberRepository(configs.DB)
+	syncRepo := repositories.NewSyncRepository(configs.DB)
+	trashRepo := repositories.NewTrashRepository(configs.DB)
+	systemClock := clock.System{}
+
+	// Rate limiters sized from the configuration
+	window := time.Duration(cfg.RateLimit.Window)
+	strictLimiter := middlewares.NewRateLimiter("strict", cfg.RateLimit.Strict, window)
+	normalLimiter := middlewares.NewRateLimiter("normal", cfg.RateLimit.Normal, window)
+	generousLimiter := middlewares.NewRateLimiter("generous", cfg.RateLimit.Generous, window)
+	stop = func() {
+		strictLimiter.Stop()
+		normalLimiter.Stop()
+		generousLimiter.Stop()
+		middlewares.IdempotencyGuard.Stop()
+	}
+
+	attendanceService := services.NewAttendanceService(attendanceRepo, classroomRepo, systemClock)
+	studentService := services.NewStudentService(studentRepo, classroomRepo, teacherRepo, schoolRepo, systemClock)
+	classroomService := services.NewClassroomService(classroomRepo, academicYearRepo, systemClock)
+	academicYearService := services.NewAcademicYearService(academicYearRepo, schoolRepo, teacherRepo, systemClock)
+	teacherService := services.NewTeacherService(teacherRepo, classroomRepo, schoolRepo)
+	logService := services.NewLogService(logRepo, systemClock)
+	authService := services.NewAuthService(teacherRepo, schoolRepo, genderRepo, prefixRepo)
+	schoolService := services.NewSchoolService(schoolRepo)
+	genderService := services.NewGenderService(genderRepo, systemClock)
+	prefixService := services.NewPrefixService(prefixRepo, systemClock)
+	classroomMemberService := services.NewClassroomMemberService(classroomMemberRepo)
+	syncService := services.NewSyncService(syncRepo, systemClock)
+	trashService := services.NewTrashService(trashRepo, teacherRepo)
+
 	// Initialize controllers
-	authController := controller.NewAuthController()
-	teacherController := controller.NewTeacherController()
-	studentController := controller.NewStudentController()
-	schoolController := controller.NewSchoolController()
-	genderController := controller.NewGenderController()
-	prefixController := controller.NewPrefixController()
-	classroomController := controller.NewClassroomController()
-	classroomMemberController := controller.NewClassroomMemberController()
-	attendanceController := controller.NewAttendanceController()
-	logController := controller.NewLogController()
+	authController := controller.NewAuthController(authService)
+	teacherController := controller.NewTeacherController(teacherService)
+	studentController := controller.NewStudentController(studentService)
+	schoolController := controller.NewSchoolController(schoolService)
+	genderController := controller.NewGenderController(genderService)
+	prefixController := controller.NewPrefixController(prefixService)
+	classroomController := controller.NewClassroomController(classroomService)
+	academicYearController := controller.NewAcademicYearController(academicYearService)
+	classroomMemberController := controller.NewClassroomMemberController(classroomMemberService)
+	attendanceController := controller.NewAttendanceController(attendanceService)
+	logController := controller.NewLogController(logService)
+	streamController := controller.NewStreamController(classroomService)
+	rollCallController := controller.NewRollCallController(attendanceService, classroomService, teacherService, cfg.CORS.AllowOrigins)
+	syncController := controller.NewSyncController(syncService, classroomService)
+	trashController := controller.NewTrashController(trashService)
+	healthController := controller.NewHealthController(2*time.Second, append([]controller.HealthCheck{
+		{Name: "database", Check: pingDatabase},
+		{Name: "migrations", Check: verifyMigrations},
+	}, workerChecks...)...)
+	openAPIController := controller.NewOpenAPIController(r, apiSpec())
+
+	// Pick the response language first so errors are rendered in it too
+	r.Use(middlewares.LocaleMiddleware())
+	// Errors attached with c.Error become problem+json responses, unknown routes included
+	r.Use(middlewares.ErrorMiddleware())
+	r.NoRoute(func(c *gin.Context) {
+		c.Error(apperror.NotFound("route.not_found", "no route matches "+c.Request.Method+" "+c.Request.URL.Path).
+			With("method", c.Request.Method).
+			With("path", c.Request.URL.Path))
+	})
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
@@ -116,12 +276,23 @@ func setupRoutes(r *gin.Engine) {
 		})
 	})
 
+	// Kubernetes probes: livez never touches dependencies, readyz gates rollouts
+	r.GET("/livez", healthController.Livez)
+	r.GET("/readyz", healthController.Readyz)
+
+	// Prometheus scrape endpoint
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// OpenAPI 3 document generated from the routes below, browsable in Swagger UI
+	r.GET("/openapi.json", openAPIController.GetDocument)
+	r.GET("/docs", openAPIController.GetSwaggerUI)
+
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 	{
 		// Auth routes (public) - with strict rate limiting
 		auth := v1.Group("/auth")
-		auth.Use(middlewares.StrictRateLimit())
+		auth.Use(strictLimiter.RateLimitMiddleware())
 		{
 			auth.POST("/login", authController.Login)
 			auth.POST("/register", authController.Register)
@@ -129,19 +300,23 @@ func setupRoutes(r *gin.Engine) {
 
 		// Test routes (public) - for testing only
 		test := v1.Group("/test")
-		test.Use(middlewares.NormalRateLimit())
+		test.Use(normalLimiter.RateLimitMiddleware())
+		test.Use(middlewares.IdempotencyKeys())
 		{
 			test.POST("/students", studentController.TestCreateStudent)
 		}
 
-		// Protected routes - with normal rate limiting
+		// Protected routes - generous rate limiting for reads, normal for writes
 		protected := v1.Group("")
 		protected.Use(middlewares.AuthMiddleware())
-		protected.Use(middlewares.NormalRateLimit())
+		protected.Use(middlewares.ReadWriteRateLimit(generousLimiter, normalLimiter))
+		protected.Use(middlewares.IdempotencyKeys())     // Replays POST retries sent with an Idempotency-Key header
+		protected.Use(middlewares.ConditionalRequests()) // ETag and 304 Not Modified for GETs, If-Match for updates
 		{
 			// Auth profile and logout routes
 			protected.GET("/auth/profile", authController.GetProfile)
 			protected.POST("/auth/logout", authController.Logout)
+			protected.POST("/auth/stream-ticket", authController.StreamTicket) // ticket สำหรับ SSE/WebSocket จากเบราว์เซอร์
 
 			// Teacher info (comprehensive data)
 			protected.GET("/teacher/info", teacherController.GetTeacherInfo)
@@ -155,6 +330,7 @@ func setupRoutes(r *gin.Engine) {
 				teachers.GET("/:id", teacherController.GetTeacherByID)
 				teachers.PUT("/:id", teacherController.UpdateTeacher)
 				teachers.DELETE("/:id", teacherController.DeleteTeacher)
+				teachers.POST("/:id/restore", teacherController.RestoreTeacher)
 			}
 
 			// Student routes (filtered by authenticated teacher)
@@ -165,6 +341,7 @@ func setupRoutes(r *gin.Engine) {
 				students.GET("/:id", studentController.GetStudentByID)
 				students.PUT("/:id", studentController.UpdateStudent)
 				students.DELETE("/:id", studentController.DeleteStudent)
+				students.POST("/:id/restore", studentController.RestoreStudent)
 			}
 
 			// School routes
@@ -205,6 +382,18 @@ func setupRoutes(r *gin.Engine) {
 				classrooms.GET("/:id", classroomController.GetClassroomByID)
 				classrooms.PUT("/:id", classroomController.UpdateClassroom)
 				classrooms.DELETE("/:id", classroomController.DeleteClassroom)
+				classrooms.POST("/:id/restore", classroomController.RestoreClassroom)
+			}
+
+			// Academic year routes (terms and the yearly rollover of classrooms)
+			academicYears := protected.Group("/academic-years")
+			{
+				academicYears.GET("", academicYearController.GetAllAcademicYears) // ?filter[school_id]=
+				academicYears.POST("", academicYearController.CreateAcademicYear)
+				academicYears.GET("/:id", academicYearController.GetAcademicYearByID)
+				academicYears.GET("/:id/terms", academicYearController.GetTerms)
+				academicYears.POST("/:id/terms", academicYearController.CreateTerm)
+				academicYears.POST("/:id/rollover", academicYearController.Rollover) // เลื่อนชั้นขึ้นปีการศึกษาใหม่
 			}
 
 			// Classroom Member routes
@@ -225,6 +414,7 @@ func setupRoutes(r *gin.Engine) {
 				attendances.GET("/:id", attendanceController.GetAttendanceByID)
 				attendances.PUT("/:id", attendanceController.UpdateAttendance)
 				attendances.DELETE("/:id", attendanceController.DeleteAttendance)
+				attendances.POST("/:id/restore", attendanceController.RestoreAttendance)
 				attendances.GET("/classroom/:classroom_id", attendanceController.GetAttendancesByClassroom)
 				attendances.GET("/student/:student_id", attendanceController.GetAttendancesByStudent)
 			}
@@ -238,6 +428,31 @@ func setupRoutes(r *gin.Engine) {
 				logs.GET("/teacher/:teacherId", logController.GetLogsByTeacher) // ดึง logs ตาม teacher
 				logs.GET("/action", logController.GetLogsByAction)              // ดึง logs ตาม action (query param)
 			}
+
+			// Offline-first sync for mobile clients
+			offlineSync := protected.Group("/sync")
+			{
+				offlineSync.POST("/attendances", syncController.SyncAttendances)             // ส่งคิวออฟไลน์ + ดึงการเปลี่ยนแปลง
+				offlineSync.GET("/attendances/changes", syncController.GetAttendanceChanges) // ?cursor=&limit=
+			}
+
+			// Trash: deleted records that can still be restored until they are purged
+			protected.GET("/trash", trashController.GetTrash) // ?type=classrooms|students|teachers|attendances, paged
+		}
+
+		// Long-lived real-time connections; browsers authenticate them with a ?ticket=
+		// from POST /auth/stream-ticket because they cannot set the Authorization header
+		live := v1.Group("")
+		live.Use(middlewares.TicketAuthMiddleware(jwt.TicketScopeStream))
+		live.Use(normalLimiter.RateLimitMiddleware())
+		{
+			// Real-time stream routes (Server-Sent Events)
+			live.GET("/stream/attendance", streamController.StreamAttendance) // ?classroom_id= (optional)
+
+			// Live roll-call collaboration (WebSocket)
+			live.GET("/rollcall/:classroom_id/ws", rollCallController.Connect) // ?session_date=YYYY-MM-DD
 		}
 	}
+
+	return stop
 }
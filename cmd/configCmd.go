@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the resolved configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the configuration after defaults, config file, environment and flags are applied",
+	Args:  NotReqArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := appConfig
+		if redacted, _ := cmd.Flags().GetBool("redacted"); redacted {
+			cfg = cfg.Redacted()
+		}
+
+		out, err := cfg.YAML()
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(out))
+	},
+}
+
+func init() {
+	configPrintCmd.Flags().Bool("redacted", false, "Mask passwords and secrets")
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -4,6 +4,7 @@ import (
 	"easy-attend-service/configs"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/spf13/cobra"
 )
@@ -14,7 +15,7 @@ func Migrate() *cobra.Command {
 		Use:  "migrate",
 		Args: NotReqArgs,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			configs.ConnectDatabase()
+			configs.ConnectDatabase(appConfig.Database)
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
@@ -23,18 +24,25 @@ func Migrate() *cobra.Command {
 	}
 	cmd.AddCommand(migrateUp())
 	cmd.AddCommand(migrateDown())
-	cmd.AddCommand(migrateRefresh())
+	cmd.AddCommand(migrateRedo())
+	cmd.AddCommand(migrateStatus())
 	cmd.AddCommand(migrateIntID())
 	return cmd
 }
 
 func migrateUp() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "up",
-		Args: NotReqArgs,
+		Use:   "up [N]",
+		Short: "Apply pending migrations (all, or the next N)",
+		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := modelUp(); err != nil {
-				fmt.Printf("%s", err)
+			steps, err := stepsArg(args, 0)
+			if err != nil {
+				fmt.Printf("%s\n", err)
+				os.Exit(1)
+			}
+			if err := modelUp(steps); err != nil {
+				fmt.Printf("%s\n", err)
 				os.Exit(1)
 			}
 			fmt.Println("Migration up completed successfully!")
@@ -46,11 +54,17 @@ func migrateUp() *cobra.Command {
 
 func migrateDown() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "down",
-		Args: NotReqArgs,
+		Use:   "down [N]",
+		Short: "Roll back the last N applied migrations (default 1)",
+		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := modelDown(); err != nil {
-				fmt.Printf("%s", err)
+			steps, err := stepsArg(args, 1)
+			if err != nil {
+				fmt.Printf("%s\n", err)
+				os.Exit(1)
+			}
+			if err := modelDown(steps); err != nil {
+				fmt.Printf("%s\n", err)
 				os.Exit(1)
 			}
 			fmt.Println("Migration down completed successfully!")
@@ -60,26 +74,51 @@ func migrateDown() *cobra.Command {
 	return cmd
 }
 
-func migrateRefresh() *cobra.Command {
+func migrateRedo() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:  "refresh",
-		Args: NotReqArgs,
+		Use:   "redo",
+		Short: "Roll back and re-apply the last migration",
+		Args:  NotReqArgs,
 		Run: func(cmd *cobra.Command, args []string) {
-			if err := modelDown(); err != nil {
-				fmt.Printf("%s", err)
+			if err := modelRedo(); err != nil {
+				fmt.Printf("%s\n", err)
 				os.Exit(1)
 			}
-			if err := modelUp(); err != nil {
-				fmt.Printf("%s", err)
+			fmt.Println("Migration redo completed successfully!")
+			os.Exit(0)
+		},
+	}
+	return cmd
+}
+
+func migrateStatus() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show applied and pending migrations",
+		Args:  NotReqArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := modelStatus(); err != nil {
+				fmt.Printf("%s\n", err)
 				os.Exit(1)
 			}
-			fmt.Println("Migration refresh completed successfully!")
 			os.Exit(0)
 		},
 	}
 	return cmd
 }
 
+// stepsArg parses the optional N argument of up/down
+func stepsArg(args []string, defaultSteps int) (int, error) {
+	if len(args) == 0 {
+		return defaultSteps, nil
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("N must be a positive number")
+	}
+	return steps, nil
+}
+
 func migrateIntID() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:  "int-id",
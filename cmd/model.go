@@ -1,24 +1,122 @@
 package cmd
 
 import (
+	"context"
 	"easy-attend-service/configs"
 	"easy-attend-service/database/migrations"
+	"fmt"
 	"log"
+	"os"
+	"text/tabwriter"
+	"time"
 )
 
-func modelUp() error {
+func modelUp(steps int) error {
 	log.Printf("Executing model up...")
-	configs.AutoMigrate()
+	migrator, err := migrations.NewMigrator(configs.DB)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(steps)
+	for _, migration := range applied {
+		log.Printf("Applied %04d_%s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		log.Printf("Database is already up to date")
+	}
 	return nil
 }
 
-func modelDown() error {
+func modelDown(steps int) error {
 	log.Printf("Executing model down...")
-	log.Printf("Manual model rollback not implemented - use database tools")
+	migrator, err := migrations.NewMigrator(configs.DB)
+	if err != nil {
+		return err
+	}
+
+	rolledBack, err := migrator.Down(steps)
+	for _, migration := range rolledBack {
+		log.Printf("Rolled back %04d_%s", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(rolledBack) == 0 {
+		log.Printf("No applied migration to roll back")
+	}
 	return nil
 }
 
+func modelRedo() error {
+	log.Printf("Executing model redo...")
+	migrator, err := migrations.NewMigrator(configs.DB)
+	if err != nil {
+		return err
+	}
+
+	redone, err := migrator.Redo()
+	if err != nil {
+		return err
+	}
+	log.Printf("Redid %04d_%s", redone.Version, redone.Name)
+	return nil
+}
+
+func modelStatus() error {
+	migrator, err := migrations.NewMigrator(configs.DB)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Missing:
+			state = "applied (file missing)"
+		case status.Modified:
+			state = "applied (modified)"
+		case status.Applied:
+			state = "applied"
+		}
+		appliedAt := "-"
+		if status.Applied {
+			appliedAt = time.Unix(status.AppliedAt, 0).Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
+
 func migrateToIntID() error {
 	log.Printf("Migrating to int ID schema...")
 	return migrations.CreateIntIDTables(configs.DB)
 }
+
+// pingDatabase checks that the connection pool can reach Postgres
+func pingDatabase(ctx context.Context) error {
+	sqlDB, err := configs.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// verifyMigrations checks that the database is at the newest migration of this build
+func verifyMigrations(ctx context.Context) error {
+	migrator, err := migrations.NewMigrator(configs.DB)
+	if err != nil {
+		return err
+	}
+	return migrator.Verify(ctx)
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"easy-attend-service/controller"
+	"easy-attend-service/models"
+	"easy-attend-service/requests"
+	"easy-attend-service/response"
+	"easy-attend-service/services"
+	"easy-attend-service/utils/buildinfo"
+	"easy-attend-service/utils/openapi"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Query parameters the handlers read one by one with c.Query; only the spec uses these
+type logActionQuery struct {
+	Action models.LogAction `form:"action" binding:"required"`
+}
+
+type streamQuery struct {
+	ClassroomID uint   `form:"classroom_id"`  // Only events of this classroom
+	LastEventID string `form:"last_event_id"` // Resume after this event, like the Last-Event-ID header
+	Ticket      string `form:"ticket"`        // From POST /auth/stream-ticket, instead of the Authorization header
+}
+
+type rollCallQuery struct {
+	SessionDate string `form:"session_date"` // YYYY-MM-DD, default today
+	Ticket      string `form:"ticket"`       // From POST /auth/stream-ticket, instead of the Authorization header
+}
+
+// apiSpec describes every route setupRoutes registers. TestOpenAPICoversEveryRoute
+// fails when a route is added or removed without updating this table.
+func apiSpec() openapi.Spec {
+	return openapi.Spec{
+		Info: openapi.Info{
+			Title:       "Easy Attend Service API",
+			Version:     buildinfo.Version,
+			Description: "Attendance tracking for schools. Errors are RFC 7807 problem documents; messages follow Accept-Language (th, en).",
+		},
+		Routes:   apiRoutes(),
+		Envelope: response.Response{},
+		Problem:  response.Problem{},
+		Overrides: map[reflect.Type]*openapi.Schema{
+			reflect.TypeFor[models.DeletedAt]():        {Type: "integer", Format: "int64", Nullable: true, Description: "Unix seconds, set while the record is in the trash"},
+			reflect.TypeFor[models.AttendanceStatus](): {Type: "string", Enum: []string{"present", "absent", "late", "leave"}},
+		},
+	}
+}
+
+func apiRoutes() []openapi.Route {
+	health := gin.H{}
+	return []openapi.Route{
+		// Operations
+		{Method: http.MethodGet, Path: "/health", Tag: "Health", Summary: "Liveness check kept for old monitors", Public: true, Result: health},
+		{Method: http.MethodGet, Path: "/livez", Tag: "Health", Summary: "Liveness probe with build info", Public: true, Result: health},
+		{Method: http.MethodGet, Path: "/readyz", Tag: "Health", Summary: "Readiness probe; 503 when a dependency check fails", Public: true, Result: health},
+		{Method: http.MethodGet, Path: "/metrics", Tag: "Health", Summary: "Prometheus metrics", Public: true, ContentType: "text/plain"},
+		{Method: http.MethodGet, Path: "/openapi.json", Tag: "Docs", Summary: "This OpenAPI document", Public: true, Result: gin.H{}},
+		{Method: http.MethodGet, Path: "/docs", Tag: "Docs", Summary: "Swagger UI", Public: true, ContentType: "text/html"},
+
+		// Auth
+		{Method: http.MethodPost, Path: "/api/v1/auth/login", Tag: "Auth", Summary: "Sign in and get a token", Public: true, Body: requests.LoginRequest{}, Data: services.LoginResponse{}},
+		{Method: http.MethodPost, Path: "/api/v1/auth/register", Tag: "Auth", Summary: "Register a teacher and their school", Public: true, Body: requests.AuthRequest{}, Status: http.StatusCreated, Data: models.Teacher{}},
+		{Method: http.MethodGet, Path: "/api/v1/auth/profile", Tag: "Auth", Summary: "Profile of the signed-in teacher", Data: models.Teacher{}},
+		{Method: http.MethodPost, Path: "/api/v1/auth/logout", Tag: "Auth", Summary: "Record a logout", Idempotent: true},
+		{Method: http.MethodPost, Path: "/api/v1/auth/stream-ticket", Tag: "Auth", Summary: "One-minute ticket for opening the attendance stream or roll-call socket from a browser", Idempotent: true, Data: controller.StreamTicketResponse{}},
+		{Method: http.MethodPost, Path: "/api/v1/test/students", Tag: "Test", Summary: "Create a student without signing in (testing only)", Public: true, Idempotent: true, Body: requests.StudentQuickCreateRequest{}, Data: models.Student{}},
+
+		// Teachers
+		{Method: http.MethodGet, Path: "/api/v1/teacher/info", Tag: "Teachers", Summary: "Signed-in teacher with school, classrooms and stats", Data: services.TeacherInfo{}},
+		{Method: http.MethodGet, Path: "/api/v1/teacher/school", Tag: "Schools", Summary: "School of the signed-in teacher", Data: models.School{}},
+		{Method: http.MethodGet, Path: "/api/v1/teachers", Tag: "Teachers", Summary: "List teachers", List: &services.TeacherListing, Data: []models.Teacher{}},
+		{Method: http.MethodPost, Path: "/api/v1/teachers", Tag: "Teachers", Summary: "Create a teacher", Idempotent: true, Body: requests.TeacherCreateRequest{}, Status: http.StatusCreated, Data: models.Teacher{}},
+		{Method: http.MethodGet, Path: "/api/v1/teachers/:id", Tag: "Teachers", Summary: "Get a teacher", Data: models.Teacher{}},
+		{Method: http.MethodPut, Path: "/api/v1/teachers/:id", Tag: "Teachers", Summary: "Update a teacher", Body: requests.TeacherUpdateRequest{}, Data: models.Teacher{}},
+		{Method: http.MethodDelete, Path: "/api/v1/teachers/:id", Tag: "Teachers", Summary: "Move a teacher to the trash"},
+		{Method: http.MethodPost, Path: "/api/v1/teachers/:id/restore", Tag: "Trash", Summary: "Restore a teacher from the trash", Idempotent: true, Data: models.Teacher{}},
+
+		// Students
+		{Method: http.MethodGet, Path: "/api/v1/students", Tag: "Students", Summary: "List the students the teacher teaches", List: &services.StudentListing, Fields: &services.StudentFields, Data: []models.Student{}},
+		{Method: http.MethodPost, Path: "/api/v1/students", Tag: "Students", Summary: "Create a student", Idempotent: true, Body: requests.StudentQuickCreateRequest{}, Status: http.StatusCreated, Data: models.Student{}},
+		{Method: http.MethodGet, Path: "/api/v1/students/:id", Tag: "Students", Summary: "Get a student", Fields: &services.StudentFields, Data: models.Student{}},
+		{Method: http.MethodPut, Path: "/api/v1/students/:id", Tag: "Students", Summary: "Update a student", Body: requests.StudentUpdateRequest{}, Data: models.Student{}},
+		{Method: http.MethodDelete, Path: "/api/v1/students/:id", Tag: "Students", Summary: "Move a student to the trash"},
+		{Method: http.MethodPost, Path: "/api/v1/students/:id/restore", Tag: "Trash", Summary: "Restore a student from the trash", Idempotent: true, Data: models.Student{}},
+
+		// Schools
+		{Method: http.MethodGet, Path: "/api/v1/schools", Tag: "Schools", Summary: "List schools", List: &services.SchoolListing, Data: []models.School{}},
+		{Method: http.MethodPost, Path: "/api/v1/schools", Tag: "Schools", Summary: "Create a school", Idempotent: true, Body: requests.SchoolCreateRequest{}, Status: http.StatusCreated, Data: models.School{}},
+		{Method: http.MethodGet, Path: "/api/v1/schools/:id", Tag: "Schools", Summary: "Get a school", Data: models.School{}},
+		{Method: http.MethodPut, Path: "/api/v1/schools/:id", Tag: "Schools", Summary: "Update a school and its sync and student number settings", Body: requests.SchoolUpdateRequest{}, Data: models.School{}},
+		{Method: http.MethodDelete, Path: "/api/v1/schools/:id", Tag: "Schools", Summary: "Delete a school"},
+
+		// Lookups
+		{Method: http.MethodGet, Path: "/api/v1/genders", Tag: "Lookups", Summary: "List genders", List: &services.GenderListing, Data: []models.Gender{}},
+		{Method: http.MethodPost, Path: "/api/v1/genders", Tag: "Lookups", Summary: "Create a gender", Idempotent: true, Body: requests.GenderCreateRequest{}, Status: http.StatusCreated, Data: models.Gender{}},
+		{Method: http.MethodGet, Path: "/api/v1/genders/:id", Tag: "Lookups", Summary: "Get a gender", Data: models.Gender{}},
+		{Method: http.MethodPut, Path: "/api/v1/genders/:id", Tag: "Lookups", Summary: "Update a gender", Body: requests.GenderUpdateRequest{}, Data: models.Gender{}},
+		{Method: http.MethodDelete, Path: "/api/v1/genders/:id", Tag: "Lookups", Summary: "Delete a gender"},
+		{Method: http.MethodGet, Path: "/api/v1/prefixes", Tag: "Lookups", Summary: "List name prefixes", List: &services.PrefixListing, Data: []models.Prefix{}},
+		{Method: http.MethodPost, Path: "/api/v1/prefixes", Tag: "Lookups", Summary: "Create a name prefix", Idempotent: true, Body: requests.PrefixCreateRequest{}, Status: http.StatusCreated, Data: models.Prefix{}},
+		{Method: http.MethodGet, Path: "/api/v1/prefixes/:id", Tag: "Lookups", Summary: "Get a name prefix", Data: models.Prefix{}},
+		{Method: http.MethodPut, Path: "/api/v1/prefixes/:id", Tag: "Lookups", Summary: "Update a name prefix", Body: requests.PrefixUpdateRequest{}, Data: models.Prefix{}},
+		{Method: http.MethodDelete, Path: "/api/v1/prefixes/:id", Tag: "Lookups", Summary: "Delete a name prefix"},
+
+		// Classrooms
+		{Method: http.MethodGet, Path: "/api/v1/classrooms", Tag: "Classrooms", Summary: "List the teacher's classrooms", List: &services.ClassroomListing, Data: []models.Classroom{}},
+		{Method: http.MethodPost, Path: "/api/v1/classrooms", Tag: "Classrooms", Summary: "Create a classroom", Idempotent: true, Body: requests.ClassroomCreateRequest{}, Status: http.StatusCreated, Data: models.Classroom{}},
+		{Method: http.MethodGet, Path: "/api/v1/classrooms/:id", Tag: "Classrooms", Summary: "Get a classroom", Data: models.Classroom{}},
+		{Method: http.MethodPut, Path: "/api/v1/classrooms/:id", Tag: "Classrooms", Summary: "Update a classroom", Body: requests.ClassroomUpdateRequest{}, Data: models.Classroom{}},
+		{Method: http.MethodDelete, Path: "/api/v1/classrooms/:id", Tag: "Classrooms", Summary: "Move a classroom to the trash"},
+		{Method: http.MethodPost, Path: "/api/v1/classrooms/:id/restore", Tag: "Trash", Summary: "Restore a classroom from the trash", Idempotent: true, Data: models.Classroom{}},
+
+		// Academic years
+		{Method: http.MethodGet, Path: "/api/v1/academic-years", Tag: "Academic years", Summary: "List academic years, latest first", List: &services.AcademicYearListing, Data: []models.AcademicYear{}},
+		{Method: http.MethodPost, Path: "/api/v1/academic-years", Tag: "Academic years", Summary: "Create an academic year; a school's first year adopts its classrooms", Idempotent: true, Body: requests.AcademicYearCreateRequest{}, Status: http.StatusCreated, Data: models.AcademicYear{}},
+		{Method: http.MethodGet, Path: "/api/v1/academic-years/:id", Tag: "Academic years", Summary: "Get an academic year with its terms", Data: models.AcademicYear{}},
+		{Method: http.MethodGet, Path: "/api/v1/academic-years/:id/terms", Tag: "Academic years", Summary: "List the terms of an academic year, in order", List: &services.TermListing, Data: []models.Term{}},
+		{Method: http.MethodPost, Path: "/api/v1/academic-years/:id/terms", Tag: "Academic years", Summary: "Add a term; attendances in its dates are assigned to it", Idempotent: true, Body: requests.TermCreateRequest{}, Status: http.StatusCreated, Data: models.Term{}},
+		{Method: http.MethodPost, Path: "/api/v1/academic-years/:id/rollover", Tag: "Academic years", Summary: "Clone the classrooms into the next year and promote the students", Idempotent: true, Body: requests.RolloverRequest{}, Data: services.RolloverResult{}},
+
+		// Classroom members
+		{Method: http.MethodGet, Path: "/api/v1/classroom-members", Tag: "Classroom members", Summary: "List classroom members", List: &services.ClassroomMemberListing, Data: []models.ClassroomMember{}},
+		{Method: http.MethodGet, Path: "/api/v1/classroom-members/classroom/:classroom_id", Tag: "Classroom members", Summary: "List the members of a classroom", List: &services.ClassroomMemberListing, Data: []models.ClassroomMember{}},
+		{Method: http.MethodPost, Path: "/api/v1/classroom-members", Tag: "Classroom members", Summary: "Add a teacher or student to a classroom", Idempotent: true, Body: requests.ClassroomMemberCreateRequest{}, Status: http.StatusCreated, Data: models.ClassroomMember{}},
+		{Method: http.MethodPut, Path: "/api/v1/classroom-members/:classroom_id/:member_id", Tag: "Classroom members", Summary: "Update a classroom member", Body: requests.ClassroomMemberUpdateRequest{}, Data: models.ClassroomMember{}},
+		{Method: http.MethodDelete, Path: "/api/v1/classroom-members/:classroom_id/:member_id", Tag: "Classroom members", Summary: "Remove a classroom member"},
+
+		// Attendances
+		{Method: http.MethodGet, Path: "/api/v1/attendances", Tag: "Attendances", Summary: "List the teacher's attendance records, latest check first", List: &services.TeacherAttendanceListing, Fields: &services.AttendanceFields, Data: []models.Attendance{}},
+		{Method: http.MethodPost, Path: "/api/v1/attendances", Tag: "Attendances", Summary: "Record attendance", Idempotent: true, Body: requests.AttendanceCreateRequest{}, Status: http.StatusCreated, Data: models.Attendance{}},
+		{Method: http.MethodGet, Path: "/api/v1/attendances/:id", Tag: "Attendances", Summary: "Get an attendance record", Fields: &services.AttendanceFields, Data: models.Attendance{}},
+		{Method: http.MethodPut, Path: "/api/v1/attendances/:id", Tag: "Attendances", Summary: "Update an attendance record", Body: requests.AttendanceUpdateRequest{}, Data: models.Attendance{}},
+		{Method: http.MethodDelete, Path: "/api/v1/attendances/:id", Tag: "Attendances", Summary: "Move an attendance record to the trash"},
+		{Method: http.MethodPost, Path: "/api/v1/attendances/:id/restore", Tag: "Trash", Summary: "Restore an attendance record from the trash", Idempotent: true, Data: models.Attendance{}},
+		{Method: http.MethodGet, Path: "/api/v1/attendances/classroom/:classroom_id", Tag: "Attendances", Summary: "List a classroom's attendance, latest session first", List: &services.AttendanceListing, Fields: &services.AttendanceFields, Data: []models.Attendance{}},
+		{Method: http.MethodGet, Path: "/api/v1/attendances/student/:student_id", Tag: "Attendances", Summary: "List a student's attendance, latest session first", List: &services.AttendanceListing, Fields: &services.AttendanceFields, Data: []models.Attendance{}},
+
+		// Activity logs
+		{Method: http.MethodGet, Path: "/api/v1/logs", Tag: "Logs", Summary: "List activity log entries, newest first", List: &services.LogListing, Data: []models.Log{}},
+		{Method: http.MethodPost, Path: "/api/v1/logs", Tag: "Logs", Summary: "Add a free-text log entry", Idempotent: true, Body: requests.LogCreateRequest{}, Status: http.StatusCreated, Data: models.Log{}},
+		{Method: http.MethodGet, Path: "/api/v1/logs/:id", Tag: "Logs", Summary: "Get a log entry", Data: models.Log{}},
+		{Method: http.MethodGet, Path: "/api/v1/logs/teacher/:teacherId", Tag: "Logs", Summary: "List a teacher's log entries", List: &services.LogListing, Data: []models.Log{}},
+		{Method: http.MethodGet, Path: "/api/v1/logs/action", Tag: "Logs", Summary: "List log entries by action", Query: logActionQuery{}, List: &services.LogListing, Data: []models.Log{}},
+
+		// Real-time and offline sync
+		{Method: http.MethodGet, Path: "/api/v1/stream/attendance", Tag: "Real-time", Summary: "Attendance changes as Server-Sent Events", Query: streamQuery{}, ContentType: "text/event-stream"},
+		{Method: http.MethodGet, Path: "/api/v1/rollcall/:classroom_id/ws", Tag: "Real-time", Summary: "Live roll-call WebSocket; answers 101 Switching Protocols", Query: rollCallQuery{}, Status: http.StatusSwitchingProtocols, ContentType: "application/json"},
+		{Method: http.MethodPost, Path: "/api/v1/sync/attendances", Tag: "Sync", Summary: "Push an offline queue and pull changes", Idempotent: true, Body: requests.AttendanceSyncRequest{}, Data: services.AttendanceSyncResponse{}},
+		{Method: http.MethodGet, Path: "/api/v1/sync/attendances/changes", Tag: "Sync", Summary: "Pull attendance changes after a cursor", Query: requests.AttendanceChangesRequest{}, Data: services.AttendanceChanges{}},
+
+		// Trash
+		{Method: http.MethodGet, Path: "/api/v1/trash", Tag: "Trash", Summary: "List deleted records that can still be restored", Query: requests.TrashListRequest{}, List: &services.TrashListing, Data: []any{}},
+	}
+}
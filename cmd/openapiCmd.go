@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"easy-attend-service/utils/openapi"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+)
+
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Print the OpenAPI document served at /openapi.json, e.g. to generate a client",
+	Args:  NotReqArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Registering the routes needs no database; nothing is served
+		gin.SetMode(gin.ReleaseMode)
+		r := gin.New()
+		stop := setupRoutes(r, appConfig)
+		defer stop()
+
+		out, err := json.MarshalIndent(openapi.Build(apiSpec(), r.Routes()), "", "  ")
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(openapiCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"easy-attend-service/configs"
+	"easy-attend-service/utils/openapi"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPICoversEveryRoute fails when setupRoutes registers a route that apiRoutes
+// does not describe, or apiRoutes describes one that no longer exists
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	t.Cleanup(setupRoutes(r, configs.Default()))
+
+	undocumented, unknown := openapi.Coverage(apiRoutes(), r.Routes())
+	for _, route := range undocumented {
+		t.Errorf("%s is registered but has no entry in apiRoutes", route)
+	}
+	for _, route := range unknown {
+		t.Errorf("%s is in apiRoutes but not registered", route)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d", rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" || len(doc.Paths) == 0 {
+		t.Errorf("document = %s %d paths", doc.OpenAPI, len(doc.Paths))
+	}
+
+	// Every $ref must resolve, or client generators reject the document
+	for name := range refs(rec.Body.Bytes()) {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			if _, ok := doc.Components.Responses[name]; !ok {
+				if _, ok := doc.Components.Parameters[name]; !ok {
+					t.Errorf("$ref %q does not resolve", name)
+				}
+			}
+		}
+	}
+}
+
+// refs collects the component names of every $ref in a JSON document
+func refs(data []byte) map[string]bool {
+	found := map[string]bool{}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, value := range v {
+				if ref, ok := value.(string); ok && key == "$ref" {
+					found[ref[strings.LastIndex(ref, "/")+1:]] = true
+					continue
+				}
+				walk(value)
+			}
+		case []any:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	var doc any
+	json.Unmarshal(data, &doc)
+	walk(doc)
+	return found
+}
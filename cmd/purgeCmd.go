@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"easy-attend-service/configs"
+	"easy-attend-service/repositories"
+	"easy-attend-service/services"
+
+	"github.com/spf13/cobra"
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Permanently remove records that have been in the trash longer than the retention period",
+	Args:  NotReqArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		days, _ := cmd.Flags().GetInt("days")
+		if !cmd.Flags().Changed("days") {
+			days = appConfig.Trash.RetentionDays
+		}
+		if days < 0 {
+			fmt.Println("retention days must not be negative")
+			os.Exit(1)
+		}
+
+		configs.ConnectDatabase(appConfig.Database)
+
+		cutoff := time.Now().AddDate(0, 0, -days)
+		trashService := services.NewTrashService(repositories.NewTrashRepository(configs.DB), repositories.NewTeacherRepository(configs.DB))
+		result, err := trashService.PurgeDeleted(cmd.Context(), cutoff)
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Purged records deleted before %s\n", cutoff.Format(time.RFC3339))
+		fmt.Printf("  attendances: %d\n  students:    %d\n  classrooms:  %d\n  teachers:    %d\n",
+			result.Attendances, result.Students, result.Classrooms, result.Teachers)
+		if result.KeptStudents+result.KeptClassrooms+result.KeptTeachers > 0 {
+			fmt.Println("Kept because other records still reference them:")
+			fmt.Printf("  students:    %d\n  classrooms:  %d\n  teachers:    %d\n",
+				result.KeptStudents, result.KeptClassrooms, result.KeptTeachers)
+		}
+	},
+}
+
+func init() {
+	purgeCmd.Flags().Int("days", configs.Default().Trash.RetentionDays, "Retention period in days (default from TRASH_RETENTION_DAYS)")
+	rootCmd.AddCommand(purgeCmd)
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"easy-attend-service/configs"
+	"easy-attend-service/repositories"
+	"easy-attend-service/requests"
+	"easy-attend-service/services"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/logger"
+
+	"github.com/spf13/cobra"
+)
+
+var rolloverCmd = &cobra.Command{
+	Use:   "rollover",
+	Short: "Clone the classrooms of an academic year into the next one and promote the students",
+	Args:  NotReqArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetUint("from")
+		to, _ := cmd.Flags().GetUint("to")
+		repeating, _ := cmd.Flags().GetUintSlice("repeat")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		teacherID, _ := cmd.Flags().GetUint("teacher-id")
+		if from == 0 || to == 0 || teacherID == 0 {
+			fmt.Println("--from and --to academic year IDs and --teacher-id are required")
+			os.Exit(1)
+		}
+
+		// The service logs and writes a translated activity entry
+		logger.InitLogger(appConfig.Log)
+		i18n.Init(appConfig.I18n)
+		configs.ConnectDatabase(appConfig.Database)
+
+		service := services.NewAcademicYearService(repositories.NewAcademicYearRepository(configs.DB),
+			repositories.NewSchoolRepository(configs.DB), repositories.NewTeacherRepository(configs.DB), clock.System{})
+		result, err := service.Rollover(cmd.Context(), teacherID, from, &requests.RolloverRequest{
+			ToAcademicYearID:    to,
+			RepeatingStudentIDs: repeating,
+			DryRun:              dryRun,
+		})
+		if err != nil {
+			fmt.Printf("%s\n", err)
+			os.Exit(1)
+		}
+
+		if result.DryRun {
+			fmt.Println("Dry run, nothing was written")
+		}
+		fmt.Printf("Rolled over academic year %d to %d\n", result.FromAcademicYearID, result.ToAcademicYearID)
+		for _, classroom := range result.Classrooms {
+			fmt.Printf("  classroom %-12s grade %s\n", classroom.Name, classroom.Grade)
+		}
+		fmt.Printf("  promoted:  %d\n  repeated:  %d\n  graduated: %d\n",
+			len(result.Promoted), len(result.Repeated), len(result.Graduated))
+		for _, student := range result.Renumbered {
+			fmt.Printf("  student %d renumbered %s -> %s\n", student.StudentID, student.From, student.To)
+		}
+	},
+}
+
+func init() {
+	rolloverCmd.Flags().Uint("from", 0, "Academic year to roll over")
+	rolloverCmd.Flags().Uint("to", 0, "Academic year that receives the classrooms")
+	rolloverCmd.Flags().UintSlice("repeat", nil, "IDs of students who repeat their grade, comma separated")
+	rolloverCmd.Flags().Bool("dry-run", false, "Print the plan without writing it")
+	rolloverCmd.Flags().Uint("teacher-id", 0, "Teacher of the school the rollover is run for, recorded in the activity log")
+	rootCmd.AddCommand(rolloverCmd)
+}
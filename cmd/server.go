@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"easy-attend-service/configs"
+	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/realtime"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newHTTPServer wraps the router with the configured timeouts
+func newHTTPServer(cfg configs.ServerConfig, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              ":" + strconv.Itoa(cfg.Port),
+		Handler:           handler,
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.IdleTimeout),
+	}
+
+	// Shutdown does not wait for hijacked or streaming connections; end them so
+	// SSE and roll-call clients reconnect to another instance
+	srv.RegisterOnShutdown(func() {
+		realtime.Attendance.CloseAll()
+		realtime.RollCall.CloseAll()
+	})
+	return srv
+}
+
+// serve runs srv until SIGINT or SIGTERM, then shuts down in order: stop accepting
+// and drain in-flight requests, stop background workers, close the DB pool
+func serve(srv *http.Server, cfg configs.ServerConfig, workers ...func()) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.LogInfo(ctx, "Server starting", logrus.Fields{"addr": srv.Addr, "tls": cfg.TLSEnabled()})
+		var err error
+		if cfg.TLSEnabled() {
+			err = srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		serveErr <- err
+	}()
+
+	var result error
+	select {
+	case err := <-serveErr:
+		// The listener failed (port in use, bad certificate); still release the workers
+		result = fmt.Errorf("server stopped: %w", err)
+	case <-ctx.Done():
+		stop() // a second signal kills the process immediately
+		logger.LogInfo(ctx, "Shutting down, draining in-flight requests", logrus.Fields{"timeout": time.Duration(cfg.ShutdownTimeout).String()})
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.LogWarning(ctx, "Requests still running after the shutdown timeout, closing connections", logrus.Fields{"error": err.Error()})
+			srv.Close()
+		}
+		if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			result = fmt.Errorf("server stopped: %w", err)
+		}
+	}
+
+	for _, stopWorker := range workers {
+		stopWorker()
+	}
+
+	if configs.DB != nil {
+		if sqlDB, err := configs.DB.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				logger.LogError(ctx, err, "Failed to close database pool", nil)
+			}
+		}
+	}
+
+	logger.LogInfo(ctx, "Server stopped", nil)
+	return result
+}
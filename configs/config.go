@@ -0,0 +1,438 @@
+package configs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+)
+
+// Config holds every setting the service reads at startup. Values are layered:
+// defaults, then the optional config file, then environment variables (a .env file
+// included), then command-line flags
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	CORS      CORSConfig      `yaml:"cors" toml:"cors"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Outbox    OutboxConfig    `yaml:"outbox" toml:"outbox"`
+	Trash     TrashConfig     `yaml:"trash" toml:"trash"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	I18n      I18nConfig      `yaml:"i18n" toml:"i18n"`
+}
+
+type ServerConfig struct {
+	Port           int    `yaml:"port" toml:"port"`
+	GinMode        string `yaml:"gin_mode" toml:"gin_mode"` // debug, release or test
+	MigrateOnStart bool   `yaml:"migrate_on_start" toml:"migrate_on_start"`
+
+	ReadTimeout  Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout  Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	// ShutdownTimeout is how long in-flight requests may drain after SIGTERM
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+
+	// TLS is served directly when both files are set (single-box deployments)
+	TLSCertFile string `yaml:"tls_cert_file" toml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file" toml:"tls_key_file"`
+}
+
+// TLSEnabled reports whether the server should listen with HTTPS
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	Name     string `yaml:"name" toml:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
+	TimeZone string `yaml:"timezone" toml:"timezone"`
+}
+
+type JWTConfig struct {
+	Secret      string `yaml:"secret" toml:"secret"`
+	ExpireHours int    `yaml:"expire_hours" toml:"expire_hours"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`   // DEBUG, INFO, WARN or ERROR
+	Format string `yaml:"format" toml:"format"` // text or json
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `yaml:"allow_origins" toml:"allow_origins"` // "*" allows every origin
+	MaxAge       Duration `yaml:"max_age" toml:"max_age"`
+}
+
+// RateLimitConfig is the number of requests a client may send per window on the
+// auth routes (strict), reads of the API (generous) and its other calls (normal)
+type RateLimitConfig struct {
+	Window   Duration `yaml:"window" toml:"window"`
+	Strict   int      `yaml:"strict" toml:"strict"`
+	Normal   int      `yaml:"normal" toml:"normal"`
+	Generous int      `yaml:"generous" toml:"generous"`
+}
+
+type OutboxConfig struct {
+	PollInterval  Duration `yaml:"poll_interval" toml:"poll_interval"`
+	BatchSize     int      `yaml:"batch_size" toml:"batch_size"`
+	MaxAttempts   int      `yaml:"max_attempts" toml:"max_attempts"`
+	WebhookURL    string   `yaml:"webhook_url" toml:"webhook_url"`
+	WebhookSecret string   `yaml:"webhook_secret" toml:"webhook_secret"`
+}
+
+type TrashConfig struct {
+	RetentionDays int `yaml:"retention_days" toml:"retention_days"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"` // none, otlp or stdout
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"` // OTLP/HTTP collector host:port; empty uses OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure    bool    `yaml:"insecure" toml:"insecure"` // plain HTTP to the collector
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"` // share of new traces recorded, 0 to 1
+}
+
+type I18nConfig struct {
+	DefaultLanguage string `yaml:"default_language" toml:"default_language"` // th or en, used when the request does not pick one
+}
+
+// Duration is a time.Duration written as "2s" or "12h" in files, env and flags
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default returns the settings used when nothing is configured
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            8080,
+			GinMode:         "debug",
+			ReadTimeout:     Duration(15 * time.Second),
+			WriteTimeout:    Duration(30 * time.Second),
+			IdleTimeout:     Duration(60 * time.Second),
+			ShutdownTimeout: Duration(20 * time.Second),
+		},
+		Database: DatabaseConfig{
+			Host:     "localhost",
+			Port:     5432,
+			User:     "postgres",
+			Name:     "easy-attend-serviceV2",
+			SSLMode:  "disable",
+			TimeZone: "Asia/Bangkok",
+		},
+		JWT: JWTConfig{
+			ExpireHours: 24,
+		},
+		Log: LogConfig{
+			Level:  "INFO",
+			Format: "text",
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"*"},
+			MaxAge:       Duration(12 * time.Hour),
+		},
+		RateLimit: RateLimitConfig{
+			Window:   Duration(time.Minute),
+			Strict:   10,
+			Normal:   100,
+			Generous: 300,
+		},
+		Outbox: OutboxConfig{
+			PollInterval: Duration(2 * time.Second),
+			BatchSize:    50,
+			MaxAttempts:  10,
+		},
+		Trash: TrashConfig{
+			RetentionDays: 30,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "easy-attend-service",
+			SampleRatio: 1,
+		},
+		I18n: I18nConfig{
+			DefaultLanguage: "th",
+		},
+	}
+}
+
+// binding ties a setting to its environment variable and command-line flag
+type binding struct {
+	env    string
+	flag   string
+	usage  string
+	target func(c *Config) any
+}
+
+var bindings = []binding{
+	{"PORT", "port", "HTTP port", func(c *Config) any { return &c.Server.Port }},
+	{"GIN_MODE", "gin-mode", "Gin mode: debug, release or test", func(c *Config) any { return &c.Server.GinMode }},
+	{"MIGRATE_ON_START", "migrate-on-start", "Apply pending migrations when serve starts", func(c *Config) any { return &c.Server.MigrateOnStart }},
+	{"SERVER_READ_TIMEOUT", "read-timeout", "Maximum time to read a request", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"SERVER_WRITE_TIMEOUT", "write-timeout", "Maximum time to write a response (streams are exempt)", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"SERVER_IDLE_TIMEOUT", "idle-timeout", "How long keep-alive connections stay open", func(c *Config) any { return &c.Server.IdleTimeout }},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "How long in-flight requests may drain on shutdown", func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"TLS_CERT_FILE", "tls-cert-file", "TLS certificate file (PEM)", func(c *Config) any { return &c.Server.TLSCertFile }},
+	{"TLS_KEY_FILE", "tls-key-file", "TLS private key file (PEM)", func(c *Config) any { return &c.Server.TLSKeyFile }},
+
+	{"DB_HOST", "db-host", "Database host", func(c *Config) any { return &c.Database.Host }},
+	{"DB_PORT", "db-port", "Database port", func(c *Config) any { return &c.Database.Port }},
+	{"DB_USER", "db-user", "Database user", func(c *Config) any { return &c.Database.User }},
+	{"DB_PASSWORD", "db-password", "Database password", func(c *Config) any { return &c.Database.Password }},
+	{"DB_DATABASE", "db-name", "Database name", func(c *Config) any { return &c.Database.Name }},
+	{"DB_SSLMODE", "db-sslmode", "Postgres sslmode", func(c *Config) any { return &c.Database.SSLMode }},
+	{"DB_TIMEZONE", "db-timezone", "Session time zone of database connections", func(c *Config) any { return &c.Database.TimeZone }},
+
+	{"JWT_SECRET", "jwt-secret", "Secret used to sign access tokens", func(c *Config) any { return &c.JWT.Secret }},
+	{"JWT_EXPIRE_HOURS", "jwt-expire-hours", "Access token lifetime in hours", func(c *Config) any { return &c.JWT.ExpireHours }},
+
+	{"LOG_LEVEL", "log-level", "Log level: DEBUG, INFO, WARN or ERROR", func(c *Config) any { return &c.Log.Level }},
+	{"LOG_FORMAT", "log-format", "Log format: text or json", func(c *Config) any { return &c.Log.Format }},
+
+	{"CORS_ALLOW_ORIGINS", "cors-allow-origins", "Comma-separated allowed origins, * for any", func(c *Config) any { return &c.CORS.AllowOrigins }},
+	{"CORS_MAX_AGE", "cors-max-age", "How long browsers may cache preflight results", func(c *Config) any { return &c.CORS.MaxAge }},
+
+	{"RATE_LIMIT_WINDOW", "rate-limit-window", "Rate limit window", func(c *Config) any { return &c.RateLimit.Window }},
+	{"RATE_LIMIT_STRICT", "rate-limit-strict", "Requests per window on auth routes", func(c *Config) any { return &c.RateLimit.Strict }},
+	{"RATE_LIMIT_NORMAL", "rate-limit-normal", "Requests per window on API writes and live connections", func(c *Config) any { return &c.RateLimit.Normal }},
+	{"RATE_LIMIT_GENEROUS", "rate-limit-generous", "Requests per window on API reads (GET)", func(c *Config) any { return &c.RateLimit.Generous }},
+
+	{"OUTBOX_POLL_INTERVAL", "outbox-poll-interval", "How often the outbox dispatcher polls", func(c *Config) any { return &c.Outbox.PollInterval }},
+	{"OUTBOX_BATCH_SIZE", "outbox-batch-size", "Outbox events handled per poll", func(c *Config) any { return &c.Outbox.BatchSize }},
+	{"OUTBOX_MAX_ATTEMPTS", "outbox-max-attempts", "Delivery attempts before an outbox event is dead", func(c *Config) any { return &c.Outbox.MaxAttempts }},
+	{"OUTBOX_WEBHOOK_URL", "outbox-webhook-url", "Webhook receiving outbox events", func(c *Config) any { return &c.Outbox.WebhookURL }},
+	{"OUTBOX_WEBHOOK_SECRET", "outbox-webhook-secret", "HMAC secret for webhook signatures", func(c *Config) any { return &c.Outbox.WebhookSecret }},
+
+	{"TRASH_RETENTION_DAYS", "trash-retention-days", "Days deleted records stay in the trash", func(c *Config) any { return &c.Trash.RetentionDays }},
+	{"TRACING_EXPORTER", "tracing-exporter", "Trace exporter: none, otlp or stdout", func(c *Config) any { return &c.Tracing.Exporter }},
+	{"TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP collector host:port", func(c *Config) any { return &c.Tracing.Endpoint }},
+	{"TRACING_INSECURE", "tracing-insecure", "Send traces to the collector over plain HTTP", func(c *Config) any { return &c.Tracing.Insecure }},
+	{"OTEL_SERVICE_NAME", "tracing-service-name", "Service name reported on spans", func(c *Config) any { return &c.Tracing.ServiceName }},
+	{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "Share of new traces recorded, 0 to 1", func(c *Config) any { return &c.Tracing.SampleRatio }},
+
+	{"DEFAULT_LANGUAGE", "default-language", "Language of messages when neither the profile nor Accept-Language picks one: th or en", func(c *Config) any { return &c.I18n.DefaultLanguage }},
+}
+
+// BindFlags registers --config and one flag per setting on fs
+func BindFlags(fs *pflag.FlagSet) {
+	fs.String("config", "", "Path to a YAML or TOML config file (env CONFIG_FILE)")
+	for _, b := range bindings {
+		fs.String(b.flag, "", fmt.Sprintf("%s (env %s)", b.usage, b.env))
+	}
+}
+
+// Load builds the configuration from the defaults, the config file, the environment
+// and the flags registered by BindFlags; fs may be nil. The result is validated
+func Load(fs *pflag.FlagSet) (*Config, error) {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg := Default()
+
+	path := os.Getenv("CONFIG_FILE")
+	if fs != nil && fs.Changed("config") {
+		path, _ = fs.GetString("config")
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+
+	for _, b := range bindings {
+		if value, ok := os.LookupEnv(b.env); ok {
+			if err := setValue(b.target(cfg), value); err != nil {
+				return nil, fmt.Errorf("%s: %w", b.env, err)
+			}
+		}
+	}
+
+	if fs != nil {
+		for _, b := range bindings {
+			if !fs.Changed(b.flag) {
+				continue
+			}
+			value, _ := fs.GetString(b.flag)
+			if err := setValue(b.target(cfg), value); err != nil {
+				return nil, fmt.Errorf("--%s: %w", b.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overlays a YAML (.yaml, .yml) or TOML (.toml) file; unknown keys are errors
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.UnmarshalWithOptions(data, c, yaml.Strict())
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(c)
+	default:
+		return errors.New("unsupported format, use .yaml, .yml or .toml")
+	}
+}
+
+// setValue parses raw into the setting target points at. Empty values leave
+// non-string settings unchanged, so VAR= in a .env file means "not set"
+func setValue(target any, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if _, isString := target.(*string); !isString && raw == "" {
+		return nil
+	}
+
+	switch t := target.(type) {
+	case *string:
+		*t = raw
+	case *int:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", raw)
+		}
+		*t = v
+	case *bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", raw)
+		}
+		*t = v
+	case *float64:
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		*t = v
+	case *Duration:
+		if err := t.UnmarshalText([]byte(raw)); err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 12h", raw)
+		}
+	case *[]string:
+		var values []string
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		*t = values
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535")
+	check(slices.Contains([]string{"debug", "release", "test"}, c.Server.GinMode), "server.gin_mode must be debug, release or test")
+	check(c.Server.ReadTimeout > 0 && c.Server.WriteTimeout > 0 && c.Server.IdleTimeout > 0, "server read, write and idle timeouts must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "server.tls_cert_file and server.tls_key_file must be set together")
+	for _, file := range []string{c.Server.TLSCertFile, c.Server.TLSKeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, fmt.Errorf("tls file %q: %w", file, err))
+		}
+	}
+
+	check(c.Database.Host != "", "database.host is required")
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port must be between 1 and 65535")
+	check(c.Database.User != "", "database.user is required")
+	check(c.Database.Name != "", "database.name is required")
+	if _, err := time.LoadLocation(c.Database.TimeZone); err != nil || c.Database.TimeZone == "" {
+		errs = append(errs, fmt.Errorf("database.timezone %q is not a known time zone", c.Database.TimeZone))
+	}
+
+	check(c.JWT.ExpireHours > 0, "jwt.expire_hours must be positive")
+
+	check(slices.Contains([]string{"DEBUG", "INFO", "WARN", "ERROR"}, strings.ToUpper(c.Log.Level)), "log.level must be DEBUG, INFO, WARN or ERROR")
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format must be text or json")
+
+	check(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins needs at least one origin")
+	check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+
+	check(c.RateLimit.Window > 0, "rate_limit.window must be positive")
+	check(c.RateLimit.Strict > 0 && c.RateLimit.Normal > 0 && c.RateLimit.Generous > 0,
+		"rate_limit.strict, rate_limit.normal and rate_limit.generous must be positive")
+
+	check(c.Outbox.PollInterval > 0, "outbox.poll_interval must be positive")
+	check(c.Outbox.BatchSize > 0, "outbox.batch_size must be positive")
+	check(c.Outbox.MaxAttempts > 0, "outbox.max_attempts must be positive")
+
+	check(c.Trash.RetentionDays >= 0, "trash.retention_days must not be negative")
+
+	check(slices.Contains([]string{"none", "otlp", "stdout"}, c.Tracing.Exporter), "tracing.exporter must be none, otlp or stdout")
+	check(c.Tracing.ServiceName != "", "tracing.service_name is required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	check(c.I18n.DefaultLanguage == "th" || c.I18n.DefaultLanguage == "en", "i18n.default_language must be th or en")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Redacted returns a copy with passwords and secrets masked, safe to print or log
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.CORS.AllowOrigins = slices.Clone(c.CORS.AllowOrigins)
+	for _, secret := range []*string{&redacted.Database.Password, &redacted.JWT.Secret, &redacted.Outbox.WebhookSecret} {
+		if *secret != "" {
+			*secret = "********"
+		}
+	}
+	return &redacted
+}
+
+// YAML renders the configuration in the config file format
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"easy-attend-service/requests"
+	"easy-attend-service/response"
+	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/listquery"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AcademicYearController คือคอนโทรลเลอร์สำหรับปีการศึกษา ภาคเรียน และการเลื่อนชั้น
+type AcademicYearController struct {
+	academicYearService *services.AcademicYearService
+}
+
+// NewAcademicYearController สร้างอินสแตนซ์ใหม่ของ AcademicYearController
+func NewAcademicYearController(academicYearService *services.AcademicYearService) *AcademicYearController {
+	return &AcademicYearController{
+		academicYearService: academicYearService,
+	}
+}
+
+// GetAllAcademicYears ดึงปีการศึกษา กรองตามโรงเรียนด้วย filter[school_id]=
+func (ac *AcademicYearController) GetAllAcademicYears(c *gin.Context) {
+	query, err := listquery.Parse(c.Request.URL.Query(), services.AcademicYearListing)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	years, meta, err := ac.academicYearService.GetAcademicYears(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.SuccessWithPaginate(c, "success.academic_years_retrieved", years, meta)
+}
+
+// GetAcademicYearByID ดึงปีการศึกษาพร้อมภาคเรียน
+func (ac *AcademicYearController) GetAcademicYearByID(c *gin.Context) {
+	yearID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(errInvalidID("academic_year_id"))
+		return
+	}
+
+	year, err := ac.academicYearService.GetAcademicYearByID(c.Request.Context(), uint(yearID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.SetValidators(c, year.ETag(), year.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.academic_year_retrieved", year))
+}
+
+// CreateAcademicYear สร้างปีการศึกษาใหม่ให้โรงเรียนของครู
+func (ac *AcademicYearController) CreateAcademicYear(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req requests.AcademicYearCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errInvalidRequest(err))
+		return
+	}
+
+	year, err := ac.academicYearService.CreateAcademicYear(c.Request.Context(), teacherID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.academic_year_created", year))
+}
+
+// GetTerms ดึงภาคเรียนของปีการศึกษา
+func (ac *AcademicYearController) GetTerms(c *gin.Context) {
+	yearID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(errInvalidID("academic_year_id"))
+		return
+	}
+
+	query, err := listquery.Parse(c.Request.URL.Query(), services.TermListing)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	terms, meta, err := ac.academicYearService.GetTerms(c.Request.Context(), uint(yearID), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.SuccessWithPaginate(c, "success.terms_retrieved", terms, meta)
+}
+
+// CreateTerm เพิ่มภาคเรียนให้ปีการศึกษาของโรงเรียนของครู
+func (ac *AcademicYearController) CreateTerm(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	yearID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(errInvalidID("academic_year_id"))
+		return
+	}
+
+	var req requests.TermCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errInvalidRequest(err))
+		return
+	}
+
+	term, err := ac.academicYearService.CreateTerm(c.Request.Context(), teacherID, uint(yearID), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.term_created", term))
+}
+
+// Rollover ขึ้นปีการศึกษาใหม่: คัดลอกห้องเรียนและเลื่อนชั้นนักเรียน
+func (ac *AcademicYearController) Rollover(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	yearID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(errInvalidID("academic_year_id"))
+		return
+	}
+
+	var req requests.RolloverRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errInvalidRequest(err))
+		return
+	}
+
+	result, err := ac.academicYearService.Rollover(c.Request.Context(), teacherID, uint(yearID), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.academic_year_rolled_over", result))
+}
@@ -4,6 +4,9 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/listquery"
 	"net/http"
 	"strconv"
 
@@ -14,39 +17,39 @@ type AttendanceController struct {
 	attendanceService *services.AttendanceService
 }
 
-func NewAttendanceController() *AttendanceController {
+func NewAttendanceController(attendanceService *services.AttendanceService) *AttendanceController {
 	return &AttendanceController{
-		attendanceService: services.NewAttendanceService(),
+		attendanceService: attendanceService,
 	}
 }
 
 func (ac *AttendanceController) GetAllAttendances(c *gin.Context) {
 	// Get teacher ID from JWT context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "User ID not found in token"))
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	teacherIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "Invalid user ID format"))
+	query, err := listquery.Parse(c.Request.URL.Query(), services.TeacherAttendanceListing)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	teacherID, err := strconv.ParseUint(teacherIDStr, 10, 64)
+	selection, err := fieldset.Parse(c.Request.URL.Query(), services.AttendanceFields)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid teacher ID", "Teacher ID must be a valid number"))
+		c.Error(err)
 		return
 	}
 
-	attendances, err := ac.attendanceService.GetAttendancesByTeacher(uint(teacherID))
+	attendances, meta, err := ac.attendanceService.GetAttendancesByTeacher(c.Request.Context(), teacherID, query, selection)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch attendances", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Attendances retrieved successfully", attendances))
+	response.SuccessWithPaginate(c, "success.attendances_retrieved", selection.Render(attendances), meta)
 }
 
 func (ac *AttendanceController) GetAttendanceByID(c *gin.Context) {
@@ -55,21 +58,24 @@ func (ac *AttendanceController) GetAttendanceByID(c *gin.Context) {
 	// Convert string to uint
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid attendance ID", "ID must be a valid number"))
+		c.Error(errInvalidID("attendance_id"))
 		return
 	}
 
-	attendance, err := ac.attendanceService.GetAttendanceByID(uint(id))
+	selection, err := fieldset.Parse(c.Request.URL.Query(), services.AttendanceFields)
 	if err != nil {
-		if err.Error() == "attendance not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Attendance not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch attendance", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Attendance retrieved successfully", attendance))
+	attendance, err := ac.attendanceService.GetAttendanceByID(c.Request.Context(), uint(id), selection)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.SetValidators(c, attendance.ETag(), attendance.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.attendance_retrieved", selection.Render(attendance)))
 }
 
 func (ac *AttendanceController) GetAttendancesByClassroom(c *gin.Context) {
@@ -78,38 +84,29 @@ func (ac *AttendanceController) GetAttendancesByClassroom(c *gin.Context) {
 	// Convert string to uint
 	classroomID, err := strconv.ParseUint(classroomIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid classroom ID", "ID must be a valid number"))
+		c.Error(errInvalidID("classroom_id"))
 		return
 	}
 
-	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-
-	// Validate pagination
-	if page < 1 {
-		page = 1
+	query, err := listquery.Parse(c.Request.URL.Query(), services.AttendanceListing)
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if limit < 1 || limit > 100 {
-		limit = 50
+
+	selection, err := fieldset.Parse(c.Request.URL.Query(), services.AttendanceFields)
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	attendances, total, err := ac.attendanceService.GetAttendancesByClassroom(uint(classroomID), page, limit)
+	attendances, meta, err := ac.attendanceService.GetAttendancesByClassroom(c.Request.Context(), uint(classroomID), query, selection)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch attendances", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Attendances retrieved successfully",
-		"data":    attendances,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
-	})
+	response.SuccessWithPaginate(c, "success.attendances_retrieved", selection.Render(attendances), meta)
 }
 
 func (ac *AttendanceController) GetAttendancesByStudent(c *gin.Context) {
@@ -118,58 +115,45 @@ func (ac *AttendanceController) GetAttendancesByStudent(c *gin.Context) {
 	// Convert string to uint
 	studentID, err := strconv.ParseUint(studentIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid student ID", "ID must be a valid number"))
+		c.Error(errInvalidID("student_id"))
 		return
 	}
 
-	// Get pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-
-	// Validate pagination
-	if page < 1 {
-		page = 1
+	query, err := listquery.Parse(c.Request.URL.Query(), services.AttendanceListing)
+	if err != nil {
+		c.Error(err)
+		return
 	}
-	if limit < 1 || limit > 100 {
-		limit = 50
+
+	selection, err := fieldset.Parse(c.Request.URL.Query(), services.AttendanceFields)
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	attendances, total, err := ac.attendanceService.GetAttendancesByStudent(uint(studentID), page, limit)
+	attendances, meta, err := ac.attendanceService.GetAttendancesByStudent(c.Request.Context(), uint(studentID), query, selection)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch attendances", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "Attendances retrieved successfully",
-		"data":    attendances,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
-	})
+	response.SuccessWithPaginate(c, "success.attendances_retrieved", selection.Render(attendances), meta)
 }
 
 func (ac *AttendanceController) CreateAttendance(c *gin.Context) {
 	var req requests.AttendanceCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	attendance, err := ac.attendanceService.CreateAttendance(&req)
+	attendance, err := ac.attendanceService.CreateAttendance(c.Request.Context(), &req)
 	if err != nil {
-		if err.Error() == "attendance for this student on this date already exists" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse("Attendance already exists", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to create attendance", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Attendance created successfully", attendance))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.attendance_created", attendance))
 }
 
 func (ac *AttendanceController) UpdateAttendance(c *gin.Context) {
@@ -178,27 +162,24 @@ func (ac *AttendanceController) UpdateAttendance(c *gin.Context) {
 	// Convert string to uint
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid attendance ID", "ID must be a valid number"))
+		c.Error(errInvalidID("attendance_id"))
 		return
 	}
 
 	var req requests.AttendanceUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	attendance, err := ac.attendanceService.UpdateAttendance(uint(id), &req)
+	attendance, err := ac.attendanceService.UpdateAttendance(c.Request.Context(), uint(id), &req)
 	if err != nil {
-		if err.Error() == "attendance not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Attendance not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to update attendance", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Attendance updated successfully", attendance))
+	response.SetValidators(c, attendance.ETag(), attendance.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.attendance_updated", attendance))
 }
 
 func (ac *AttendanceController) DeleteAttendance(c *gin.Context) {
@@ -207,19 +188,31 @@ func (ac *AttendanceController) DeleteAttendance(c *gin.Context) {
 	// Convert string to uint
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid attendance ID", "ID must be a valid number"))
+		c.Error(errInvalidID("attendance_id"))
+		return
+	}
+
+	err = ac.attendanceService.DeleteAttendance(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.attendance_deleted", nil))
+}
+
+func (ac *AttendanceController) RestoreAttendance(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(errInvalidID("attendance_id"))
 		return
 	}
 
-	err = ac.attendanceService.DeleteAttendance(uint(id))
+	attendance, err := ac.attendanceService.RestoreAttendance(c.Request.Context(), uint(id))
 	if err != nil {
-		if err.Error() == "attendance not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Attendance not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to delete attendance", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Attendance deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.attendance_restored", attendance))
 }
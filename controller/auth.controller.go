@@ -1,12 +1,14 @@
 package controller
 
 import (
-	"easy-attend-service/models"
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
 	"easy-attend-service/utils"
+	"easy-attend-service/utils/jwt"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/metrics"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -16,71 +18,103 @@ type AuthController struct {
 	authService *services.AuthService
 }
 
-func NewAuthController() *AuthController {
+func NewAuthController(authService *services.AuthService) *AuthController {
 	return &AuthController{
-		authService: services.NewAuthService(),
+		authService: authService,
 	}
 }
 
 func (ac *AuthController) Login(c *gin.Context) {
 	var req requests.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		metrics.LoginFailures.WithLabelValues("invalid_request").Inc()
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	result, err := ac.authService.Login(&req)
+	result, err := ac.authService.Login(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Login failed", err.Error()))
+		reason := "error"
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			reason = "invalid_credentials"
+		}
+		metrics.LoginFailures.WithLabelValues(reason).Inc()
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Login successful", result))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.login", result))
 }
 
 func (ac *AuthController) Register(c *gin.Context) {
 	var req requests.AuthRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	teacher, err := ac.authService.Register(&req)
+	teacher, err := ac.authService.Register(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Registration failed", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Teacher registered successfully", teacher))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.teacher_registered", teacher))
 }
 
 func (ac *AuthController) GetProfile(c *gin.Context) {
 	// Get teacher ID from context using utility function
 	teacherID, err := utils.GetTeacherIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	teacher, err := ac.authService.GetProfile(teacherID)
+	teacher, err := ac.authService.GetProfile(c.Request.Context(), teacherID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, response.ErrorResponse("Profile not found", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Profile retrieved successfully", teacher))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.profile_retrieved", teacher))
+}
+
+// StreamTicket ออก ticket อายุสั้นสำหรับเปิด /stream/attendance และ roll-call WebSocket
+// จากเบราว์เซอร์ ซึ่งส่ง header Authorization ไม่ได้ ส่งเป็น ?ticket=
+func (ac *AuthController) StreamTicket(c *gin.Context) {
+	ticket, expiresAt, err := jwt.GenerateTicket(jwt.CustomClaims{
+		UserID:   c.GetString("user_id"),
+		Email:    c.GetString("email"),
+		UserType: c.GetString("user_type"),
+		Language: c.GetString("language"),
+	}, jwt.TicketScopeStream)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.stream_ticket_issued", StreamTicketResponse{
+		Ticket:    ticket,
+		ExpiresAt: expiresAt.Unix(),
+	}))
+}
+
+// StreamTicketResponse is a ticket for one ?ticket= connection
+type StreamTicketResponse struct {
+	Ticket    string `json:"ticket"`
+	ExpiresAt int64  `json:"expires_at"` // Unix seconds; connections opened before stay open
 }
 
 func (ac *AuthController) Logout(c *gin.Context) {
 	// Get teacher ID from context
 	teacherID, err := utils.GetTeacherIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", err.Error()))
+		c.Error(err)
 		return
 	}
 
 	// Get teacher info for school ID
-	teacher, err := ac.authService.GetProfile(teacherID)
+	teacher, err := ac.authService.GetProfile(c.Request.Context(), teacherID)
 	if err != nil {
 		// Still allow logout even if we can't get profile
 	}
@@ -101,7 +135,9 @@ func (ac *AuthController) Logout(c *gin.Context) {
 		teacherIDVal = *teacherIDUint
 	}
 
-	logger.LogActivity(teacherIDVal, models.LogActionLogout, "ออกจากระบบ", schoolID)
+	if err := ac.authService.Logout(c.Request.Context(), teacherIDVal, schoolID); err != nil {
+		logger.LogError(c.Request.Context(), err, "Failed to record logout activity", nil)
+	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Logged out successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.logged_out", nil))
 }
@@ -4,6 +4,8 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/listquery"
 	"net/http"
 	"strconv"
 
@@ -16,39 +18,33 @@ type ClassroomController struct {
 }
 
 // NewClassroomController สร้างอินสแตนซ์ใหม่ของ ClassroomController
-func NewClassroomController() *ClassroomController {
+func NewClassroomController(classroomService *services.ClassroomService) *ClassroomController {
 	return &ClassroomController{
-		classroomService: services.NewClassroomService(),
+		classroomService: classroomService,
 	}
 }
 
 // GetAllClassrooms ดึงข้อมูลห้องเรียนของครูที่ login
 func (cc *ClassroomController) GetAllClassrooms(c *gin.Context) {
 	// Get teacher ID from JWT context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "User ID not found in token"))
-		return
-	}
-
-	teacherIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "Invalid user ID format"))
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	teacherID, err := strconv.ParseUint(teacherIDStr, 10, 64)
+	query, err := listquery.Parse(c.Request.URL.Query(), services.ClassroomListing)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid teacher ID", "Teacher ID must be a valid number"))
+		c.Error(err)
 		return
 	}
 
-	classrooms, err := cc.classroomService.GetClassroomsByTeacher(uint(teacherID))
+	classrooms, meta, err := cc.classroomService.GetClassroomsByTeacher(c.Request.Context(), teacherID, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch classrooms", err.Error()))
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, response.SuccessResponse("Classrooms retrieved successfully", classrooms))
+	response.SuccessWithPaginate(c, "success.classrooms_retrieved", classrooms, meta)
 }
 
 // GetClassroomByID ดึงข้อมูลห้องเรียนตาม ID
@@ -58,40 +54,33 @@ func (cc *ClassroomController) GetClassroomByID(c *gin.Context) {
 	// Convert id from string to uint
 	classroomID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid classroom ID", err.Error()))
+		c.Error(errInvalidID("classroom_id"))
 		return
 	}
 
-	classroom, err := cc.classroomService.GetClassroomByID(uint(classroomID))
+	classroom, err := cc.classroomService.GetClassroomByID(c.Request.Context(), uint(classroomID))
 	if err != nil {
-		if err.Error() == "classroom not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Classroom not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch classroom", err.Error()))
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, response.SuccessResponse("Classroom retrieved successfully", classroom))
+	response.SetValidators(c, classroom.ETag(), classroom.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.classroom_retrieved", classroom))
 }
 
 // CreateClassroom สร้างห้องเรียนใหม่
 func (cc *ClassroomController) CreateClassroom(c *gin.Context) {
 	var req requests.ClassroomCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	classroom, err := cc.classroomService.CreateClassroom(&req)
+	classroom, err := cc.classroomService.CreateClassroom(c.Request.Context(), &req)
 	if err != nil {
-		if err.Error() == "classroom with this name already exists in this school" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse("Classroom name already exists", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to create classroom", err.Error()))
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusCreated, response.SuccessResponse("Classroom created successfully", classroom))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.classroom_created", classroom))
 }
 
 // UpdateClassroom แก้ไขข้อมูลห้องเรียน
@@ -99,31 +88,24 @@ func (cc *ClassroomController) UpdateClassroom(c *gin.Context) {
 	id := c.Param("id")
 	var req requests.ClassroomUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
 	// Convert id from string to uint
 	classroomID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid classroom ID", err.Error()))
+		c.Error(errInvalidID("classroom_id"))
 		return
 	}
 
-	classroom, err := cc.classroomService.UpdateClassroom(uint(classroomID), &req)
+	classroom, err := cc.classroomService.UpdateClassroom(c.Request.Context(), uint(classroomID), &req)
 	if err != nil {
-		if err.Error() == "classroom not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Classroom not found", err.Error()))
-			return
-		}
-		if err.Error() == "classroom with this name already exists in this school" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse("Classroom name already exists", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to update classroom", err.Error()))
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, response.SuccessResponse("Classroom updated successfully", classroom))
+	response.SetValidators(c, classroom.ETag(), classroom.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.classroom_updated", classroom))
 }
 
 // DeleteClassroom ลบห้องเรียน
@@ -133,18 +115,29 @@ func (cc *ClassroomController) DeleteClassroom(c *gin.Context) {
 	// Convert id from string to uint
 	classroomID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid classroom ID", err.Error()))
+		c.Error(errInvalidID("classroom_id"))
+		return
+	}
+
+	err = cc.classroomService.DeleteClassroom(c.Request.Context(), uint(classroomID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.classroom_deleted", nil))
+}
+
+func (cc *ClassroomController) RestoreClassroom(c *gin.Context) {
+	classroomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(errInvalidID("classroom_id"))
 		return
 	}
 
-	err = cc.classroomService.DeleteClassroom(uint(classroomID))
+	classroom, err := cc.classroomService.RestoreClassroom(c.Request.Context(), uint(classroomID))
 	if err != nil {
-		if err.Error() == "classroom not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Classroom not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to delete classroom", err.Error()))
+		c.Error(err)
 		return
 	}
-	c.JSON(http.StatusOK, response.SuccessResponse("Classroom deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.classroom_restored", classroom))
 }
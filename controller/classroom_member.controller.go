@@ -4,6 +4,7 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils/listquery"
 	"net/http"
 	"strconv"
 
@@ -14,20 +15,26 @@ type ClassroomMemberController struct {
 	classroomMemberService *services.ClassroomMemberService
 }
 
-func NewClassroomMemberController() *ClassroomMemberController {
+func NewClassroomMemberController(classroomMemberService *services.ClassroomMemberService) *ClassroomMemberController {
 	return &ClassroomMemberController{
-		classroomMemberService: services.NewClassroomMemberService(),
+		classroomMemberService: classroomMemberService,
 	}
 }
 
 func (cmc *ClassroomMemberController) GetAllClassroomMembers(c *gin.Context) {
-	members, err := cmc.classroomMemberService.GetAllClassroomMembers()
+	query, err := listquery.Parse(c.Request.URL.Query(), services.ClassroomMemberListing)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch classroom members", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Classroom members retrieved successfully", members))
+	members, meta, err := cmc.classroomMemberService.GetAllClassroomMembers(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.SuccessWithPaginate(c, "success.classroom_members_retrieved", members, meta)
 }
 
 func (cmc *ClassroomMemberController) GetClassroomMembersByClassroomID(c *gin.Context) {
@@ -36,37 +43,38 @@ func (cmc *ClassroomMemberController) GetClassroomMembersByClassroomID(c *gin.Co
 	// Convert string to uint
 	classroomID, err := strconv.ParseUint(classroomIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid classroom ID", "ID must be a valid number"))
+		c.Error(errInvalidID("classroom_id"))
 		return
 	}
-	members, err := cmc.classroomMemberService.GetClassroomMembersByClassroomID(uint(classroomID))
+	query, err := listquery.Parse(c.Request.URL.Query(), services.ClassroomMemberListing)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	members, meta, err := cmc.classroomMemberService.GetClassroomMembersByClassroomID(c.Request.Context(), uint(classroomID), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch classroom members", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Classroom members retrieved successfully", members))
+	response.SuccessWithPaginate(c, "success.classroom_members_retrieved", members, meta)
 }
 
 func (cmc *ClassroomMemberController) CreateClassroomMember(c *gin.Context) {
 	var req requests.ClassroomMemberCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	member, err := cmc.classroomMemberService.CreateClassroomMember(&req)
+	member, err := cmc.classroomMemberService.CreateClassroomMember(c.Request.Context(), &req)
 	if err != nil {
-		if err.Error() == "either teacher_id or student_id must be provided, but not both" ||
-			err.Error() == "member already exists in this classroom" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to create classroom member", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Classroom member created successfully", member))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.classroom_member_created", member))
 }
 
 func (cmc *ClassroomMemberController) UpdateClassroomMember(c *gin.Context) {
@@ -76,36 +84,28 @@ func (cmc *ClassroomMemberController) UpdateClassroomMember(c *gin.Context) {
 	// Convert string to uint
 	classroomID, err := strconv.ParseUint(classroomIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid classroom ID", "ID must be a valid number"))
+		c.Error(errInvalidID("classroom_id"))
 		return
 	}
 
 	memberID, err := strconv.ParseUint(memberIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid member ID", "ID must be a valid number"))
+		c.Error(errInvalidID("member_id"))
 		return
 	}
 
 	var req requests.ClassroomMemberUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
-	member, err := cmc.classroomMemberService.UpdateClassroomMember(uint(classroomID), uint(memberID), &req)
+	member, err := cmc.classroomMemberService.UpdateClassroomMember(c.Request.Context(), uint(classroomID), uint(memberID), &req)
 	if err != nil {
-		if err.Error() == "classroom member not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Classroom member not found", err.Error()))
-			return
-		}
-		if err.Error() == "invalid member ID format" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid member ID", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to update classroom member", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Classroom member updated successfully", member))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.classroom_member_updated", member))
 }
 
 func (cmc *ClassroomMemberController) DeleteClassroomMember(c *gin.Context) {
@@ -115,28 +115,20 @@ func (cmc *ClassroomMemberController) DeleteClassroomMember(c *gin.Context) {
 	// Convert string to uint
 	classroomID, err := strconv.ParseUint(classroomIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid classroom ID", "ID must be a valid number"))
+		c.Error(errInvalidID("classroom_id"))
 		return
 	}
 
 	memberID, err := strconv.ParseUint(memberIDStr, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid member ID", "ID must be a valid number"))
+		c.Error(errInvalidID("member_id"))
 		return
 	}
-	err = cmc.classroomMemberService.DeleteClassroomMember(uint(classroomID), uint(memberID))
+	err = cmc.classroomMemberService.DeleteClassroomMember(c.Request.Context(), uint(classroomID), uint(memberID))
 	if err != nil {
-		if err.Error() == "classroom member not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Classroom member not found", err.Error()))
-			return
-		}
-		if err.Error() == "invalid member ID format" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid member ID", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to delete classroom member", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Classroom member deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.classroom_member_deleted", nil))
 }
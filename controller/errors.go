@@ -0,0 +1,34 @@
+package controller
+
+import (
+	"context"
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/i18n"
+)
+
+// errInvalidRequest wraps a body or query binding failure; the error middleware lists
+// the failing fields
+func errInvalidRequest(err error) error {
+	return apperror.Validation("request.invalid", "request data is invalid").Wrap(err)
+}
+
+// errInvalidID reports a path or query parameter that is not a valid ID
+func errInvalidID(name string) error {
+	return apperror.Validation("request.invalid_id", name+" must be a valid number").With("name", name)
+}
+
+// errMissingID reports an empty ID path parameter
+func errMissingID(name string) error {
+	return apperror.Validation("request.missing_id", name+" is required").With("name", name)
+}
+
+var errClassroomForbidden = apperror.Forbidden("classroom.forbidden", "you do not have access to this classroom")
+
+// errorText renders a domain error in the request language, for channels that
+// cannot carry a problem+json body such as the roll-call socket
+func errorText(ctx context.Context, appErr *apperror.Error) string {
+	if text, ok := i18n.Lookup(i18n.FromContext(ctx), "error."+appErr.Code, appErr.Params); ok {
+		return text
+	}
+	return appErr.Message
+}
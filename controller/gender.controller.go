@@ -4,6 +4,7 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils/listquery"
 	"fmt"
 	"net/http"
 
@@ -14,20 +15,26 @@ type GenderController struct {
 	genderService *services.GenderService
 }
 
-func NewGenderController() *GenderController {
+func NewGenderController(genderService *services.GenderService) *GenderController {
 	return &GenderController{
-		genderService: services.NewGenderService(),
+		genderService: genderService,
 	}
 }
 
 func (gc *GenderController) GetAllGenders(c *gin.Context) {
-	genders, err := gc.genderService.GetAllGenders()
+	query, err := listquery.Parse(c.Request.URL.Query(), services.GenderListing)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch genders", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Genders retrieved successfully", genders))
+	genders, meta, err := gc.genderService.GetAllGenders(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.SuccessWithPaginate(c, "success.genders_retrieved", genders, meta)
 }
 
 func (gc *GenderController) GetGenderByID(c *gin.Context) {
@@ -35,71 +42,57 @@ func (gc *GenderController) GetGenderByID(c *gin.Context) {
 	// Convert id from string to uint
 	var genderID uint
 	if _, err := fmt.Sscanf(id, "%d", &genderID); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid gender ID", err.Error()))
+		c.Error(errInvalidID("gender_id"))
 		return
 	}
 
-	gender, err := gc.genderService.GetGenderByID(genderID)
+	gender, err := gc.genderService.GetGenderByID(c.Request.Context(), genderID)
 	if err != nil {
-		if err.Error() == "gender not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Gender not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch gender", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Gender retrieved successfully", gender))
+	response.SetValidators(c, gender.ETag(), gender.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.gender_retrieved", gender))
 }
 
 func (gc *GenderController) CreateGender(c *gin.Context) {
 	var req requests.GenderCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Invalid request body"))
+		c.Error(errInvalidRequest(err))
 		return
 	}
-	gender, err := gc.genderService.CreateGender(&req)
+	gender, err := gc.genderService.CreateGender(c.Request.Context(), &req)
 	if err != nil {
-		if err.Error() == "gender with this name already exists" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Duplicate gender name"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to create gender", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Gender created successfully", gender))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.gender_created", gender))
 }
 
 func (gc *GenderController) UpdateGender(c *gin.Context) {
 	id := c.Param("id")
 	var req requests.GenderUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Invalid request body"))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
 	// Convert id from string to uint
 	var genderID uint
 	if _, err := fmt.Sscanf(id, "%d", &genderID); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid gender ID", err.Error()))
+		c.Error(errInvalidID("gender_id"))
 		return
 	}
-	gender, err := gc.genderService.UpdateGender(genderID, &req)
+	gender, err := gc.genderService.UpdateGender(c.Request.Context(), genderID, &req)
 	if err != nil {
-		if err.Error() == "gender not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Gender not found", err.Error()))
-			return
-		}
-		if err.Error() == "gender with this name already exists" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Duplicate gender name"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to update gender", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Gender updated successfully", gender))
+	response.SetValidators(c, gender.ETag(), gender.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.gender_updated", gender))
 }
 
 func (gc *GenderController) DeleteGender(c *gin.Context) {
@@ -107,19 +100,15 @@ func (gc *GenderController) DeleteGender(c *gin.Context) {
 	// Convert id from string to uint
 	var genderID uint
 	if _, err := fmt.Sscanf(id, "%d", &genderID); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid gender ID", err.Error()))
+		c.Error(errInvalidID("gender_id"))
 		return
 	}
 
-	err := gc.genderService.DeleteGender(genderID)
+	err := gc.genderService.DeleteGender(c.Request.Context(), genderID)
 	if err != nil {
-		if err.Error() == "gender not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Gender not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to delete gender", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Gender deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.gender_deleted", nil))
 }
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"easy-attend-service/utils/buildinfo"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck is one dependency readiness depends on; Check returns nil when it is usable
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Worker is a background loop that must be running for the instance to be ready
+type Worker interface {
+	Running() bool
+}
+
+// WorkerCheck reports an error while the worker is stopped
+func WorkerCheck(name string, worker Worker) HealthCheck {
+	return HealthCheck{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			if !worker.Running() {
+				return errors.New("not running")
+			}
+			return nil
+		},
+	}
+}
+
+// HealthController ตอบ liveness/readiness probe สำหรับ Kubernetes
+type HealthController struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+// NewHealthController runs every check with the given timeout on each readiness probe
+func NewHealthController(timeout time.Duration, checks ...HealthCheck) *HealthController {
+	return &HealthController{checks: checks, timeout: timeout}
+}
+
+type checkResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Livez only tells the process is serving requests; it never touches dependencies
+// so a database outage does not get every pod restarted
+func (hc *HealthController) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"build":  buildinfo.Get(),
+	})
+}
+
+// Readyz runs the dependency checks concurrently and answers 503 when any fails
+func (hc *HealthController) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), hc.timeout)
+	defer cancel()
+
+	results := make(map[string]checkResult, len(hc.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range hc.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			started := time.Now()
+			err := check.Check(ctx)
+
+			result := checkResult{Status: "ok", Duration: time.Since(started).Round(time.Millisecond).String()}
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+			mu.Lock()
+			results[check.Name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	status, code := "ok", http.StatusOK
+	for _, result := range results {
+		if result.Status != "ok" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	c.JSON(code, gin.H{
+		"status": status,
+		"checks": results,
+		"build":  buildinfo.Get(),
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils/listquery"
 	"fmt"
 	"net/http"
 
@@ -15,20 +16,26 @@ type LogController struct {
 	logService *services.LogService
 }
 
-func NewLogController() *LogController {
+func NewLogController(logService *services.LogService) *LogController {
 	return &LogController{
-		logService: services.NewLogService(),
+		logService: logService,
 	}
 }
 
 func (lc *LogController) GetAllLogs(c *gin.Context) {
-	logs, err := lc.logService.GetAllLogs()
+	query, err := listquery.Parse(c.Request.URL.Query(), services.LogListing)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch logs", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Logs retrieved successfully", logs))
+	logs, meta, err := lc.logService.GetAllLogs(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.SuccessWithPaginate(c, "success.logs_retrieved", logs, meta)
 }
 
 func (lc *LogController) GetLogByID(c *gin.Context) {
@@ -36,58 +43,66 @@ func (lc *LogController) GetLogByID(c *gin.Context) {
 	// Convert id from string to uint
 	var logID uint
 	if _, err := fmt.Sscanf(id, "%d", &logID); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid log ID", err.Error()))
+		c.Error(errInvalidID("log_id"))
 		return
 	}
 
-	log, err := lc.logService.GetLogByID(logID)
+	log, err := lc.logService.GetLogByID(c.Request.Context(), logID)
 	if err != nil {
-		if err.Error() == "log not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Log not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch log", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Log retrieved successfully", log))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.log_retrieved", log))
 }
 
 func (lc *LogController) GetLogsByTeacher(c *gin.Context) {
-	teacherID := c.Param("teacher_id")
-	logs, err := lc.logService.GetLogsByTeacher(teacherID)
+	teacherID := c.Param("teacherId")
+	query, err := listquery.Parse(c.Request.URL.Query(), services.LogListing)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logs, meta, err := lc.logService.GetLogsByTeacher(c.Request.Context(), teacherID, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch logs", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Logs retrieved successfully", logs))
+	response.SuccessWithPaginate(c, "success.logs_retrieved", logs, meta)
 }
 
 func (lc *LogController) GetLogsByAction(c *gin.Context) {
-	actionParam := c.Param("action")
-	action := models.LogAction(actionParam)
-	logs, err := lc.logService.GetLogsByAction(action)
+	// The route is /logs/action?action=, the path has no parameter
+	action := models.LogAction(c.Query("action"))
+	query, err := listquery.Parse(c.Request.URL.Query(), services.LogListing)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	logs, meta, err := lc.logService.GetLogsByAction(c.Request.Context(), action, query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch logs", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Logs retrieved successfully", logs))
+	response.SuccessWithPaginate(c, "success.logs_retrieved", logs, meta)
 }
 
 func (lc *LogController) CreateLog(c *gin.Context) {
 	var req requests.LogCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	log, err := lc.logService.CreateLog(&req)
+	log, err := lc.logService.CreateLog(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to create log", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Log created successfully", log))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.log_created", log))
 }
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"easy-attend-service/utils/openapi"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIController serves the OpenAPI document of the routes registered on an engine
+type OpenAPIController struct {
+	engine *gin.Engine
+	spec   openapi.Spec
+
+	once     sync.Once
+	document *openapi.Document
+}
+
+func NewOpenAPIController(engine *gin.Engine, spec openapi.Spec) *OpenAPIController {
+	return &OpenAPIController{engine: engine, spec: spec}
+}
+
+// GetDocument returns the OpenAPI 3 document. It is built on the first request,
+// once every route has been registered.
+func (oc *OpenAPIController) GetDocument(c *gin.Context) {
+	oc.once.Do(func() {
+		oc.document = openapi.Build(oc.spec, oc.engine.Routes())
+	})
+	c.JSON(http.StatusOK, oc.document)
+}
+
+// GetSwaggerUI renders the document in Swagger UI
+func (oc *OpenAPIController) GetSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.SwaggerUI)
+}
@@ -4,6 +4,7 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils/listquery"
 	"fmt"
 	"net/http"
 
@@ -14,20 +15,26 @@ type PrefixController struct {
 	prefixService *services.PrefixService
 }
 
-func NewPrefixController() *PrefixController {
+func NewPrefixController(prefixService *services.PrefixService) *PrefixController {
 	return &PrefixController{
-		prefixService: services.NewPrefixService(),
+		prefixService: prefixService,
 	}
 }
 
 func (pc *PrefixController) GetAllPrefixes(c *gin.Context) {
-	prefixes, err := pc.prefixService.GetAllPrefixes()
+	query, err := listquery.Parse(c.Request.URL.Query(), services.PrefixListing)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch prefixes", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Prefixes retrieved successfully", prefixes))
+	prefixes, meta, err := pc.prefixService.GetAllPrefixes(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.SuccessWithPaginate(c, "success.prefixes_retrieved", prefixes, meta)
 }
 
 func (pc *PrefixController) GetPrefixByID(c *gin.Context) {
@@ -35,71 +42,57 @@ func (pc *PrefixController) GetPrefixByID(c *gin.Context) {
 	// Convert id from string to uint
 	var uintID uint
 	if _, err := fmt.Sscanf(id, "%d", &uintID); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid prefix ID", err.Error()))
+		c.Error(errInvalidID("prefix_id"))
 		return
 	}
 
-	prefix, err := pc.prefixService.GetPrefixByID(uintID)
+	prefix, err := pc.prefixService.GetPrefixByID(c.Request.Context(), uintID)
 	if err != nil {
-		if err.Error() == "prefix not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Prefix not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to fetch prefix", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Prefix retrieved successfully", prefix))
+	response.SetValidators(c, prefix.ETag(), prefix.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.prefix_retrieved", prefix))
 }
 
 func (pc *PrefixController) CreatePrefix(c *gin.Context) {
 	var req requests.PrefixCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Invalid request body"))
+		c.Error(errInvalidRequest(err))
 		return
 	}
-	prefix, err := pc.prefixService.CreatePrefix(&req)
+	prefix, err := pc.prefixService.CreatePrefix(c.Request.Context(), &req)
 	if err != nil {
-		if err.Error() == "prefix with this name already exists" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Duplicate prefix name"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to create prefix", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Prefix created successfully", prefix))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.prefix_created", prefix))
 }
 
 func (pc *PrefixController) UpdatePrefix(c *gin.Context) {
 	id := c.Param("id")
 	var req requests.PrefixUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Invalid request body"))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
 	// Convert id from string to uint
 	var uintID uint
 	if _, err := fmt.Sscanf(id, "%d", &uintID); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid prefix ID", err.Error()))
+		c.Error(errInvalidID("prefix_id"))
 		return
 	}
-	prefix, err := pc.prefixService.UpdatePrefix(uintID, &req)
+	prefix, err := pc.prefixService.UpdatePrefix(c.Request.Context(), uintID, &req)
 	if err != nil {
-		if err.Error() == "prefix not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Prefix not found", err.Error()))
-			return
-		}
-		if err.Error() == "prefix with this name already exists" {
-			c.JSON(http.StatusBadRequest, response.ErrorResponse(err.Error(), "Duplicate prefix name"))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to update prefix", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Prefix updated successfully", prefix))
+	response.SetValidators(c, prefix.ETag(), prefix.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.prefix_updated", prefix))
 }
 
 func (pc *PrefixController) DeletePrefix(c *gin.Context) {
@@ -107,19 +100,15 @@ func (pc *PrefixController) DeletePrefix(c *gin.Context) {
 	// Convert id from string to uint
 	var uintID uint
 	if _, err := fmt.Sscanf(id, "%d", &uintID); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid prefix ID", err.Error()))
+		c.Error(errInvalidID("prefix_id"))
 		return
 	}
 
-	err := pc.prefixService.DeletePrefix(uintID)
+	err := pc.prefixService.DeletePrefix(c.Request.Context(), uintID)
 	if err != nil {
-		if err.Error() == "prefix not found" {
-			c.JSON(http.StatusNotFound, response.ErrorResponse("Prefix not found", err.Error()))
-			return
-		}
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to delete prefix", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Prefix deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.prefix_deleted", nil))
 }
@@ -0,0 +1,344 @@
+package controller
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/requests"
+	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/realtime"
+	"easy-attend-service/utils/tracing"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	rollCallWriteWait  = 10 * time.Second
+	rollCallPongWait   = 60 * time.Second
+	rollCallPingPeriod = (rollCallPongWait * 9) / 10
+	rollCallMaxMessage = 4096
+)
+
+// rollCallMessage is the envelope for every message on the roll-call socket
+type rollCallMessage struct {
+	Type         string                        `json:"type"`
+	StudentID    *uint                         `json:"student_id,omitempty"`
+	Mark         *requests.RollCallMarkRequest `json:"mark,omitempty"`
+	Attendance   *models.Attendance            `json:"attendance,omitempty"`
+	Attendances  []models.Attendance           `json:"attendances,omitempty"`
+	Participants []realtime.Participant        `json:"participants,omitempty"`
+	By           *realtime.Participant         `json:"by,omitempty"`
+	Message      string                        `json:"message,omitempty"`
+	Code         string                        `json:"code,omitempty"` // stable error code of conflict and error messages
+}
+
+// RollCallController ให้ครูหลายคนเช็คชื่อห้องเดียวกันพร้อมกันผ่าน WebSocket
+type RollCallController struct {
+	attendanceService *services.AttendanceService
+	classroomService  *services.ClassroomService
+	teacherService    *services.TeacherService
+	upgrader          websocket.Upgrader
+}
+
+// NewRollCallController accepts sockets from the same host and from allowOrigins,
+// the CORS allow-list ("*" for any origin)
+func NewRollCallController(attendanceService *services.AttendanceService, classroomService *services.ClassroomService, teacherService *services.TeacherService, allowOrigins []string) *RollCallController {
+	return &RollCallController{
+		attendanceService: attendanceService,
+		classroomService:  classroomService,
+		teacherService:    teacherService,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     checkOrigin(allowOrigins),
+		},
+	}
+}
+
+// checkOrigin stops other sites from opening a socket with a teacher's ticket.
+// Requests without an Origin header come from native clients, not browsers.
+func checkOrigin(allowOrigins []string) func(r *http.Request) bool {
+	allowAll := slices.Contains(allowOrigins, "*")
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowAll {
+			return true
+		}
+		for _, allowed := range allowOrigins {
+			if strings.EqualFold(origin, allowed) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// Connect upgrades to a WebSocket joined to the classroom's roll-call session for the given date
+func (rc *RollCallController) Connect(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	classroomID64, err := strconv.ParseUint(c.Param("classroom_id"), 10, 32)
+	if err != nil {
+		c.Error(errInvalidID("classroom_id"))
+		return
+	}
+	classroomID := uint(classroomID64)
+
+	sessionDate := c.DefaultQuery("session_date", time.Now().Format("2006-01-02"))
+	if _, err := time.Parse("2006-01-02", sessionDate); err != nil {
+		c.Error(apperror.Validation("request.invalid_date", "session_date must be YYYY-MM-DD"))
+		return
+	}
+
+	classroomIDs, err := rc.classroomService.GetAccessibleClassroomIDs(c.Request.Context(), teacherID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	allowed := false
+	for _, id := range classroomIDs {
+		if id == classroomID {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		c.Error(errClassroomForbidden)
+		return
+	}
+
+	teacher, err := rc.teacherService.GetTeacherByID(c.Request.Context(), teacherID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Subscribe before reading the snapshot so no mark made in between is missed;
+	// REST and offline sync marks reach the room through the same hub
+	sub, _, _ := realtime.Attendance.Subscribe(func(id uint) bool { return id == classroomID }, 0)
+	defer realtime.Attendance.Unsubscribe(sub)
+
+	attendances, err := rc.attendanceService.GetAttendancesBySession(c.Request.Context(), classroomID, sessionDate)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	conn, err := rc.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already written the HTTP error
+		return
+	}
+
+	participant := &realtime.Participant{
+		ClientID:  uuid.NewString(),
+		TeacherID: teacherID,
+		Name:      strings.TrimSpace(teacher.FirstName + " " + teacher.LastName),
+		JoinedAt:  time.Now().Unix(),
+		Send:      make(chan []byte, 32),
+	}
+	// Immutable identity used in outgoing messages, the shared participant is mutated by the room
+	self := realtime.Participant{
+		ClientID:  participant.ClientID,
+		TeacherID: participant.TeacherID,
+		Name:      participant.Name,
+		JoinedAt:  participant.JoinedAt,
+	}
+	room := realtime.RollCall.Join(fmt.Sprintf("%d:%s", classroomID, sessionDate), participant)
+
+	logger.LogInfo(c.Request.Context(), "Roll call participant joined", logrus.Fields{
+		"classroom_id": fmt.Sprintf("%d", classroomID),
+		"session_date": sessionDate,
+		"teacher_id":   fmt.Sprintf("%d", teacherID),
+		"client_id":    participant.ClientID,
+	})
+
+	go rollCallWriter(conn, participant.Send)
+
+	rc.send(room, participant, rollCallMessage{
+		Type:         "snapshot",
+		Attendances:  attendances,
+		Participants: room.Participants(),
+		By:           &self,
+	})
+	rc.broadcastPresence(room)
+	go rc.relay(room, &self, sub, sessionDate)
+
+	rc.readLoop(c.Request.Context(), conn, room, &self, classroomID, sessionDate)
+
+	realtime.RollCall.Leave(room, self.ClientID)
+	rc.broadcastPresence(room)
+
+	logger.LogInfo(c.Request.Context(), "Roll call participant left", logrus.Fields{
+		"classroom_id": fmt.Sprintf("%d", classroomID),
+		"teacher_id":   fmt.Sprintf("%d", teacherID),
+		"client_id":    self.ClientID,
+	})
+}
+
+// readLoop handles incoming messages until the client disconnects
+func (rc *RollCallController) readLoop(ctx context.Context, conn *websocket.Conn, room *realtime.Room, participant *realtime.Participant, classroomID uint, sessionDate string) {
+	conn.SetReadLimit(rollCallMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(rollCallPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(rollCallPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg rollCallMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			rc.send(room, participant, rollCallMessage{Type: "error", Message: "invalid message"})
+			continue
+		}
+
+		switch msg.Type {
+		case "editing":
+			room.SetEditing(participant.ClientID, msg.StudentID)
+			rc.broadcastPresence(room)
+
+		case "idle":
+			room.SetEditing(participant.ClientID, nil)
+			rc.broadcastPresence(room)
+
+		case "mark":
+			if msg.Mark == nil || msg.Mark.StudentID == 0 {
+				rc.send(room, participant, rollCallMessage{Type: "error", Message: "mark requires student_id and status"})
+				continue
+			}
+
+			// Each mark is its own trace, linked to the long-lived connection span
+			markCtx, span := tracing.Start(ctx, "RollCall.mark", trace.WithNewRoot(), trace.WithLinks(trace.LinkFromContext(ctx)))
+			_, err := rc.attendanceService.MarkAttendance(markCtx, classroomID, sessionDate, participant.TeacherID, msg.Mark)
+			span.End()
+			if err != nil {
+				var conflict *services.AttendanceConflictError
+				if errors.As(err, &conflict) {
+					// Only the writer learns about the conflict, together with the current state
+					rc.send(room, participant, rollCallMessage{
+						Type:       "conflict",
+						StudentID:  &msg.Mark.StudentID,
+						Attendance: conflict.Current,
+						Message:    errorText(ctx, apperror.From(err)),
+						Code:       apperror.From(err).Code,
+					})
+					continue
+				}
+				appErr := apperror.From(err)
+				rc.send(room, participant, rollCallMessage{Type: "error", StudentID: &msg.Mark.StudentID, Message: errorText(ctx, appErr), Code: appErr.Code})
+				continue
+			}
+
+			// Everyone, the writer included, gets the "marked" message from relay
+			room.SetEditing(participant.ClientID, nil)
+			rc.broadcastPresence(room)
+
+		default:
+			rc.send(room, participant, rollCallMessage{Type: "error", Message: "unknown message type"})
+		}
+	}
+}
+
+// relay forwards attendance events of the room's session date to one participant,
+// whichever way the mark was written. When the hub drops the subscription for falling
+// behind, the participant leaves so the client reconnects and gets a fresh snapshot.
+func (rc *RollCallController) relay(room *realtime.Room, participant *realtime.Participant, sub *realtime.Subscription, sessionDate string) {
+	for event := range sub.C {
+		var attendance *models.Attendance
+		switch data := event.Data.(type) {
+		case models.Attendance:
+			attendance = &data
+		case *models.Attendance:
+			attendance = data
+		}
+		if attendance == nil || !strings.HasPrefix(attendance.SessionDate, sessionDate) {
+			continue
+		}
+
+		msg := rollCallMessage{Type: "marked", Attendance: attendance}
+		if attendance.TeacherID != nil {
+			for _, p := range room.Participants() {
+				if p.TeacherID == *attendance.TeacherID {
+					msg.By = &p
+					break
+				}
+			}
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+		if !room.SendTo(participant.ClientID, data) {
+			return
+		}
+	}
+	realtime.RollCall.Leave(room, participant.ClientID)
+}
+
+func (rc *RollCallController) send(room *realtime.Room, participant *realtime.Participant, msg rollCallMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	room.SendTo(participant.ClientID, data)
+}
+
+func (rc *RollCallController) broadcastPresence(room *realtime.Room) {
+	data, err := json.Marshal(rollCallMessage{Type: "presence", Participants: room.Participants()})
+	if err != nil {
+		return
+	}
+	room.Broadcast(data, "")
+}
+
+// rollCallWriter owns all writes to the connection and keeps it alive with pings
+func rollCallWriter(conn *websocket.Conn, send <-chan []byte) {
+	ticker := time.NewTicker(rollCallPingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-send:
+			conn.SetWriteDeadline(time.Now().Add(rollCallWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(rollCallWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/listquery"
 	"net/http"
 	"strconv"
 
@@ -14,155 +16,131 @@ type SchoolController struct {
 	schoolService *services.SchoolService
 }
 
-func NewSchoolController() *SchoolController {
+func NewSchoolController(schoolService *services.SchoolService) *SchoolController {
 	return &SchoolController{
-		schoolService: services.NewSchoolService(),
+		schoolService: schoolService,
 	}
 }
 
 func (sc *SchoolController) GetAllSchools(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
-	}
-
-	schools, total, err := sc.schoolService.GetAllSchools(page, limit)
+	query, err := listquery.Parse(c.Request.URL.Query(), services.SchoolListing)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to get schools", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	result := gin.H{
-		"schools": schools,
-		"pagination": gin.H{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": (total + int64(limit) - 1) / int64(limit),
-		},
+	schools, meta, err := sc.schoolService.GetAllSchools(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Schools retrieved successfully", result))
+	response.SuccessWithPaginate(c, "success.schools_retrieved", schools, meta)
 }
 
 func (sc *SchoolController) GetSchoolByID(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "School ID is required"))
+		c.Error(errMissingID("school_id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "School ID must be a valid number"))
+		c.Error(errInvalidID("school_id"))
 		return
 	}
 
-	school, err := sc.schoolService.GetSchoolByID(uint(id))
+	school, err := sc.schoolService.GetSchoolByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, response.ErrorResponse("School not found", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("School retrieved successfully", school))
+	response.SetValidators(c, school.ETag(), school.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.school_retrieved", school))
 }
 
 func (sc *SchoolController) CreateSchool(c *gin.Context) {
 	var req requests.SchoolCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	school, err := sc.schoolService.CreateSchool(req.Name)
+	school, err := sc.schoolService.CreateSchool(c.Request.Context(), req.Name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to create school", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("School created successfully", school))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.school_created", school))
 }
 
 func (sc *SchoolController) UpdateSchool(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "School ID is required"))
+		c.Error(errMissingID("school_id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "School ID must be a valid number"))
+		c.Error(errInvalidID("school_id"))
 		return
 	}
 
 	var req requests.SchoolUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	school, err := sc.schoolService.UpdateSchool(uint(id), req.Name)
+	school, err := sc.schoolService.UpdateSchool(c.Request.Context(), uint(id), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to update school", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("School updated successfully", school))
+	response.SetValidators(c, school.ETag(), school.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.school_updated", school))
 }
 
 func (sc *SchoolController) DeleteSchool(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "School ID is required"))
+		c.Error(errMissingID("school_id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "School ID must be a valid number"))
+		c.Error(errInvalidID("school_id"))
 		return
 	}
 
-	if err := sc.schoolService.DeleteSchool(uint(id)); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to delete school", err.Error()))
+	if err := sc.schoolService.DeleteSchool(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("School deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.school_deleted", nil))
 }
 
 // GetTeacherSchool gets the school information for the authenticated teacher
 func (sc *SchoolController) GetTeacherSchool(c *gin.Context) {
 	// Get teacher ID from JWT context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "User ID not found in token"))
-		return
-	}
-
-	teacherIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "Invalid user ID format"))
-		return
-	}
-
-	teacherID, err := strconv.ParseUint(teacherIDStr, 10, 64)
+	teacherID, err := utils.GetTeacherIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid teacher ID", "Teacher ID must be a valid number"))
+		c.Error(err)
 		return
 	}
 
-	school, err := sc.schoolService.GetSchoolByTeacher(uint(teacherID))
+	school, err := sc.schoolService.GetSchoolByTeacher(c.Request.Context(), teacherID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to get teacher school", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Teacher school retrieved successfully", school))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.teacher_school_retrieved", school))
 }
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/realtime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps proxies and load balancers from closing idle streams
+const heartbeatInterval = 15 * time.Second
+
+// StreamController ส่งข้อมูลแบบ real-time ผ่าน Server-Sent Events
+type StreamController struct {
+	classroomService *services.ClassroomService
+}
+
+func NewStreamController(classroomService *services.ClassroomService) *StreamController {
+	return &StreamController{
+		classroomService: classroomService,
+	}
+}
+
+// StreamAttendance streams attendance create/update events for the classrooms the caller may see
+func (sc *StreamController) StreamAttendance(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	classroomIDs, err := sc.classroomService.GetAccessibleClassroomIDs(c.Request.Context(), teacherID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	allowed := make(map[uint]bool, len(classroomIDs))
+	for _, id := range classroomIDs {
+		allowed[id] = true
+	}
+
+	// Narrow the stream to one classroom when requested
+	if classroomIDStr := c.Query("classroom_id"); classroomIDStr != "" {
+		classroomID, err := strconv.ParseUint(classroomIDStr, 10, 32)
+		if err != nil {
+			c.Error(errInvalidID("classroom_id"))
+			return
+		}
+		if !allowed[uint(classroomID)] {
+			c.Error(errClassroomForbidden)
+			return
+		}
+		allowed = map[uint]bool{uint(classroomID): true}
+	}
+
+	// Resume from the last event the client saw (header set by EventSource on reconnect)
+	lastEventIDStr := c.GetHeader("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.Query("last_event_id")
+	}
+	lastEventID, _ := strconv.ParseUint(lastEventIDStr, 10, 64)
+
+	sub, replay, complete := realtime.Attendance.Subscribe(func(classroomID uint) bool {
+		return allowed[classroomID]
+	}, lastEventID)
+	defer realtime.Attendance.Unsubscribe(sub)
+
+	// The stream outlives the server's write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.Render(-1, sse.Event{Event: "ready", Retry: 3000, Data: gin.H{"classroom_ids": classroomIDs}})
+	if !complete {
+		// Events were missed, so the client must reload its state before applying new ones
+		c.Render(-1, sse.Event{Event: "resync", Data: gin.H{"last_event_id": lastEventID}})
+	}
+	for _, event := range replay {
+		renderEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with Last-Event-ID
+				return
+			}
+			renderEvent(c, event)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			c.Render(-1, sse.Event{Event: "heartbeat", Data: gin.H{"time": time.Now().Unix()}})
+			c.Writer.Flush()
+		}
+	}
+}
+
+func renderEvent(c *gin.Context, event realtime.Event) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: event.Type,
+		Data:  event,
+	})
+}
@@ -4,6 +4,9 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/listquery"
 	"net/http"
 	"strconv"
 
@@ -14,97 +17,75 @@ type StudentController struct {
 	studentService *services.StudentService
 }
 
-func NewStudentController() *StudentController {
+func NewStudentController(studentService *services.StudentService) *StudentController {
 	return &StudentController{
-		studentService: services.NewStudentService(),
+		studentService: studentService,
 	}
 }
 
 func (sc *StudentController) GetAllStudents(c *gin.Context) {
 	// Get teacher ID from JWT context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "User ID not found in token"))
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	teacherIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "Invalid user ID format"))
+	query, err := listquery.Parse(c.Request.URL.Query(), services.StudentListing)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	teacherID, err := strconv.ParseUint(teacherIDStr, 10, 64)
+	selection, err := fieldset.Parse(c.Request.URL.Query(), services.StudentFields)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid teacher ID", "Teacher ID must be a valid number"))
+		c.Error(err)
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
-	}
-
 	// Get students for this teacher only
-	students, total, err := sc.studentService.GetStudentsByTeacherPaginated(uint(teacherID), page, limit)
+	students, meta, err := sc.studentService.GetStudentsByTeacher(c.Request.Context(), teacherID, query, selection)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to get students", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	result := gin.H{
-		"students": students,
-		"pagination": gin.H{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": (total + int64(limit) - 1) / int64(limit),
-		},
-	}
-
-	c.JSON(http.StatusOK, response.SuccessResponse("Students retrieved successfully", result))
+	response.SuccessWithPaginate(c, "success.students_retrieved", selection.Render(students), meta)
 }
 
 func (sc *StudentController) GetStudentByID(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "Student ID is required"))
+		c.Error(errMissingID("student_id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid student ID", "Student ID must be a positive integer"))
+		c.Error(errInvalidID("student_id"))
+		return
+	}
+
+	selection, err := fieldset.Parse(c.Request.URL.Query(), services.StudentFields)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	student, err := sc.studentService.GetStudentByID(uint(id))
+	student, err := sc.studentService.GetStudentByID(c.Request.Context(), uint(id), selection)
 	if err != nil {
-		c.JSON(http.StatusNotFound, response.ErrorResponse("Student not found", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Student retrieved successfully", student))
+	response.SetValidators(c, student.ETag(), student.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.student_retrieved", selection.Render(student)))
 }
 
 func (sc *StudentController) CreateStudent(c *gin.Context) {
-	type StudentRequest struct {
-		Firstname  string `json:"firstname" binding:"required"`
-		Lastname   string `json:"lastname" binding:"required"`
-		SchoolName string `json:"school_name" binding:"required"`
-		StudentNo  string `json:"student_no"` // Optional, will auto-generate if empty
-		GenderID   *uint  `json:"gender_id"`
-		PrefixID   *uint  `json:"prefix_id"`
-	}
-
-	var req StudentRequest
+	var req requests.StudentQuickCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
@@ -112,78 +93,86 @@ func (sc *StudentController) CreateStudent(c *gin.Context) {
 	if req.StudentNo != "" {
 		studentNoPtr = &req.StudentNo
 	}
-	student, err := sc.studentService.TestCreateStudent(&req.SchoolName, &req.Firstname, &req.Lastname, studentNoPtr, req.GenderID, req.PrefixID)
+	student, err := sc.studentService.TestCreateStudent(c.Request.Context(), &req.SchoolName, &req.Firstname, &req.Lastname, studentNoPtr, req.GenderID, req.PrefixID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to create student", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Student created successfully", student))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.student_created", student))
 }
 
 func (sc *StudentController) UpdateStudent(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "Student ID is required"))
+		c.Error(errMissingID("student_id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid student ID", "Student ID must be a positive integer"))
+		c.Error(errInvalidID("student_id"))
 		return
 	}
 
 	var req requests.StudentUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	student, err := sc.studentService.UpdateStudent(uint(id), &req)
+	student, err := sc.studentService.UpdateStudent(c.Request.Context(), uint(id), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to update student", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Student updated successfully", student))
+	response.SetValidators(c, student.ETag(), student.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.student_updated", student))
 }
 
 func (sc *StudentController) DeleteStudent(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "Student ID is required"))
+		c.Error(errMissingID("student_id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid student ID", "Student ID must be a positive integer"))
+		c.Error(errInvalidID("student_id"))
 		return
 	}
 
-	if err := sc.studentService.DeleteStudent(uint(id)); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to delete student", err.Error()))
+	if err := sc.studentService.DeleteStudent(c.Request.Context(), uint(id)); err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Student deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.student_deleted", nil))
 }
 
-// TestCreateStudent creates a student with auto-generated classroom for testing
-func (sc *StudentController) TestCreateStudent(c *gin.Context) {
-	type TestStudentRequest struct {
-		Firstname  string `json:"firstname" binding:"required"`
-		Lastname   string `json:"lastname" binding:"required"`
-		SchoolName string `json:"school_name" binding:"required"`
-		StudentNo  string `json:"student_no"` // Optional, will auto-generate if empty
-		GenderID   *uint  `json:"gender_id"`
-		PrefixID   *uint  `json:"prefix_id"`
+func (sc *StudentController) RestoreStudent(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(errInvalidID("student_id"))
+		return
 	}
 
-	var req TestStudentRequest
+	student, err := sc.studentService.RestoreStudent(c.Request.Context(), uint(id))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.student_restored", student))
+}
+
+// TestCreateStudent creates a student with auto-generated classroom for testing
+func (sc *StudentController) TestCreateStudent(c *gin.Context) {
+	var req requests.StudentQuickCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
@@ -191,11 +180,11 @@ func (sc *StudentController) TestCreateStudent(c *gin.Context) {
 	if req.StudentNo != "" {
 		studentNoPtr = &req.StudentNo
 	}
-	student, err := sc.studentService.TestCreateStudent(&req.SchoolName, &req.Firstname, &req.Lastname, studentNoPtr, req.GenderID, req.PrefixID)
+	student, err := sc.studentService.TestCreateStudent(c.Request.Context(), &req.SchoolName, &req.Firstname, &req.Lastname, studentNoPtr, req.GenderID, req.PrefixID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to create student", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Student created successfully", student))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.student_created", student))
 }
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"easy-attend-service/requests"
+	"easy-attend-service/response"
+	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SyncController รับคิวการเช็คชื่อจากแอปมือถือที่ทำงานแบบออฟไลน์
+type SyncController struct {
+	syncService      *services.SyncService
+	classroomService *services.ClassroomService
+}
+
+func NewSyncController(syncService *services.SyncService, classroomService *services.ClassroomService) *SyncController {
+	return &SyncController{
+		syncService:      syncService,
+		classroomService: classroomService,
+	}
+}
+
+// SyncAttendances applies a batch of offline mutations and returns the server changes since the cursor
+func (sc *SyncController) SyncAttendances(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req requests.AttendanceSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errInvalidRequest(err))
+		return
+	}
+
+	classroomIDs, err := sc.classroomService.GetAccessibleClassroomIDs(c.Request.Context(), teacherID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	result, err := sc.syncService.SyncAttendances(c.Request.Context(), teacherID, classroomIDs, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.attendances_synced", result))
+}
+
+// GetAttendanceChanges pulls the change feed without pushing anything
+func (sc *SyncController) GetAttendanceChanges(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req requests.AttendanceChangesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(errInvalidRequest(err))
+		return
+	}
+
+	classroomIDs, err := sc.classroomService.GetAccessibleClassroomIDs(c.Request.Context(), teacherID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	changes, err := sc.syncService.GetAttendanceChanges(c.Request.Context(), classroomIDs, req.Cursor, req.Limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.changes_retrieved", changes))
+}
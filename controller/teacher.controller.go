@@ -4,6 +4,8 @@ import (
 	"easy-attend-service/requests"
 	"easy-attend-service/response"
 	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/listquery"
 	"net/http"
 	"strconv"
 
@@ -14,143 +16,129 @@ type TeacherController struct {
 	teacherService *services.TeacherService
 }
 
-func NewTeacherController() *TeacherController {
+func NewTeacherController(teacherService *services.TeacherService) *TeacherController {
 	return &TeacherController{
-		teacherService: services.NewTeacherService(),
+		teacherService: teacherService,
 	}
 }
 
 func (tc *TeacherController) GetAllTeachers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
-	}
-
-	teachers, total, err := tc.teacherService.GetAllTeachers(page, limit)
+	query, err := listquery.Parse(c.Request.URL.Query(), services.TeacherListing)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, response.ErrorResponse("Failed to get teachers", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	result := gin.H{
-		"teachers": teachers,
-		"pagination": gin.H{
-			"page":        page,
-			"limit":       limit,
-			"total":       total,
-			"total_pages": (total + int64(limit) - 1) / int64(limit),
-		},
+	teachers, meta, err := tc.teacherService.GetAllTeachers(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Teachers retrieved successfully", result))
+	response.SuccessWithPaginate(c, "success.teachers_retrieved", teachers, meta)
 }
 
 // GetTeacherInfo gets comprehensive information for the authenticated teacher
 func (tc *TeacherController) GetTeacherInfo(c *gin.Context) {
 	// Get teacher ID from JWT context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "User ID not found in token"))
-		return
-	}
-
-	teacherIDStr, ok := userID.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, response.ErrorResponse("Unauthorized", "Invalid user ID format"))
-		return
-	}
-
-	teacherID, err := strconv.ParseUint(teacherIDStr, 10, 64)
+	teacherID, err := utils.GetTeacherIDFromContext(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid teacher ID", "Teacher ID must be a valid number"))
+		c.Error(err)
 		return
 	}
 
-	info, err := tc.teacherService.GetTeacherInfo(uint(teacherID))
+	info, err := tc.teacherService.GetTeacherInfo(c.Request.Context(), teacherID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, response.ErrorResponse("Teacher info not found", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Teacher info retrieved successfully", info))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.teacher_info_retrieved", info))
 }
 
 func (tc *TeacherController) GetTeacherByID(c *gin.Context) {
 	idStr := c.Param("id")
 	if idStr == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "Teacher ID is required"))
+		c.Error(errMissingID("teacher_id"))
 		return
 	}
 
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid teacher ID", "Teacher ID must be a positive integer"))
+		c.Error(errInvalidID("teacher_id"))
 		return
 	}
 
-	teacher, err := tc.teacherService.GetTeacherByID(uint(id))
+	teacher, err := tc.teacherService.GetTeacherByID(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, response.ErrorResponse("Teacher not found", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Teacher retrieved successfully", teacher))
+	response.SetValidators(c, teacher.ETag(), teacher.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.teacher_retrieved", teacher))
 }
 
 func (tc *TeacherController) CreateTeacher(c *gin.Context) {
 	var req requests.TeacherCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	teacher, err := tc.teacherService.CreateTeacher(&req)
+	teacher, err := tc.teacherService.CreateTeacher(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to create teacher", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, response.SuccessResponse("Teacher created successfully", teacher))
+	c.JSON(http.StatusCreated, response.SuccessResponse(c, "success.teacher_created", teacher))
 }
 
 func (tc *TeacherController) UpdateTeacher(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "Teacher ID is required"))
+		c.Error(errMissingID("teacher_id"))
 		return
 	}
 
 	var req requests.TeacherUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request data", err.Error()))
+		c.Error(errInvalidRequest(err))
 		return
 	}
 
-	teacher, err := tc.teacherService.UpdateTeacher(id, &req)
+	teacher, err := tc.teacherService.UpdateTeacher(c.Request.Context(), id, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to update teacher", err.Error()))
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Teacher updated successfully", teacher))
+	response.SetValidators(c, teacher.ETag(), teacher.UpdatedAt)
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.teacher_updated", teacher))
 }
 
 func (tc *TeacherController) DeleteTeacher(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Invalid request", "Teacher ID is required"))
+		c.Error(errMissingID("teacher_id"))
 		return
 	}
 
-	if err := tc.teacherService.DeleteTeacher(id); err != nil {
-		c.JSON(http.StatusBadRequest, response.ErrorResponse("Failed to delete teacher", err.Error()))
+	if err := tc.teacherService.DeleteTeacher(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.teacher_deleted", nil))
+}
+
+func (tc *TeacherController) RestoreTeacher(c *gin.Context) {
+	teacher, err := tc.teacherService.RestoreTeacher(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, response.SuccessResponse("Teacher deleted successfully", nil))
+	c.JSON(http.StatusOK, response.SuccessResponse(c, "success.teacher_restored", teacher))
 }
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"easy-attend-service/requests"
+	"easy-attend-service/response"
+	"easy-attend-service/services"
+	"easy-attend-service/utils"
+	"easy-attend-service/utils/listquery"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashController แสดงข้อมูลที่ถูกลบ ซึ่งยังกู้คืนได้จนกว่าจะถูก purge
+type TrashController struct {
+	trashService *services.TrashService
+}
+
+func NewTrashController(trashService *services.TrashService) *TrashController {
+	return &TrashController{
+		trashService: trashService,
+	}
+}
+
+// GetTrash lists the deleted records the teacher can restore
+func (tc *TrashController) GetTrash(c *gin.Context) {
+	teacherID, err := utils.GetTeacherIDFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var req requests.TrashListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(errInvalidRequest(err))
+		return
+	}
+
+	query, err := listquery.Parse(c.Request.URL.Query(), services.TrashListing)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	items, meta, err := tc.trashService.GetTrash(c.Request.Context(), teacherID, req.Type, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response.SuccessWithPaginate(c, "success.trash_retrieved", items, meta)
+}
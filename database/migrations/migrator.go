@@ -0,0 +1,365 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+// migrationFilePattern matches files like 0001_baseline.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrIrreversible is returned when a rollback would revert the baseline, which
+// creates the schema: undoing it would drop every table and its data
+var ErrIrreversible = errors.New("the baseline migration cannot be rolled back")
+
+// migrationLockKey is the advisory lock held while migrating so replicas cannot migrate at once
+const migrationLockKey = "easy-attend:schema_migrations"
+
+// Migration is one numbered schema change with its rollback
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script, detects edits after it was applied
+}
+
+// SchemaMigration records an applied migration
+type SchemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"type:varchar(255);not null"`
+	Checksum  string `gorm:"type:char(64);not null"`
+	AppliedAt int64  `gorm:"not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus describes a migration as seen from both the files and the database
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt int64
+	Modified  bool // Applied with a different checksum than the file now has
+	Missing   bool // Applied but the file is no longer part of this build
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the embedded migration files
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(sqlFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Up applies up to steps pending migrations in version order, all of them when steps is 0
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		var err error
+		applied, err = m.up(conn, steps)
+		return err
+	})
+	return applied, err
+}
+
+// Down rolls back the last steps applied migrations, newest first. It never goes
+// below the baseline: nothing is rolled back when steps would reach it.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		var err error
+		rolledBack, err = m.down(conn, steps)
+		return err
+	})
+	return rolledBack, err
+}
+
+// Redo rolls back the last applied migration and applies it again
+func (m *Migrator) Redo() (*Migration, error) {
+	var redone *Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		rolledBack, err := m.down(conn, 1)
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			return errors.New("no applied migration to redo")
+		}
+		if _, err := m.up(conn, 1); err != nil {
+			return err
+		}
+		redone = &rolledBack[0]
+		return nil
+	})
+	return redone, err
+}
+
+// Status lists every known migration plus applied versions that have no file
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	if err := m.ensureTable(m.db); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+			status.Modified = record.Checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+	for version, record := range applied {
+		if !known[version] {
+			statuses = append(statuses, MigrationStatus{
+				Version:   version,
+				Name:      record.Name,
+				Applied:   true,
+				AppliedAt: record.AppliedAt,
+				Missing:   true,
+			})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// LatestVersion is the newest migration in this build, the version a ready database is at
+func (m *Migrator) LatestVersion() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Verify reports an error unless every migration in this build is applied unmodified.
+// It only reads schema_migrations, so it is cheap enough for readiness probes.
+func (m *Migrator) Verify(ctx context.Context) error {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		if !ok {
+			return fmt.Errorf("migration %04d_%s is pending (expected version %d)", migration.Version, migration.Name, m.LatestVersion())
+		}
+		if record.Checksum != migration.Checksum {
+			return fmt.Errorf("migration %04d_%s was modified after it was applied", migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) up(conn *gorm.DB, steps int) ([]Migration, error) {
+	applied, err := m.applied(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse to build on top of a migration whose file was edited after it ran
+	for _, migration := range m.migrations {
+		if record, ok := applied[migration.Version]; ok && record.Checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %04d_%s was modified after it was applied (checksum mismatch)", migration.Version, migration.Name)
+		}
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		if err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: time.Now().Unix(),
+			}).Error
+		}); err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func (m *Migrator) down(conn *gorm.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var records []SchemaMigration
+	if err := conn.Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	rollback, err := m.rollbackPlan(records)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range rollback {
+		if err := conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+		}); err != nil {
+			return done, fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// rollbackPlan returns the migrations to revert for the applied records, newest
+// first, or an error before anything runs when one of them cannot be reverted
+func (m *Migrator) rollbackPlan(records []SchemaMigration) ([]Migration, error) {
+	byVersion := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	plan := make([]Migration, 0, len(records))
+	for i, record := range records {
+		if len(m.migrations) > 0 && record.Version <= m.migrations[0].Version {
+			return nil, fmt.Errorf("%w: %04d_%s creates the schema, at most %d migrations can be rolled back",
+				ErrIrreversible, record.Version, record.Name, i)
+		}
+		migration, ok := byVersion[record.Version]
+		if !ok {
+			return nil, fmt.Errorf("migration %04d_%s is applied but its file is missing", record.Version, record.Name)
+		}
+		if migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no down script", migration.Version, migration.Name)
+		}
+		plan = append(plan, migration)
+	}
+	return plan, nil
+}
+
+// withLock runs fn on a single connection that holds the migration advisory lock
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(hashtext(?))", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(hashtext(?))", migrationLockKey)
+
+		if err := m.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) ensureTable(conn *gorm.DB) error {
+	return conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       varchar(255) NOT NULL,
+		checksum   char(64) NOT NULL,
+		applied_at bigint NOT NULL
+	)`).Error
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[int64]SchemaMigration, error) {
+	var records []SchemaMigration
+	if err := conn.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// loadMigrations pairs up/down files by version and sorts them
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		content, err := fs.ReadFile(fsys, "sql/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
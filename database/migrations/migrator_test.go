@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"errors"
+	"testing"
+)
+
+// appliedUpTo is what schema_migrations holds after migrating to version, newest
+// first the way down reads it
+func appliedUpTo(migrations []Migration, version int64) []SchemaMigration {
+	var records []SchemaMigration
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if migrations[i].Version <= version {
+			records = append(records, SchemaMigration{Version: migrations[i].Version, Name: migrations[i].Name, Checksum: migrations[i].Checksum})
+		}
+	}
+	return records
+}
+
+func TestRollbackRefusesToRevertTheBaseline(t *testing.T) {
+	migrations, err := loadMigrations(sqlFiles)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	m := &Migrator{migrations: migrations}
+	latest := m.LatestVersion()
+
+	// Everything above the baseline can go
+	records := appliedUpTo(migrations, latest)
+	plan, err := m.rollbackPlan(records[:len(records)-1])
+	if err != nil {
+		t.Fatalf("rollback to the baseline: %v", err)
+	}
+	if len(plan) != len(records)-1 || plan[0].Version != latest {
+		t.Errorf("plan has %d migrations starting at %d, want %d from %d", len(plan), plan[0].Version, len(records)-1, latest)
+	}
+
+	// One step too many is refused as a whole, not after rolling back the others
+	for _, records := range [][]SchemaMigration{records, appliedUpTo(migrations, migrations[0].Version)} {
+		plan, err := m.rollbackPlan(records)
+		if !errors.Is(err, ErrIrreversible) {
+			t.Errorf("rollback of %d migrations: error = %v, want %v", len(records), err, ErrIrreversible)
+		}
+		if len(plan) != 0 {
+			t.Errorf("rollback of %d migrations planned %d of them", len(records), len(plan))
+		}
+	}
+}
@@ -0,0 +1,119 @@
+-- Baseline schema as previously created by GORM AutoMigrate.
+-- IF NOT EXISTS lets databases that were auto-migrated adopt this history as is.
+
+CREATE TABLE IF NOT EXISTS genders (
+    id         bigserial PRIMARY KEY,
+    name       varchar(10) NOT NULL UNIQUE,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint
+);
+CREATE INDEX IF NOT EXISTS idx_genders_deleted_at ON genders (deleted_at);
+
+CREATE TABLE IF NOT EXISTS prefixes (
+    id         bigserial PRIMARY KEY,
+    name       varchar(20) NOT NULL UNIQUE,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint
+);
+CREATE INDEX IF NOT EXISTS idx_prefixes_deleted_at ON prefixes (deleted_at);
+
+CREATE TABLE IF NOT EXISTS schools (
+    id         bigserial PRIMARY KEY,
+    name       varchar(255) NOT NULL UNIQUE,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint
+);
+CREATE INDEX IF NOT EXISTS idx_schools_deleted_at ON schools (deleted_at);
+
+CREATE TABLE IF NOT EXISTS teachers (
+    id         bigserial PRIMARY KEY,
+    school_id  bigint NOT NULL,
+    email      varchar(255) NOT NULL,
+    password   varchar(255) NOT NULL,
+    first_name varchar(100) NOT NULL,
+    last_name  varchar(100) NOT NULL,
+    phone      varchar(20),
+    gender_id  bigint,
+    prefix_id  bigint,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint,
+    CONSTRAINT fk_teachers_school FOREIGN KEY (school_id) REFERENCES schools (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT fk_teachers_gender FOREIGN KEY (gender_id) REFERENCES genders (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT fk_teachers_prefix FOREIGN KEY (prefix_id) REFERENCES prefixes (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_teachers_email ON teachers (email);
+CREATE INDEX IF NOT EXISTS idx_teachers_deleted_at ON teachers (deleted_at);
+
+CREATE TABLE IF NOT EXISTS classrooms (
+    id         bigserial PRIMARY KEY,
+    school_id  bigint NOT NULL,
+    teacher_id bigint NOT NULL,
+    name       varchar(255) NOT NULL,
+    grade      varchar(10) NOT NULL,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at bigint,
+    CONSTRAINT fk_classrooms_school FOREIGN KEY (school_id) REFERENCES schools (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT fk_classrooms_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_classrooms_deleted_at ON classrooms (deleted_at);
+
+CREATE TABLE IF NOT EXISTS students (
+    id           bigserial PRIMARY KEY,
+    school_id    bigint NOT NULL,
+    classroom_id bigint NOT NULL,
+    student_no   varchar(20) NOT NULL,
+    first_name   varchar(100) NOT NULL,
+    last_name    varchar(100) NOT NULL,
+    gender_id    bigint,
+    prefix_id    bigint,
+    created_at   bigint,
+    updated_at   bigint,
+    deleted_at   bigint,
+    CONSTRAINT fk_students_school FOREIGN KEY (school_id) REFERENCES schools (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT fk_students_classroom FOREIGN KEY (classroom_id) REFERENCES classrooms (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_students_gender FOREIGN KEY (gender_id) REFERENCES genders (id) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT fk_students_prefix FOREIGN KEY (prefix_id) REFERENCES prefixes (id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_student_no_classroom ON students (classroom_id, student_no);
+CREATE INDEX IF NOT EXISTS idx_students_deleted_at ON students (deleted_at);
+
+CREATE TABLE IF NOT EXISTS classroom_members (
+    teacher_id   bigint,
+    student_id   bigint,
+    classroom_id bigint NOT NULL,
+    CONSTRAINT fk_classroom_members_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_classroom_members_student FOREIGN KEY (student_id) REFERENCES students (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_classroom_members_classroom FOREIGN KEY (classroom_id) REFERENCES classrooms (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS attendances (
+    id           bigserial PRIMARY KEY,
+    classroom_id bigint NOT NULL,
+    teacher_id   bigint NOT NULL,
+    student_id   bigint NOT NULL,
+    session_date date NOT NULL,
+    status       varchar(20) NOT NULL,
+    checked_at   bigint NOT NULL,
+    remark       text,
+    created_at   bigint,
+    updated_at   bigint,
+    deleted_at   bigint,
+    CONSTRAINT fk_attendances_classroom FOREIGN KEY (classroom_id) REFERENCES classrooms (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_attendances_teacher FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_attendances_student FOREIGN KEY (student_id) REFERENCES students (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_attendances_deleted_at ON attendances (deleted_at);
+
+CREATE TABLE IF NOT EXISTS logs (
+    id         bigserial PRIMARY KEY,
+    teacher_id bigint NOT NULL,
+    action     varchar(100) NOT NULL,
+    detail     text,
+    created_at bigint,
+    school_id  bigint
+);
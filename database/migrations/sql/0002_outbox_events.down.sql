@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_logs_event_id;
+ALTER TABLE logs DROP COLUMN IF EXISTS event_id;
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              bigserial PRIMARY KEY,
+    event_type      varchar(100) NOT NULL,
+    aggregate_type  varchar(50) NOT NULL,
+    aggregate_id    bigint NOT NULL,
+    teacher_id      bigint NOT NULL,
+    school_id       bigint,
+    action          varchar(100),
+    detail          text,
+    payload         text,
+    status          varchar(20) NOT NULL DEFAULT 'pending',
+    attempts        bigint NOT NULL DEFAULT 0,
+    next_attempt_at bigint NOT NULL,
+    last_error      text,
+    created_at      bigint,
+    published_at    bigint
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_event_type ON outbox_events (event_type);
+CREATE INDEX IF NOT EXISTS idx_outbox_status_next ON outbox_events (status, next_attempt_at);
+
+-- Lets the log consumer skip events it has already written
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS event_id bigint;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_logs_event_id ON logs (event_id);
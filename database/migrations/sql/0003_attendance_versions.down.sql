@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_attendance_change;
+ALTER TABLE attendances DROP COLUMN IF EXISTS change_tx_id;
+ALTER TABLE attendances DROP COLUMN IF EXISTS version;
@@ -0,0 +1,5 @@
+-- version backs compare-and-swap writes from live roll call and offline sync,
+-- change_tx_id orders the sync change feed by writing transaction
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS change_tx_id bigint NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_attendance_change ON attendances (change_tx_id);
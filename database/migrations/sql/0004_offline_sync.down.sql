@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sync_mutations;
+ALTER TABLE schools DROP COLUMN IF EXISTS sync_conflict_policy;
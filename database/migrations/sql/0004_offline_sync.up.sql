@@ -0,0 +1,10 @@
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS sync_conflict_policy varchar(20) NOT NULL DEFAULT 'server_wins';
+
+CREATE TABLE IF NOT EXISTS sync_mutations (
+    id                 bigserial PRIMARY KEY,
+    teacher_id         bigint NOT NULL,
+    client_mutation_id uuid NOT NULL,
+    attendance_id      bigint,
+    created_at         bigint
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_mutation_client ON sync_mutations (teacher_id, client_mutation_id);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id            bigserial PRIMARY KEY,
+    scope         varchar(100) NOT NULL,
+    key           varchar(255) NOT NULL,
+    fingerprint   char(64) NOT NULL,
+    status        varchar(20) NOT NULL,
+    response_code bigint,
+    content_type  varchar(100),
+    response_body text,
+    created_at    bigint,
+    expires_at    bigint NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_scope_key ON idempotency_keys (scope, key);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE attendances DROP CONSTRAINT IF EXISTS chk_attendances_version;
+ALTER TABLE attendances DROP CONSTRAINT IF EXISTS chk_attendances_status;
+DROP INDEX IF EXISTS uq_attendances_slot;
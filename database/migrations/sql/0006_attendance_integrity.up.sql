@@ -0,0 +1,60 @@
+-- One attendance per student per date per classroom, enforced by the database.
+-- Earlier check-then-insert races may have left duplicates: keep the latest write of each
+-- and soft-delete the others, so they can still be inspected and restored by hand.
+DO $$
+DECLARE
+    superseded bigint;
+BEGIN
+    UPDATE attendances a
+    SET deleted_at = EXTRACT(EPOCH FROM now())::bigint,
+        updated_at = EXTRACT(EPOCH FROM now())::bigint,
+        version = a.version + 1
+    FROM attendances b
+    WHERE a.deleted_at IS NULL
+      AND b.deleted_at IS NULL
+      AND a.classroom_id = b.classroom_id
+      AND a.student_id = b.student_id
+      AND a.session_date = b.session_date
+      AND (COALESCE(a.updated_at, 0), a.id) < (COALESCE(b.updated_at, 0), b.id);
+    GET DIAGNOSTICS superseded = ROW_COUNT;
+    IF superseded > 0 THEN
+        RAISE NOTICE 'soft-deleted % duplicate attendances, find them with deleted_at = %', superseded, EXTRACT(EPOCH FROM now())::bigint;
+    END IF;
+END $$;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_attendances_slot
+    ON attendances (classroom_id, student_id, session_date)
+    WHERE deleted_at IS NULL;
+
+-- Foreign keys may be missing on databases that were migrated by hand
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_attendances_classroom') THEN
+        ALTER TABLE attendances ADD CONSTRAINT fk_attendances_classroom
+            FOREIGN KEY (classroom_id) REFERENCES classrooms (id) ON UPDATE CASCADE ON DELETE CASCADE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_attendances_teacher') THEN
+        ALTER TABLE attendances ADD CONSTRAINT fk_attendances_teacher
+            FOREIGN KEY (teacher_id) REFERENCES teachers (id) ON UPDATE CASCADE ON DELETE CASCADE;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_attendances_student') THEN
+        ALTER TABLE attendances ADD CONSTRAINT fk_attendances_student
+            FOREIGN KEY (student_id) REFERENCES students (id) ON UPDATE CASCADE ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Statuses were never validated on create, so old rows may not pass the check:
+-- the constraint always applies to new writes and is validated when the data allows it
+ALTER TABLE attendances ADD CONSTRAINT chk_attendances_status
+    CHECK (status IN ('present', 'absent', 'late', 'leave')) NOT VALID;
+ALTER TABLE attendances ADD CONSTRAINT chk_attendances_version
+    CHECK (version >= 1) NOT VALID;
+
+DO $$
+BEGIN
+    ALTER TABLE attendances VALIDATE CONSTRAINT chk_attendances_status;
+EXCEPTION WHEN check_violation THEN
+    RAISE NOTICE 'attendances contain unknown statuses, chk_attendances_status left NOT VALID';
+END $$;
+
+ALTER TABLE attendances VALIDATE CONSTRAINT chk_attendances_version;
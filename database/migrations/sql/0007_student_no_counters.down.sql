@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS student_no_counters;
+ALTER TABLE schools DROP CONSTRAINT IF EXISTS chk_schools_student_no_year;
+ALTER TABLE schools DROP CONSTRAINT IF EXISTS chk_schools_student_no_width;
+ALTER TABLE schools DROP COLUMN IF EXISTS student_no_year;
+ALTER TABLE schools DROP COLUMN IF EXISTS student_no_width;
+ALTER TABLE schools DROP COLUMN IF EXISTS student_no_prefix;
@@ -0,0 +1,17 @@
+-- Student number format, configurable per school
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS student_no_prefix varchar(20) NOT NULL DEFAULT 'STD';
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS student_no_width bigint NOT NULL DEFAULT 3;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS student_no_year varchar(10) NOT NULL DEFAULT 'none';
+ALTER TABLE schools ADD CONSTRAINT chk_schools_student_no_width CHECK (student_no_width BETWEEN 1 AND 10);
+ALTER TABLE schools ADD CONSTRAINT chk_schools_student_no_year CHECK (student_no_year IN ('none', 'ce', 'be'));
+
+-- Last number handed out per classroom (and per year for yearly formats).
+-- Rows are created lazily and seeded from the highest existing number.
+CREATE TABLE IF NOT EXISTS student_no_counters (
+    classroom_id bigint NOT NULL,
+    period       varchar(10) NOT NULL DEFAULT '',
+    last_value   bigint NOT NULL,
+    updated_at   bigint,
+    PRIMARY KEY (classroom_id, period),
+    CONSTRAINT fk_student_no_counters_classroom FOREIGN KEY (classroom_id) REFERENCES classrooms (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,6 @@
+-- Fails if a live row and a deleted row share a value; purge the trash first
+DROP INDEX IF EXISTS idx_student_no_classroom;
+CREATE UNIQUE INDEX idx_student_no_classroom ON students (classroom_id, student_no);
+
+DROP INDEX IF EXISTS idx_teachers_email;
+CREATE UNIQUE INDEX idx_teachers_email ON teachers (email);
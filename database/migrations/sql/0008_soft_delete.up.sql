@@ -0,0 +1,8 @@
+-- Deleted teachers and students now stay in the table until purged, so
+-- uniqueness only applies to live rows. A deleted email or student number
+-- can be reused; restoring the old row then fails with a conflict.
+DROP INDEX IF EXISTS idx_teachers_email;
+CREATE UNIQUE INDEX idx_teachers_email ON teachers (email) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_student_no_classroom;
+CREATE UNIQUE INDEX idx_student_no_classroom ON students (classroom_id, student_no) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_logs_request_id;
+ALTER TABLE logs DROP COLUMN IF EXISTS request_id;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS request_id;
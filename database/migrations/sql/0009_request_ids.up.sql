@@ -0,0 +1,4 @@
+-- request_id ties activity log entries to the X-Request-ID of the request that caused them
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS request_id varchar(128);
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS request_id varchar(128);
+CREATE INDEX IF NOT EXISTS idx_logs_request_id ON logs (request_id);
@@ -0,0 +1,5 @@
+ALTER TABLE logs DROP COLUMN IF EXISTS message_params;
+ALTER TABLE logs DROP COLUMN IF EXISTS message_key;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS message_params;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS message_key;
+ALTER TABLE teachers DROP COLUMN IF EXISTS language;
@@ -0,0 +1,7 @@
+-- language is the teacher's preferred API language, empty to follow Accept-Language
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS language varchar(5) NOT NULL DEFAULT '';
+-- Activity entries keep their message key and parameters so they can be rendered in any language
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS message_key varchar(100);
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS message_params jsonb;
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS message_key varchar(100);
+ALTER TABLE logs ADD COLUMN IF NOT EXISTS message_params jsonb;
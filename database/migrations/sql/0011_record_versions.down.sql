@@ -0,0 +1,2 @@
+ALTER TABLE classrooms DROP COLUMN IF EXISTS version;
+ALTER TABLE students DROP COLUMN IF EXISTS version;
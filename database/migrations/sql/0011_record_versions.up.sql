@@ -0,0 +1,3 @@
+-- version backs compare-and-swap updates of students and classrooms, like attendances
+ALTER TABLE students ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE classrooms ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
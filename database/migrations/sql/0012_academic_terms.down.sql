@@ -0,0 +1,8 @@
+ALTER TABLE attendances DROP CONSTRAINT IF EXISTS fk_attendances_term;
+ALTER TABLE attendances DROP COLUMN IF EXISTS term_id;
+ALTER TABLE classrooms DROP CONSTRAINT IF EXISTS fk_classrooms_source_classroom;
+ALTER TABLE classrooms DROP CONSTRAINT IF EXISTS fk_classrooms_academic_year;
+ALTER TABLE classrooms DROP COLUMN IF EXISTS source_classroom_id;
+ALTER TABLE classrooms DROP COLUMN IF EXISTS academic_year_id;
+DROP TABLE IF EXISTS terms;
+DROP TABLE IF EXISTS academic_years;
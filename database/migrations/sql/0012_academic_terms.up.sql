@@ -0,0 +1,52 @@
+-- Academic years and their terms, per school. The years of a school do not overlap and
+-- neither do its terms, so a session date falls in at most one term. The exclusion
+-- constraints compare school_id with = inside a GiST index, which needs btree_gist.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS academic_years (
+    id         bigserial PRIMARY KEY,
+    school_id  bigint NOT NULL,
+    name       varchar(20) NOT NULL,
+    start_date date NOT NULL,
+    end_date   date NOT NULL,
+    created_at bigint,
+    updated_at bigint,
+    CONSTRAINT fk_academic_years_school FOREIGN KEY (school_id) REFERENCES schools (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT chk_academic_years_dates CHECK (start_date <= end_date),
+    CONSTRAINT excl_academic_years_dates EXCLUDE USING gist (school_id WITH =, daterange(start_date, end_date, '[]') WITH &&)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_academic_years_school_name ON academic_years (school_id, name);
+
+CREATE TABLE IF NOT EXISTS terms (
+    id               bigserial PRIMARY KEY,
+    academic_year_id bigint NOT NULL,
+    school_id        bigint NOT NULL,
+    number           bigint NOT NULL,
+    start_date       date NOT NULL,
+    end_date         date NOT NULL,
+    created_at       bigint,
+    updated_at       bigint,
+    CONSTRAINT fk_terms_academic_year FOREIGN KEY (academic_year_id) REFERENCES academic_years (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_terms_school FOREIGN KEY (school_id) REFERENCES schools (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT chk_terms_dates CHECK (start_date <= end_date),
+    CONSTRAINT chk_terms_number CHECK (number >= 1),
+    CONSTRAINT excl_terms_dates EXCLUDE USING gist (school_id WITH =, daterange(start_date, end_date, '[]') WITH &&)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_terms_year_number ON terms (academic_year_id, number);
+CREATE INDEX IF NOT EXISTS idx_terms_school_dates ON terms (school_id, start_date, end_date);
+
+-- Classrooms belong to a year; rows created before years existed keep NULL until the
+-- school's first year adopts them. source_classroom_id links a rollover clone to last year's room.
+ALTER TABLE classrooms ADD COLUMN IF NOT EXISTS academic_year_id bigint;
+ALTER TABLE classrooms ADD COLUMN IF NOT EXISTS source_classroom_id bigint;
+ALTER TABLE classrooms ADD CONSTRAINT fk_classrooms_academic_year
+    FOREIGN KEY (academic_year_id) REFERENCES academic_years (id) ON UPDATE CASCADE ON DELETE SET NULL;
+ALTER TABLE classrooms ADD CONSTRAINT fk_classrooms_source_classroom
+    FOREIGN KEY (source_classroom_id) REFERENCES classrooms (id) ON UPDATE CASCADE ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_classrooms_academic_year_id ON classrooms (academic_year_id);
+
+-- The term an attendance was taken in, resolved from its session date
+ALTER TABLE attendances ADD COLUMN IF NOT EXISTS term_id bigint;
+ALTER TABLE attendances ADD CONSTRAINT fk_attendances_term
+    FOREIGN KEY (term_id) REFERENCES terms (id) ON UPDATE CASCADE ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_attendances_term_id ON attendances (term_id);
@@ -0,0 +1,2 @@
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS claimed_by;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS locked_until;
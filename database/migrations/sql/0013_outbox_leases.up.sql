@@ -0,0 +1,4 @@
+-- The dispatcher claims a batch by leasing its rows instead of holding them locked while
+-- consumers run; an expired lease means the worker died and the rows are claimed again
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS locked_until bigint;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS claimed_by varchar(100);
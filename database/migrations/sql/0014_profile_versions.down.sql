@@ -0,0 +1,4 @@
+ALTER TABLE prefixes DROP COLUMN IF EXISTS version;
+ALTER TABLE genders DROP COLUMN IF EXISTS version;
+ALTER TABLE schools DROP COLUMN IF EXISTS version;
+ALTER TABLE teachers DROP COLUMN IF EXISTS version;
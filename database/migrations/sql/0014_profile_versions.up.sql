@@ -0,0 +1,6 @@
+-- version backs compare-and-swap updates of teachers, schools, genders and prefixes, so
+-- two saves within the same second no longer share an ETag
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE schools ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE genders ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE prefixes ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
@@ -1,8 +1,9 @@
 package middlewares
 
 import (
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/i18n"
 	"easy-attend-service/utils/jwt"
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,47 +13,79 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			abortWithError(ctx, apperror.Unauthorized("auth.missing_token", "authorization header is required"))
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+			abortWithError(ctx, apperror.Unauthorized("auth.malformed_token", "authorization header format must be Bearer {token}"))
 			return
 		}
 
-		token := parts[1]
-		claims, err := jwt.VerifyToken(token)
-		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			return
-		}
+		authenticate(ctx, parts[1], "")
+	}
+}
 
-		// Extract user information from token claims
-		userID, exists := claims["user_id"].(string)
-		if !exists {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: user_id not found"})
+// TicketAuthMiddleware also accepts a ticket of scope in the ?ticket= query parameter,
+// for browser EventSource and WebSocket clients that cannot set the Authorization header
+func TicketAuthMiddleware(scope string) gin.HandlerFunc {
+	bearer := AuthMiddleware()
+	return func(ctx *gin.Context) {
+		if ticket := ctx.Query("ticket"); ticket != "" {
+			authenticate(ctx, ticket, scope)
 			return
 		}
+		bearer(ctx)
+	}
+}
 
-		email, exists := claims["email"].(string)
-		if !exists {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: email not found"})
-			return
-		}
+// authenticate verifies token and puts the teacher in the context. scope is empty for
+// bearer tokens; a ticket only works on the routes of its own scope.
+func authenticate(ctx *gin.Context, token, scope string) {
+	claims, err := jwt.VerifyToken(token)
+	if err != nil {
+		abortWithError(ctx, apperror.Unauthorized("auth.invalid_token", "invalid or expired token").Wrap(err))
+		return
+	}
+	if tokenScope, _ := claims["scope"].(string); tokenScope != scope {
+		abortWithError(ctx, apperror.Unauthorized("auth.invalid_token", "invalid token: wrong scope"))
+		return
+	}
 
-		userType, exists := claims["user_type"].(string)
-		if !exists {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: user_type not found"})
-			return
-		}
+	// Extract user information from token claims
+	userID, exists := claims["user_id"].(string)
+	if !exists {
+		abortWithError(ctx, apperror.Unauthorized("auth.invalid_token", "invalid token: user_id not found"))
+		return
+	}
+
+	email, exists := claims["email"].(string)
+	if !exists {
+		abortWithError(ctx, apperror.Unauthorized("auth.invalid_token", "invalid token: email not found"))
+		return
+	}
+
+	userType, exists := claims["user_type"].(string)
+	if !exists {
+		abortWithError(ctx, apperror.Unauthorized("auth.invalid_token", "invalid token: user_type not found"))
+		return
+	}
 
-		// Set user information in context
-		ctx.Set("user_id", userID)
-		ctx.Set("email", email)
-		ctx.Set("user_type", userType)
+	// Set user information in context
+	ctx.Set("user_id", userID)
+	ctx.Set("email", email)
+	ctx.Set("user_type", userType)
 
-		ctx.Next()
+	// The profile language, when the teacher chose one, stands in for an Accept-Language
+	// that is missing or names no supported language; a supported one always wins
+	language, _ := claims["language"].(string)
+	if _, supported := i18n.Negotiate(ctx.GetHeader("Accept-Language")); language != "" && !supported {
+		if lang, ok := i18n.Parse(language); ok {
+			setLanguage(ctx, lang)
+		}
 	}
+	ctx.Set("language", language)
+
+	ctx.Next()
 }
@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"easy-attend-service/utils/jwt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProfileLanguageStandsInForUnsupportedAcceptLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(LocaleMiddleware(), AuthMiddleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	token, _, err := jwt.GenerateToken(jwt.CustomClaims{UserID: "1", Email: "teacher@example.com", UserType: "teacher", Language: "en"})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+
+	for _, tc := range []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{"", "en"},             // No header, the profile picks
+		{"th", "th"},           // A supported header wins over the profile
+		{"fr", "en"},           // Nothing supported in the header, the profile picks
+		{"fr, th;q=0.5", "th"}, // A supported fallback in the header still wins
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("Accept-Language %q: status = %d", tc.acceptLanguage, rec.Code)
+		}
+		if got := rec.Header().Get("Content-Language"); got != tc.want {
+			t.Errorf("Accept-Language %q: Content-Language = %q, want %q", tc.acceptLanguage, got, tc.want)
+		}
+	}
+}
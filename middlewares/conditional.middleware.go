@@ -0,0 +1,157 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"easy-attend-service/utils/etag"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConditionalRequests handles the conditional request headers. A GET is held back
+// until the handler returns so it can be tagged: with the record's own ETag extended
+// by a hash of the body when the handler set one (response.SetValidators), else with
+// a weak hash of the body. Either way each representation of a record, by ?fields=,
+// ?expand= or language, has its own tag. A client that already has that tag gets 304
+// Not Modified without a body. If-Match is handed to the services, which refuse to
+// update a record that has changed with 412.
+func ConditionalRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if header := c.GetHeader("If-Match"); header != "" {
+			c.Request = c.Request.WithContext(etag.WithIfMatch(c.Request.Context(), header))
+		}
+		if c.Request.Method != http.MethodGet || c.IsWebsocket() {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		held := &heldResponse{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = held
+		c.Next()
+		c.Writer = original
+		// Nothing written means an error the error middleware renders; streams went out as they came
+		if !held.written || held.streaming {
+			return
+		}
+
+		if held.status == http.StatusOK {
+			header := original.Header()
+			tag := header.Get("ETag")
+			if tag == "" {
+				tag = etag.Weak(held.body.Bytes())
+			} else {
+				tag = etag.Representation(tag, held.body.Bytes())
+			}
+			header.Set("ETag", tag)
+			if header.Get("Cache-Control") == "" {
+				// Cache per user but ask the server each time; the answer is 304 when nothing changed
+				header.Set("Cache-Control", "private, no-cache")
+			}
+			if notModified(c.Request, tag, header.Get("Last-Modified")) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				original.WriteHeader(http.StatusNotModified)
+				original.WriteHeaderNow()
+				return
+			}
+		}
+		original.WriteHeader(held.status)
+		original.Write(held.body.Bytes())
+	}
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when the client sent no tag
+func notModified(r *http.Request, tag, lastModified string) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return etag.Matches(header, tag, true)
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && !modified.After(since.Truncate(time.Second))
+}
+
+// heldResponse buffers a response until ConditionalRequests knows its tag. A handler
+// that flushes or hijacks (Server-Sent Events, WebSockets) is let through unbuffered.
+type heldResponse struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	status    int
+	written   bool
+	streaming bool
+}
+
+func (w *heldResponse) WriteHeader(code int) {
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *heldResponse) WriteHeaderNow() {
+	if w.streaming {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.written = true
+}
+
+func (w *heldResponse) Write(data []byte) (int, error) {
+	w.written = true
+	if w.streaming {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *heldResponse) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *heldResponse) Written() bool {
+	return w.written
+}
+
+func (w *heldResponse) Status() int {
+	if w.streaming {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *heldResponse) Size() int {
+	if w.streaming {
+		return w.ResponseWriter.Size()
+	}
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+// Flush sends what was held and stops holding
+func (w *heldResponse) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		w.written = true
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *heldResponse) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.streaming = true
+	w.written = true
+	return w.ResponseWriter.Hijack()
+}
@@ -0,0 +1,142 @@
+package middlewares
+
+import (
+	"easy-attend-service/response"
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/requestid"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+)
+
+var jsonFieldNamesOnce sync.Once
+
+// ErrorMiddleware renders the error a handler attached with c.Error as an RFC 7807
+// problem+json response. Handlers and middlewares call c.Error and return without
+// writing anything; the status comes from the error's kind.
+func ErrorMiddleware() gin.HandlerFunc {
+	jsonFieldNamesOnce.Do(useJSONFieldNames)
+
+	return func(c *gin.Context) {
+		c.Next()
+		writeError(c)
+	}
+}
+
+// abortWithError stops the chain with err, for middlewares that reject a request
+func abortWithError(c *gin.Context, err error) {
+	c.Error(err)
+	c.Abort()
+}
+
+// writeError renders the last error of the request unless a response was already written
+func writeError(c *gin.Context) {
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+	ctx := c.Request.Context()
+	lang := i18n.FromContext(ctx)
+	err := c.Errors.Last().Err
+	appErr := apperror.From(err)
+	status := statusOf(appErr.Kind)
+
+	detail, ok := i18n.Lookup(lang, "error."+appErr.Code, appErr.Params)
+	if !ok {
+		detail = appErr.Message
+	}
+
+	problem := response.Problem{
+		Type:      response.ProblemTypePrefix + appErr.Code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      appErr.Code,
+		RequestID: requestid.FromContext(ctx),
+		Current:   appErr.Current,
+	}
+
+	switch appErr.Kind {
+	case apperror.KindInternal:
+		logger.LogError(ctx, err, "Request failed", logrus.Fields{
+			"code": appErr.Code,
+			"path": c.Request.URL.Path,
+		})
+	case apperror.KindValidation:
+		var invalid validator.ValidationErrors
+		if errors.As(appErr, &invalid) {
+			for _, field := range invalid {
+				problem.Errors = append(problem.Errors, response.FieldError{
+					Field:   field.Field(),
+					Rule:    field.Tag(),
+					Param:   field.Param(),
+					Message: fieldMessage(lang, field),
+				})
+			}
+		} else if appErr.Err != nil {
+			// Malformed JSON or a wrong type; the decoder message points at the spot
+			problem.Detail += ": " + appErr.Err.Error()
+		}
+	}
+
+	c.Header("Content-Type", response.ProblemContentType)
+	c.JSON(status, problem)
+}
+
+// fieldMessage explains a failed binding rule in lang, e.g. "กรุณากรอก email"
+func fieldMessage(lang i18n.Lang, field validator.FieldError) string {
+	params := map[string]string{"field": field.Field(), "param": field.Param()}
+	if message, ok := i18n.Lookup(lang, "validation."+field.Tag(), params); ok {
+		return message
+	}
+	return i18n.Render(lang, "validation.invalid", params)
+}
+
+func statusOf(kind apperror.Kind) int {
+	switch kind {
+	case apperror.KindValidation:
+		return http.StatusBadRequest
+	case apperror.KindUnauthorized:
+		return http.StatusUnauthorized
+	case apperror.KindForbidden:
+		return http.StatusForbidden
+	case apperror.KindNotFound:
+		return http.StatusNotFound
+	case apperror.KindConflict:
+		return http.StatusConflict
+	case apperror.KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case apperror.KindRateLimited:
+		return http.StatusTooManyRequests
+	case apperror.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusInternalServerError
+}
+
+// useJSONFieldNames makes validation errors name fields as clients send them
+// ("student_id") rather than by their Go names
+func useJSONFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
@@ -0,0 +1,270 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"easy-attend-service/configs"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/logger"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header clients set to make a POST safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	maxIdempotencyKeyLength = 255
+)
+
+type Idempotency struct {
+	ttl time.Duration
+	// lease frees a key whose first request died without finishing; a running
+	// request renews it, so a slow request keeps its key however long it takes
+	lease       time.Duration
+	cleanupOnce sync.Once
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewIdempotency creates an idempotency guard
+// ttl: how long a stored response is replayed (e.g., 24 hours)
+func NewIdempotency(ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		ttl:   ttl,
+		lease: 1 * time.Minute,
+		stop:  make(chan struct{}),
+	}
+}
+
+// Global idempotency guard: responses are kept for 24 hours
+var IdempotencyGuard = NewIdempotency(24 * time.Hour)
+
+// IdempotencyMiddleware replays the stored response when a POST is retried with the same Idempotency-Key.
+// It must run after AuthMiddleware so keys are scoped per teacher.
+func (i *Idempotency) IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+
+		i.cleanupOnce.Do(func() {
+			go i.cleanupExpiredKeys()
+		})
+
+		if len(key) > maxIdempotencyKeyLength {
+			abortWithError(c, apperror.Validation("idempotency.invalid_key", "Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithError(c, apperror.Validation("request.unreadable_body", "failed to read request body").Wrap(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record := models.IdempotencyKey{
+			Scope:       idempotencyScope(c),
+			Key:         key,
+			Fingerprint: requestFingerprint(c.Request.Method, c.Request.URL.Path, body),
+			Status:      models.IdempotencyStatusProcessing,
+			ExpiresAt:   time.Now().Add(i.lease).Unix(),
+		}
+
+		claimed, existing, err := i.claim(c.Request.Context(), &record)
+		if err != nil {
+			logger.LogError(c.Request.Context(), err, "Failed to claim idempotency key", logrus.Fields{
+				"scope": record.Scope,
+			})
+			abortWithError(c, apperror.Internal("idempotency.check_failed", "idempotency check failed, please retry the request").Wrap(err))
+			return
+		}
+
+		if !claimed {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				abortWithError(c, apperror.Unprocessable("idempotency.key_reused", "this Idempotency-Key was already used with a different request"))
+			case existing.Status == models.IdempotencyStatusProcessing:
+				abortWithError(c, apperror.Conflict("idempotency.in_progress", "a request with this Idempotency-Key is still being processed"))
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.ResponseCode, existing.ContentType, []byte(existing.ResponseBody))
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// The key outlives a client that hangs up, so its writes must not be cancelled with the request
+		ctx := context.WithoutCancel(c.Request.Context())
+		done := make(chan struct{})
+		go i.renewLease(ctx, record.ID, done)
+
+		completed := false
+		defer func() {
+			close(done)
+			// Server errors and panics release the key so the client can retry for real
+			if !completed {
+				configs.DB.WithContext(ctx).Delete(&models.IdempotencyKey{}, record.ID)
+			}
+		}()
+
+		c.Next()
+		// Render a handler error now so the problem body is what gets stored and replayed
+		writeError(c)
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		if err := configs.DB.WithContext(ctx).Model(&record).Updates(map[string]interface{}{
+			"status":        models.IdempotencyStatusCompleted,
+			"expires_at":    time.Now().Add(i.ttl).Unix(),
+			"response_code": c.Writer.Status(),
+			"content_type":  c.Writer.Header().Get("Content-Type"),
+			"response_body": recorder.body.String(),
+		}).Error; err != nil {
+			logger.LogError(c.Request.Context(), err, "Failed to store idempotent response", logrus.Fields{
+				"scope": record.Scope,
+			})
+			return
+		}
+		completed = true
+	}
+}
+
+// claim inserts the key in processing state. When the key is already taken it returns
+// the stored row instead, after taking over rows that have expired or were abandoned.
+func (i *Idempotency) claim(ctx context.Context, record *models.IdempotencyKey) (bool, *models.IdempotencyKey, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		result := configs.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return false, nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return true, nil, nil
+		}
+
+		var existing models.IdempotencyKey
+		err := configs.DB.WithContext(ctx).Where("scope = ? AND key = ?", record.Scope, record.Key).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released in the meantime, try to claim again
+			continue
+		}
+		if err != nil {
+			return false, nil, err
+		}
+
+		// A processing row stays taken until its lease runs out, a completed one until its TTL does
+		now := time.Now().Unix()
+		if existing.ExpiresAt > now {
+			return false, &existing, nil
+		}
+
+		// Delete by ID, status and expiry so only one of several racing retries wins the
+		// takeover, and none of them takes a lease the first request has just renewed
+		if err := configs.DB.WithContext(ctx).Where("id = ? AND status = ? AND expires_at <= ?", existing.ID, existing.Status, now).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return false, nil, err
+		}
+	}
+	return false, nil, errors.New("idempotency key is contended")
+}
+
+// renewLease extends the processing lease of the key until done is closed
+func (i *Idempotency) renewLease(ctx context.Context, id uint, done <-chan struct{}) {
+	ticker := time.NewTicker(i.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := configs.DB.WithContext(ctx).Model(&models.IdempotencyKey{}).
+				Where("id = ? AND status = ?", id, models.IdempotencyStatusProcessing).
+				Update("expires_at", time.Now().Add(i.lease).Unix()).Error; err != nil {
+				logger.LogError(ctx, err, "Failed to renew idempotency key lease", logrus.Fields{
+					"idempotency_key_id": id,
+				})
+			}
+		}
+	}
+}
+
+// cleanupExpiredKeys removes stored responses past their TTL and abandoned leases
+func (i *Idempotency) cleanupExpiredKeys() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.stop:
+			return
+		case <-ticker.C:
+			if err := configs.DB.Where("expires_at < ?", time.Now().Unix()).
+				Delete(&models.IdempotencyKey{}).Error; err != nil {
+				logger.LogError(context.Background(), err, "Failed to clean up idempotency keys", nil)
+			}
+		}
+	}
+}
+
+// Stop ends the cleanup goroutine; it is safe to call more than once
+func (i *Idempotency) Stop() {
+	i.stopOnce.Do(func() { close(i.stop) })
+}
+
+// idempotencyScope keeps keys of different teachers apart; public routes fall back to the client IP
+func idempotencyScope(c *gin.Context) string {
+	if uid, exists := c.Get("user_id"); exists {
+		if userID, ok := uid.(string); ok && userID != "" {
+			return "teacher:" + userID
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotencyRecorder copies the response body while it is written to the client
+type idempotencyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Convenience function for middleware
+func IdempotencyKeys() gin.HandlerFunc {
+	return IdempotencyGuard.IdempotencyMiddleware()
+}
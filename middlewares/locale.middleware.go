@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"easy-attend-service/utils/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LocaleMiddleware picks the response language from Accept-Language, falling back to
+// the configured default. When Accept-Language is missing or names no supported
+// language, AuthMiddleware later replaces the default with the teacher's profile
+// preference if one is set.
+func LocaleMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang, ok := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		if !ok {
+			lang = i18n.Default()
+		}
+		setLanguage(c, lang)
+		// Every message is localized, so caches must keep one copy per language
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// setLanguage stores lang in the request context and announces it in Content-Language
+func setLanguage(c *gin.Context, lang i18n.Lang) {
+	c.Request = c.Request.WithContext(i18n.NewContext(c.Request.Context(), lang))
+	c.Header("Content-Language", string(lang))
+}
@@ -8,19 +8,15 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// LoggingMiddleware logs every request and its response; both lines carry the
+// request_id (and trace_id) so they can be joined
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Start timer
 		startTime := time.Now()
 
-		// Get user ID from context (if authenticated)
-		userID := ""
-		if uid, exists := c.Get("user_id"); exists {
-			userID = uid.(string)
-		}
-
-		// Log request
-		logger.LogAPIRequest(c.Request.Method, c.Request.URL.Path, userID)
+		// Log request; the teacher is not known until AuthMiddleware has run
+		logger.LogAPIRequest(c.Request.Context(), c.Request.Method, c.Request.URL.Path)
 
 		// Process request
 		c.Next()
@@ -28,11 +24,18 @@ func LoggingMiddleware() gin.HandlerFunc {
 		// Calculate latency
 		latency := time.Since(startTime)
 
+		// AuthMiddleware stores the user ID while the chain runs
+		userID := ""
+		if uid, exists := c.Get("user_id"); exists {
+			userID, _ = uid.(string)
+		}
+
 		// Log response
-		logger.Log.WithFields(logrus.Fields{
+		logger.Log.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"type":        "api_response",
 			"method":      c.Request.Method,
 			"path":        c.Request.URL.Path,
+			"route":       c.FullPath(),
 			"status_code": c.Writer.Status(),
 			"latency":     latency.String(),
 			"user_id":     userID,
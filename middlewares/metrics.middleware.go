@@ -0,0 +1,29 @@
+package middlewares
+
+import (
+	"easy-attend-service/utils/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsMiddleware records request counts and latency by route template so
+// /students/1 and /students/2 share one series
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startTime := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			// 404s would otherwise create a series per scanned URL
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(startTime).Seconds())
+	}
+}
@@ -1,6 +1,8 @@
 package middlewares
 
 import (
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/metrics"
 	"net/http"
 	"sync"
 	"time"
@@ -9,10 +11,13 @@ import (
 )
 
 type RateLimiter struct {
+	name     string // metrics label, e.g. strict or normal
 	visitors map[string]*Visitor
 	mu       sync.RWMutex
 	rate     int           // requests per window
 	window   time.Duration // time window
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 type Visitor struct {
@@ -22,13 +27,16 @@ type Visitor struct {
 }
 
 // NewRateLimiter creates a new rate limiter
+// name: label for the rejection metric (e.g., strict)
 // rate: number of requests allowed per window
 // window: time window duration (e.g., 1 minute)
-func NewRateLimiter(rate int, window time.Duration) *RateLimiter {
+func NewRateLimiter(name string, rate int, window time.Duration) *RateLimiter {
 	rl := &RateLimiter{
+		name:     name,
 		visitors: make(map[string]*Visitor),
 		rate:     rate,
 		window:   window,
+		stop:     make(chan struct{}),
 	}
 
 	// Clean up old visitors every 5 minutes
@@ -72,11 +80,8 @@ func (rl *RateLimiter) RateLimitMiddleware() gin.HandlerFunc {
 		// Within window - check rate limit
 		if visitor.count >= rl.rate {
 			rl.mu.Unlock()
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Rate limit exceeded",
-				"message": "Too many requests. Please try again later.",
-			})
-			c.Abort()
+			metrics.RateLimitRejections.WithLabelValues(rl.name).Inc()
+			abortWithError(c, apperror.RateLimited("rate_limit.exceeded", "too many requests, please try again later"))
 			return
 		}
 
@@ -89,12 +94,31 @@ func (rl *RateLimiter) RateLimitMiddleware() gin.HandlerFunc {
 	}
 }
 
+// ReadWriteRateLimit counts GET and HEAD requests against reads and every other
+// method against writes, so browsing lists does not use up the budget for changes
+func ReadWriteRateLimit(reads, writes *RateLimiter) gin.HandlerFunc {
+	limitReads, limitWrites := reads.RateLimitMiddleware(), writes.RateLimitMiddleware()
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			limitReads(c)
+		} else {
+			limitWrites(c)
+		}
+	}
+}
+
 // cleanupVisitors removes old visitors to prevent memory leaks
 func (rl *RateLimiter) cleanupVisitors() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-ticker.C:
+		}
+
 		rl.mu.Lock()
 		now := time.Now()
 		for ip, visitor := range rl.visitors {
@@ -107,27 +131,7 @@ func (rl *RateLimiter) cleanupVisitors() {
 	}
 }
 
-// Global rate limiters with different configurations
-var (
-	// Strict rate limiter: 10 requests per minute (for sensitive endpoints like login)
-	StrictRateLimiter = NewRateLimiter(10, 1*time.Minute)
-
-	// Normal rate limiter: 100 requests per minute (for regular API endpoints)
-	NormalRateLimiter = NewRateLimiter(100, 1*time.Minute)
-
-	// Generous rate limiter: 300 requests per minute (for read-heavy endpoints)
-	GenerousRateLimiter = NewRateLimiter(300, 1*time.Minute)
-)
-
-// Convenience functions for middleware
-func StrictRateLimit() gin.HandlerFunc {
-	return StrictRateLimiter.RateLimitMiddleware()
-}
-
-func NormalRateLimit() gin.HandlerFunc {
-	return NormalRateLimiter.RateLimitMiddleware()
-}
-
-func GenerousRateLimit() gin.HandlerFunc {
-	return GenerousRateLimiter.RateLimitMiddleware()
+// Stop ends the cleanup goroutine; it is safe to call more than once
+func (rl *RateLimiter) Stop() {
+	rl.stopOnce.Do(func() { close(rl.stop) })
 }
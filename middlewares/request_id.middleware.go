@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"easy-attend-service/utils/requestid"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDMiddleware accepts the caller's X-Request-ID or generates one, stores it in
+// the gin and request contexts and echoes it in the response, so a support ticket
+// quoting the ID leads to every log line and activity entry of that request
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		c.Set("request_id", id)
+		ctx := requestid.NewContext(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(requestid.Header, id)
+
+		// Lets a trace be found from the ID the client reports
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", id))
+
+		c.Next()
+	}
+}
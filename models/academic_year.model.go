@@ -0,0 +1,28 @@
+package models
+
+// AcademicYear is one school year of a school, e.g. "2568" from 2025-05-16 to 2026-03-31.
+// Classrooms belong to a year, so "ม.1/2" exists once per year instead of being overwritten.
+type AcademicYear struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	SchoolID  *uint  `gorm:"not null;uniqueIndex:idx_academic_years_school_name" json:"school_id"`
+	Name      string `gorm:"type:varchar(20);not null;uniqueIndex:idx_academic_years_school_name" json:"name"`
+	StartDate string `gorm:"type:date;not null" json:"start_date"` // YYYY-MM-DD
+	EndDate   string `gorm:"type:date;not null" json:"end_date"`   // YYYY-MM-DD, inclusive
+	CreatedAt int64  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt int64  `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// Foreign Key Relationships
+	School *School `gorm:"foreignKey:SchoolID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"school,omitempty"`
+
+	// Has Many Relationships
+	Terms      []Term      `gorm:"foreignKey:AcademicYearID" json:"terms,omitempty"`
+	Classrooms []Classroom `gorm:"foreignKey:AcademicYearID" json:"classrooms,omitempty"`
+}
+
+func (a *AcademicYear) TableName() string {
+	return "academic_years"
+}
+
+func (a *AcademicYear) ETag() string {
+	return recordTag(a.TableName(), a.ID, a.UpdatedAt, 0)
+}
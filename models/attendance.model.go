@@ -1,5 +1,7 @@
 package models
 
+import "gorm.io/gorm"
+
 // AttendanceStatus enum for attendance status
 type AttendanceStatus string
 
@@ -12,27 +14,83 @@ const (
 
 type Attendance struct {
 	ID          uint             `gorm:"primaryKey;autoIncrement" json:"id"`
-	ClassroomID *uint            `gorm:"not null" json:"classroom_id"`
+	ClassroomID *uint            `gorm:"not null;uniqueIndex:uq_attendances_slot,where:deleted_at IS NULL" json:"classroom_id"`
 	TeacherID   *uint            `gorm:"not null" json:"teacher_id"`
-	StudentID   *uint            `gorm:"not null" json:"student_id"`
-	SessionDate string           `gorm:"type:date;not null" json:"session_date"` // YYYY-MM-DD format
-	Status      AttendanceStatus `gorm:"type:varchar(20);not null" json:"status"`
+	StudentID   *uint            `gorm:"not null;uniqueIndex:uq_attendances_slot,where:deleted_at IS NULL" json:"student_id"`
+	SessionDate string           `gorm:"type:date;not null;uniqueIndex:uq_attendances_slot,where:deleted_at IS NULL" json:"session_date"` // YYYY-MM-DD format
+	Status      AttendanceStatus `gorm:"type:varchar(20);not null;check:chk_attendances_status,status IN ('present','absent','late','leave')" json:"status"`
 	CheckedAt   int64            `gorm:"not null" json:"checked_at"`
 	Remark      string           `gorm:"type:text" json:"remark"`
+	Version     uint             `gorm:"not null;default:1" json:"version"`                       // Bumped on every write for conflict detection
+	ChangeTxID  int64            `gorm:"not null;default:0;index:idx_attendance_change" json:"-"` // Writing transaction ID, orders the sync change feed
+	TermID      *uint            `gorm:"index" json:"term_id"`                                    // Term the session date falls in, NULL when the school has none
 	CreatedAt   int64            `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt   int64            `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt   *int64           `gorm:"index" json:"deleted_at,omitempty"`
+	DeletedAt   DeletedAt        `gorm:"index" json:"deleted_at,omitzero"`
 
 	// Foreign Key Relationships
 	Classroom *Classroom `gorm:"foreignKey:ClassroomID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"classroom,omitempty"`
 	Teacher   *Teacher   `gorm:"foreignKey:TeacherID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"teacher,omitempty"`
 	Student   *Student   `gorm:"foreignKey:StudentID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"student,omitempty"`
+	Term      *Term      `gorm:"foreignKey:TermID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"term,omitempty"`
 }
 
 func (a *Attendance) TableName() string {
 	return "attendances"
 }
 
+func (a *Attendance) ETag() string {
+	return recordTag(a.TableName(), a.ID, a.UpdatedAt, a.Version)
+}
+
+// BeforeCreate stamps the row with the writing transaction for the sync change feed
+// and with the term of its session date, whichever path (API, roll-call, sync) creates it
+func (a *Attendance) BeforeCreate(tx *gorm.DB) error {
+	txID, err := currentTxID(tx)
+	if err != nil {
+		return err
+	}
+	a.ChangeTxID = txID
+	if a.TermID == nil && a.ClassroomID != nil {
+		termID, err := termOfSession(tx, *a.ClassroomID, a.SessionDate)
+		if err != nil {
+			return err
+		}
+		a.TermID = termID
+	}
+	return nil
+}
+
+// BeforeUpdate re-stamps the row so it moves to the head of the sync change feed.
+// Callers updating with Select must include "ChangeTxID".
+func (a *Attendance) BeforeUpdate(tx *gorm.DB) error {
+	txID, err := currentTxID(tx)
+	if err != nil {
+		return err
+	}
+	tx.Statement.SetColumn("ChangeTxID", txID)
+	return nil
+}
+
+// termOfSession finds the term of the classroom's school that contains sessionDate
+func termOfSession(tx *gorm.DB, classroomID uint, sessionDate string) (*uint, error) {
+	var termIDs []uint
+	err := tx.Session(&gorm.Session{NewDB: true}).Model(&Term{}).
+		Joins("JOIN classrooms ON classrooms.school_id = terms.school_id").
+		Where("classrooms.id = ? AND ? BETWEEN terms.start_date AND terms.end_date", classroomID, sessionDate).
+		Limit(1).Pluck("terms.id", &termIDs).Error
+	if err != nil || len(termIDs) == 0 {
+		return nil, err
+	}
+	return &termIDs[0], nil
+}
+
+func currentTxID(tx *gorm.DB) (int64, error) {
+	var txID int64
+	err := tx.Session(&gorm.Session{NewDB: true}).Raw("SELECT txid_current()").Scan(&txID).Error
+	return txID, err
+}
+
 // IsValidStatus checks if the provided status is valid
 func (a *Attendance) IsValidStatus() bool {
 	switch a.Status {
@@ -14,12 +14,6 @@ type BaseModel struct {
 	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
-type Paginate struct {
-	Page  int64 `json:"page"`
-	Size  int64 `json:"size"`
-	Total int64 `json:"total"`
-}
-
 // Legacy Unix timestamp models (keeping for compatibility)
 type CreateUpdateUnixTimestamp struct {
 	CreateUnixTimestamp
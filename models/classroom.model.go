@@ -1,18 +1,23 @@
 package models
 
 type Classroom struct {
-	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	SchoolID  *uint  `gorm:"not null" json:"school_id"`
-	TeacherID *uint  `gorm:"not null" json:"teacher_id"`
-	Name      string `gorm:"type:varchar(255);not null" json:"name"`
-	Grade     string `gorm:"type:varchar(10);not null" json:"grade"` // ชั้นเรียน เช่น "ม.1", "ป.6"
-	CreatedAt int64  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt int64  `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt *int64 `gorm:"index" json:"deleted_at,omitempty"`
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SchoolID  *uint     `gorm:"not null" json:"school_id"`
+	TeacherID *uint     `gorm:"not null" json:"teacher_id"`
+	Name      string    `gorm:"type:varchar(255);not null" json:"name"`
+	Grade     string    `gorm:"type:varchar(10);not null" json:"grade"` // ชั้นเรียน เช่น "ม.1", "ป.6"
+	Version   uint      `gorm:"not null;default:1" json:"version"`      // Bumped on every update for conflict detection
+	CreatedAt int64     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt int64     `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
+
+	AcademicYearID    *uint `gorm:"index" json:"academic_year_id"` // NULL for classrooms created before academic years
+	SourceClassroomID *uint `json:"source_classroom_id,omitempty"` // Last year's classroom this one was rolled over from
 
 	// Foreign Key Relationships
-	School  *School  `gorm:"foreignKey:SchoolID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"school,omitempty"`
-	Teacher *Teacher `gorm:"foreignKey:TeacherID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"teacher,omitempty"`
+	School       *School       `gorm:"foreignKey:SchoolID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"school,omitempty"`
+	Teacher      *Teacher      `gorm:"foreignKey:TeacherID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"teacher,omitempty"`
+	AcademicYear *AcademicYear `gorm:"foreignKey:AcademicYearID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"academic_year,omitempty"`
 
 	// Has Many Relationships
 	Members     []ClassroomMember `gorm:"foreignKey:ClassroomID" json:"members,omitempty"`
@@ -23,3 +28,7 @@ type Classroom struct {
 func (c *Classroom) TableName() string {
 	return "classrooms"
 }
+
+func (c *Classroom) ETag() string {
+	return recordTag(c.TableName(), c.ID, c.UpdatedAt, c.Version)
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+// recordTag is the strong ETag the models' ETag methods return. It changes
+// whenever the row is written: version moves on every change even within the same
+// second. Tables that are never updated pass 0 and rely on updated_at.
+func recordTag(table string, id uint, updatedAt int64, version uint) string {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s/%d/%d/%d", table, id, updatedAt, version)
+	return `"` + strconv.FormatUint(h.Sum64(), 36) + `"`
+}
@@ -3,6 +3,7 @@ package models
 type Gender struct {
 	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name      string `gorm:"type:varchar(10);not null;unique" json:"name"`
+	Version   uint   `gorm:"not null;default:1" json:"version"` // Bumped on every update for conflict detection
 	CreatedAt int64  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt int64  `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt *int64 `gorm:"index" json:"deleted_at,omitempty"`
@@ -11,3 +12,7 @@ type Gender struct {
 func (Gender) TableName() string {
 	return "genders"
 }
+
+func (g Gender) ETag() string {
+	return recordTag(g.TableName(), g.ID, g.UpdatedAt, g.Version)
+}
@@ -0,0 +1,28 @@
+package models
+
+// IdempotencyStatus enum for the state of a keyed request
+type IdempotencyStatus string
+
+const (
+	IdempotencyStatusProcessing IdempotencyStatus = "processing" // First request is still running
+	IdempotencyStatusCompleted  IdempotencyStatus = "completed"  // Response stored for replay
+)
+
+// IdempotencyKey stores the outcome of a POST sent with an Idempotency-Key header
+// so that retries get the original response instead of running the handler again.
+type IdempotencyKey struct {
+	ID           uint              `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope        string            `gorm:"type:varchar(100);not null;uniqueIndex:idx_idempotency_scope_key" json:"scope"` // Teacher ID, or client IP for public routes
+	Key          string            `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_scope_key" json:"key"`
+	Fingerprint  string            `gorm:"type:char(64);not null" json:"fingerprint"` // SHA-256 of method, path and body
+	Status       IdempotencyStatus `gorm:"type:varchar(20);not null" json:"status"`
+	ResponseCode int               `json:"response_code"`
+	ContentType  string            `gorm:"type:varchar(100)" json:"content_type"`
+	ResponseBody string            `gorm:"type:text" json:"response_body"`
+	CreatedAt    int64             `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt    int64             `gorm:"not null;index" json:"expires_at"` // End of the lease while processing, of the replay window once completed
+}
+
+func (k *IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
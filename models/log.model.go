@@ -4,18 +4,23 @@ package models
 type LogAction string
 
 const (
-	LogActionLogin           LogAction = "login"
-	LogActionLogout          LogAction = "logout"
-	LogActionAttendance      LogAction = "attendance"
-	LogActionCreateClassroom LogAction = "create_classroom"
-	LogActionUpdateClassroom LogAction = "update_classroom"
-	LogActionDeleteClassroom LogAction = "delete_classroom"
-	LogActionCreateStudent   LogAction = "create_student"
-	LogActionUpdateStudent   LogAction = "update_student"
-	LogActionDeleteStudent   LogAction = "delete_student"
-	LogActionCreateTeacher   LogAction = "create_teacher"
-	LogActionUpdateTeacher   LogAction = "update_teacher"
-	LogActionDeleteTeacher   LogAction = "delete_teacher"
+	LogActionLogin            LogAction = "login"
+	LogActionLogout           LogAction = "logout"
+	LogActionAttendance       LogAction = "attendance"
+	LogActionCreateClassroom  LogAction = "create_classroom"
+	LogActionUpdateClassroom  LogAction = "update_classroom"
+	LogActionDeleteClassroom  LogAction = "delete_classroom"
+	LogActionRestoreClassroom LogAction = "restore_classroom"
+	LogActionCreateStudent    LogAction = "create_student"
+	LogActionUpdateStudent    LogAction = "update_student"
+	LogActionDeleteStudent    LogAction = "delete_student"
+	LogActionRestoreStudent   LogAction = "restore_student"
+	LogActionCreateTeacher    LogAction = "create_teacher"
+	LogActionUpdateTeacher    LogAction = "update_teacher"
+	LogActionDeleteTeacher    LogAction = "delete_teacher"
+	LogActionRestoreTeacher   LogAction = "restore_teacher"
+
+	LogActionRolloverAcademicYear LogAction = "rollover_academic_year"
 )
 
 type Log struct {
@@ -23,8 +28,14 @@ type Log struct {
 	TeacherID uint      `gorm:"not null" json:"teacher_id"`
 	Action    LogAction `gorm:"type:varchar(100);not null" json:"action"`
 	Detail    string    `gorm:"type:text" json:"detail"`
-	CreatedAt int64     `gorm:"autoCreateTime" json:"created_at"`
-	SchoolID  *uint     `gorm:"default:null" json:"school_id,omitempty"`
+	// MessageKey and MessageParams keep the entry translatable; Detail is rendered from them
+	// in the reader's language, and holds the free text of manually created entries
+	MessageKey    string        `gorm:"type:varchar(100)" json:"message_key,omitempty"`
+	MessageParams MessageParams `gorm:"type:jsonb" json:"message_params,omitempty"`
+	CreatedAt     int64         `gorm:"autoCreateTime" json:"created_at"`
+	SchoolID      *uint         `gorm:"default:null" json:"school_id,omitempty"`
+	EventID       *uint         `gorm:"uniqueIndex" json:"event_id,omitempty"`               // Outbox event that produced this entry
+	RequestID     *string       `gorm:"type:varchar(128);index" json:"request_id,omitempty"` // X-Request-ID of the request that caused it
 }
 
 func (l *Log) TableName() string {
@@ -35,9 +46,10 @@ func (l *Log) TableName() string {
 func (l *Log) IsValidAction() bool {
 	switch l.Action {
 	case LogActionLogin, LogActionLogout, LogActionAttendance,
-		LogActionCreateClassroom, LogActionUpdateClassroom, LogActionDeleteClassroom,
-		LogActionCreateStudent, LogActionUpdateStudent, LogActionDeleteStudent,
-		LogActionCreateTeacher, LogActionUpdateTeacher, LogActionDeleteTeacher:
+		LogActionCreateClassroom, LogActionUpdateClassroom, LogActionDeleteClassroom, LogActionRestoreClassroom,
+		LogActionCreateStudent, LogActionUpdateStudent, LogActionDeleteStudent, LogActionRestoreStudent,
+		LogActionCreateTeacher, LogActionUpdateTeacher, LogActionDeleteTeacher, LogActionRestoreTeacher,
+		LogActionRolloverAcademicYear:
 		return true
 	default:
 		return false
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MessageParams are the placeholder values of a translatable message, stored as a JSON object
+type MessageParams map[string]string
+
+// Scan implements the Scanner interface.
+func (p *MessageParams) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into MessageParams", value)
+	}
+	return json.Unmarshal(data, p)
+}
+
+// Value implements the driver Valuer interface.
+func (p MessageParams) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,39 @@
+package models
+
+// OutboxStatus enum for outbox event delivery state
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"   // Waiting to be published
+	OutboxStatusPublished OutboxStatus = "published" // Delivered to every consumer
+	OutboxStatusFailed    OutboxStatus = "failed"    // Gave up after max attempts
+)
+
+// OutboxEvent is a side effect recorded in the same transaction as the business
+// change. The dispatcher publishes pending rows to the consumers at least once.
+type OutboxEvent struct {
+	ID            uint          `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventType     string        `gorm:"type:varchar(100);not null;index" json:"event_type"` // e.g. "attendance.created"
+	AggregateType string        `gorm:"type:varchar(50);not null" json:"aggregate_type"`
+	AggregateID   uint          `gorm:"not null" json:"aggregate_id"`
+	TeacherID     uint          `gorm:"not null" json:"teacher_id"`
+	SchoolID      *uint         `gorm:"default:null" json:"school_id,omitempty"`
+	Action        LogAction     `gorm:"type:varchar(100)" json:"action,omitempty"`      // Activity log action, empty if none
+	Detail        string        `gorm:"type:text" json:"detail,omitempty"`              // Rendered in the default language
+	MessageKey    string        `gorm:"type:varchar(100)" json:"message_key,omitempty"` // Catalog key of Detail
+	MessageParams MessageParams `gorm:"type:jsonb" json:"message_params,omitempty"`
+	Payload       string        `gorm:"type:text" json:"payload"`                      // JSON encoded snapshot
+	RequestID     *string       `gorm:"type:varchar(128)" json:"request_id,omitempty"` // Request that recorded the event
+	Status        OutboxStatus  `gorm:"type:varchar(20);not null;default:pending;index:idx_outbox_status_next" json:"status"`
+	Attempts      int           `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt int64         `gorm:"not null;index:idx_outbox_status_next" json:"next_attempt_at"`
+	LastError     string        `gorm:"type:text" json:"last_error,omitempty"`
+	LockedUntil   *int64        `json:"locked_until,omitempty"`                        // Lease of the dispatcher publishing the row
+	ClaimedBy     *string       `gorm:"type:varchar(100)" json:"claimed_by,omitempty"` // Dispatcher holding the lease
+	CreatedAt     int64         `gorm:"autoCreateTime" json:"created_at"`
+	PublishedAt   *int64        `json:"published_at,omitempty"`
+}
+
+func (o *OutboxEvent) TableName() string {
+	return "outbox_events"
+}
@@ -3,6 +3,7 @@ package models
 type Prefix struct {
 	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name      string `gorm:"type:varchar(20);not null;unique" json:"name"`
+	Version   uint   `gorm:"not null;default:1" json:"version"` // Bumped on every update for conflict detection
 	CreatedAt int64  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt int64  `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt *int64 `gorm:"index" json:"deleted_at,omitempty"`
@@ -11,3 +12,7 @@ type Prefix struct {
 func (Prefix) TableName() string {
 	return "prefixes"
 }
+
+func (p Prefix) ETag() string {
+	return recordTag(p.TableName(), p.ID, p.UpdatedAt, p.Version)
+}
@@ -1,11 +1,39 @@
 package models
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SyncConflictPolicy decides who wins when an offline mutation hits a newer server row
+type SyncConflictPolicy string
+
+const (
+	SyncConflictServerWins     SyncConflictPolicy = "server_wins"      // Stale mutations are returned as conflicts
+	SyncConflictLastWriterWins SyncConflictPolicy = "last_writer_wins" // The newer client timestamp overwrites
+)
+
+// StudentNoYear picks the year component of generated student numbers
+type StudentNoYear string
+
+const (
+	StudentNoYearNone StudentNoYear = "none" // STD001
+	StudentNoYearCE   StudentNoYear = "ce"   // STD2025001, numbering restarts every year
+	StudentNoYearBE   StudentNoYear = "be"   // STD2568001 (พ.ศ.), numbering restarts every year
+)
+
 type School struct {
-	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name      string `gorm:"type:varchar(255);not null;unique" json:"name"`
-	CreatedAt int64  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt int64  `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt *int64 `gorm:"index" json:"deleted_at,omitempty"`
+	ID                 uint               `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name               string             `gorm:"type:varchar(255);not null;unique" json:"name"`
+	SyncConflictPolicy SyncConflictPolicy `gorm:"type:varchar(20);not null;default:server_wins" json:"sync_conflict_policy"`
+	StudentNoPrefix    string             `gorm:"type:varchar(20);not null;default:STD" json:"student_no_prefix"`
+	StudentNoWidth     int                `gorm:"not null;default:3" json:"student_no_width"` // Minimum digits of the running number
+	StudentNoYear      StudentNoYear      `gorm:"type:varchar(10);not null;default:none" json:"student_no_year"`
+	Version            uint               `gorm:"not null;default:1" json:"version"` // Bumped on every update for conflict detection
+	CreatedAt          int64              `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          int64              `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt          *int64             `gorm:"index" json:"deleted_at,omitempty"`
 
 	// Relationships
 	Students []Student `gorm:"foreignKey:SchoolID" json:"students,omitempty"`
@@ -15,3 +43,37 @@ type School struct {
 func (s *School) TableName() string {
 	return "schools"
 }
+
+func (s *School) ETag() string {
+	return recordTag(s.TableName(), s.ID, s.UpdatedAt, s.Version)
+}
+
+// StudentNoPeriod is the counter period for numbers generated at now: the year when
+// the format contains one, so numbering restarts every year, or "" otherwise
+func (s *School) StudentNoPeriod(now time.Time) string {
+	if s.StudentNoYear == StudentNoYearCE || s.StudentNoYear == StudentNoYearBE {
+		return strconv.Itoa(now.Year())
+	}
+	return ""
+}
+
+// StudentNoStem is the part of a generated student number before the running number
+func (s *School) StudentNoStem(now time.Time) string {
+	switch s.StudentNoYear {
+	case StudentNoYearCE:
+		return s.StudentNoPrefix + strconv.Itoa(now.Year())
+	case StudentNoYearBE:
+		return s.StudentNoPrefix + strconv.Itoa(now.Year()+543)
+	default:
+		return s.StudentNoPrefix
+	}
+}
+
+// FormatStudentNo renders the running number seq in the school's student number format
+func (s *School) FormatStudentNo(seq int64, now time.Time) string {
+	width := s.StudentNoWidth
+	if width <= 0 {
+		width = 3
+	}
+	return fmt.Sprintf("%s%0*d", s.StudentNoStem(now), width, seq)
+}
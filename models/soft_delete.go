@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DeletedAt is a soft-delete marker stored as unix seconds, NULL while the row is live.
+// It works like gorm.DeletedAt: Delete stamps the column instead of removing the row,
+// and queries skip deleted rows unless Unscoped is used.
+type DeletedAt sql.NullInt64
+
+// NewDeletedAt returns a marker set to the given time
+func NewDeletedAt(t time.Time) DeletedAt {
+	return DeletedAt{Int64: t.Unix(), Valid: true}
+}
+
+// Scan implements the Scanner interface.
+func (n *DeletedAt) Scan(value interface{}) error {
+	return (*sql.NullInt64)(n).Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (n DeletedAt) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Int64, nil
+}
+
+func (n DeletedAt) MarshalJSON() ([]byte, error) {
+	if n.Valid {
+		return json.Marshal(n.Int64)
+	}
+	return json.Marshal(nil)
+}
+
+func (n *DeletedAt) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		n.Int64, n.Valid = 0, false
+		return nil
+	}
+	err := json.Unmarshal(b, &n.Int64)
+	n.Valid = err == nil
+	return err
+}
+
+func (DeletedAt) QueryClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{gorm.SoftDeleteQueryClause{Field: f}}
+}
+
+func (DeletedAt) UpdateClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{gorm.SoftDeleteUpdateClause{Field: f}}
+}
+
+func (DeletedAt) DeleteClauses(f *schema.Field) []clause.Interface {
+	return []clause.Interface{softDeleteClause{Field: f}}
+}
+
+// softDeleteClause turns DELETE into an UPDATE of the marker, same as gorm's
+// SoftDeleteDeleteClause but writing unix seconds instead of a timestamp
+type softDeleteClause struct {
+	Field *schema.Field
+}
+
+func (sd softDeleteClause) Name() string {
+	return ""
+}
+
+func (sd softDeleteClause) Build(clause.Builder) {
+}
+
+func (sd softDeleteClause) MergeClause(*clause.Clause) {
+}
+
+func (sd softDeleteClause) ModifyStatement(stmt *gorm.Statement) {
+	if stmt.SQL.Len() != 0 || stmt.Statement.Unscoped {
+		return
+	}
+
+	deletedAt := NewDeletedAt(stmt.DB.NowFunc())
+	stmt.AddClause(clause.Set{{Column: clause.Column{Name: sd.Field.DBName}, Value: deletedAt}})
+	stmt.SetColumn(sd.Field.DBName, deletedAt, true)
+
+	if stmt.Schema != nil {
+		_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+		if len(values) > 0 {
+			stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+		}
+
+		if stmt.ReflectValue.CanAddr() && stmt.Dest != stmt.Model && stmt.Model != nil {
+			_, queryValues = schema.GetIdentityFieldValuesMap(stmt.Context, reflect.ValueOf(stmt.Model), stmt.Schema.PrimaryFields)
+			column, values = schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+			if len(values) > 0 {
+				stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+			}
+		}
+	}
+
+	gorm.SoftDeleteQueryClause{Field: sd.Field}.ModifyStatement(stmt)
+	stmt.AddClauseIfNotExists(clause.Update{})
+	stmt.Build(stmt.DB.Callback().Update().Clauses...)
+}
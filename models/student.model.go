@@ -1,17 +1,18 @@
 package models
 
 type Student struct {
-	ID          uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	SchoolID    *uint  `gorm:"not null" json:"school_id"`
-	ClassroomID *uint  `gorm:"not null;uniqueIndex:idx_student_no_classroom" json:"classroom_id"`
-	StudentNo   string `gorm:"type:varchar(20);not null;uniqueIndex:idx_student_no_classroom" json:"student_no"`
-	FirstName   string `gorm:"type:varchar(100);not null" json:"firstname"`
-	LastName    string `gorm:"type:varchar(100);not null" json:"lastname"`
-	GenderID    *uint  `json:"gender_id"`
-	PrefixID    *uint  `json:"prefix_id"`
-	CreatedAt   int64  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt   int64  `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt   *int64 `gorm:"index" json:"deleted_at,omitempty"`
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SchoolID    *uint     `gorm:"not null" json:"school_id"`
+	ClassroomID *uint     `gorm:"not null;uniqueIndex:idx_student_no_classroom,where:deleted_at IS NULL" json:"classroom_id"`
+	StudentNo   string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_student_no_classroom,where:deleted_at IS NULL" json:"student_no"`
+	FirstName   string    `gorm:"type:varchar(100);not null" json:"firstname"`
+	LastName    string    `gorm:"type:varchar(100);not null" json:"lastname"`
+	GenderID    *uint     `json:"gender_id"`
+	PrefixID    *uint     `json:"prefix_id"`
+	Version     uint      `gorm:"not null;default:1" json:"version"` // Bumped on every update for conflict detection
+	CreatedAt   int64     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   int64     `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt   DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
 
 	// Foreign Key Relationships
 	School    *School    `gorm:"foreignKey:SchoolID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"school,omitempty"`
@@ -27,3 +28,7 @@ type Student struct {
 func (s *Student) TableName() string {
 	return "students"
 }
+
+func (s *Student) ETag() string {
+	return recordTag(s.TableName(), s.ID, s.UpdatedAt, s.Version)
+}
@@ -0,0 +1,17 @@
+package models
+
+// StudentNoCounter holds the last student number handed out in a classroom.
+// The row is locked by the allocating transaction, so concurrent creates queue up
+// instead of computing the same next number.
+type StudentNoCounter struct {
+	ClassroomID uint   `gorm:"primaryKey;autoIncrement:false" json:"classroom_id"`
+	Period      string `gorm:"primaryKey;type:varchar(10)" json:"period"` // Year for yearly formats, empty otherwise
+	LastValue   int64  `gorm:"not null" json:"last_value"`
+	UpdatedAt   int64  `json:"updated_at"`
+
+	Classroom *Classroom `gorm:"foreignKey:ClassroomID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"classroom,omitempty"`
+}
+
+func (c *StudentNoCounter) TableName() string {
+	return "student_no_counters"
+}
@@ -0,0 +1,15 @@
+package models
+
+// SyncMutation records an offline mutation that has been applied, so a client
+// re-sending its queue after a dropped response does not apply it twice.
+type SyncMutation struct {
+	ID               uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	TeacherID        uint   `gorm:"not null;uniqueIndex:idx_sync_mutation_client" json:"teacher_id"`
+	ClientMutationID string `gorm:"type:uuid;not null;uniqueIndex:idx_sync_mutation_client" json:"client_mutation_id"`
+	AttendanceID     *uint  `gorm:"default:null" json:"attendance_id,omitempty"`
+	CreatedAt        int64  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (m *SyncMutation) TableName() string {
+	return "sync_mutations"
+}
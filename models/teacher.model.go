@@ -1,18 +1,20 @@
 package models
 
 type Teacher struct {
-	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	SchoolID  *uint  `gorm:"not null" json:"school_id"`
-	Email     string `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"`
-	Password  string `gorm:"type:varchar(255);not null" json:"-"` // Hide password from JSON
-	FirstName string `gorm:"type:varchar(100);not null" json:"firstname"`
-	LastName  string `gorm:"type:varchar(100);not null" json:"lastname"`
-	Phone     string `gorm:"type:varchar(20)" json:"phone"`
-	GenderID  *uint  `json:"gender_id"`
-	PrefixID  *uint  `json:"prefix_id"`
-	CreatedAt int64  `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt int64  `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt *int64 `gorm:"index" json:"deleted_at,omitempty"`
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	SchoolID  *uint     `gorm:"not null" json:"school_id"`
+	Email     string    `gorm:"type:varchar(255);uniqueIndex:idx_teachers_email,where:deleted_at IS NULL;not null" json:"email"`
+	Password  string    `gorm:"type:varchar(255);not null" json:"-"` // Hide password from JSON
+	FirstName string    `gorm:"type:varchar(100);not null" json:"firstname"`
+	LastName  string    `gorm:"type:varchar(100);not null" json:"lastname"`
+	Phone     string    `gorm:"type:varchar(20)" json:"phone"`
+	GenderID  *uint     `json:"gender_id"`
+	PrefixID  *uint     `json:"prefix_id"`
+	Language  string    `gorm:"type:varchar(5);not null;default:''" json:"language"` // Preferred language ("th", "en"), empty for Accept-Language
+	Version   uint      `gorm:"not null;default:1" json:"version"`                   // Bumped on every update for conflict detection
+	CreatedAt int64     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt int64     `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt DeletedAt `gorm:"index" json:"deleted_at,omitzero"`
 
 	// Foreign Key Relationships
 	School *School `gorm:"foreignKey:SchoolID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;" json:"school,omitempty"`
@@ -28,3 +30,7 @@ type Teacher struct {
 func (t *Teacher) TableName() string {
 	return "teachers"
 }
+
+func (t *Teacher) ETag() string {
+	return recordTag(t.TableName(), t.ID, t.UpdatedAt, t.Version)
+}
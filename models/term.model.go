@@ -0,0 +1,26 @@
+package models
+
+// Term is a semester of an academic year. Attendances are stamped with the term their
+// session date falls in, so past terms can be queried after the classrooms roll over.
+type Term struct {
+	ID             uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	AcademicYearID *uint  `gorm:"not null;uniqueIndex:idx_terms_year_number" json:"academic_year_id"`
+	SchoolID       *uint  `gorm:"not null;index:idx_terms_school_dates" json:"school_id"`   // Copied from the year, for date lookups
+	Number         int    `gorm:"not null;uniqueIndex:idx_terms_year_number" json:"number"` // ภาคเรียนที่ 1, 2, ...
+	StartDate      string `gorm:"type:date;not null;index:idx_terms_school_dates" json:"start_date"`
+	EndDate        string `gorm:"type:date;not null;index:idx_terms_school_dates" json:"end_date"`
+	CreatedAt      int64  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      int64  `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// Foreign Key Relationships
+	AcademicYear *AcademicYear `gorm:"foreignKey:AcademicYearID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"academic_year,omitempty"`
+	School       *School       `gorm:"foreignKey:SchoolID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;" json:"school,omitempty"`
+}
+
+func (t *Term) TableName() string {
+	return "terms"
+}
+
+func (t *Term) ETag() string {
+	return recordTag(t.TableName(), t.ID, t.UpdatedAt, 0)
+}
@@ -0,0 +1,239 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/outbox"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AcademicYearRepository reads and writes academic years, their terms and the
+// classrooms and students a rollover moves into the next year
+type AcademicYearRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) AcademicYearRepository
+	// WithTx runs fn with a repository bound to one transaction, committed when fn returns nil
+	WithTx(fn func(repo AcademicYearRepository) error) error
+	// Enqueue records an outbox event in the repository's transaction
+	Enqueue(event outbox.Event) error
+
+	// FindByID loads the year with its terms in order
+	FindByID(id uint) (*models.AcademicYear, error)
+	// Lock holds the year's row until the transaction ends, so rollovers into it queue up
+	Lock(id uint) error
+	// FindCurrent returns the school's year whose dates contain day (YYYY-MM-DD)
+	FindCurrent(schoolID uint, day string) (*models.AcademicYear, error)
+	// List returns one page of query plus a look-ahead row, see listquery.Page
+	List(query listquery.Query) ([]models.AcademicYear, error)
+	NameTaken(schoolID uint, name string) (bool, error)
+	// Overlaps reports whether another year of the school shares a day with startDate..endDate
+	Overlaps(schoolID uint, startDate, endDate string) (bool, error)
+	// Create inserts the year; the school's first year adopts the classrooms that have none.
+	// Dates overlapping another year of the school fail with ErrDatesOverlap.
+	Create(year *models.AcademicYear) error
+
+	// ListTerms returns one page of the year's terms plus a look-ahead row, see listquery.Page
+	ListTerms(academicYearID uint, query listquery.Query) ([]models.Term, error)
+	TermNumberTaken(academicYearID uint, number int) (bool, error)
+	// TermOverlaps reports whether another term of the school shares a day with startDate..endDate
+	TermOverlaps(schoolID uint, startDate, endDate string) (bool, error)
+	// CreateTerm inserts the term and stamps the school's attendances in its dates that have no term yet.
+	// Dates overlapping another term of the school fail with ErrDatesOverlap.
+	CreateTerm(term *models.Term) error
+
+	// ListClassrooms returns the year's live classrooms with their live students
+	ListClassrooms(academicYearID uint) ([]models.Classroom, error)
+	CreateClassroom(classroom *models.Classroom) error
+	// MoveStudents puts the students in the classroom, bumping their version
+	MoveStudents(studentIDs []uint, classroomID uint) error
+	// RenumberStudent puts one student in the classroom under a new student number
+	RenumberStudent(studentID, classroomID uint, studentNo string) error
+	// HighestStudentNo and NextStudentNo work as on StudentRepository, for students
+	// whose number is already taken in the classroom they move into
+	HighestStudentNo(classroomID uint, stem string) (int64, error)
+	NextStudentNo(classroomID uint, period string, floor int64, now int64) (int64, error)
+}
+
+// ErrDatesOverlap is returned when the exclusion constraint keeping a school's years,
+// or its terms, apart rejects a write that raced past the Overlaps check
+var ErrDatesOverlap = errors.New("dates overlap another row of the school")
+
+// exclusionViolation is the SQLSTATE of a failed EXCLUDE constraint; GORM's error
+// translation does not cover it
+const exclusionViolation = "23P01"
+
+func datesOverlap(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == exclusionViolation {
+		return ErrDatesOverlap
+	}
+	return err
+}
+
+type academicYearRepository struct {
+	db *gorm.DB
+}
+
+func NewAcademicYearRepository(db *gorm.DB) AcademicYearRepository {
+	return &academicYearRepository{db: db}
+}
+
+func (r *academicYearRepository) WithContext(ctx context.Context) AcademicYearRepository {
+	return &academicYearRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *academicYearRepository) WithTx(fn func(repo AcademicYearRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&academicYearRepository{db: tx})
+	})
+}
+
+func (r *academicYearRepository) Enqueue(event outbox.Event) error {
+	return outbox.Enqueue(r.db, event)
+}
+
+func (r *academicYearRepository) FindByID(id uint) (*models.AcademicYear, error) {
+	var year models.AcademicYear
+	err := r.db.
+		Preload("Terms", func(db *gorm.DB) *gorm.DB { return db.Order("number") }).
+		Where("id = ?", id).First(&year).Error
+	if err != nil {
+		return nil, err
+	}
+	return &year, nil
+}
+
+func (r *academicYearRepository) Lock(id uint) error {
+	var locked models.AcademicYear
+	return r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", id).First(&locked).Error
+}
+
+func (r *academicYearRepository) FindCurrent(schoolID uint, day string) (*models.AcademicYear, error) {
+	var year models.AcademicYear
+	err := r.db.Where("school_id = ? AND ? BETWEEN start_date AND end_date", schoolID, day).First(&year).Error
+	if err != nil {
+		return nil, err
+	}
+	return &year, nil
+}
+
+func (r *academicYearRepository) List(query listquery.Query) ([]models.AcademicYear, error) {
+	var years []models.AcademicYear
+	err := r.db.Scopes(query.Scope).Find(&years).Error
+	return years, err
+}
+
+func (r *academicYearRepository) NameTaken(schoolID uint, name string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.AcademicYear{}).Where("school_id = ? AND name = ?", schoolID, name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *academicYearRepository) Overlaps(schoolID uint, startDate, endDate string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.AcademicYear{}).
+		Where("school_id = ? AND start_date <= ? AND end_date >= ?", schoolID, endDate, startDate).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *academicYearRepository) Create(year *models.AcademicYear) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.AcademicYear{}).Where("school_id = ?", year.SchoolID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(year).Error; err != nil {
+			return datesOverlap(err)
+		}
+		if existing > 0 {
+			return nil
+		}
+		return tx.Unscoped().Model(&models.Classroom{}).
+			Where("school_id = ? AND academic_year_id IS NULL", year.SchoolID).
+			UpdateColumn("academic_year_id", year.ID).Error
+	})
+}
+
+func (r *academicYearRepository) ListTerms(academicYearID uint, query listquery.Query) ([]models.Term, error) {
+	var terms []models.Term
+	err := r.db.Where("academic_year_id = ?", academicYearID).Scopes(query.Scope).Find(&terms).Error
+	return terms, err
+}
+
+func (r *academicYearRepository) TermNumberTaken(academicYearID uint, number int) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Term{}).Where("academic_year_id = ? AND number = ?", academicYearID, number).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *academicYearRepository) TermOverlaps(schoolID uint, startDate, endDate string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Term{}).
+		Where("school_id = ? AND start_date <= ? AND end_date >= ?", schoolID, endDate, startDate).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateTerm stamps the attendances with UpdateColumn so the sync change feed does not
+// replay them: term_id is derived from the session date, not a change made by a teacher
+func (r *academicYearRepository) CreateTerm(term *models.Term) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(term).Error; err != nil {
+			return datesOverlap(err)
+		}
+		return tx.Unscoped().Model(&models.Attendance{}).
+			Where("term_id IS NULL AND session_date BETWEEN ? AND ?", term.StartDate, term.EndDate).
+			Where("classroom_id IN (?)", tx.Unscoped().Model(&models.Classroom{}).Select("id").Where("school_id = ?", term.SchoolID)).
+			UpdateColumn("term_id", term.ID).Error
+	})
+}
+
+func (r *academicYearRepository) ListClassrooms(academicYearID uint) ([]models.Classroom, error) {
+	var classrooms []models.Classroom
+	err := r.db.
+		Preload("Students", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("academic_year_id = ?", academicYearID).
+		Order("id").
+		Find(&classrooms).Error
+	return classrooms, err
+}
+
+func (r *academicYearRepository) CreateClassroom(classroom *models.Classroom) error {
+	return r.db.Create(classroom).Error
+}
+
+func (r *academicYearRepository) MoveStudents(studentIDs []uint, classroomID uint) error {
+	if len(studentIDs) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.Student{}).
+		Where("id IN ?", studentIDs).
+		Updates(map[string]any{
+			"classroom_id": classroomID,
+			"version":      gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *academicYearRepository) RenumberStudent(studentID, classroomID uint, studentNo string) error {
+	return r.db.Model(&models.Student{}).
+		Where("id = ?", studentID).
+		Updates(map[string]any{
+			"classroom_id": classroomID,
+			"student_no":   studentNo,
+			"version":      gorm.Expr("version + 1"),
+		}).Error
+}
+
+func (r *academicYearRepository) HighestStudentNo(classroomID uint, stem string) (int64, error) {
+	return highestStudentNo(r.db, classroomID, stem)
+}
+
+func (r *academicYearRepository) NextStudentNo(classroomID uint, period string, floor int64, now int64) (int64, error) {
+	return nextStudentNo(r.db, classroomID, period, floor, now)
+}
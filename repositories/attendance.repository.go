@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/outbox"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AttendanceRepository reads and writes attendance records
+type AttendanceRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) AttendanceRepository
+	// WithTx runs fn with a repository bound to one transaction, committed when fn returns nil
+	WithTx(fn func(repo AttendanceRepository) error) error
+	// Enqueue records an outbox event in the repository's transaction
+	Enqueue(event outbox.Event) error
+	// WithSelection returns a repository whose reads select only the fields and
+	// preload only the relations of sel
+	WithSelection(sel fieldset.Selection) AttendanceRepository
+	// LockSlot serializes writers of one student's attendance on one date until the transaction ends
+	LockSlot(classroomID, studentID uint, sessionDate string) error
+
+	FindByID(id uint) (*models.Attendance, error)
+	FindDeletedByID(id uint) (*models.Attendance, error)
+	FindBySlot(classroomID, studentID uint, sessionDate string) (*models.Attendance, error)
+	// FindDeletedBySlot returns the most recently deleted record of the slot
+	FindDeletedBySlot(classroomID, studentID uint, sessionDate string) (*models.Attendance, error)
+	// The List methods return one page of query plus a look-ahead row, see listquery.Page
+	ListByClassroom(classroomID uint, query listquery.Query) ([]models.Attendance, error)
+	ListByStudent(studentID uint, query listquery.Query) ([]models.Attendance, error)
+	ListByTeacher(teacherID uint, query listquery.Query) ([]models.Attendance, error)
+	ListBySession(classroomID uint, sessionDate string) ([]models.Attendance, error)
+	// HasLiveParents reports whether the classroom and the student both exist outside the trash
+	HasLiveParents(classroomID, studentID uint) (bool, error)
+
+	// Create inserts a record; it returns gorm.ErrDuplicatedKey when the slot is already taken
+	Create(attendance *models.Attendance) error
+	// UpdateIfVersion writes the mark fields only while the row still has baseVersion
+	UpdateIfVersion(attendance *models.Attendance, baseVersion uint) (bool, error)
+	SoftDelete(attendance *models.Attendance, deletedAt models.DeletedAt) error
+	Restore(attendance *models.Attendance) error
+}
+
+// attendanceSlotConflict targets the unique index on (classroom_id, student_id, session_date)
+var attendanceSlotConflict = clause.OnConflict{
+	Columns: []clause.Column{{Name: "classroom_id"}, {Name: "student_id"}, {Name: "session_date"}},
+	TargetWhere: clause.Where{Exprs: []clause.Expression{
+		clause.Expr{SQL: "deleted_at IS NULL"},
+	}},
+	DoNothing: true,
+}
+
+type attendanceRepository struct {
+	db *gorm.DB
+}
+
+func NewAttendanceRepository(db *gorm.DB) AttendanceRepository {
+	return &attendanceRepository{db: db}
+}
+
+func (r *attendanceRepository) WithContext(ctx context.Context) AttendanceRepository {
+	return &attendanceRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *attendanceRepository) WithTx(fn func(repo AttendanceRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&attendanceRepository{db: tx})
+	})
+}
+
+func (r *attendanceRepository) Enqueue(event outbox.Event) error {
+	return outbox.Enqueue(r.db, event)
+}
+
+func (r *attendanceRepository) WithSelection(sel fieldset.Selection) AttendanceRepository {
+	return &attendanceRepository{db: sel.Scope(r.db).Session(&gorm.Session{})}
+}
+
+func (r *attendanceRepository) LockSlot(classroomID, studentID uint, sessionDate string) error {
+	return LockAttendanceSlot(r.db, classroomID, studentID, sessionDate)
+}
+
+func (r *attendanceRepository) FindByID(id uint) (*models.Attendance, error) {
+	var attendance models.Attendance
+	if err := r.db.Where("id = ?", id).First(&attendance).Error; err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+func (r *attendanceRepository) FindDeletedByID(id uint) (*models.Attendance, error) {
+	var attendance models.Attendance
+	if err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&attendance).Error; err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+func (r *attendanceRepository) FindBySlot(classroomID, studentID uint, sessionDate string) (*models.Attendance, error) {
+	var attendance models.Attendance
+	if err := r.db.Where("classroom_id = ? AND student_id = ? AND session_date = ?",
+		classroomID, studentID, sessionDate).First(&attendance).Error; err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+func (r *attendanceRepository) FindDeletedBySlot(classroomID, studentID uint, sessionDate string) (*models.Attendance, error) {
+	var attendance models.Attendance
+	if err := r.db.Unscoped().
+		Where("classroom_id = ? AND student_id = ? AND session_date = ? AND deleted_at IS NOT NULL",
+			classroomID, studentID, sessionDate).
+		Order("deleted_at DESC, id DESC").
+		First(&attendance).Error; err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+func (r *attendanceRepository) ListByClassroom(classroomID uint, query listquery.Query) ([]models.Attendance, error) {
+	var attendances []models.Attendance
+	err := r.db.Scopes(query.Scope).Where("classroom_id = ?", classroomID).Find(&attendances).Error
+	return attendances, err
+}
+
+func (r *attendanceRepository) ListByStudent(studentID uint, query listquery.Query) ([]models.Attendance, error) {
+	var attendances []models.Attendance
+	err := r.db.Scopes(query.Scope).Where("student_id = ?", studentID).Find(&attendances).Error
+	return attendances, err
+}
+
+func (r *attendanceRepository) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Attendance, error) {
+	var attendances []models.Attendance
+	err := r.db.Scopes(query.Scope).Where("teacher_id = ?", teacherID).Find(&attendances).Error
+	return attendances, err
+}
+
+func (r *attendanceRepository) ListBySession(classroomID uint, sessionDate string) ([]models.Attendance, error) {
+	var attendances []models.Attendance
+	err := r.db.
+		Where("classroom_id = ? AND session_date = ?", classroomID, sessionDate).
+		Order("student_id").
+		Find(&attendances).Error
+	return attendances, err
+}
+
+func (r *attendanceRepository) HasLiveParents(classroomID, studentID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Student{}).
+		Joins("JOIN classrooms ON classrooms.id = students.classroom_id AND classrooms.deleted_at IS NULL").
+		Where("students.id = ? AND classrooms.id = ?", studentID, classroomID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Create lets the unique slot index decide between concurrent creates instead of a check-then-insert
+func (r *attendanceRepository) Create(attendance *models.Attendance) error {
+	result := r.db.Clauses(attendanceSlotConflict).Create(attendance)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrDuplicatedKey
+	}
+	return nil
+}
+
+func (r *attendanceRepository) UpdateIfVersion(attendance *models.Attendance, baseVersion uint) (bool, error) {
+	result := r.db.Model(attendance).
+		Where("version = ?", baseVersion).
+		Select("TeacherID", "Status", "Remark", "CheckedAt", "Version", "ChangeTxID").
+		Updates(attendance)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *attendanceRepository) SoftDelete(attendance *models.Attendance, deletedAt models.DeletedAt) error {
+	if _, err := SoftDeleteAttendances(r.db, deletedAt, "id = ?", attendance.ID); err != nil {
+		return err
+	}
+	attendance.DeletedAt = deletedAt
+	return nil
+}
+
+func (r *attendanceRepository) Restore(attendance *models.Attendance) error {
+	_, err := RestoreAttendances(r.db, attendance.DeletedAt, "id = ?", attendance.ID)
+	return err
+}
+
+// LockAttendanceSlot serializes writers of one student's attendance on one date
+// until the surrounding transaction ends
+func LockAttendanceSlot(tx *gorm.DB, classroomID, studentID uint, sessionDate string) error {
+	lockKey := fmt.Sprintf("attendance:%d:%d:%s", classroomID, studentID, sessionDate)
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lockKey).Error
+}
+
+// SoftDeleteAttendances moves the matching live attendances to the trash. The
+// delete is an update so the rows get a new version and a change feed position,
+// which is how offline clients learn about it.
+func SoftDeleteAttendances(tx *gorm.DB, deletedAt models.DeletedAt, query interface{}, args ...interface{}) (int64, error) {
+	result := tx.Model(&models.Attendance{}).Where(query, args...).Updates(map[string]interface{}{
+		"deleted_at": deletedAt,
+		"version":    gorm.Expr("version + 1"),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// RestoreAttendances undoes SoftDeleteAttendances for rows deleted at exactly deletedAt,
+// so a cascaded restore only brings back what the matching delete removed
+func RestoreAttendances(tx *gorm.DB, deletedAt models.DeletedAt, query interface{}, args ...interface{}) (int64, error) {
+	result := tx.Unscoped().Model(&models.Attendance{}).
+		Where("deleted_at = ?", deletedAt).
+		Where(query, args...).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	return result.RowsAffected, result.Error
+}
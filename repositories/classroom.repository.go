@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/outbox"
+
+	"gorm.io/gorm"
+)
+
+// ClassroomRepository reads and writes classrooms
+type ClassroomRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) ClassroomRepository
+	// WithTx runs fn with a repository bound to one transaction, committed when fn returns nil
+	WithTx(fn func(repo ClassroomRepository) error) error
+	// Enqueue records an outbox event in the repository's transaction
+	Enqueue(event outbox.Event) error
+
+	FindByID(id uint) (*models.Classroom, error)
+	FindDeletedByID(id uint) (*models.Classroom, error)
+	FindByName(schoolID uint, name string) (*models.Classroom, error)
+	// NameTaken reports whether another live classroom of the school's academic year
+	// already uses the name; classrooms without a year are compared with each other
+	NameTaken(schoolID uint, academicYearID *uint, name string, excludeID uint) (bool, error)
+	// ListByTeacher returns one page of query plus a look-ahead row, see listquery.Page
+	ListByTeacher(teacherID uint, query listquery.Query) ([]models.Classroom, error)
+	// ListWithStudentsByTeacher loads the teacher's classrooms with students, genders and prefixes
+	ListWithStudentsByTeacher(teacherID uint) ([]models.Classroom, error)
+	// AccessibleIDs returns the live classrooms a teacher owns or has joined as a member
+	AccessibleIDs(teacherID uint) ([]uint, error)
+
+	Create(classroom *models.Classroom) error
+	// UpdateIfVersion writes the editable fields only while the row still has baseVersion
+	UpdateIfVersion(classroom *models.Classroom, baseVersion uint) (bool, error)
+	// SoftDelete moves the classroom, its students and its attendances to the trash with one timestamp
+	SoftDelete(classroom *models.Classroom, deletedAt models.DeletedAt) error
+	// Restore brings the classroom back with the students and attendances deleted at the same time
+	Restore(classroom *models.Classroom) error
+}
+
+type classroomRepository struct {
+	db *gorm.DB
+}
+
+func NewClassroomRepository(db *gorm.DB) ClassroomRepository {
+	return &classroomRepository{db: db}
+}
+
+func (r *classroomRepository) WithContext(ctx context.Context) ClassroomRepository {
+	return &classroomRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *classroomRepository) WithTx(fn func(repo ClassroomRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&classroomRepository{db: tx})
+	})
+}
+
+func (r *classroomRepository) Enqueue(event outbox.Event) error {
+	return outbox.Enqueue(r.db, event)
+}
+
+func (r *classroomRepository) FindByID(id uint) (*models.Classroom, error) {
+	var classroom models.Classroom
+	if err := r.db.Where("id = ?", id).First(&classroom).Error; err != nil {
+		return nil, err
+	}
+	return &classroom, nil
+}
+
+func (r *classroomRepository) FindDeletedByID(id uint) (*models.Classroom, error) {
+	var classroom models.Classroom
+	if err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&classroom).Error; err != nil {
+		return nil, err
+	}
+	return &classroom, nil
+}
+
+// FindByName returns the newest classroom of that name, i.e. the one of the latest year
+func (r *classroomRepository) FindByName(schoolID uint, name string) (*models.Classroom, error) {
+	var classroom models.Classroom
+	if err := r.db.Where("school_id = ? AND name = ?", schoolID, name).Order("id DESC").First(&classroom).Error; err != nil {
+		return nil, err
+	}
+	return &classroom, nil
+}
+
+func (r *classroomRepository) NameTaken(schoolID uint, academicYearID *uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Classroom{}).
+		Where("name = ? AND school_id = ? AND academic_year_id IS NOT DISTINCT FROM ? AND id != ?", name, schoolID, academicYearID, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *classroomRepository) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Classroom, error) {
+	var classrooms []models.Classroom
+	err := r.db.Scopes(query.Scope).Where("teacher_id = ?", teacherID).Find(&classrooms).Error
+	return classrooms, err
+}
+
+func (r *classroomRepository) ListWithStudentsByTeacher(teacherID uint) ([]models.Classroom, error) {
+	var classrooms []models.Classroom
+	err := r.db.
+		Preload("Students", func(db *gorm.DB) *gorm.DB {
+			return db.Preload("Gender").Preload("Prefix")
+		}).
+		Where("teacher_id = ?", teacherID).
+		Find(&classrooms).Error
+	return classrooms, err
+}
+
+func (r *classroomRepository) AccessibleIDs(teacherID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Classroom{}).
+		Where("teacher_id = ? OR id IN (?)", teacherID,
+			r.db.Model(&models.ClassroomMember{}).Select("classroom_id").Where("teacher_id = ?", teacherID)).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *classroomRepository) Create(classroom *models.Classroom) error {
+	return r.db.Create(classroom).Error
+}
+
+func (r *classroomRepository) UpdateIfVersion(classroom *models.Classroom, baseVersion uint) (bool, error) {
+	result := r.db.Model(classroom).
+		Where("version = ?", baseVersion).
+		Select("SchoolID", "TeacherID", "AcademicYearID", "Name", "Grade", "Version").
+		Updates(classroom)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SoftDelete and Restore write several statements; inside WithTx GORM runs the
+// nested Transaction as a savepoint
+func (r *classroomRepository) SoftDelete(classroom *models.Classroom, deletedAt models.DeletedAt) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(classroom).Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Student{}).Where("classroom_id = ?", classroom.ID).
+			Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		_, err := SoftDeleteAttendances(tx, deletedAt, "classroom_id = ?", classroom.ID)
+		return err
+	})
+}
+
+func (r *classroomRepository) Restore(classroom *models.Classroom) error {
+	deletedAt := classroom.DeletedAt
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(classroom).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Student{}).
+			Where("classroom_id = ? AND deleted_at = ?", classroom.ID, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		_, err := RestoreAttendances(tx, deletedAt, "classroom_id = ?", classroom.ID)
+		return err
+	})
+}
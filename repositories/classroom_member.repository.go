@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+
+	"gorm.io/gorm"
+)
+
+// ClassroomMemberRepository reads and writes the teachers and students who joined a
+// classroom. A member row has no ID of its own; memberID is its teacher or student ID.
+type ClassroomMemberRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) ClassroomMemberRepository
+
+	// The List methods return one page of query plus a look-ahead row, see listquery.Page
+	List(query listquery.Query) ([]models.ClassroomMember, error)
+	ListByClassroom(classroomID uint, query listquery.Query) ([]models.ClassroomMember, error)
+	// Exists reports whether the teacher or student is already a member of the classroom
+	Exists(classroomID uint, teacherID, studentID *uint) (bool, error)
+	FindByMember(classroomID, memberID uint) (*models.ClassroomMember, error)
+
+	Create(member *models.ClassroomMember) error
+	// UpdateByMember writes the teacher and student of the member found by FindByMember
+	UpdateByMember(classroomID, memberID uint, member *models.ClassroomMember) error
+	// DeleteByMember removes the member and returns how many rows matched
+	DeleteByMember(classroomID, memberID uint) (int64, error)
+}
+
+type classroomMemberRepository struct {
+	db *gorm.DB
+}
+
+func NewClassroomMemberRepository(db *gorm.DB) ClassroomMemberRepository {
+	return &classroomMemberRepository{db: db}
+}
+
+func (r *classroomMemberRepository) WithContext(ctx context.Context) ClassroomMemberRepository {
+	return &classroomMemberRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *classroomMemberRepository) List(query listquery.Query) ([]models.ClassroomMember, error) {
+	var members []models.ClassroomMember
+	err := r.db.Scopes(query.Scope).Find(&members).Error
+	return members, err
+}
+
+func (r *classroomMemberRepository) ListByClassroom(classroomID uint, query listquery.Query) ([]models.ClassroomMember, error) {
+	var members []models.ClassroomMember
+	err := r.db.Scopes(query.Scope).Where("classroom_id = ?", classroomID).Find(&members).Error
+	return members, err
+}
+
+func (r *classroomMemberRepository) Exists(classroomID uint, teacherID, studentID *uint) (bool, error) {
+	query := r.db.Model(&models.ClassroomMember{}).Where("classroom_id = ?", classroomID)
+	if teacherID != nil {
+		query = query.Where("teacher_id = ?", *teacherID)
+	}
+	if studentID != nil {
+		query = query.Where("student_id = ?", *studentID)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *classroomMemberRepository) FindByMember(classroomID, memberID uint) (*models.ClassroomMember, error) {
+	var member models.ClassroomMember
+	if err := r.db.Where("classroom_id = ? AND (teacher_id = ? OR student_id = ?)", classroomID, memberID, memberID).
+		First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *classroomMemberRepository) Create(member *models.ClassroomMember) error {
+	return r.db.Create(member).Error
+}
+
+// The table has no primary key, so the row is addressed the way FindByMember found it
+func (r *classroomMemberRepository) UpdateByMember(classroomID, memberID uint, member *models.ClassroomMember) error {
+	return r.db.Model(&models.ClassroomMember{}).
+		Where("classroom_id = ? AND (teacher_id = ? OR student_id = ?)", classroomID, memberID, memberID).
+		Select("TeacherID", "StudentID").
+		Updates(member).Error
+}
+
+func (r *classroomMemberRepository) DeleteByMember(classroomID, memberID uint) (int64, error) {
+	result := r.db.Where("classroom_id = ? AND (teacher_id = ? OR student_id = ?)", classroomID, memberID, memberID).
+		Delete(&models.ClassroomMember{})
+	return result.RowsAffected, result.Error
+}
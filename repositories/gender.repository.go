@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+
+	"gorm.io/gorm"
+)
+
+// GenderRepository reads and writes the gender lookup table. Deleted genders keep
+// their row with deleted_at set, so the finders skip them unless noted.
+type GenderRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) GenderRepository
+
+	FindByID(id uint) (*models.Gender, error)
+	// FindByName also finds deleted genders, whose names stay unique
+	FindByName(name string) (*models.Gender, error)
+	// NameTaken reports whether another live gender already uses the name
+	NameTaken(name string, excludeID uint) (bool, error)
+	// List returns one page of query plus a look-ahead row, see listquery.Page
+	List(query listquery.Query) ([]models.Gender, error)
+
+	Create(gender *models.Gender) error
+	// UpdateIfVersion writes the name and deletion time only while the row still has baseVersion
+	UpdateIfVersion(gender *models.Gender, baseVersion uint) (bool, error)
+}
+
+type genderRepository struct {
+	db *gorm.DB
+}
+
+func NewGenderRepository(db *gorm.DB) GenderRepository {
+	return &genderRepository{db: db}
+}
+
+func (r *genderRepository) WithContext(ctx context.Context) GenderRepository {
+	return &genderRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *genderRepository) FindByID(id uint) (*models.Gender, error) {
+	var gender models.Gender
+	if err := r.db.Where("id = ? AND deleted_at IS NULL", id).First(&gender).Error; err != nil {
+		return nil, err
+	}
+	return &gender, nil
+}
+
+func (r *genderRepository) FindByName(name string) (*models.Gender, error) {
+	var gender models.Gender
+	if err := r.db.Where("name = ?", name).First(&gender).Error; err != nil {
+		return nil, err
+	}
+	return &gender, nil
+}
+
+func (r *genderRepository) NameTaken(name string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Gender{}).Where("name = ? AND id != ? AND deleted_at IS NULL", name, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *genderRepository) List(query listquery.Query) ([]models.Gender, error) {
+	var genders []models.Gender
+	err := r.db.Where("deleted_at IS NULL").Scopes(query.Scope).Find(&genders).Error
+	return genders, err
+}
+
+func (r *genderRepository) Create(gender *models.Gender) error {
+	return r.db.Create(gender).Error
+}
+
+func (r *genderRepository) UpdateIfVersion(gender *models.Gender, baseVersion uint) (bool, error) {
+	result := r.db.Model(gender).
+		Where("version = ?", baseVersion).
+		Select("Name", "DeletedAt", "Version").
+		Updates(gender)
+	return result.RowsAffected > 0, result.Error
+}
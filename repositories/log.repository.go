@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+
+	"gorm.io/gorm"
+)
+
+// LogRepository reads and appends activity logs; logs are never updated or deleted
+type LogRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) LogRepository
+	// The List methods return one page of query plus a look-ahead row, see listquery.Page
+	List(query listquery.Query) ([]models.Log, error)
+	FindByID(id uint) (*models.Log, error)
+	ListByTeacher(teacherID uint, query listquery.Query) ([]models.Log, error)
+	ListByAction(action models.LogAction, query listquery.Query) ([]models.Log, error)
+	Create(log *models.Log) error
+}
+
+type logRepository struct {
+	db *gorm.DB
+}
+
+func NewLogRepository(db *gorm.DB) LogRepository {
+	return &logRepository{db: db}
+}
+
+func (r *logRepository) WithContext(ctx context.Context) LogRepository {
+	return &logRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *logRepository) List(query listquery.Query) ([]models.Log, error) {
+	var logs []models.Log
+	err := r.db.Scopes(query.Scope).Find(&logs).Error
+	return logs, err
+}
+
+func (r *logRepository) FindByID(id uint) (*models.Log, error) {
+	var log models.Log
+	if err := r.db.Where("id = ?", id).First(&log).Error; err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+func (r *logRepository) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Log, error) {
+	var logs []models.Log
+	err := r.db.Scopes(query.Scope).Where("teacher_id = ?", teacherID).Find(&logs).Error
+	return logs, err
+}
+
+func (r *logRepository) ListByAction(action models.LogAction, query listquery.Query) ([]models.Log, error) {
+	var logs []models.Log
+	err := r.db.Scopes(query.Scope).Where("action = ?", action).Find(&logs).Error
+	return logs, err
+}
+
+func (r *logRepository) Create(log *models.Log) error {
+	return r.db.Create(log).Error
+}
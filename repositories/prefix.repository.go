@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+
+	"gorm.io/gorm"
+)
+
+// PrefixRepository reads and writes the name prefix lookup table. Deleted prefixes keep
+// their row with deleted_at set, so the finders skip them unless noted.
+type PrefixRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) PrefixRepository
+
+	FindByID(id uint) (*models.Prefix, error)
+	// FindByName also finds deleted prefixes, whose names stay unique
+	FindByName(name string) (*models.Prefix, error)
+	// NameTaken reports whether another live prefix already uses the name
+	NameTaken(name string, excludeID uint) (bool, error)
+	// List returns one page of query plus a look-ahead row, see listquery.Page
+	List(query listquery.Query) ([]models.Prefix, error)
+
+	Create(prefix *models.Prefix) error
+	// UpdateIfVersion writes the name and deletion time only while the row still has baseVersion
+	UpdateIfVersion(prefix *models.Prefix, baseVersion uint) (bool, error)
+}
+
+type prefixRepository struct {
+	db *gorm.DB
+}
+
+func NewPrefixRepository(db *gorm.DB) PrefixRepository {
+	return &prefixRepository{db: db}
+}
+
+func (r *prefixRepository) WithContext(ctx context.Context) PrefixRepository {
+	return &prefixRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *prefixRepository) FindByID(id uint) (*models.Prefix, error) {
+	var prefix models.Prefix
+	if err := r.db.Where("id = ? AND deleted_at IS NULL", id).First(&prefix).Error; err != nil {
+		return nil, err
+	}
+	return &prefix, nil
+}
+
+func (r *prefixRepository) FindByName(name string) (*models.Prefix, error) {
+	var prefix models.Prefix
+	if err := r.db.Where("name = ?", name).First(&prefix).Error; err != nil {
+		return nil, err
+	}
+	return &prefix, nil
+}
+
+func (r *prefixRepository) NameTaken(name string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Prefix{}).Where("name = ? AND id != ? AND deleted_at IS NULL", name, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *prefixRepository) List(query listquery.Query) ([]models.Prefix, error) {
+	var prefixes []models.Prefix
+	err := r.db.Where("deleted_at IS NULL").Scopes(query.Scope).Find(&prefixes).Error
+	return prefixes, err
+}
+
+func (r *prefixRepository) Create(prefix *models.Prefix) error {
+	return r.db.Create(prefix).Error
+}
+
+func (r *prefixRepository) UpdateIfVersion(prefix *models.Prefix, baseVersion uint) (bool, error) {
+	result := r.db.Model(prefix).
+		Where("version = ?", baseVersion).
+		Select("Name", "DeletedAt", "Version").
+		Updates(prefix)
+	return result.RowsAffected > 0, result.Error
+}
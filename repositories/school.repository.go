@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+
+	"gorm.io/gorm"
+)
+
+// SchoolRepository reads and writes schools
+type SchoolRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) SchoolRepository
+
+	FindByID(id uint) (*models.School, error)
+	FindByName(name string) (*models.School, error)
+	// FindByClassroomID returns the school a classroom belongs to
+	FindByClassroomID(classroomID uint) (*models.School, error)
+	// FindByTeacherID returns the school a teacher works at
+	FindByTeacherID(teacherID uint) (*models.School, error)
+	// NameTaken reports whether another school already uses the name
+	NameTaken(name string, excludeID uint) (bool, error)
+	// List returns one page of query plus a look-ahead row, see listquery.Page
+	List(query listquery.Query) ([]models.School, error)
+
+	Create(school *models.School) error
+	// UpdateIfVersion writes the editable fields only while the row still has baseVersion
+	UpdateIfVersion(school *models.School, baseVersion uint) (bool, error)
+	Delete(school *models.School) error
+}
+
+type schoolRepository struct {
+	db *gorm.DB
+}
+
+func NewSchoolRepository(db *gorm.DB) SchoolRepository {
+	return &schoolRepository{db: db}
+}
+
+func (r *schoolRepository) WithContext(ctx context.Context) SchoolRepository {
+	return &schoolRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *schoolRepository) FindByID(id uint) (*models.School, error) {
+	var school models.School
+	if err := r.db.Where("id = ?", id).First(&school).Error; err != nil {
+		return nil, err
+	}
+	return &school, nil
+}
+
+func (r *schoolRepository) FindByName(name string) (*models.School, error) {
+	var school models.School
+	if err := r.db.Where("name = ?", name).First(&school).Error; err != nil {
+		return nil, err
+	}
+	return &school, nil
+}
+
+func (r *schoolRepository) FindByClassroomID(classroomID uint) (*models.School, error) {
+	var school models.School
+	if err := r.db.Joins("JOIN classrooms ON classrooms.school_id = schools.id").
+		Where("classrooms.id = ?", classroomID).
+		First(&school).Error; err != nil {
+		return nil, err
+	}
+	return &school, nil
+}
+
+func (r *schoolRepository) FindByTeacherID(teacherID uint) (*models.School, error) {
+	var school models.School
+	if err := r.db.Joins("JOIN teachers ON schools.id = teachers.school_id").
+		Where("teachers.id = ?", teacherID).
+		First(&school).Error; err != nil {
+		return nil, err
+	}
+	return &school, nil
+}
+
+func (r *schoolRepository) NameTaken(name string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.School{}).Where("name = ? AND id != ?", name, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *schoolRepository) List(query listquery.Query) ([]models.School, error) {
+	var schools []models.School
+	err := r.db.Scopes(query.Scope).Find(&schools).Error
+	return schools, err
+}
+
+func (r *schoolRepository) Create(school *models.School) error {
+	return r.db.Create(school).Error
+}
+
+func (r *schoolRepository) UpdateIfVersion(school *models.School, baseVersion uint) (bool, error) {
+	result := r.db.Model(school).
+		Where("version = ?", baseVersion).
+		Select("Name", "SyncConflictPolicy", "StudentNoPrefix", "StudentNoWidth", "StudentNoYear", "Version").
+		Updates(school)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *schoolRepository) Delete(school *models.School) error {
+	return r.db.Delete(school).Error
+}
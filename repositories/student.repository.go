@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/outbox"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+// StudentRepository reads and writes students and their per-classroom number counters
+type StudentRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) StudentRepository
+	// WithTx runs fn with a repository bound to one transaction, committed when fn returns nil
+	WithTx(fn func(repo StudentRepository) error) error
+	// Enqueue records an outbox event in the repository's transaction
+	Enqueue(event outbox.Event) error
+	// WithSelection returns a repository whose reads select only the fields and
+	// preload only the relations of sel
+	WithSelection(sel fieldset.Selection) StudentRepository
+
+	FindByID(id uint) (*models.Student, error)
+	FindDeletedByID(id uint) (*models.Student, error)
+	FindByStudentNo(classroomID uint, studentNo string) (*models.Student, error)
+	// StudentNoTaken reports whether another live student already uses the number, in any classroom
+	StudentNoTaken(studentNo string, excludeID uint) (bool, error)
+	// ListByTeacher returns one page of query plus a look-ahead row, see listquery.Page
+	ListByTeacher(teacherID uint, query listquery.Query) ([]models.Student, error)
+	// LoadRelations fills School, Classroom, Gender and Prefix
+	LoadRelations(student *models.Student) error
+
+	// HighestStudentNo returns the largest number already used after stem in the classroom
+	HighestStudentNo(classroomID uint, stem string) (int64, error)
+	// NextStudentNo bumps the classroom counter of period to at least floor and returns it.
+	// The counter row stays locked until the transaction ends.
+	NextStudentNo(classroomID uint, period string, floor int64, now int64) (int64, error)
+
+	Create(student *models.Student) error
+	// UpdateIfVersion writes the editable fields only while the row still has baseVersion
+	UpdateIfVersion(student *models.Student, baseVersion uint) (bool, error)
+	// SoftDelete moves the student and their attendances to the trash with one timestamp
+	SoftDelete(student *models.Student, deletedAt models.DeletedAt) error
+	// Restore brings the student back with the attendances deleted at the same time
+	Restore(student *models.Student) error
+}
+
+type studentRepository struct {
+	db *gorm.DB
+}
+
+func NewStudentRepository(db *gorm.DB) StudentRepository {
+	return &studentRepository{db: db}
+}
+
+func (r *studentRepository) WithContext(ctx context.Context) StudentRepository {
+	return &studentRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *studentRepository) WithTx(fn func(repo StudentRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&studentRepository{db: tx})
+	})
+}
+
+func (r *studentRepository) Enqueue(event outbox.Event) error {
+	return outbox.Enqueue(r.db, event)
+}
+
+func (r *studentRepository) WithSelection(sel fieldset.Selection) StudentRepository {
+	return &studentRepository{db: sel.Scope(r.db).Session(&gorm.Session{})}
+}
+
+func (r *studentRepository) FindByID(id uint) (*models.Student, error) {
+	var student models.Student
+	if err := r.db.Where("id = ?", id).First(&student).Error; err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+func (r *studentRepository) FindDeletedByID(id uint) (*models.Student, error) {
+	var student models.Student
+	if err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&student).Error; err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+func (r *studentRepository) FindByStudentNo(classroomID uint, studentNo string) (*models.Student, error) {
+	var student models.Student
+	if err := r.db.Where("student_no = ? AND classroom_id = ?", studentNo, classroomID).First(&student).Error; err != nil {
+		return nil, err
+	}
+	return &student, nil
+}
+
+func (r *studentRepository) StudentNoTaken(studentNo string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Student{}).Where("student_no = ? AND id != ?", studentNo, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *studentRepository) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Student, error) {
+	var students []models.Student
+	err := r.db.
+		Joins("JOIN classrooms ON students.classroom_id = classrooms.id").
+		Scopes(query.Scope).
+		Where("classrooms.teacher_id = ?", teacherID).
+		Find(&students).Error
+	return students, err
+}
+
+func (r *studentRepository) LoadRelations(student *models.Student) error {
+	return r.db.Preload("School").Preload("Classroom").Preload("Gender").Preload("Prefix").First(student, student.ID).Error
+}
+
+func (r *studentRepository) HighestStudentNo(classroomID uint, stem string) (int64, error) {
+	return highestStudentNo(r.db, classroomID, stem)
+}
+
+func (r *studentRepository) NextStudentNo(classroomID uint, period string, floor int64, now int64) (int64, error) {
+	return nextStudentNo(r.db, classroomID, period, floor, now)
+}
+
+func highestStudentNo(db *gorm.DB, classroomID uint, stem string) (int64, error) {
+	// Deleted students are included so their numbers are not handed out again
+	var highest int64
+	err := db.Raw(`SELECT COALESCE(MAX(CAST(SUBSTRING(student_no FROM ?) AS BIGINT)), 0)
+		FROM students WHERE classroom_id = ? AND student_no ~ ?`,
+		len(stem)+1, classroomID, "^"+regexp.QuoteMeta(stem)+"[0-9]{1,18}$").
+		Scan(&highest).Error
+	return highest, err
+}
+
+func nextStudentNo(db *gorm.DB, classroomID uint, period string, floor int64, now int64) (int64, error) {
+	var next int64
+	err := db.Raw(`INSERT INTO student_no_counters (classroom_id, period, last_value, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (classroom_id, period) DO UPDATE
+		SET last_value = GREATEST(student_no_counters.last_value + 1, EXCLUDED.last_value),
+			updated_at = EXCLUDED.updated_at
+		RETURNING last_value`,
+		classroomID, period, floor, now).
+		Scan(&next).Error
+	return next, err
+}
+
+func (r *studentRepository) Create(student *models.Student) error {
+	return r.db.Create(student).Error
+}
+
+func (r *studentRepository) UpdateIfVersion(student *models.Student, baseVersion uint) (bool, error) {
+	result := r.db.Model(student).
+		Where("version = ?", baseVersion).
+		Select("SchoolID", "StudentNo", "FirstName", "LastName", "Version").
+		Updates(student)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SoftDelete and Restore write several statements; inside WithTx GORM runs the
+// nested Transaction as a savepoint
+func (r *studentRepository) SoftDelete(student *models.Student, deletedAt models.DeletedAt) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(student).Update("deleted_at", deletedAt).Error; err != nil {
+			return err
+		}
+		_, err := SoftDeleteAttendances(tx, deletedAt, "student_id = ?", student.ID)
+		return err
+	})
+}
+
+func (r *studentRepository) Restore(student *models.Student) error {
+	deletedAt := student.DeletedAt
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(student).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		_, err := RestoreAttendances(tx, deletedAt, "student_id = ?", student.ID)
+		return err
+	})
+}
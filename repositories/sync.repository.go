@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/outbox"
+
+	"gorm.io/gorm"
+)
+
+// SyncRepository backs offline sync: the log of applied mutations, the attendance
+// change feed, and the attendance writes of a mutation, which share its transaction
+type SyncRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) SyncRepository
+	// WithTx runs fn with a repository bound to one transaction, committed when fn returns nil
+	WithTx(fn func(repo SyncRepository) error) error
+	// Enqueue records an outbox event in the repository's transaction
+	Enqueue(event outbox.Event) error
+	// Attendances returns an attendance repository on the same connection or transaction
+	Attendances() AttendanceRepository
+
+	// SyncSettings returns the school and conflict policy of each classroom
+	SyncSettings(classroomIDs []uint) ([]ClassroomSyncSetting, error)
+	FindMutation(teacherID uint, clientMutationID string) (*models.SyncMutation, error)
+	CreateMutation(mutation *models.SyncMutation) error
+	// ChangesSince returns up to limit attendances of the classrooms, deleted ones included,
+	// written after the (txID, id) position. Rows only appear once every transaction that
+	// could still write before them has finished.
+	ChangesSince(classroomIDs []uint, txID int64, id uint, limit int) ([]models.Attendance, error)
+}
+
+// ClassroomSyncSetting is what applying a mutation needs to know about its classroom.
+// Policy is empty when the classroom has no school.
+type ClassroomSyncSetting struct {
+	ClassroomID uint
+	SchoolID    *uint
+	Policy      models.SyncConflictPolicy
+}
+
+type syncRepository struct {
+	db *gorm.DB
+}
+
+func NewSyncRepository(db *gorm.DB) SyncRepository {
+	return &syncRepository{db: db}
+}
+
+func (r *syncRepository) WithContext(ctx context.Context) SyncRepository {
+	return &syncRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *syncRepository) WithTx(fn func(repo SyncRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&syncRepository{db: tx})
+	})
+}
+
+func (r *syncRepository) Enqueue(event outbox.Event) error {
+	return outbox.Enqueue(r.db, event)
+}
+
+func (r *syncRepository) Attendances() AttendanceRepository {
+	return &attendanceRepository{db: r.db}
+}
+
+func (r *syncRepository) SyncSettings(classroomIDs []uint) ([]ClassroomSyncSetting, error) {
+	var rows []struct {
+		ID       uint
+		SchoolID *uint
+		Policy   *string
+	}
+	if err := r.db.Table("classrooms").
+		Select("classrooms.id, classrooms.school_id, schools.sync_conflict_policy AS policy").
+		Joins("LEFT JOIN schools ON schools.id = classrooms.school_id").
+		Where("classrooms.id IN ?", classroomIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	settings := make([]ClassroomSyncSetting, 0, len(rows))
+	for _, row := range rows {
+		setting := ClassroomSyncSetting{ClassroomID: row.ID, SchoolID: row.SchoolID}
+		if row.Policy != nil {
+			setting.Policy = models.SyncConflictPolicy(*row.Policy)
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+func (r *syncRepository) FindMutation(teacherID uint, clientMutationID string) (*models.SyncMutation, error) {
+	var mutation models.SyncMutation
+	if err := r.db.Where("teacher_id = ? AND client_mutation_id = ?", teacherID, clientMutationID).
+		First(&mutation).Error; err != nil {
+		return nil, err
+	}
+	return &mutation, nil
+}
+
+func (r *syncRepository) CreateMutation(mutation *models.SyncMutation) error {
+	return r.db.Create(mutation).Error
+}
+
+func (r *syncRepository) ChangesSince(classroomIDs []uint, txID int64, id uint, limit int) ([]models.Attendance, error) {
+	var horizon int64
+	if err := r.db.Raw("SELECT txid_snapshot_xmin(txid_current_snapshot())").Scan(&horizon).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []models.Attendance
+	err := r.db.Unscoped().
+		Where("classroom_id IN ?", classroomIDs).
+		Where("change_tx_id < ?", horizon).
+		Where("(change_tx_id > ? OR (change_tx_id = ? AND id > ?))", txID, txID, id).
+		Order("change_tx_id, id").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
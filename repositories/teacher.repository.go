@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/outbox"
+
+	"gorm.io/gorm"
+)
+
+// TeacherRepository reads and writes teachers
+type TeacherRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) TeacherRepository
+	// WithTx runs fn with a repository bound to one transaction, committed when fn returns nil
+	WithTx(fn func(repo TeacherRepository) error) error
+	// Enqueue records an outbox event in the repository's transaction
+	Enqueue(event outbox.Event) error
+
+	FindByID(id uint) (*models.Teacher, error)
+	FindDeletedByID(id uint) (*models.Teacher, error)
+	FindByEmail(email string) (*models.Teacher, error)
+	// FindProfile loads the teacher with School, Gender and Prefix
+	FindProfile(id uint) (*models.Teacher, error)
+	FindFirstBySchool(schoolID uint) (*models.Teacher, error)
+	// EmailTaken reports whether another live teacher already uses the email
+	EmailTaken(email string, excludeID uint) (bool, error)
+	// List returns one page of query plus a look-ahead row, see listquery.Page
+	List(query listquery.Query) ([]models.Teacher, error)
+	// CountAttendancesByClassroom counts the attendances a teacher recorded per classroom
+	CountAttendancesByClassroom(teacherID uint) (map[uint]int64, error)
+
+	Create(teacher *models.Teacher) error
+	// UpdateIfVersion writes the editable fields only while the row still has baseVersion
+	UpdateIfVersion(teacher *models.Teacher, baseVersion uint) (bool, error)
+	SoftDelete(teacher *models.Teacher) error
+	Restore(teacher *models.Teacher) error
+}
+
+type teacherRepository struct {
+	db *gorm.DB
+}
+
+func NewTeacherRepository(db *gorm.DB) TeacherRepository {
+	return &teacherRepository{db: db}
+}
+
+func (r *teacherRepository) WithContext(ctx context.Context) TeacherRepository {
+	return &teacherRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *teacherRepository) WithTx(fn func(repo TeacherRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&teacherRepository{db: tx})
+	})
+}
+
+func (r *teacherRepository) Enqueue(event outbox.Event) error {
+	return outbox.Enqueue(r.db, event)
+}
+
+func (r *teacherRepository) FindByID(id uint) (*models.Teacher, error) {
+	var teacher models.Teacher
+	if err := r.db.Where("id = ?", id).First(&teacher).Error; err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *teacherRepository) FindDeletedByID(id uint) (*models.Teacher, error) {
+	var teacher models.Teacher
+	if err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&teacher).Error; err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *teacherRepository) FindByEmail(email string) (*models.Teacher, error) {
+	var teacher models.Teacher
+	if err := r.db.Where("email = ?", email).First(&teacher).Error; err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *teacherRepository) FindProfile(id uint) (*models.Teacher, error) {
+	var teacher models.Teacher
+	if err := r.db.Preload("School").Preload("Gender").Preload("Prefix").Where("id = ?", id).First(&teacher).Error; err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *teacherRepository) FindFirstBySchool(schoolID uint) (*models.Teacher, error) {
+	var teacher models.Teacher
+	if err := r.db.Where("school_id = ?", schoolID).First(&teacher).Error; err != nil {
+		return nil, err
+	}
+	return &teacher, nil
+}
+
+func (r *teacherRepository) EmailTaken(email string, excludeID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Teacher{}).Where("email = ? AND id != ?", email, excludeID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *teacherRepository) List(query listquery.Query) ([]models.Teacher, error) {
+	var teachers []models.Teacher
+	err := r.db.Scopes(query.Scope).Find(&teachers).Error
+	return teachers, err
+}
+
+func (r *teacherRepository) CountAttendancesByClassroom(teacherID uint) (map[uint]int64, error) {
+	var rows []struct {
+		ClassroomID uint
+		Count       int64
+	}
+	if err := r.db.Model(&models.Attendance{}).
+		Select("classroom_id, COUNT(*) as count").
+		Where("teacher_id = ?", teacherID).
+		Group("classroom_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ClassroomID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *teacherRepository) Create(teacher *models.Teacher) error {
+	return r.db.Create(teacher).Error
+}
+
+func (r *teacherRepository) UpdateIfVersion(teacher *models.Teacher, baseVersion uint) (bool, error) {
+	result := r.db.Model(teacher).
+		Where("version = ?", baseVersion).
+		Select("SchoolID", "Email", "Password", "FirstName", "LastName", "Phone", "Language", "Version").
+		Updates(teacher)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *teacherRepository) SoftDelete(teacher *models.Teacher) error {
+	return r.db.Delete(teacher).Error
+}
+
+func (r *teacherRepository) Restore(teacher *models.Teacher) error {
+	if err := r.db.Unscoped().Model(teacher).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	teacher.DeletedAt = models.DeletedAt{}
+	return nil
+}
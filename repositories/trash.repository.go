@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/listquery"
+
+	"gorm.io/gorm"
+)
+
+// TrashRepository lists soft-deleted rows and removes them for good once they are old enough
+type TrashRepository interface {
+	// WithContext returns a repository whose queries carry ctx (cancellation, trace spans)
+	WithContext(ctx context.Context) TrashRepository
+	// WithTx runs fn with a repository bound to one transaction, committed when fn returns nil
+	WithTx(fn func(repo TrashRepository) error) error
+
+	// The List methods return one page of deleted rows. Classrooms, students and
+	// attendances are those of the classrooms the teacher owns or joined, deleted
+	// classrooms included; teachers are those of the school.
+	ListClassrooms(teacherID uint, query listquery.Query) ([]models.Classroom, error)
+	ListStudents(teacherID uint, query listquery.Query) ([]models.Student, error)
+	ListTeachers(schoolID *uint, query listquery.Query) ([]models.Teacher, error)
+	ListAttendances(teacherID uint, query listquery.Query) ([]models.Attendance, error)
+
+	// The Purge methods delete rows that were deleted before the given Unix time and
+	// return how many went. Rows still referenced by any other row, live or deleted,
+	// stay and are counted as kept.
+	PurgeAttendances(before int64) (int64, error)
+	PurgeStudents(before int64) (purged, kept int64, err error)
+	PurgeClassrooms(before int64) (purged, kept int64, err error)
+	PurgeTeachers(before int64) (purged, kept int64, err error)
+}
+
+// Conditions that keep a deleted row while anything still points at it
+const (
+	studentUnreferenced = "NOT EXISTS (SELECT 1 FROM attendances WHERE attendances.student_id = students.id)"
+
+	classroomUnreferenced = "NOT EXISTS (SELECT 1 FROM students WHERE students.classroom_id = classrooms.id) AND " +
+		"NOT EXISTS (SELECT 1 FROM attendances WHERE attendances.classroom_id = classrooms.id)"
+
+	teacherUnreferenced = "NOT EXISTS (SELECT 1 FROM classrooms WHERE classrooms.teacher_id = teachers.id) AND " +
+		"NOT EXISTS (SELECT 1 FROM attendances WHERE attendances.teacher_id = teachers.id)"
+)
+
+type trashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) TrashRepository {
+	return &trashRepository{db: db}
+}
+
+func (r *trashRepository) WithContext(ctx context.Context) TrashRepository {
+	return &trashRepository{db: r.db.WithContext(ctx)}
+}
+
+func (r *trashRepository) WithTx(fn func(repo TrashRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&trashRepository{db: tx})
+	})
+}
+
+// classroomIDs selects every classroom of the teacher, deleted or not
+func (r *trashRepository) classroomIDs(teacherID uint) *gorm.DB {
+	return r.db.Unscoped().Model(&models.Classroom{}).Select("id").
+		Where("teacher_id = ? OR id IN (?)", teacherID,
+			r.db.Model(&models.ClassroomMember{}).Select("classroom_id").Where("teacher_id = ?", teacherID))
+}
+
+func (r *trashRepository) deleted(query listquery.Query) *gorm.DB {
+	return query.Scope(r.db.Unscoped().Where("deleted_at IS NOT NULL"))
+}
+
+func (r *trashRepository) ListClassrooms(teacherID uint, query listquery.Query) ([]models.Classroom, error) {
+	classrooms := []models.Classroom{}
+	err := r.deleted(query).Where("id IN (?)", r.classroomIDs(teacherID)).Find(&classrooms).Error
+	return classrooms, err
+}
+
+func (r *trashRepository) ListStudents(teacherID uint, query listquery.Query) ([]models.Student, error) {
+	students := []models.Student{}
+	err := r.deleted(query).Where("classroom_id IN (?)", r.classroomIDs(teacherID)).Find(&students).Error
+	return students, err
+}
+
+func (r *trashRepository) ListTeachers(schoolID *uint, query listquery.Query) ([]models.Teacher, error) {
+	teachers := []models.Teacher{}
+	err := r.deleted(query).Where("school_id = ?", schoolID).Find(&teachers).Error
+	return teachers, err
+}
+
+func (r *trashRepository) ListAttendances(teacherID uint, query listquery.Query) ([]models.Attendance, error) {
+	attendances := []models.Attendance{}
+	err := r.deleted(query).Where("classroom_id IN (?)", r.classroomIDs(teacherID)).Find(&attendances).Error
+	return attendances, err
+}
+
+// purge deletes the expired rows that pass unreferenced, then counts the expired rows left behind
+func (r *trashRepository) purge(model interface{}, before int64, unreferenced string) (purged, kept int64, err error) {
+	expired := func() *gorm.DB {
+		return r.db.Unscoped().Model(model).Where("deleted_at IS NOT NULL AND deleted_at < ?", before)
+	}
+	query := expired()
+	if unreferenced != "" {
+		query = query.Where(unreferenced)
+	}
+	result := query.Delete(model)
+	if result.Error != nil {
+		return 0, 0, result.Error
+	}
+	if unreferenced != "" {
+		err = expired().Count(&kept).Error
+	}
+	return result.RowsAffected, kept, err
+}
+
+func (r *trashRepository) PurgeAttendances(before int64) (int64, error) {
+	purged, _, err := r.purge(&models.Attendance{}, before, "")
+	return purged, err
+}
+
+func (r *trashRepository) PurgeStudents(before int64) (purged, kept int64, err error) {
+	return r.purge(&models.Student{}, before, studentUnreferenced)
+}
+
+func (r *trashRepository) PurgeClassrooms(before int64) (purged, kept int64, err error) {
+	return r.purge(&models.Classroom{}, before, classroomUnreferenced)
+}
+
+func (r *trashRepository) PurgeTeachers(before int64) (purged, kept int64, err error) {
+	return r.purge(&models.Teacher{}, before, teacherUnreferenced)
+}
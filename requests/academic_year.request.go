@@ -0,0 +1,24 @@
+package requests
+
+// AcademicYearCreateRequest represents the request payload for creating an academic year
+type AcademicYearCreateRequest struct {
+	SchoolID  uint   `json:"school_id" binding:"required"`
+	Name      string `json:"name" binding:"required,min=1,max=20"`              // ปีการศึกษา เช่น "2568"
+	StartDate string `json:"start_date" binding:"required,datetime=2006-01-02"` // YYYY-MM-DD
+	EndDate   string `json:"end_date" binding:"required,datetime=2006-01-02"`   // YYYY-MM-DD, inclusive
+}
+
+// TermCreateRequest represents the request payload for adding a term to an academic year
+type TermCreateRequest struct {
+	Number    int    `json:"number" binding:"required,min=1,max=4"` // ภาคเรียนที่
+	StartDate string `json:"start_date" binding:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" binding:"required,datetime=2006-01-02"`
+}
+
+// RolloverRequest moves the classrooms of an academic year into the next one.
+// Students listed in RepeatingStudentIDs stay in their grade (ซ้ำชั้น).
+type RolloverRequest struct {
+	ToAcademicYearID    uint   `json:"to_academic_year_id" binding:"required"`
+	RepeatingStudentIDs []uint `json:"repeating_student_ids"`
+	DryRun              bool   `json:"dry_run"` // Return the plan without writing it
+}
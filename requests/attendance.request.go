@@ -10,14 +10,16 @@ type AttendanceCreateRequest struct {
 	TeacherID   uint                    `json:"teacher_id" binding:"required"`
 	StudentID   uint                    `json:"student_id" binding:"required"`
 	SessionDate string                  `json:"session_date" binding:"required"` // YYYY-MM-DD
-	Status      models.AttendanceStatus `json:"status" binding:"required"`
+	Status      models.AttendanceStatus `json:"status" binding:"required,oneof=present absent late leave"`
 	CheckedAt   int64                   `json:"checked_at" binding:"required"`
 	Remark      string                  `json:"remark"`
 }
 
-// AttendanceUpdateRequest represents the request payload for updating attendance
+// AttendanceUpdateRequest changes only the fields that are sent; Version, when sent,
+// must still be the attendance's version
 type AttendanceUpdateRequest struct {
-	Status    models.AttendanceStatus `json:"status" binding:"required,oneof=present absent late leave"`
-	CheckedAt int64                   `json:"checked_at" binding:"required"`
-	Remark    string                  `json:"remark"`
+	Status    *models.AttendanceStatus `json:"status" binding:"omitempty,oneof=present absent late leave"`
+	CheckedAt *int64                   `json:"checked_at" binding:"omitempty,min=1"`
+	Remark    *string                  `json:"remark"`
+	Version   *uint                    `json:"version" binding:"omitempty,min=1"`
 }
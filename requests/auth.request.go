@@ -7,8 +7,9 @@ type AuthRequest struct {
 	LastName   string `json:"lastname" binding:"required"`
 	Phone      string `json:"phone"`
 	SchoolName string `json:"school_name" binding:"required"`
-	GenderName string `json:"gender_name"` // Optional, will auto-create if provided
-	PrefixName string `json:"prefix_name"` // Optional, will auto-create if provided
+	GenderName string `json:"gender_name"`                              // Optional, will auto-create if provided
+	PrefixName string `json:"prefix_name"`                              // Optional, will auto-create if provided
+	Language   string `json:"language" binding:"omitempty,oneof=th en"` // Optional, empty to follow Accept-Language
 }
 
 type LoginRequest struct {
@@ -42,4 +43,5 @@ type TeacherUpdateRequest struct {
 	FirstName  string `json:"firstname"`
 	LastName   string `json:"lastname"`
 	Phone      string `json:"phone"`
+	Language   string `json:"language" binding:"omitempty,oneof=th en"` // Takes effect from the next login
 }
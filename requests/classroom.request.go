@@ -6,12 +6,18 @@ type ClassroomCreateRequest struct {
 	TeacherID uint   `json:"teacher_id" binding:"required"`
 	Name      string `json:"name" binding:"required,min=1,max=255"`
 	Grade     string `json:"grade" binding:"required,min=1,max=10"` // ชั้นเรียน
+	// AcademicYearID defaults to the school's year that contains today
+	AcademicYearID *uint `json:"academic_year_id" binding:"omitempty,min=1"`
 }
 
-// ClassroomUpdateRequest represents the request payload for updating a classroom
+// ClassroomUpdateRequest changes only the fields that are sent; Version, when sent,
+// must still be the classroom's version
 type ClassroomUpdateRequest struct {
-	SchoolID  uint   `json:"school_id" binding:"required"`
-	TeacherID uint   `json:"teacher_id" binding:"required"`
-	Name      string `json:"name" binding:"required,min=1,max=255"`
-	Grade     string `json:"grade" binding:"required,min=1,max=10"` // ชั้นเรียน
+	SchoolID  *uint   `json:"school_id" binding:"omitempty,min=1"`
+	TeacherID *uint   `json:"teacher_id" binding:"omitempty,min=1"`
+	Name      *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Grade     *string `json:"grade" binding:"omitempty,min=1,max=10"` // ชั้นเรียน
+	Version   *uint   `json:"version" binding:"omitempty,min=1"`
+
+	AcademicYearID *uint `json:"academic_year_id" binding:"omitempty,min=1"`
 }
@@ -0,0 +1,13 @@
+package requests
+
+import (
+	"easy-attend-service/models"
+)
+
+// RollCallMarkRequest represents a status change sent over the live roll-call socket
+type RollCallMarkRequest struct {
+	StudentID   uint                    `json:"student_id"`
+	Status      models.AttendanceStatus `json:"status"`
+	Remark      string                  `json:"remark"`
+	BaseVersion uint                    `json:"base_version"` // Version the client last saw, 0 if it saw no record
+}
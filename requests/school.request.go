@@ -1,5 +1,9 @@
 package requests
 
+import (
+	"easy-attend-service/models"
+)
+
 type SchoolRequest struct {
 	Page   int64  `json:"page" form:"page"`
 	Size   int64  `json:"size" form:"size"`
@@ -14,6 +18,11 @@ type SchoolCreateRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
+// SchoolUpdateRequest represents the request payload for updating a school; settings left out stay unchanged
 type SchoolUpdateRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name               string                    `json:"name" binding:"required"`
+	SyncConflictPolicy models.SyncConflictPolicy `json:"sync_conflict_policy" binding:"omitempty,oneof=server_wins last_writer_wins"`
+	StudentNoPrefix    *string                   `json:"student_no_prefix" binding:"omitempty,max=20"`
+	StudentNoWidth     *int                      `json:"student_no_width" binding:"omitempty,min=1,max=10"`
+	StudentNoYear      models.StudentNoYear      `json:"student_no_year" binding:"omitempty,oneof=none ce be"`
 }
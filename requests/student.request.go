@@ -10,6 +10,17 @@ type StudentIdRequest struct {
 	ID string `json:"id" uri:"id" binding:"required"`
 }
 
+// StudentQuickCreateRequest creates a student by school name; the classroom is
+// picked or created automatically
+type StudentQuickCreateRequest struct {
+	Firstname  string `json:"firstname" binding:"required"`
+	Lastname   string `json:"lastname" binding:"required"`
+	SchoolName string `json:"school_name" binding:"required"`
+	StudentNo  string `json:"student_no"` // Optional, will auto-generate if empty
+	GenderID   *uint  `json:"gender_id"`
+	PrefixID   *uint  `json:"prefix_id"`
+}
+
 type StudentCreateRequest struct {
 	SchoolName  string `json:"school_name" binding:"required"`
 	ClassroomID uint   `json:"classroom_id" binding:"required"`
@@ -20,9 +31,12 @@ type StudentCreateRequest struct {
 	PrefixID    *uint  `json:"prefix_id"`
 }
 
+// StudentUpdateRequest changes only the fields that are sent. Version is the version
+// the client edited; the update is refused with 409 when the student has moved on.
 type StudentUpdateRequest struct {
-	SchoolName string `json:"school_name" binding:"required"`
-	StudentNo  string `json:"student_no" binding:"required"`
-	Firstname  string `json:"firstname" binding:"required"`
-	Lastname   string `json:"lastname" binding:"required"`
+	SchoolName *string `json:"school_name" binding:"omitempty,min=1"`
+	StudentNo  *string `json:"student_no" binding:"omitempty,min=1"`
+	Firstname  *string `json:"firstname" binding:"omitempty,min=1"`
+	Lastname   *string `json:"lastname" binding:"omitempty,min=1"`
+	Version    *uint   `json:"version" binding:"omitempty,min=1"`
 }
@@ -0,0 +1,41 @@
+package requests
+
+import (
+	"easy-attend-service/models"
+)
+
+// SyncOperation is the kind of change an offline client queued
+type SyncOperation string
+
+const (
+	SyncOperationUpsert SyncOperation = "upsert"
+	SyncOperationDelete SyncOperation = "delete"
+)
+
+// AttendanceSyncMutation is one attendance change queued by an offline client.
+// The record is addressed by classroom, student and session date because the
+// client may have created it offline without knowing the server ID.
+type AttendanceSyncMutation struct {
+	ClientMutationID string                  `json:"client_mutation_id" binding:"required,uuid"`
+	Op               SyncOperation           `json:"op" binding:"required,oneof=upsert delete"`
+	ClassroomID      uint                    `json:"classroom_id" binding:"required"`
+	StudentID        uint                    `json:"student_id" binding:"required"`
+	SessionDate      string                  `json:"session_date" binding:"required"` // YYYY-MM-DD
+	Status           models.AttendanceStatus `json:"status"`                          // Required for upsert
+	Remark           string                  `json:"remark"`
+	ClientTimestamp  int64                   `json:"client_timestamp" binding:"required"` // Unix time the change was made on the device
+	BaseVersion      uint                    `json:"base_version"`                        // Version the client last saw, 0 if it saw no record
+}
+
+// AttendanceSyncRequest represents the request payload for pushing an offline queue
+type AttendanceSyncRequest struct {
+	Mutations []AttendanceSyncMutation `json:"mutations" binding:"max=200,dive"`
+	Cursor    string                   `json:"cursor"` // Change feed position from the previous sync, empty for a full pull
+	Limit     int                      `json:"limit"`
+}
+
+// AttendanceChangesRequest represents the query for pulling the change feed only
+type AttendanceChangesRequest struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
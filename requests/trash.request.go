@@ -0,0 +1,6 @@
+package requests
+
+// TrashListRequest represents the query for listing deleted records
+type TrashListRequest struct {
+	Type string `form:"type" binding:"required,oneof=classrooms students teachers attendances"`
+}
@@ -1,8 +1,10 @@
 package response
 
 import (
-	"easy-attend-service/models"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,95 +19,53 @@ type Response struct {
 	Data   any            `json:"data,omitempty"`
 }
 
+// ResponsePaginate is the body of every list endpoint: one page in data and the
+// cursor of the next page in pagination
 type ResponsePaginate struct {
-	Status     StatusResponse  `json:"status"`
-	Data       any             `json:"data,omitempty"`
-	Pagination models.Paginate `json:"paginate"`
+	Status     StatusResponse       `json:"status"`
+	Data       any                  `json:"data"`
+	Pagination listquery.Pagination `json:"pagination"`
 }
 
-type ResponsePaginate0 struct {
-	Status     StatusResponse `json:"status"`
-	Data       any            `json:"data,omitempty"`
-	Pagination any            `json:"pagination"`
-}
-
-func SuccessWithPaginate(ctx *gin.Context, data any, pagination models.Paginate) {
-	if pagination.Total == 0 {
-		ctx.JSON(http.StatusOK, ResponsePaginate0{
-			Status: StatusResponse{
-				Code:    200,
-				Message: "Success",
-			},
-			Data:       []any{},
-			Pagination: gin.H{},
-		})
-		return
-	} else {
-		ctx.JSON(http.StatusOK, ResponsePaginate{
-			Status: StatusResponse{
-				Code:    200,
-				Message: "Success",
-			},
-			Data:       data,
-			Pagination: pagination,
-		})
-	}
+// SuccessWithPaginate sends one page of a list; the metadata is sent even when the page is empty
+func SuccessWithPaginate(ctx *gin.Context, key string, data any, pagination listquery.Pagination) {
+	ctx.JSON(http.StatusOK, ResponsePaginate{
+		Status: StatusResponse{
+			Code:    200,
+			Message: i18n.T(ctx.Request.Context(), key, nil),
+		},
+		Data:       data,
+		Pagination: pagination,
+	})
 }
 
 func Success(ctx *gin.Context, data any) {
 	ctx.JSON(http.StatusOK, Response{StatusResponse{
 		Code:    200,
-		Message: "Success",
+		Message: i18n.T(ctx.Request.Context(), "success.ok", nil),
 	}, data})
 }
 
-func SuccessResponse(message string, data any) Response {
+// SuccessResponse wraps data with the message key rendered in the request language
+func SuccessResponse(ctx *gin.Context, key string, data any) Response {
 	return Response{
 		Status: StatusResponse{
 			Code:    200,
-			Message: message,
+			Message: i18n.T(ctx.Request.Context(), key, nil),
 		},
 		Data: data,
 	}
 }
 
-func ErrorResponse(message string, detail string) StatusResponse {
-	return StatusResponse{
-		Code:    400,
-		Message: message + ": " + detail,
+// SetValidators sends a record's ETag, and its update time as Last-Modified, so the
+// client can revalidate it with If-None-Match and update it with If-Match
+func SetValidators(ctx *gin.Context, tag string, updatedAt int64) {
+	ctx.Header("ETag", tag)
+	if updatedAt > 0 {
+		ctx.Header("Last-Modified", time.Unix(updatedAt, 0).UTC().Format(http.TimeFormat))
 	}
 }
 
-// InternalError ส่งผลลัพธ์เมื่อมีข้อผิดพลาดภายใน
-func InternalError(ctx *gin.Context, message any, payloadCode ...string) {
-	ctx.JSON(http.StatusInternalServerError, StatusResponse{
-		Code:    500,
-		Message: message.(string), // Set the message directly here
-	})
-}
-
-func NotFound(ctx *gin.Context, message any, payloadCode ...string) {
-	ctx.JSON(http.StatusNotFound, StatusResponse{
-		Code:    404,
-		Message: message.(string), // Set the message directly here
-	})
-}
-
-// BadRequest ส่งผลลัพธ์เมื่อมีข้อผิดพลาดจากการขอข้อมูลที่ไม่ถูกต้อง
-func BadRequest(ctx *gin.Context, message any, payloadCode ...string) {
-	ctx.JSON(http.StatusBadRequest, StatusResponse{
-		Code:    400,
-		Message: message.(string), // Set the message directly here
-	})
-}
-
-func Unauthorized(ctx *gin.Context, message any, payloadCode ...string) {
-	ctx.JSON(http.StatusUnauthorized, StatusResponse{
-		Code:    401,
-		Message: message.(string), // Set the message directly here
-	})
-}
-
 // Teacher response models
 type TeacherResponses struct {
 	ID        string `json:"id"`
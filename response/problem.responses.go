@@ -0,0 +1,29 @@
+package response
+
+// ProblemContentType is the media type of every error response (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix prefixes the error code to form the problem type URI
+const ProblemTypePrefix = "urn:easy-attend:problem:"
+
+// Problem เป็นรูปแบบ error ของทุก endpoint. Code is stable and meant for clients to
+// branch on; Detail is in the language of the request and may change.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Current   any          `json:"current,omitempty"` // The record as it is now, on a version conflict
+}
+
+// FieldError describes one request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"` // localized like the problem detail
+}
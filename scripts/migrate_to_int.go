@@ -9,7 +9,11 @@ import (
 
 func main() {
 	// เชื่อมต่อ database
-	configs.ConnectDatabase()
+	cfg, err := configs.Load(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	configs.ConnectDatabase(cfg.Database)
 
 	// รัน migration
 	if err := migrations.CreateIntIDTables(configs.DB); err != nil {
@@ -0,0 +1,552 @@
+package services
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/tracing"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type AcademicYearService struct {
+	years    repositories.AcademicYearRepository
+	schools  repositories.SchoolRepository
+	teachers repositories.TeacherRepository
+	clock    clock.Clock
+}
+
+func NewAcademicYearService(years repositories.AcademicYearRepository, schools repositories.SchoolRepository, teachers repositories.TeacherRepository, clk clock.Clock) *AcademicYearService {
+	return &AcademicYearService{
+		years:    years,
+		schools:  schools,
+		teachers: teachers,
+		clock:    clk,
+	}
+}
+
+// GetAcademicYears gets one page of academic years, usually filtered by school
+func (s *AcademicYearService) GetAcademicYears(ctx context.Context, query listquery.Query) ([]models.AcademicYear, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "AcademicYearService.GetAcademicYears")
+	defer span.End()
+
+	years, err := s.years.WithContext(ctx).List(query)
+	if err != nil {
+		return nil, listquery.Pagination{}, errors.New("failed to get academic years")
+	}
+
+	years, meta := listquery.Page(query, years)
+	return years, meta, nil
+}
+
+// GetAcademicYearByID gets one academic year with its terms
+func (s *AcademicYearService) GetAcademicYearByID(ctx context.Context, id uint) (*models.AcademicYear, error) {
+	ctx, span := tracing.Start(ctx, "AcademicYearService.GetAcademicYearByID")
+	defer span.End()
+
+	return s.findYear(ctx, id)
+}
+
+func (s *AcademicYearService) findYear(ctx context.Context, id uint) (*models.AcademicYear, error) {
+	year, err := s.years.WithContext(ctx).FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAcademicYearNotFound
+		}
+		logger.LogError(ctx, err, "Failed to fetch academic year", logrus.Fields{
+			"academic_year_id": fmt.Sprintf("%d", id),
+		})
+		return nil, errors.New("failed to fetch academic year")
+	}
+	return year, nil
+}
+
+// checkSchool makes sure the teacher belongs to the school whose years they change
+func (s *AcademicYearService) checkSchool(ctx context.Context, teacherID, schoolID uint) error {
+	teacher, err := s.teachers.WithContext(ctx).FindByID(teacherID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTeacherNotFound
+		}
+		logger.LogError(ctx, err, "Failed to fetch teacher", logrus.Fields{
+			"teacher_id": fmt.Sprintf("%d", teacherID),
+		})
+		return errors.New("failed to fetch teacher")
+	}
+	if teacher.SchoolID == nil || *teacher.SchoolID != schoolID {
+		return ErrAcademicYearForbidden
+	}
+	return nil
+}
+
+// CreateAcademicYear adds a year to the teacher's school. The school's first year
+// adopts the classrooms created before academic years existed.
+func (s *AcademicYearService) CreateAcademicYear(ctx context.Context, teacherID uint, req *requests.AcademicYearCreateRequest) (*models.AcademicYear, error) {
+	ctx, span := tracing.Start(ctx, "AcademicYearService.CreateAcademicYear")
+	defer span.End()
+
+	if err := s.checkSchool(ctx, teacherID, req.SchoolID); err != nil {
+		return nil, err
+	}
+	if req.StartDate > req.EndDate {
+		return nil, ErrInvalidDateRange
+	}
+	taken, err := s.years.WithContext(ctx).NameTaken(req.SchoolID, req.Name)
+	if err != nil {
+		return nil, errors.New("failed to check academic year name")
+	}
+	if taken {
+		return nil, ErrAcademicYearNameTaken
+	}
+	overlaps, err := s.years.WithContext(ctx).Overlaps(req.SchoolID, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, errors.New("failed to check academic year dates")
+	}
+	if overlaps {
+		return nil, ErrAcademicYearOverlaps
+	}
+
+	year := models.AcademicYear{
+		SchoolID:  &req.SchoolID,
+		Name:      req.Name,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	}
+	if err := s.years.WithContext(ctx).Create(&year); err != nil {
+		// The database has the last word when two requests pass the checks together
+		switch {
+		case errors.Is(err, gorm.ErrForeignKeyViolated):
+			return nil, ErrSchoolNotFound
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrAcademicYearNameTaken
+		case errors.Is(err, repositories.ErrDatesOverlap):
+			return nil, ErrAcademicYearOverlaps
+		}
+		logger.LogError(ctx, err, "Failed to create academic year", logrus.Fields{
+			"school_id": fmt.Sprintf("%d", req.SchoolID),
+			"name":      req.Name,
+		})
+		return nil, errors.New("failed to create academic year")
+	}
+
+	logger.LogInfo(ctx, "Academic year created successfully", logrus.Fields{
+		"academic_year_id": fmt.Sprintf("%d", year.ID),
+		"name":             year.Name,
+	})
+
+	return &year, nil
+}
+
+// GetTerms gets one page of the terms of an academic year, in order by default
+func (s *AcademicYearService) GetTerms(ctx context.Context, academicYearID uint, query listquery.Query) ([]models.Term, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "AcademicYearService.GetTerms")
+	defer span.End()
+
+	if _, err := s.findYear(ctx, academicYearID); err != nil {
+		return nil, listquery.Pagination{}, err
+	}
+	terms, err := s.years.WithContext(ctx).ListTerms(academicYearID, query)
+	if err != nil {
+		return nil, listquery.Pagination{}, errors.New("failed to get terms")
+	}
+
+	terms, meta := listquery.Page(query, terms)
+	return terms, meta, nil
+}
+
+// CreateTerm adds a term to an academic year of the teacher's school. Attendances
+// already recorded in its dates are assigned to it.
+func (s *AcademicYearService) CreateTerm(ctx context.Context, teacherID, academicYearID uint, req *requests.TermCreateRequest) (*models.Term, error) {
+	ctx, span := tracing.Start(ctx, "AcademicYearService.CreateTerm")
+	defer span.End()
+
+	year, err := s.findYear(ctx, academicYearID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSchool(ctx, teacherID, *year.SchoolID); err != nil {
+		return nil, err
+	}
+	if req.StartDate > req.EndDate {
+		return nil, ErrInvalidDateRange
+	}
+	if req.StartDate < day(year.StartDate) || req.EndDate > day(year.EndDate) {
+		return nil, ErrTermOutsideYear.With("start_date", day(year.StartDate)).With("end_date", day(year.EndDate))
+	}
+	taken, err := s.years.WithContext(ctx).TermNumberTaken(year.ID, req.Number)
+	if err != nil {
+		return nil, errors.New("failed to check term number")
+	}
+	if taken {
+		return nil, ErrTermNumberTaken
+	}
+	overlaps, err := s.years.WithContext(ctx).TermOverlaps(*year.SchoolID, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, errors.New("failed to check term dates")
+	}
+	if overlaps {
+		return nil, ErrTermOverlaps
+	}
+
+	term := models.Term{
+		AcademicYearID: &year.ID,
+		SchoolID:       year.SchoolID,
+		Number:         req.Number,
+		StartDate:      req.StartDate,
+		EndDate:        req.EndDate,
+	}
+	if err := s.years.WithContext(ctx).CreateTerm(&term); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			return nil, ErrTermNumberTaken
+		case errors.Is(err, repositories.ErrDatesOverlap):
+			return nil, ErrTermOverlaps
+		}
+		logger.LogError(ctx, err, "Failed to create term", logrus.Fields{
+			"academic_year_id": fmt.Sprintf("%d", year.ID),
+			"number":           req.Number,
+		})
+		return nil, errors.New("failed to create term")
+	}
+
+	logger.LogInfo(ctx, "Term created successfully", logrus.Fields{
+		"academic_year_id": fmt.Sprintf("%d", year.ID),
+		"term_id":          fmt.Sprintf("%d", term.ID),
+	})
+
+	return &term, nil
+}
+
+// RolloverResult is what a rollover created and where each student went
+type RolloverResult struct {
+	FromAcademicYearID uint                `json:"from_academic_year_id"`
+	ToAcademicYearID   uint                `json:"to_academic_year_id"`
+	DryRun             bool                `json:"dry_run"`
+	Classrooms         []models.Classroom  `json:"classrooms"` // Created in the target year; without IDs on a dry run
+	Promoted           []uint              `json:"promoted_student_ids"`
+	Repeated           []uint              `json:"repeated_student_ids"`
+	Graduated          []uint              `json:"graduated_student_ids"` // Stay in last year's classroom
+	Renumbered         []RenumberedStudent `json:"renumbered_students"`
+}
+
+// RenumberedStudent is a student whose number was already used in the classroom they
+// moved into, typically a repeater joining the grade below
+type RenumberedStudent struct {
+	StudentID uint   `json:"student_id"`
+	From      string `json:"from"`
+	To        string `json:"to,omitempty"` // Allocated when the rollover is written, empty on a dry run
+}
+
+// Rollover clones the classrooms of an academic year into the next one and moves
+// the students up a grade: "ม.1/2" is cloned as "ม.1/2" of the new year and its
+// students go to "ม.2/2". Repeating students go to the clone of their own classroom,
+// students of a final grade (อ.3, ป.6, ม.6) graduate and stay where they were.
+// Student numbers only need to be unique per classroom, so a repeater whose number is
+// already used by a promoted student gets the next number of the new classroom.
+// Attendances keep pointing at last year's classrooms, so past terms stay queryable.
+// Only a teacher of the school may roll its years over.
+func (s *AcademicYearService) Rollover(ctx context.Context, teacherID, fromID uint, req *requests.RolloverRequest) (*RolloverResult, error) {
+	ctx, span := tracing.Start(ctx, "AcademicYearService.Rollover")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Rolling over academic year", logrus.Fields{
+		"from_academic_year_id": fmt.Sprintf("%d", fromID),
+		"to_academic_year_id":   fmt.Sprintf("%d", req.ToAcademicYearID),
+		"dry_run":               req.DryRun,
+	})
+
+	from, err := s.findYear(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSchool(ctx, teacherID, *from.SchoolID); err != nil {
+		return nil, err
+	}
+	to, err := s.findYear(ctx, req.ToAcademicYearID)
+	if err != nil {
+		return nil, err
+	}
+	if *from.SchoolID != *to.SchoolID {
+		return nil, ErrAcademicYearOtherSchool
+	}
+	if day(to.StartDate) <= day(from.EndDate) {
+		return nil, ErrRolloverBackward
+	}
+
+	var result *RolloverResult
+	if err := s.years.WithContext(ctx).WithTx(func(tx repositories.AcademicYearRepository) error {
+		// A second rollover into the same year waits here and then finds its classrooms
+		if err := tx.Lock(to.ID); err != nil {
+			return err
+		}
+		existing, err := tx.ListClassrooms(to.ID)
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			return ErrRolloverTarget.With("classrooms", strconv.Itoa(len(existing)))
+		}
+		classrooms, err := tx.ListClassrooms(from.ID)
+		if err != nil {
+			return err
+		}
+
+		plan, err := planRollover(from, to, classrooms, req.RepeatingStudentIDs)
+		if err != nil {
+			return err
+		}
+		if req.DryRun {
+			result = plan.result(true)
+			return nil
+		}
+
+		if err := s.applyRollover(ctx, tx, plan); err != nil {
+			return err
+		}
+		result = plan.result(false)
+		return tx.Enqueue(outbox.Event{
+			Type:          "academic_year.rolled_over",
+			AggregateType: "academic_year",
+			AggregateID:   to.ID,
+			TeacherID:     teacherID,
+			SchoolID:      to.SchoolID,
+			Action:        models.LogActionRolloverAcademicYear,
+			Message: i18n.Msg("log.academic_year_rolled_over",
+				"from", from.Name, "to", to.Name, "promoted", strconv.Itoa(len(result.Promoted))),
+			Payload: result,
+		})
+	}); err != nil {
+		if errors.Is(err, ErrRolloverTarget) || errors.Is(err, ErrRolloverStudent) {
+			return nil, err
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrClassroomStudentNoTaken
+		}
+		logger.LogError(ctx, err, "Failed to roll over academic year", logrus.Fields{
+			"from_academic_year_id": fmt.Sprintf("%d", from.ID),
+			"to_academic_year_id":   fmt.Sprintf("%d", to.ID),
+		})
+		return nil, errors.New("failed to roll over academic year")
+	}
+	if result.DryRun {
+		return result, nil
+	}
+
+	logger.LogInfo(ctx, "Academic year rolled over successfully", logrus.Fields{
+		"from_academic_year_id": fmt.Sprintf("%d", from.ID),
+		"to_academic_year_id":   fmt.Sprintf("%d", to.ID),
+		"classrooms":            len(result.Classrooms),
+		"promoted":              len(result.Promoted),
+		"repeated":              len(result.Repeated),
+		"graduated":             len(result.Graduated),
+		"renumbered":            len(result.Renumbered),
+	})
+
+	return result, nil
+}
+
+// applyRollover writes plan: each classroom of the new year, the students keeping
+// their number, then the renumbered ones, whose next number has to see the others
+func (s *AcademicYearService) applyRollover(ctx context.Context, tx repositories.AcademicYearRepository, plan *rolloverPlan) error {
+	var school *models.School
+	now := s.clock.Now()
+	for _, target := range plan.targets {
+		if err := tx.CreateClassroom(target.classroom); err != nil {
+			return err
+		}
+		if err := tx.MoveStudents(target.students, target.classroom.ID); err != nil {
+			return err
+		}
+		for _, student := range target.renumbered {
+			if school == nil {
+				// The new classroom is not committed yet, its source has the same school
+				found, err := s.schools.WithContext(ctx).FindByClassroomID(*target.classroom.SourceClassroomID)
+				if err != nil {
+					return err
+				}
+				school = found
+			}
+			studentNo, err := nextStudentNo(tx, school, target.classroom.ID, now)
+			if err != nil {
+				return fmt.Errorf("generate student number: %w", err)
+			}
+			if err := tx.RenumberStudent(student.StudentID, target.classroom.ID, studentNo); err != nil {
+				return err
+			}
+			student.To = studentNo
+		}
+	}
+	return nil
+}
+
+// rolloverTarget is a classroom of the new year and the students moving into it
+type rolloverTarget struct {
+	classroom  *models.Classroom
+	students   []uint
+	renumbered []*RenumberedStudent
+	numbers    map[string]bool // Student numbers already used by the students moving in
+}
+
+// add moves student into the target, renumbering them when their number is taken
+func (t *rolloverTarget) add(plan *rolloverPlan, student models.Student) {
+	if t.numbers[student.StudentNo] {
+		renumbered := &RenumberedStudent{StudentID: student.ID, From: student.StudentNo}
+		t.renumbered = append(t.renumbered, renumbered)
+		plan.renumbered = append(plan.renumbered, renumbered)
+		return
+	}
+	t.numbers[student.StudentNo] = true
+	t.students = append(t.students, student.ID)
+}
+
+type rolloverPlan struct {
+	from, to   uint
+	targets    []*rolloverTarget
+	promoted   []uint
+	repeated   []uint
+	graduated  []uint
+	renumbered []*RenumberedStudent
+}
+
+func (p *rolloverPlan) result(dryRun bool) *RolloverResult {
+	result := &RolloverResult{
+		FromAcademicYearID: p.from,
+		ToAcademicYearID:   p.to,
+		DryRun:             dryRun,
+		Classrooms:         make([]models.Classroom, 0, len(p.targets)),
+		Promoted:           append([]uint{}, p.promoted...),
+		Repeated:           append([]uint{}, p.repeated...),
+		Graduated:          append([]uint{}, p.graduated...),
+		Renumbered:         make([]RenumberedStudent, 0, len(p.renumbered)),
+	}
+	for _, target := range p.targets {
+		result.Classrooms = append(result.Classrooms, *target.classroom)
+	}
+	for _, student := range p.renumbered {
+		result.Renumbered = append(result.Renumbered, *student)
+	}
+	return result
+}
+
+// planRollover works out the classrooms of the new year and who moves where,
+// without writing anything
+func planRollover(from, to *models.AcademicYear, classrooms []models.Classroom, repeating []uint) (*rolloverPlan, error) {
+	plan := &rolloverPlan{from: from.ID, to: to.ID}
+	byName := map[string]*rolloverTarget{}
+	target := func(source models.Classroom, name, grade string) *rolloverTarget {
+		if t, ok := byName[name]; ok {
+			return t
+		}
+		sourceID := source.ID
+		t := &rolloverTarget{numbers: map[string]bool{}, classroom: &models.Classroom{
+			SchoolID:          source.SchoolID,
+			TeacherID:         source.TeacherID,
+			Name:              name,
+			Grade:             grade,
+			Version:           1,
+			AcademicYearID:    &to.ID,
+			SourceClassroomID: &sourceID,
+		}}
+		byName[name] = t
+		plan.targets = append(plan.targets, t)
+		return t
+	}
+
+	// Clone the structure first so every classroom keeps its teacher
+	for _, classroom := range classrooms {
+		target(classroom, classroom.Name, classroom.Grade)
+	}
+
+	repeats := map[uint]bool{}
+	for _, id := range repeating {
+		repeats[id] = true
+	}
+	found := map[uint]bool{}
+	for _, classroom := range classrooms {
+		next, graduates := nextGrade(classroom.Grade)
+		for _, student := range classroom.Students {
+			switch {
+			case repeats[student.ID]:
+				found[student.ID] = true
+			case graduates:
+				plan.graduated = append(plan.graduated, student.ID)
+			default:
+				target(classroom, promotedName(classroom.Name, classroom.Grade, next), next).add(plan, student)
+				plan.promoted = append(plan.promoted, student.ID)
+			}
+		}
+	}
+
+	// Whatever is left was not found in any classroom of the source year
+	for _, id := range repeating {
+		if !found[id] {
+			return nil, ErrRolloverStudent.With("student_id", strconv.FormatUint(uint64(id), 10))
+		}
+	}
+
+	// Repeaters join the grade below after it moved in, so they are the ones renumbered
+	for _, classroom := range classrooms {
+		for _, student := range classroom.Students {
+			if found[student.ID] {
+				byName[classroom.Name].add(plan, student)
+				plan.repeated = append(plan.repeated, student.ID)
+			}
+		}
+	}
+	return plan, nil
+}
+
+var gradeLevel = regexp.MustCompile(`^(.*?)(\d+)$`)
+
+// finalLevels are the last grade of each stage; their students graduate instead of moving up
+var finalLevels = map[string]int{
+	"อ.": 3, // อนุบาล
+	"ป.": 6, // ประถมศึกษา
+	"ม.": 6, // มัธยมศึกษา
+}
+
+// nextGrade returns the grade after grade ("ม.1" -> "ม.2"), or graduates for the
+// final grade of a stage. A grade without a level number stays as it is.
+func nextGrade(grade string) (next string, graduates bool) {
+	match := gradeLevel.FindStringSubmatch(grade)
+	if match == nil {
+		return grade, false
+	}
+	level, _ := strconv.Atoi(match[2])
+	if final, ok := finalLevels[match[1]]; ok && level >= final {
+		return "", true
+	}
+	return match[1] + strconv.Itoa(level+1), false
+}
+
+// promotedName is the name of the classroom the students of name move to:
+// "ม.1/2" becomes "ม.2/2". Names without the grade get the new grade in front.
+func promotedName(name, grade, next string) string {
+	if grade == next {
+		return name
+	}
+	if strings.Contains(name, grade) {
+		return strings.Replace(name, grade, next, 1)
+	}
+	return next + " " + name
+}
+
+// day trims a date read back from a date column ("2025-05-16T00:00:00Z") to YYYY-MM-DD
+func day(date string) string {
+	if len(date) > len("2006-01-02") {
+		return date[:len("2006-01-02")]
+	}
+	return date
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/requests"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// newYears adds the school years 2568 and 2569 (พ.ศ.); the first adopts the seeded classroom
+func (e *testEnv) newYears(t *testing.T, school *models.School, teacher *models.Teacher) (*models.AcademicYear, *models.AcademicYear) {
+	t.Helper()
+	current, err := e.year.CreateAcademicYear(t.Context(), teacher.ID, &requests.AcademicYearCreateRequest{
+		SchoolID: school.ID, Name: "2568", StartDate: "2025-05-16", EndDate: "2026-03-31",
+	})
+	if err != nil {
+		t.Fatalf("create 2568: %v", err)
+	}
+	next, err := e.year.CreateAcademicYear(t.Context(), teacher.ID, &requests.AcademicYearCreateRequest{
+		SchoolID: school.ID, Name: "2569", StartDate: "2026-05-16", EndDate: "2027-03-31",
+	})
+	if err != nil {
+		t.Fatalf("create 2569: %v", err)
+	}
+	return current, next
+}
+
+func TestRolloverClonesClassroomsAndPromotesStudents(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, classroom, students := env.seed(3)
+	current, next := env.newYears(t, school, teacher)
+	if !isUint(env.store.classrooms[classroom.ID].AcademicYearID, current.ID) {
+		t.Fatalf("the first year did not adopt the existing classroom")
+	}
+
+	// The clock is in 2568, so a new classroom joins it without naming the year
+	final, err := env.classroom.CreateClassroom(t.Context(), &requests.ClassroomCreateRequest{
+		SchoolID: school.ID, TeacherID: teacher.ID, Name: "ม.6/1", Grade: "ม.6",
+	})
+	if err != nil {
+		t.Fatalf("create ม.6/1: %v", err)
+	}
+	if !isUint(final.AcademicYearID, current.ID) {
+		t.Fatalf("academic_year_id = %v, want the current year %d", final.AcademicYearID, current.ID)
+	}
+	senior := models.Student{SchoolID: &school.ID, ClassroomID: &final.ID, StudentNo: "STD900", FirstName: "รุ่นพี่", LastName: "ทดสอบ"}
+	memStudentRepo{env.store}.Create(&senior)
+	attendance, _ := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+
+	events := len(env.store.events)
+	req := &requests.RolloverRequest{ToAcademicYearID: next.ID, RepeatingStudentIDs: []uint{students[2].ID}, DryRun: true}
+	planned, err := env.year.Rollover(t.Context(), teacher.ID, current.ID, req)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(env.store.classrooms) != 2 || len(env.store.events) != events {
+		t.Fatalf("dry run wrote %d classrooms and events %v", len(env.store.classrooms), env.store.eventTypes())
+	}
+
+	req.DryRun = false
+	result, err := env.year.Rollover(t.Context(), teacher.ID, current.ID, req)
+	if err != nil {
+		t.Fatalf("rollover: %v", err)
+	}
+	names := map[string]models.Classroom{}
+	for _, c := range result.Classrooms {
+		names[c.Name] = env.store.classrooms[c.ID]
+	}
+	if len(names) != 3 || len(planned.Classrooms) != 3 {
+		t.Fatalf("classrooms = %+v, want ม.1/1, ม.6/1 and ม.2/1 (dry run planned %d)", result.Classrooms, len(planned.Classrooms))
+	}
+	promotedRoom, repeatRoom := names["ม.2/1"], names["ม.1/1"]
+	if promotedRoom.Grade != "ม.2" || !isUint(promotedRoom.AcademicYearID, next.ID) || !isUint(promotedRoom.SourceClassroomID, classroom.ID) {
+		t.Errorf("ม.2/1 = %+v", promotedRoom)
+	}
+	for _, s := range students[:2] {
+		if got := env.store.students[s.ID]; !isUint(got.ClassroomID, promotedRoom.ID) || got.Version != s.Version+1 {
+			t.Errorf("student %d is in classroom %v (version %d), want the promoted ม.2/1", s.ID, *got.ClassroomID, got.Version)
+		}
+	}
+	if got := env.store.students[students[2].ID]; !isUint(got.ClassroomID, repeatRoom.ID) {
+		t.Errorf("repeating student is in classroom %v, want next year's ม.1/1 %d", *got.ClassroomID, repeatRoom.ID)
+	}
+	if got := env.store.students[senior.ID]; !isUint(got.ClassroomID, final.ID) || !slices.Equal(result.Graduated, []uint{senior.ID}) {
+		t.Errorf("ม.6 student moved to %v, graduated = %v", *got.ClassroomID, result.Graduated)
+	}
+
+	// Last year's attendance still points at last year's classroom
+	if got := env.store.attendances[attendance.ID]; !isUint(got.ClassroomID, classroom.ID) {
+		t.Errorf("attendance moved to classroom %v", *got.ClassroomID)
+	}
+	if types := env.store.eventTypes(); types[len(types)-1] != "academic_year.rolled_over" {
+		t.Errorf("events = %v", types)
+	}
+
+	if _, err := env.year.Rollover(t.Context(), teacher.ID, current.ID, req); !errors.Is(err, ErrRolloverTarget) {
+		t.Errorf("second rollover error = %v, want %v", err, ErrRolloverTarget)
+	}
+	if _, err := env.year.Rollover(t.Context(), teacher.ID, next.ID, &requests.RolloverRequest{ToAcademicYearID: current.ID}); !errors.Is(err, ErrRolloverBackward) {
+		t.Errorf("backward rollover error = %v, want %v", err, ErrRolloverBackward)
+	}
+}
+
+func TestRolloverRenumbersRepeaterJoiningTheGradeBelow(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, _, juniors := env.seed(2)
+	current, next := env.newYears(t, school, teacher)
+
+	upper, err := env.classroom.CreateClassroom(t.Context(), &requests.ClassroomCreateRequest{
+		SchoolID: school.ID, TeacherID: teacher.ID, Name: "ม.2/1", Grade: "ม.2",
+	})
+	if err != nil {
+		t.Fatalf("create ม.2/1: %v", err)
+	}
+	// Numbers restart in every classroom, so ม.2/1 has its own STD001 and STD002
+	seniors := make([]models.Student, 0, 2)
+	for _, no := range []string{"STD001", "STD002"} {
+		student := models.Student{SchoolID: &school.ID, ClassroomID: &upper.ID, StudentNo: no, FirstName: "ม.2", LastName: no}
+		memStudentRepo{env.store}.Create(&student)
+		seniors = append(seniors, student)
+	}
+	repeater := seniors[0]
+
+	req := &requests.RolloverRequest{ToAcademicYearID: next.ID, RepeatingStudentIDs: []uint{repeater.ID}, DryRun: true}
+	planned, err := env.year.Rollover(t.Context(), teacher.ID, current.ID, req)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	want := []RenumberedStudent{{StudentID: repeater.ID, From: "STD001"}}
+	if !slices.Equal(planned.Renumbered, want) {
+		t.Errorf("dry run renumbered = %+v, want %+v", planned.Renumbered, want)
+	}
+
+	req.DryRun = false
+	result, err := env.year.Rollover(t.Context(), teacher.ID, current.ID, req)
+	if err != nil {
+		t.Fatalf("rollover: %v", err)
+	}
+	want[0].To = "STD003"
+	if !slices.Equal(result.Renumbered, want) {
+		t.Errorf("renumbered = %+v, want %+v", result.Renumbered, want)
+	}
+
+	got := env.store.students[repeater.ID]
+	for _, s := range juniors {
+		if promoted := env.store.students[s.ID]; !sameUint(promoted.ClassroomID, got.ClassroomID) || promoted.StudentNo != s.StudentNo {
+			t.Errorf("ม.1 student %d is in classroom %v as %s, want the repeater's classroom %v as %s",
+				s.ID, *promoted.ClassroomID, promoted.StudentNo, *got.ClassroomID, s.StudentNo)
+		}
+	}
+	if room := env.store.classrooms[*got.ClassroomID]; room.Name != "ม.2/1" || !isUint(room.AcademicYearID, next.ID) {
+		t.Errorf("repeater is in %+v, want next year's ม.2/1", room)
+	}
+	if got.StudentNo != "STD003" || got.Version != repeater.Version+1 {
+		t.Errorf("repeater = %s (version %d), want STD003 (version %d)", got.StudentNo, got.Version, repeater.Version+1)
+	}
+	if promoted := env.store.students[seniors[1].ID]; promoted.StudentNo != "STD002" ||
+		env.store.classrooms[*promoted.ClassroomID].Name != "ม.3/1" {
+		t.Errorf("ม.2 student moved to classroom %v as %s, want ม.3/1 as STD002", *promoted.ClassroomID, promoted.StudentNo)
+	}
+}
+
+func TestRolloverRejectsRepeatingStudentOfAnotherYear(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, _, _ := env.seed(1)
+	current, next := env.newYears(t, school, teacher)
+
+	_, err := env.year.Rollover(t.Context(), teacher.ID, current.ID, &requests.RolloverRequest{
+		ToAcademicYearID: next.ID, RepeatingStudentIDs: []uint{999},
+	})
+	if !errors.Is(err, ErrRolloverStudent) {
+		t.Fatalf("error = %v, want %v", err, ErrRolloverStudent)
+	}
+	if len(env.store.classrooms) != 1 {
+		t.Errorf("a rejected rollover created classrooms")
+	}
+}
+
+func TestCreateTermAssignsAttendancesInItsDates(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, classroom, students := env.seed(1)
+	attendance, _ := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+	current, _ := env.newYears(t, school, teacher)
+
+	term, err := env.year.CreateTerm(t.Context(), teacher.ID, current.ID, &requests.TermCreateRequest{Number: 1, StartDate: "2025-05-16", EndDate: "2025-10-10"})
+	if err != nil {
+		t.Fatalf("create term: %v", err)
+	}
+	if got := env.store.attendances[attendance.ID]; !isUint(got.TermID, term.ID) {
+		t.Errorf("term_id = %v, want %d", got.TermID, term.ID)
+	}
+
+	for _, tc := range []struct {
+		req  requests.TermCreateRequest
+		want error
+	}{
+		{requests.TermCreateRequest{Number: 2, StartDate: "2025-10-01", EndDate: "2026-03-31"}, ErrTermOverlaps},
+		{requests.TermCreateRequest{Number: 1, StartDate: "2025-11-01", EndDate: "2026-03-31"}, ErrTermNumberTaken},
+		{requests.TermCreateRequest{Number: 2, StartDate: "2025-11-01", EndDate: "2026-04-30"}, ErrTermOutsideYear},
+		{requests.TermCreateRequest{Number: 2, StartDate: "2026-03-01", EndDate: "2025-11-01"}, ErrInvalidDateRange},
+	} {
+		if _, err := env.year.CreateTerm(t.Context(), teacher.ID, current.ID, &tc.req); !errors.Is(err, tc.want) {
+			t.Errorf("%+v: error = %v, want %v", tc.req, err, tc.want)
+		}
+	}
+}
+
+// staleYearRepo answers the pre-checks as if another request had not committed yet
+type staleYearRepo struct {
+	memAcademicYearRepo
+	checkErr error
+}
+
+func (r staleYearRepo) WithContext(ctx context.Context) repositories.AcademicYearRepository { return r }
+
+func (r staleYearRepo) NameTaken(schoolID uint, name string) (bool, error) { return false, r.checkErr }
+
+func (r staleYearRepo) Overlaps(schoolID uint, startDate, endDate string) (bool, error) {
+	return false, r.checkErr
+}
+
+func TestCreateAcademicYearLetsTheDatabaseDecideRaces(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, _, _ := env.seed(0)
+	env.newYears(t, school, teacher)
+
+	stale := staleYearRepo{memAcademicYearRepo: memAcademicYearRepo{env.store}}
+	years := NewAcademicYearService(stale, memSchoolRepo{env.store}, memTeacherRepo{env.store}, env.clock)
+	for _, tc := range []struct {
+		req  requests.AcademicYearCreateRequest
+		want error
+	}{
+		{requests.AcademicYearCreateRequest{SchoolID: school.ID, Name: "2568", StartDate: "2027-05-16", EndDate: "2028-03-31"}, ErrAcademicYearNameTaken},
+		{requests.AcademicYearCreateRequest{SchoolID: school.ID, Name: "2570", StartDate: "2027-01-01", EndDate: "2028-03-31"}, ErrAcademicYearOverlaps},
+	} {
+		if _, err := years.CreateAcademicYear(t.Context(), teacher.ID, &tc.req); !errors.Is(err, tc.want) {
+			t.Errorf("%s: error = %v, want %v", tc.req.Name, err, tc.want)
+		}
+	}
+
+	// A failing check is an error, not a free name
+	stale.checkErr = errors.New("connection reset")
+	years = NewAcademicYearService(stale, memSchoolRepo{env.store}, memTeacherRepo{env.store}, env.clock)
+	_, err := years.CreateAcademicYear(t.Context(), teacher.ID, &requests.AcademicYearCreateRequest{
+		SchoolID: school.ID, Name: "2570", StartDate: "2027-05-16", EndDate: "2028-03-31",
+	})
+	if err == nil || len(env.store.years) != 2 {
+		t.Errorf("error = %v with %d years, want a failure and no new year", err, len(env.store.years))
+	}
+}
+
+func TestAcademicYearsOfAnotherSchoolAreForbidden(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, _, _ := env.seed(1)
+	current, next := env.newYears(t, school, teacher)
+
+	other := &models.School{Name: "โรงเรียนข้างเคียง"}
+	memSchoolRepo{env.store}.Create(other)
+	outsider := &models.Teacher{SchoolID: &other.ID, Email: "outsider@example.com", FirstName: "สมศรี", LastName: "ต่างถิ่น"}
+	memTeacherRepo{env.store}.Create(outsider)
+
+	_, err := env.year.CreateAcademicYear(t.Context(), outsider.ID, &requests.AcademicYearCreateRequest{
+		SchoolID: school.ID, Name: "2570", StartDate: "2027-05-16", EndDate: "2028-03-31",
+	})
+	if !errors.Is(err, ErrAcademicYearForbidden) {
+		t.Errorf("create year: error = %v, want %v", err, ErrAcademicYearForbidden)
+	}
+	_, err = env.year.CreateTerm(t.Context(), outsider.ID, current.ID, &requests.TermCreateRequest{Number: 1, StartDate: "2025-05-16", EndDate: "2025-10-10"})
+	if !errors.Is(err, ErrAcademicYearForbidden) {
+		t.Errorf("create term: error = %v, want %v", err, ErrAcademicYearForbidden)
+	}
+	_, err = env.year.Rollover(t.Context(), outsider.ID, current.ID, &requests.RolloverRequest{ToAcademicYearID: next.ID})
+	if !errors.Is(err, ErrAcademicYearForbidden) {
+		t.Errorf("rollover: error = %v, want %v", err, ErrAcademicYearForbidden)
+	}
+
+	if len(env.store.years) != 2 || len(env.store.terms) != 0 || len(env.store.classrooms) != 1 || len(env.store.events) != 0 {
+		t.Errorf("forbidden calls wrote %d years, %d terms, %d classrooms and events %v",
+			len(env.store.years), len(env.store.terms), len(env.store.classrooms), env.store.eventTypes())
+	}
+}
+
+func TestGetTermsPagesInOrder(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, _, _ := env.seed(0)
+	current, _ := env.newYears(t, school, teacher)
+	// Added out of order, listed by number
+	for _, req := range []requests.TermCreateRequest{
+		{Number: 2, StartDate: "2025-11-01", EndDate: "2026-03-31"},
+		{Number: 1, StartDate: "2025-05-16", EndDate: "2025-10-10"},
+	} {
+		if _, err := env.year.CreateTerm(t.Context(), teacher.ID, current.ID, &req); err != nil {
+			t.Fatalf("create term %d: %v", req.Number, err)
+		}
+	}
+
+	first, meta, err := env.year.GetTerms(t.Context(), current.ID, list(t, TermListing, "limit=1"))
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if len(first) != 1 || first[0].Number != 1 || !meta.HasMore {
+		t.Fatalf("first page = %+v, has more %v", first, meta.HasMore)
+	}
+	second, meta, err := env.year.GetTerms(t.Context(), current.ID, list(t, TermListing, "limit=1&cursor="+*meta.NextCursor))
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if len(second) != 1 || second[0].Number != 2 || meta.HasMore {
+		t.Errorf("second page = %+v, has more %v", second, meta.HasMore)
+	}
+}
+
+func TestNextGrade(t *testing.T) {
+	for grade, want := range map[string]string{
+		"ม.1": "ม.2", "ม.3": "ม.4", "ป.5": "ป.6", "อ.2": "อ.3", "ปวช.1": "ปวช.2", "ห้องพิเศษ": "ห้องพิเศษ",
+		"ม.6": "", "ป.6": "", "อ.3": "",
+	} {
+		next, graduates := nextGrade(grade)
+		if next != want || graduates != (want == "") {
+			t.Errorf("nextGrade(%q) = %q, %v; want %q", grade, next, graduates, want)
+		}
+	}
+}
@@ -1,10 +1,20 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/metrics"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/realtime"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
 
@@ -12,106 +22,113 @@ import (
 	"gorm.io/gorm"
 )
 
-type AttendanceService struct{}
+type AttendanceService struct {
+	attendances repositories.AttendanceRepository
+	classrooms  repositories.ClassroomRepository
+	clock       clock.Clock
+}
+
+// AttendanceConflictError is returned when a write was based on a stale version.
+// Current holds the row as it is now, or nil when it no longer exists.
+type AttendanceConflictError struct {
+	Current *models.Attendance
+}
+
+func (e *AttendanceConflictError) Error() string {
+	return ErrAttendanceVersionConflict.Error()
+}
+
+// Unwrap lets the error middleware report the conflict as attendance.version_conflict,
+// with the current row when there is one
+func (e *AttendanceConflictError) Unwrap() error {
+	if e.Current == nil {
+		return ErrAttendanceVersionConflict
+	}
+	return ErrAttendanceVersionConflict.WithCurrent(e.Current)
+}
 
-func NewAttendanceService() *AttendanceService {
-	return &AttendanceService{}
+func NewAttendanceService(attendances repositories.AttendanceRepository, classrooms repositories.ClassroomRepository, clk clock.Clock) *AttendanceService {
+	return &AttendanceService{
+		attendances: attendances,
+		classrooms:  classrooms,
+		clock:       clk,
+	}
 }
 
-func (s *AttendanceService) GetAttendanceByID(id uint) (*models.Attendance, error) {
-	logger.LogInfo("Fetching attendance by ID", logrus.Fields{
+// GetAttendanceByID gets one attendance record with the fields and relations of sel
+func (s *AttendanceService) GetAttendanceByID(ctx context.Context, id uint, sel fieldset.Selection) (*models.Attendance, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.GetAttendanceByID")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching attendance by ID", logrus.Fields{
 		"attendance_id": fmt.Sprintf("%d", id),
 	})
 
-	var attendance models.Attendance
-	if err := configs.DB.Where("id = ?", id).First(&attendance).Error; err != nil {
+	// updated_at and version make the ETag, whichever fields the client asked for
+	attendance, err := s.attendances.WithContext(ctx).WithSelection(sel.Including("updated_at", "version")).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Attendance not found", logrus.Fields{
+			logger.LogWarning(ctx, "Attendance not found", logrus.Fields{
 				"attendance_id": fmt.Sprintf("%d", id),
 			})
-			return nil, errors.New("attendance not found")
+			return nil, ErrAttendanceNotFound
 		}
-		logger.LogError(err, "Failed to fetch attendance", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to fetch attendance", logrus.Fields{
 			"attendance_id": fmt.Sprintf("%d", id),
 		})
 		return nil, errors.New("failed to fetch attendance")
 	}
 
-	return &attendance, nil
+	return attendance, nil
 }
 
-func (s *AttendanceService) GetAttendancesByClassroom(classroomID uint, page, limit int) ([]models.Attendance, int64, error) {
-	logger.LogInfo("Fetching attendances by classroom", logrus.Fields{
+func (s *AttendanceService) GetAttendancesByClassroom(ctx context.Context, classroomID uint, query listquery.Query, sel fieldset.Selection) ([]models.Attendance, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.GetAttendancesByClassroom")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching attendances by classroom", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", classroomID),
-		"page":         page,
-		"limit":        limit,
+		"limit":        query.Limit,
 	})
 
-	// Count total records
-	var total int64
-	if err := configs.DB.Model(&models.Attendance{}).Where("classroom_id = ?", classroomID).Count(&total).Error; err != nil {
-		logger.LogError(err, "Failed to count attendances", logrus.Fields{
+	attendances, err := s.attendances.WithContext(ctx).WithSelection(sel.Including(query.SortFields()...)).ListByClassroom(classroomID, query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch attendances by classroom", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", classroomID),
 		})
-		return nil, 0, errors.New("failed to count attendances")
+		return nil, listquery.Pagination{}, errors.New("failed to fetch attendances")
 	}
 
-	// Calculate offset
-	offset := (page - 1) * limit
-
-	var attendances []models.Attendance
-	if err := configs.DB.
-		Where("classroom_id = ?", classroomID).
-		Order("session_date DESC, created_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&attendances).Error; err != nil {
-		logger.LogError(err, "Failed to fetch attendances by classroom", logrus.Fields{
-			"classroom_id": fmt.Sprintf("%d", classroomID),
-		})
-		return nil, 0, errors.New("failed to fetch attendances")
-	}
-
-	return attendances, total, nil
+	attendances, meta := listquery.Page(query, attendances)
+	return attendances, meta, nil
 }
 
-func (s *AttendanceService) GetAttendancesByStudent(studentID uint, page, limit int) ([]models.Attendance, int64, error) {
-	logger.LogInfo("Fetching attendances by student", logrus.Fields{
+func (s *AttendanceService) GetAttendancesByStudent(ctx context.Context, studentID uint, query listquery.Query, sel fieldset.Selection) ([]models.Attendance, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.GetAttendancesByStudent")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching attendances by student", logrus.Fields{
 		"student_id": fmt.Sprintf("%d", studentID),
-		"page":       page,
-		"limit":      limit,
+		"limit":      query.Limit,
 	})
 
-	// Count total records
-	var total int64
-	if err := configs.DB.Model(&models.Attendance{}).Where("student_id = ?", studentID).Count(&total).Error; err != nil {
-		logger.LogError(err, "Failed to count attendances", logrus.Fields{
-			"student_id": fmt.Sprintf("%d", studentID),
-		})
-		return nil, 0, errors.New("failed to count attendances")
-	}
-
-	// Calculate offset
-	offset := (page - 1) * limit
-
-	var attendances []models.Attendance
-	if err := configs.DB.
-		Where("student_id = ?", studentID).
-		Order("session_date DESC, created_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Find(&attendances).Error; err != nil {
-		logger.LogError(err, "Failed to fetch attendances by student", logrus.Fields{
+	attendances, err := s.attendances.WithContext(ctx).WithSelection(sel.Including(query.SortFields()...)).ListByStudent(studentID, query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch attendances by student", logrus.Fields{
 			"student_id": fmt.Sprintf("%d", studentID),
 		})
-		return nil, 0, errors.New("failed to fetch attendances")
+		return nil, listquery.Pagination{}, errors.New("failed to fetch attendances")
 	}
 
-	return attendances, total, nil
+	attendances, meta := listquery.Page(query, attendances)
+	return attendances, meta, nil
 }
 
-func (s *AttendanceService) CreateAttendance(req *requests.AttendanceCreateRequest) (*models.Attendance, error) {
-	logger.LogInfo("Creating new attendance", logrus.Fields{
+func (s *AttendanceService) CreateAttendance(ctx context.Context, req *requests.AttendanceCreateRequest) (*models.Attendance, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.CreateAttendance")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Creating new attendance", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
 		"teacher_id":   fmt.Sprintf("%d", req.TeacherID),
 		"student_id":   fmt.Sprintf("%d", req.StudentID),
@@ -119,18 +136,6 @@ func (s *AttendanceService) CreateAttendance(req *requests.AttendanceCreateReque
 		"status":       string(req.Status),
 	})
 
-	// Check if attendance already exists for this student on this date in this classroom
-	var existingAttendance models.Attendance
-	if err := configs.DB.Where("classroom_id = ? AND student_id = ? AND session_date = ?",
-		req.ClassroomID, req.StudentID, req.SessionDate).First(&existingAttendance).Error; err == nil {
-		logger.LogWarning("Attendance already exists", logrus.Fields{
-			"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
-			"student_id":   fmt.Sprintf("%d", req.StudentID),
-			"session_date": req.SessionDate,
-		})
-		return nil, errors.New("attendance for this student on this date already exists")
-	}
-
 	// Create new attendance
 	attendance := models.Attendance{
 		ClassroomID: &req.ClassroomID,
@@ -140,123 +145,412 @@ func (s *AttendanceService) CreateAttendance(req *requests.AttendanceCreateReque
 		Status:      req.Status,
 		CheckedAt:   req.CheckedAt,
 		Remark:      req.Remark,
+		Version:     1,
 	}
 
-	if err := configs.DB.Create(&attendance).Error; err != nil {
-		logger.LogError(err, "Failed to create attendance", logrus.Fields{
+	// Get school ID from classroom for the activity log
+	var schoolID *uint
+	if classroom, err := s.classrooms.WithContext(ctx).FindByID(req.ClassroomID); err == nil {
+		schoolID = classroom.SchoolID
+	}
+
+	// Insert the attendance and its outbox event atomically; the unique slot index
+	// decides between concurrent creates instead of a check-then-insert
+	if err := s.attendances.WithContext(ctx).WithTx(func(tx repositories.AttendanceRepository) error {
+		if err := tx.Create(&attendance); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "attendance.created",
+			AggregateType: "attendance",
+			AggregateID:   attendance.ID,
+			TeacherID:     req.TeacherID,
+			SchoolID:      schoolID,
+			Action:        models.LogActionAttendance,
+			Message:       i18n.Msg("log.attendance_recorded", "status", string(req.Status), "date", req.SessionDate),
+			Payload:       attendance,
+		})
+	}); err != nil {
+		if constraintErr := attendanceConstraintError(err); constraintErr != nil {
+			logger.LogWarning(ctx, "Attendance create rejected by constraint", logrus.Fields{
+				"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
+				"student_id":   fmt.Sprintf("%d", req.StudentID),
+				"session_date": req.SessionDate,
+				"reason":       constraintErr.Error(),
+			})
+			return nil, constraintErr
+		}
+		logger.LogError(ctx, err, "Failed to create attendance", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
 			"student_id":   fmt.Sprintf("%d", req.StudentID),
 		})
 		return nil, errors.New("failed to create attendance")
 	}
 
-	logger.LogInfo("Attendance created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Attendance created successfully", logrus.Fields{
 		"attendance_id": fmt.Sprintf("%d", attendance.ID),
 		"student_id":    fmt.Sprintf("%d", attendance.StudentID),
 		"status":        string(attendance.Status),
 	})
 
-	// Log activity automatically - get school ID from classroom
-	var classroom models.Classroom
-	if err := configs.DB.Where("id = ?", req.ClassroomID).First(&classroom).Error; err == nil {
-		logger.LogActivity(req.TeacherID, models.LogActionAttendance,
-			fmt.Sprintf("บันทึกการเข้าเรียน: %s (วันที่: %s)", string(req.Status), req.SessionDate),
-			classroom.SchoolID)
-	}
+	realtime.Attendance.Publish("attendance.created", *attendance.ClassroomID, attendance)
+	metrics.AttendanceRecorded.WithLabelValues(string(attendance.Status), "api").Inc()
 
 	return &attendance, nil
 }
 
-func (s *AttendanceService) UpdateAttendance(id uint, req *requests.AttendanceUpdateRequest) (*models.Attendance, error) {
-	logger.LogInfo("Updating attendance", logrus.Fields{
+func (s *AttendanceService) UpdateAttendance(ctx context.Context, id uint, req *requests.AttendanceUpdateRequest) (*models.Attendance, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.UpdateAttendance")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Updating attendance", logrus.Fields{
 		"attendance_id": fmt.Sprintf("%d", id),
-		"status":        string(req.Status),
 	})
 
-	var attendance models.Attendance
-	if err := configs.DB.Where("id = ?", id).First(&attendance).Error; err != nil {
+	attendance, err := s.attendances.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Attendance not found for update", logrus.Fields{
+			logger.LogWarning(ctx, "Attendance not found for update", logrus.Fields{
 				"attendance_id": fmt.Sprintf("%d", id),
 			})
-			return nil, errors.New("attendance not found")
+			return nil, ErrAttendanceNotFound
 		}
-		logger.LogError(err, "Failed to find attendance for update", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find attendance for update", logrus.Fields{
 			"attendance_id": fmt.Sprintf("%d", id),
 		})
 		return nil, errors.New("failed to find attendance")
 	}
 
-	// Update fields
-	attendance.Status = req.Status
-	attendance.CheckedAt = req.CheckedAt
-	attendance.Remark = req.Remark
+	if err := etag.CheckIfMatch(ctx, attendance); err != nil {
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != attendance.Version {
+		return nil, &AttendanceConflictError{Current: attendance}
+	}
 
-	if err := configs.DB.Save(&attendance).Error; err != nil {
-		logger.LogError(err, "Failed to update attendance", logrus.Fields{
+	// Only the fields that were sent change
+	baseVersion := attendance.Version
+	if req.Status != nil {
+		attendance.Status = *req.Status
+	}
+	if req.CheckedAt != nil {
+		attendance.CheckedAt = *req.CheckedAt
+	}
+	if req.Remark != nil {
+		attendance.Remark = *req.Remark
+	}
+	attendance.Version++
+
+	if err := s.attendances.WithContext(ctx).WithTx(func(tx repositories.AttendanceRepository) error {
+		updated, err := tx.UpdateIfVersion(attendance, baseVersion)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// Another writer got in between the read and this write
+			current, err := tx.FindByID(id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrAttendanceNotFound
+			}
+			if err != nil {
+				return err
+			}
+			return &AttendanceConflictError{Current: current}
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "attendance.updated",
+			AggregateType: "attendance",
+			AggregateID:   attendance.ID,
+			TeacherID:     *attendance.TeacherID,
+			Payload:       attendance,
+		})
+	}); err != nil {
+		var conflict *AttendanceConflictError
+		if errors.As(err, &conflict) || errors.Is(err, ErrAttendanceNotFound) {
+			logger.LogWarning(ctx, "Attendance update rejected - version conflict", logrus.Fields{
+				"attendance_id": fmt.Sprintf("%d", id),
+				"base_version":  baseVersion,
+			})
+			return nil, err
+		}
+		if constraintErr := attendanceConstraintError(err); constraintErr != nil {
+			return nil, constraintErr
+		}
+		logger.LogError(ctx, err, "Failed to update attendance", logrus.Fields{
 			"attendance_id": fmt.Sprintf("%d", id),
 		})
 		return nil, errors.New("failed to update attendance")
 	}
 
-	logger.LogInfo("Attendance updated successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Attendance updated successfully", logrus.Fields{
 		"attendance_id": fmt.Sprintf("%d", attendance.ID),
 		"status":        string(attendance.Status),
 	})
 
-	return &attendance, nil
+	realtime.Attendance.Publish("attendance.updated", *attendance.ClassroomID, *attendance)
+	metrics.AttendanceRecorded.WithLabelValues(string(attendance.Status), "api").Inc()
+
+	return attendance, nil
 }
 
-func (s *AttendanceService) DeleteAttendance(id uint) error {
-	logger.LogInfo("Deleting attendance", logrus.Fields{
+func (s *AttendanceService) DeleteAttendance(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "AttendanceService.DeleteAttendance")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Deleting attendance", logrus.Fields{
 		"attendance_id": fmt.Sprintf("%d", id),
 	})
 
-	var attendance models.Attendance
-	if err := configs.DB.Where("id = ?", id).First(&attendance).Error; err != nil {
+	attendance, err := s.attendances.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Attendance not found for deletion", logrus.Fields{
+			logger.LogWarning(ctx, "Attendance not found for deletion", logrus.Fields{
 				"attendance_id": fmt.Sprintf("%d", id),
 			})
-			return errors.New("attendance not found")
+			return ErrAttendanceNotFound
 		}
-		logger.LogError(err, "Failed to find attendance for deletion", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find attendance for deletion", logrus.Fields{
 			"attendance_id": fmt.Sprintf("%d", id),
 		})
 		return errors.New("failed to find attendance")
 	}
 
-	if err := configs.DB.Delete(&attendance).Error; err != nil {
-		logger.LogError(err, "Failed to delete attendance", logrus.Fields{
+	if err := s.attendances.WithContext(ctx).WithTx(func(tx repositories.AttendanceRepository) error {
+		if err := tx.SoftDelete(attendance, models.NewDeletedAt(s.clock.Now())); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "attendance.deleted",
+			AggregateType: "attendance",
+			AggregateID:   attendance.ID,
+			TeacherID:     *attendance.TeacherID,
+			Payload:       attendance,
+		})
+	}); err != nil {
+		logger.LogError(ctx, err, "Failed to delete attendance", logrus.Fields{
 			"attendance_id": fmt.Sprintf("%d", id),
 		})
 		return errors.New("failed to delete attendance")
 	}
 
-	logger.LogInfo("Attendance deleted successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Attendance deleted successfully", logrus.Fields{
 		"attendance_id": fmt.Sprintf("%d", id),
 	})
 
 	return nil
 }
 
-// GetAttendancesByTeacher gets all attendance records for a specific teacher
-func (s *AttendanceService) GetAttendancesByTeacher(teacherID uint) ([]models.Attendance, error) {
-	var attendances []models.Attendance
-
-	if err := configs.DB.
-		Preload("Student").
-		Preload("Student.School").
-		Preload("Student.Classroom").
-		Preload("Student.Gender").
-		Preload("Student.Prefix").
-		Preload("Classroom").
-		Preload("Teacher").
-		Where("teacher_id = ?", teacherID).
-		Order("checked_at DESC").
-		Find(&attendances).Error; err != nil {
-		return nil, errors.New("failed to get attendances by teacher")
+// RestoreAttendance brings an attendance back from the trash. It fails while its
+// classroom or student is deleted, or when the slot was marked again in the meantime.
+func (s *AttendanceService) RestoreAttendance(ctx context.Context, id uint) (*models.Attendance, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.RestoreAttendance")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Restoring attendance", logrus.Fields{
+		"attendance_id": fmt.Sprintf("%d", id),
+	})
+
+	attendance, err := s.attendances.WithContext(ctx).FindDeletedByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeletedAttendanceNotFound
+		}
+		return nil, errors.New("failed to find attendance")
+	}
+
+	live, err := s.attendances.WithContext(ctx).HasLiveParents(*attendance.ClassroomID, *attendance.StudentID)
+	if err != nil {
+		return nil, errors.New("failed to restore attendance")
+	}
+	if !live {
+		return nil, ErrAttendanceParentInTrash
+	}
+
+	if err := s.attendances.WithContext(ctx).WithTx(func(tx repositories.AttendanceRepository) error {
+		if err := tx.Restore(attendance); err != nil {
+			return err
+		}
+		restored, err := tx.FindByID(attendance.ID)
+		if err != nil {
+			return err
+		}
+		attendance = restored
+		return tx.Enqueue(outbox.Event{
+			Type:          "attendance.restored",
+			AggregateType: "attendance",
+			AggregateID:   attendance.ID,
+			TeacherID:     *attendance.TeacherID,
+			Payload:       attendance,
+		})
+	}); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrAttendanceExists
+		}
+		logger.LogError(ctx, err, "Failed to restore attendance", logrus.Fields{
+			"attendance_id": fmt.Sprintf("%d", id),
+		})
+		return nil, errors.New("failed to restore attendance")
+	}
+
+	logger.LogInfo(ctx, "Attendance restored successfully", logrus.Fields{
+		"attendance_id": fmt.Sprintf("%d", id),
+	})
+
+	realtime.Attendance.Publish("attendance.restored", *attendance.ClassroomID, *attendance)
+
+	return attendance, nil
+}
+
+// GetAttendancesByTeacher gets one page of the attendance records of a specific teacher
+func (s *AttendanceService) GetAttendancesByTeacher(ctx context.Context, teacherID uint, query listquery.Query, sel fieldset.Selection) ([]models.Attendance, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.GetAttendancesByTeacher")
+	defer span.End()
+
+	attendances, err := s.attendances.WithContext(ctx).WithSelection(sel.Including(query.SortFields()...)).ListByTeacher(teacherID, query)
+	if err != nil {
+		return nil, listquery.Pagination{}, errors.New("failed to get attendances by teacher")
+	}
+
+	attendances, meta := listquery.Page(query, attendances)
+	return attendances, meta, nil
+}
+
+// GetAttendancesBySession gets the attendance records of one classroom on one date
+func (s *AttendanceService) GetAttendancesBySession(ctx context.Context, classroomID uint, sessionDate string) ([]models.Attendance, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.GetAttendancesBySession")
+	defer span.End()
+
+	attendances, err := s.attendances.WithContext(ctx).ListBySession(classroomID, sessionDate)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch attendances by session", logrus.Fields{
+			"classroom_id": fmt.Sprintf("%d", classroomID),
+			"session_date": sessionDate,
+		})
+		return nil, errors.New("failed to fetch attendances")
 	}
 
 	return attendances, nil
 }
+
+// MarkAttendance applies a live roll-call write using compare-and-swap on the row version.
+// Writes for the same student and date are serialized so concurrent first marks cannot
+// both insert; a stale BaseVersion yields an AttendanceConflictError.
+func (s *AttendanceService) MarkAttendance(ctx context.Context, classroomID uint, sessionDate string, teacherID uint, req *requests.RollCallMarkRequest) (*models.Attendance, error) {
+	ctx, span := tracing.Start(ctx, "AttendanceService.MarkAttendance")
+	defer span.End()
+
+	if !(&models.Attendance{Status: req.Status}).IsValidStatus() {
+		return nil, ErrInvalidAttendanceStatus
+	}
+
+	var schoolID *uint
+	if classroom, err := s.classrooms.WithContext(ctx).FindByID(classroomID); err == nil {
+		schoolID = classroom.SchoolID
+	}
+
+	var attendance models.Attendance
+	eventType := "attendance.updated"
+
+	err := s.attendances.WithContext(ctx).WithTx(func(tx repositories.AttendanceRepository) error {
+		if err := tx.LockSlot(classroomID, req.StudentID, sessionDate); err != nil {
+			return err
+		}
+
+		existing, err := tx.FindBySlot(classroomID, req.StudentID, sessionDate)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if req.BaseVersion != 0 {
+				return &AttendanceConflictError{}
+			}
+			attendance = models.Attendance{
+				ClassroomID: &classroomID,
+				TeacherID:   &teacherID,
+				StudentID:   &req.StudentID,
+				SessionDate: sessionDate,
+				Status:      req.Status,
+				CheckedAt:   s.clock.Now().Unix(),
+				Remark:      req.Remark,
+				Version:     1,
+			}
+			if err := tx.Create(&attendance); err != nil {
+				if errors.Is(err, gorm.ErrDuplicatedKey) {
+					// Created through the REST API in the meantime
+					return &AttendanceConflictError{}
+				}
+				return err
+			}
+			eventType = "attendance.created"
+		} else {
+			attendance = *existing
+			if attendance.Version != req.BaseVersion {
+				current := attendance
+				return &AttendanceConflictError{Current: &current}
+			}
+			attendance.TeacherID = &teacherID
+			attendance.Status = req.Status
+			attendance.Remark = req.Remark
+			attendance.CheckedAt = s.clock.Now().Unix()
+			attendance.Version = req.BaseVersion + 1
+
+			updated, err := tx.UpdateIfVersion(&attendance, req.BaseVersion)
+			if err != nil {
+				return err
+			}
+			if !updated {
+				return &AttendanceConflictError{}
+			}
+		}
+
+		return tx.Enqueue(outbox.Event{
+			Type:          eventType,
+			AggregateType: "attendance",
+			AggregateID:   attendance.ID,
+			TeacherID:     teacherID,
+			SchoolID:      schoolID,
+			Action:        models.LogActionAttendance,
+			Message:       i18n.Msg("log.attendance_recorded", "status", string(req.Status), "date", sessionDate),
+			Payload:       attendance,
+		})
+	})
+	if err != nil {
+		var conflict *AttendanceConflictError
+		if errors.As(err, &conflict) {
+			logger.LogWarning(ctx, "Attendance mark rejected - version conflict", logrus.Fields{
+				"classroom_id": fmt.Sprintf("%d", classroomID),
+				"student_id":   fmt.Sprintf("%d", req.StudentID),
+				"base_version": req.BaseVersion,
+			})
+			return nil, conflict
+		}
+		if constraintErr := attendanceConstraintError(err); constraintErr != nil {
+			return nil, constraintErr
+		}
+		logger.LogError(ctx, err, "Failed to mark attendance", logrus.Fields{
+			"classroom_id": fmt.Sprintf("%d", classroomID),
+			"student_id":   fmt.Sprintf("%d", req.StudentID),
+		})
+		return nil, errors.New("failed to mark attendance")
+	}
+
+	realtime.Attendance.Publish(eventType, classroomID, attendance)
+	metrics.AttendanceRecorded.WithLabelValues(string(attendance.Status), "rollcall").Inc()
+
+	return &attendance, nil
+}
+
+// attendanceConstraintError turns a database constraint violation into the error
+// reported as 409 Conflict, or returns nil for any other error
+func attendanceConstraintError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrAttendanceExists
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrAttendanceReference
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return ErrAttendanceConstraint
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"easy-attend-service/models"
+	"easy-attend-service/requests"
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/fieldset"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newAttendanceRequest(classroom *models.Classroom, teacher *models.Teacher, student models.Student) *requests.AttendanceCreateRequest {
+	return &requests.AttendanceCreateRequest{
+		ClassroomID: classroom.ID,
+		TeacherID:   teacher.ID,
+		StudentID:   student.ID,
+		SessionDate: "2025-06-02",
+		Status:      models.AttendanceStatusPresent,
+		CheckedAt:   1748853000,
+	}
+}
+
+func TestCreateAttendanceRejectsTakenSlot(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+
+	if _, err := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0])); err != nil {
+		t.Fatalf("first create: %v", err)
+	}
+	_, err := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+	if err == nil || err.Error() != "attendance for this student on this date already exists" {
+		t.Fatalf("second create error = %v", err)
+	}
+
+	if got := env.store.eventTypes(); !slices.Equal(got, []string{"attendance.created"}) {
+		t.Errorf("events = %v, want only the first create", got)
+	}
+}
+
+func TestCreateAttendanceRollsBackWhenOutboxFails(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	env.store.enqueueErr = errors.New("outbox unavailable")
+
+	_, err := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+	if err == nil || err.Error() != "failed to create attendance" {
+		t.Fatalf("error = %v", err)
+	}
+	if len(env.store.attendances) != 0 {
+		t.Errorf("attendance was kept without its outbox event")
+	}
+}
+
+func TestMarkAttendanceComparesVersions(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	mark := func(baseVersion uint, status models.AttendanceStatus) (*models.Attendance, error) {
+		return env.attendance.MarkAttendance(t.Context(), classroom.ID, "2025-06-02", teacher.ID, &requests.RollCallMarkRequest{
+			StudentID:   students[0].ID,
+			Status:      status,
+			BaseVersion: baseVersion,
+		})
+	}
+
+	created, err := mark(0, models.AttendanceStatusPresent)
+	if err != nil {
+		t.Fatalf("first mark: %v", err)
+	}
+	if created.Version != 1 || created.CheckedAt != env.clock.Now().Unix() {
+		t.Errorf("created = version %d checked_at %d", created.Version, created.CheckedAt)
+	}
+
+	// A second client that also saw no record loses and gets the current row back
+	_, err = mark(0, models.AttendanceStatusAbsent)
+	var conflict *AttendanceConflictError
+	if !errors.As(err, &conflict) || conflict.Current == nil || conflict.Current.Status != models.AttendanceStatusPresent {
+		t.Fatalf("stale mark error = %v", err)
+	}
+
+	env.clock.Advance(time.Minute)
+	updated, err := mark(1, models.AttendanceStatusLate)
+	if err != nil {
+		t.Fatalf("mark on version 1: %v", err)
+	}
+	if updated.Version != 2 || updated.Status != models.AttendanceStatusLate || updated.CheckedAt != env.clock.Now().Unix() {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if got := env.store.eventTypes(); !slices.Equal(got, []string{"attendance.created", "attendance.updated"}) {
+		t.Errorf("events = %v", got)
+	}
+}
+
+func TestMarkAttendanceWithVersionForMissingRow(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+
+	_, err := env.attendance.MarkAttendance(t.Context(), classroom.ID, "2025-06-02", teacher.ID, &requests.RollCallMarkRequest{
+		StudentID:   students[0].ID,
+		Status:      models.AttendanceStatusPresent,
+		BaseVersion: 3,
+	})
+	var conflict *AttendanceConflictError
+	if !errors.As(err, &conflict) || conflict.Current != nil {
+		t.Fatalf("error = %v, want a conflict without a current row", err)
+	}
+}
+
+func TestDeleteAndRestoreAttendance(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	attendance, _ := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+
+	if err := env.attendance.DeleteAttendance(t.Context(), attendance.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := env.attendance.GetAttendanceByID(t.Context(), attendance.ID, fieldset.Selection{}); err == nil || err.Error() != "attendance not found" {
+		t.Fatalf("get after delete error = %v", err)
+	}
+	if deletedAt := env.store.attendances[attendance.ID].DeletedAt; deletedAt != models.NewDeletedAt(env.clock.Now()) {
+		t.Errorf("deleted_at = %v, want the clock time", deletedAt)
+	}
+
+	restored, err := env.attendance.RestoreAttendance(t.Context(), attendance.ID)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	// Create, delete and restore each bump the version so sync clients see every step
+	if restored.Version != 3 || restored.DeletedAt.Valid {
+		t.Errorf("restored = version %d deleted %v", restored.Version, restored.DeletedAt.Valid)
+	}
+
+	if _, err := env.attendance.RestoreAttendance(t.Context(), attendance.ID); err == nil || err.Error() != "deleted attendance not found" {
+		t.Errorf("second restore error = %v", err)
+	}
+}
+
+func TestRestoreAttendanceChecksParentsAndSlot(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	attendance, _ := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+	env.attendance.DeleteAttendance(t.Context(), attendance.ID)
+
+	// The slot was marked again after the delete
+	if _, err := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0])); err != nil {
+		t.Fatalf("re-create: %v", err)
+	}
+	_, err := env.attendance.RestoreAttendance(t.Context(), attendance.ID)
+	if err == nil || err.Error() != "attendance for this student on this date already exists" {
+		t.Fatalf("restore over a taken slot error = %v", err)
+	}
+
+	env.clock.Advance(time.Hour)
+	if err := env.student.DeleteStudent(t.Context(), students[0].ID); err != nil {
+		t.Fatalf("delete student: %v", err)
+	}
+	_, err = env.attendance.RestoreAttendance(t.Context(), attendance.ID)
+	if err == nil || err.Error() != "restore the classroom and student of this attendance first" {
+		t.Fatalf("restore under a deleted student error = %v", err)
+	}
+}
+
+func TestClassroomAttendancesFilterAndSort(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(3)
+	for i, status := range []models.AttendanceStatus{models.AttendanceStatusLate, models.AttendanceStatusPresent, models.AttendanceStatusAbsent} {
+		req := newAttendanceRequest(classroom, teacher, students[i])
+		req.Status = status
+		if _, err := env.attendance.CreateAttendance(t.Context(), req); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	missing, meta, err := env.attendance.GetAttendancesByClassroom(t.Context(), classroom.ID,
+		list(t, AttendanceListing, "filter[status]=late,absent&sort=-student_id"), fieldset.Selection{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(missing) != 2 || *missing[0].StudentID != students[2].ID || *missing[1].StudentID != students[0].ID {
+		t.Errorf("attendances = %+v", missing)
+	}
+	if meta.HasMore || meta.NextCursor != nil {
+		t.Errorf("meta = %+v, want a single page", meta)
+	}
+}
+
+func TestAttendanceFieldsAndExpand(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	created, err := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	selection := fields(t, AttendanceFields, "fields=status,session_date")
+	attendance, err := env.attendance.GetAttendanceByID(t.Context(), created.ID, selection)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	row := selection.Render(attendance).(map[string]json.RawMessage)
+	if keys := slices.Sorted(maps.Keys(row)); !slices.Equal(keys, []string{"id", "session_date", "status"}) {
+		t.Errorf("rendered keys = %v, want id, session_date and status", keys)
+	}
+
+	selection = fields(t, AttendanceFields, "fields=status&expand=student")
+	attendances, _, err := env.attendance.GetAttendancesByClassroom(t.Context(), classroom.ID, list(t, AttendanceListing, ""), selection)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	rows := selection.Render(attendances).([]map[string]json.RawMessage)
+	if len(rows) != 1 || rows[0]["status"] == nil || rows[0]["classroom_id"] != nil {
+		t.Errorf("rendered rows = %v", rows)
+	}
+
+	for raw, code := range map[string]string{
+		"fields=status,password": "fields.invalid",
+		"expand=students":        "expand.invalid",
+		"expand=student.teacher": "expand.invalid",
+	} {
+		values, _ := url.ParseQuery(raw)
+		_, err := fieldset.Parse(values, AttendanceFields)
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) || appErr.Code != code {
+			t.Errorf("%s: error = %v, want %s", raw, err, code)
+		}
+	}
+}
+
+func TestUpdateAttendanceHonoursIfMatch(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	created, err := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	read := created.ETag()
+	req := &requests.AttendanceUpdateRequest{Status: ptr(models.AttendanceStatusLate), CheckedAt: ptr(int64(1748853600))}
+
+	updated, err := env.attendance.UpdateAttendance(etag.WithIfMatch(t.Context(), read), created.ID, req)
+	if err != nil {
+		t.Fatalf("update with the current ETag: %v", err)
+	}
+	if updated.ETag() == read {
+		t.Fatalf("ETag %s did not change with the update", read)
+	}
+
+	// A second client still holding the first version must not overwrite the update
+	req.Status = ptr(models.AttendanceStatusAbsent)
+	if _, err := env.attendance.UpdateAttendance(etag.WithIfMatch(t.Context(), read), created.ID, req); !errors.Is(err, etag.ErrPreconditionFailed) {
+		t.Fatalf("update with a stale ETag: error = %v, want %v", err, etag.ErrPreconditionFailed)
+	}
+	if _, err := env.attendance.UpdateAttendance(etag.WithIfMatch(t.Context(), "*"), created.ID, req); err != nil {
+		t.Fatalf("update with If-Match *: %v", err)
+	}
+}
@@ -1,12 +1,16 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
 	"easy-attend-service/utils"
+	"easy-attend-service/utils/i18n"
 	"easy-attend-service/utils/jwt"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
 	"time"
@@ -14,10 +18,20 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-type AuthService struct{}
+type AuthService struct {
+	teachers repositories.TeacherRepository
+	schools  repositories.SchoolRepository
+	genders  repositories.GenderRepository
+	prefixes repositories.PrefixRepository
+}
 
-func NewAuthService() *AuthService {
-	return &AuthService{}
+func NewAuthService(teachers repositories.TeacherRepository, schools repositories.SchoolRepository, genders repositories.GenderRepository, prefixes repositories.PrefixRepository) *AuthService {
+	return &AuthService{
+		teachers: teachers,
+		schools:  schools,
+		genders:  genders,
+		prefixes: prefixes,
+	}
 }
 
 type LoginResponse struct {
@@ -26,28 +40,32 @@ type LoginResponse struct {
 	ExpiresAt time.Time      `json:"expires_at"`
 }
 
-func (s *AuthService) Login(req *requests.LoginRequest) (*LoginResponse, error) {
-	logger.LogInfo("User login attempt", logrus.Fields{
+func (s *AuthService) Login(ctx context.Context, req *requests.LoginRequest) (*LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	logger.LogInfo(ctx, "User login attempt", logrus.Fields{
 		"email": req.Email,
 	})
 
-	var teacher models.Teacher
+	teachers := s.teachers.WithContext(ctx)
 
 	// Find teacher by email
-	if err := configs.DB.Where("email = ?", req.Email).First(&teacher).Error; err != nil {
-		logger.LogWarning("Login failed - user not found", logrus.Fields{
+	teacher, err := teachers.FindByEmail(req.Email)
+	if err != nil {
+		logger.LogWarning(ctx, "Login failed - user not found", logrus.Fields{
 			"email": req.Email,
 		})
-		return nil, errors.New("invalid email or password")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if !utils.CheckPasswordHash(req.Password, teacher.Password) {
-		logger.LogWarning("Login failed - invalid password", logrus.Fields{
+		logger.LogWarning(ctx, "Login failed - invalid password", logrus.Fields{
 			"email":   req.Email,
 			"user_id": fmt.Sprintf("%d", teacher.ID),
 		})
-		return nil, errors.New("invalid email or password")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Generate JWT token
@@ -55,48 +73,62 @@ func (s *AuthService) Login(req *requests.LoginRequest) (*LoginResponse, error)
 		UserID:   fmt.Sprintf("%d", teacher.ID),
 		Email:    teacher.Email,
 		UserType: "teacher",
+		Language: teacher.Language,
 	}
 
 	token, expiresAt, err := jwt.GenerateToken(claims)
 	if err != nil {
-		logger.LogError(err, "Failed to generate JWT token", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to generate JWT token", logrus.Fields{
 			"user_id": fmt.Sprintf("%d", teacher.ID),
 			"email":   teacher.Email,
 		})
 		return nil, errors.New("failed to generate token")
 	}
 
-	logger.LogInfo("User login successful", logrus.Fields{
+	logger.LogInfo(ctx, "User login successful", logrus.Fields{
 		"user_id": fmt.Sprintf("%d", teacher.ID),
 		"email":   teacher.Email,
 	})
 
 	// Log activity automatically
-	logger.LogActivity(teacher.ID, models.LogActionLogin, fmt.Sprintf("เข้าสู่ระบบด้วยอีเมล: %s", req.Email), teacher.SchoolID)
+	if err := teachers.Enqueue(outbox.Event{
+		Type:          "teacher.logged_in",
+		AggregateType: "teacher",
+		AggregateID:   teacher.ID,
+		TeacherID:     teacher.ID,
+		SchoolID:      teacher.SchoolID,
+		Action:        models.LogActionLogin,
+		Message:       i18n.Msg("log.login", "email", req.Email),
+	}); err != nil {
+		logger.LogError(ctx, err, "Failed to enqueue login event", logrus.Fields{
+			"user_id": fmt.Sprintf("%d", teacher.ID),
+		})
+	}
 
 	return &LoginResponse{
 		Token:     token,
-		Teacher:   teacher,
+		Teacher:   *teacher,
 		ExpiresAt: expiresAt,
 	}, nil
 }
 
-func (s *AuthService) Register(req *requests.AuthRequest) (*models.Teacher, error) {
+func (s *AuthService) Register(ctx context.Context, req *requests.AuthRequest) (*models.Teacher, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Register")
+	defer span.End()
+
 	// Check if teacher already exists
-	var existingTeacher models.Teacher
-	if err := configs.DB.Where("email = ?", req.Email).First(&existingTeacher).Error; err == nil {
-		return nil, errors.New("teacher with this email already exists")
+	if _, err := s.teachers.WithContext(ctx).FindByEmail(req.Email); err == nil {
+		return nil, ErrTeacherEmailTaken
 	}
 
 	// Find or create school
-	var school models.School
-	err := configs.DB.Where("name = ?", req.SchoolName).First(&school).Error
+	school, err := s.schools.WithContext(ctx).FindByName(req.SchoolName)
 	if err != nil {
 		// School doesn't exist, create new one
-		school = models.School{
+		school = &models.School{
 			Name: req.SchoolName,
 		}
-		if err := configs.DB.Create(&school).Error; err != nil {
+		if err := s.schools.WithContext(ctx).Create(school); err != nil {
 			return nil, errors.New("failed to create school")
 		}
 	}
@@ -104,14 +136,13 @@ func (s *AuthService) Register(req *requests.AuthRequest) (*models.Teacher, erro
 	// Find or create gender if provided
 	var genderID *uint
 	if req.GenderName != "" {
-		var gender models.Gender
-		err := configs.DB.Where("name = ?", req.GenderName).First(&gender).Error
+		gender, err := s.genders.WithContext(ctx).FindByName(req.GenderName)
 		if err != nil {
 			// Gender doesn't exist, create new one
-			gender = models.Gender{
+			gender = &models.Gender{
 				Name: req.GenderName,
 			}
-			if err := configs.DB.Create(&gender).Error; err != nil {
+			if err := s.genders.WithContext(ctx).Create(gender); err != nil {
 				return nil, errors.New("failed to create gender")
 			}
 		}
@@ -121,14 +152,13 @@ func (s *AuthService) Register(req *requests.AuthRequest) (*models.Teacher, erro
 	// Find or create prefix if provided
 	var prefixID *uint
 	if req.PrefixName != "" {
-		var prefix models.Prefix
-		err := configs.DB.Where("name = ?", req.PrefixName).First(&prefix).Error
+		prefix, err := s.prefixes.WithContext(ctx).FindByName(req.PrefixName)
 		if err != nil {
 			// Prefix doesn't exist, create new one
-			prefix = models.Prefix{
+			prefix = &models.Prefix{
 				Name: req.PrefixName,
 			}
-			if err := configs.DB.Create(&prefix).Error; err != nil {
+			if err := s.prefixes.WithContext(ctx).Create(prefix); err != nil {
 				return nil, errors.New("failed to create prefix")
 			}
 		}
@@ -149,24 +179,55 @@ func (s *AuthService) Register(req *requests.AuthRequest) (*models.Teacher, erro
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Phone:     req.Phone,
+		Language:  req.Language,
 		GenderID:  genderID,
 		PrefixID:  prefixID,
 	}
 
-	if err := configs.DB.Create(&teacher).Error; err != nil {
+	if err := s.teachers.WithContext(ctx).WithTx(func(tx repositories.TeacherRepository) error {
+		if err := tx.Create(&teacher); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "teacher.registered",
+			AggregateType: "teacher",
+			AggregateID:   teacher.ID,
+			TeacherID:     teacher.ID,
+			SchoolID:      teacher.SchoolID,
+			Action:        models.LogActionCreateTeacher,
+			Message:       i18n.Msg("log.teacher_registered", "first_name", req.FirstName, "last_name", req.LastName, "email", req.Email),
+			Payload:       teacher,
+		})
+	}); err != nil {
 		return nil, errors.New("failed to create teacher")
 	}
 
-	// Log activity automatically
-	logger.LogActivity(teacher.ID, models.LogActionCreateTeacher, fmt.Sprintf("ลงทะเบียนครูใหม่: %s %s (%s)", req.FirstName, req.LastName, req.Email), teacher.SchoolID)
-
 	return &teacher, nil
 }
 
-func (s *AuthService) GetProfile(userID uint) (*models.Teacher, error) {
-	var teacher models.Teacher
-	if err := configs.DB.Preload("School").Preload("Gender").Preload("Prefix").Where("id = ?", userID).First(&teacher).Error; err != nil {
-		return nil, errors.New("teacher not found")
+func (s *AuthService) GetProfile(ctx context.Context, userID uint) (*models.Teacher, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.GetProfile")
+	defer span.End()
+
+	teacher, err := s.teachers.WithContext(ctx).FindProfile(userID)
+	if err != nil {
+		return nil, ErrTeacherNotFound
 	}
-	return &teacher, nil
+	return teacher, nil
+}
+
+// Logout records the logout activity for a teacher
+func (s *AuthService) Logout(ctx context.Context, teacherID uint, schoolID *uint) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	return s.teachers.WithContext(ctx).Enqueue(outbox.Event{
+		Type:          "teacher.logged_out",
+		AggregateType: "teacher",
+		AggregateID:   teacherID,
+		TeacherID:     teacherID,
+		SchoolID:      schoolID,
+		Action:        models.LogActionLogout,
+		Message:       i18n.Msg("log.logout"),
+	})
 }
@@ -1,61 +1,112 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-type ClassroomService struct{}
+type ClassroomService struct {
+	classrooms repositories.ClassroomRepository
+	years      repositories.AcademicYearRepository
+	clock      clock.Clock
+}
 
-func NewClassroomService() *ClassroomService {
-	return &ClassroomService{}
+func NewClassroomService(classrooms repositories.ClassroomRepository, years repositories.AcademicYearRepository, clk clock.Clock) *ClassroomService {
+	return &ClassroomService{
+		classrooms: classrooms,
+		years:      years,
+		clock:      clk,
+	}
 }
 
-func (s *ClassroomService) GetClassroomByID(id uint) (*models.Classroom, error) {
-	logger.LogInfo("Fetching classroom by ID", logrus.Fields{
+// academicYearFor checks that an explicitly chosen year belongs to the school, or
+// picks the school's year that contains today. Schools without years get nil.
+func (s *ClassroomService) academicYearFor(ctx context.Context, schoolID uint, academicYearID *uint) (*uint, error) {
+	if academicYearID != nil {
+		year, err := s.years.WithContext(ctx).FindByID(*academicYearID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAcademicYearNotFound
+		}
+		if err != nil {
+			return nil, errors.New("failed to find academic year")
+		}
+		if year.SchoolID == nil || *year.SchoolID != schoolID {
+			return nil, ErrAcademicYearOtherSchool
+		}
+		return &year.ID, nil
+	}
+
+	year, err := s.years.WithContext(ctx).FindCurrent(schoolID, s.clock.Now().Format("2006-01-02"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("failed to find academic year")
+	}
+	return &year.ID, nil
+}
+
+func (s *ClassroomService) GetClassroomByID(ctx context.Context, id uint) (*models.Classroom, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomService.GetClassroomByID")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching classroom by ID", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", id),
 	})
 
-	var classroom models.Classroom
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&classroom).Error; err != nil {
+	classroom, err := s.classrooms.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Classroom not found", logrus.Fields{
+			logger.LogWarning(ctx, "Classroom not found", logrus.Fields{
 				"classroom_id": fmt.Sprintf("%d", id),
 			})
-			return nil, errors.New("classroom not found")
+			return nil, ErrClassroomNotFound
 		}
-		logger.LogError(err, "Failed to fetch classroom", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to fetch classroom", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", id),
 		})
 		return nil, errors.New("failed to fetch classroom")
 	}
 
-	return &classroom, nil
+	return classroom, nil
 }
 
-func (s *ClassroomService) CreateClassroom(req *requests.ClassroomCreateRequest) (*models.Classroom, error) {
-	logger.LogInfo("Creating new classroom", logrus.Fields{
+func (s *ClassroomService) CreateClassroom(ctx context.Context, req *requests.ClassroomCreateRequest) (*models.Classroom, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomService.CreateClassroom")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Creating new classroom", logrus.Fields{
 		"name":       req.Name,
 		"school_id":  fmt.Sprintf("%d", req.SchoolID),
 		"teacher_id": fmt.Sprintf("%d", req.TeacherID),
 	})
 
-	// Check if classroom name already exists in the same school
-	var existingClassroom models.Classroom
-	if err := configs.DB.Where("name = ? AND school_id = ? AND deleted_at IS NULL", req.Name, req.SchoolID).First(&existingClassroom).Error; err == nil {
-		logger.LogWarning("Classroom creation failed - name already exists in school", logrus.Fields{
+	academicYearID, err := s.academicYearFor(ctx, req.SchoolID, req.AcademicYearID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if classroom name already exists in the same school year
+	if taken, err := s.classrooms.WithContext(ctx).NameTaken(req.SchoolID, academicYearID, req.Name, 0); err == nil && taken {
+		logger.LogWarning(ctx, "Classroom creation failed - name already exists in school", logrus.Fields{
 			"name":      req.Name,
 			"school_id": fmt.Sprintf("%d", req.SchoolID),
 		})
-		return nil, errors.New("classroom with this name already exists in this school")
+		return nil, ErrClassroomNameTaken
 	}
 
 	// Create new classroom
@@ -64,133 +115,292 @@ func (s *ClassroomService) CreateClassroom(req *requests.ClassroomCreateRequest)
 		TeacherID: &req.TeacherID,
 		Name:      req.Name,
 		Grade:     req.Grade,
-		CreatedAt: time.Now().Unix(),
-		UpdatedAt: time.Now().Unix(),
+		Version:   1,
+		CreatedAt: s.clock.Now().Unix(),
+		UpdatedAt: s.clock.Now().Unix(),
+
+		AcademicYearID: academicYearID,
 	}
 
-	if err := configs.DB.Create(&classroom).Error; err != nil {
-		logger.LogError(err, "Failed to create classroom", logrus.Fields{
+	if err := s.classrooms.WithContext(ctx).WithTx(func(tx repositories.ClassroomRepository) error {
+		if err := tx.Create(&classroom); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "classroom.created",
+			AggregateType: "classroom",
+			AggregateID:   classroom.ID,
+			TeacherID:     req.TeacherID,
+			SchoolID:      &req.SchoolID,
+			Action:        models.LogActionCreateClassroom,
+			Message:       i18n.Msg("log.classroom_created", "name", req.Name),
+			Payload:       classroom,
+		})
+	}); err != nil {
+		logger.LogError(ctx, err, "Failed to create classroom", logrus.Fields{
 			"name": req.Name,
 		})
 		return nil, errors.New("failed to create classroom")
 	}
 
-	logger.LogInfo("Classroom created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Classroom created successfully", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", classroom.ID),
 		"name":         classroom.Name,
 	})
 
-	// Log activity automatically
-	logger.LogActivity(req.TeacherID, models.LogActionCreateClassroom, fmt.Sprintf("สร้างห้องเรียนใหม่: %s", req.Name), &req.SchoolID)
-
 	return &classroom, nil
 }
 
-func (s *ClassroomService) UpdateClassroom(id uint, req *requests.ClassroomUpdateRequest) (*models.Classroom, error) {
-	logger.LogInfo("Updating classroom", logrus.Fields{
+func (s *ClassroomService) UpdateClassroom(ctx context.Context, id uint, req *requests.ClassroomUpdateRequest) (*models.Classroom, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomService.UpdateClassroom")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Updating classroom", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", id),
-		"name":         req.Name,
 	})
 
-	var classroom models.Classroom
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&classroom).Error; err != nil {
+	classroom, err := s.classrooms.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Classroom not found for update", logrus.Fields{
+			logger.LogWarning(ctx, "Classroom not found for update", logrus.Fields{
 				"classroom_id": fmt.Sprintf("%d", id),
 			})
-			return nil, errors.New("classroom not found")
+			return nil, ErrClassroomNotFound
 		}
-		logger.LogError(err, "Failed to find classroom for update", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find classroom for update", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", id),
 		})
 		return nil, errors.New("failed to find classroom")
 	}
 
-	// Check if name is being changed and if it already exists in the same school
-	if req.Name != classroom.Name {
-		var existingClassroom models.Classroom
-		if err := configs.DB.Where("name = ? AND school_id = ? AND id != ? AND deleted_at IS NULL", req.Name, req.SchoolID, id).First(&existingClassroom).Error; err == nil {
-			logger.LogWarning("Classroom update failed - name already exists in school", logrus.Fields{
+	if err := etag.CheckIfMatch(ctx, classroom); err != nil {
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != classroom.Version {
+		return nil, ErrClassroomVersionConflict.WithCurrent(classroom)
+	}
+
+	// Only the fields that were sent change
+	baseVersion := classroom.Version
+	moved := req.SchoolID != nil && *req.SchoolID != *classroom.SchoolID
+	renamed := req.Name != nil && *req.Name != classroom.Name
+	regrouped := req.AcademicYearID != nil &&
+		(classroom.AcademicYearID == nil || *classroom.AcademicYearID != *req.AcademicYearID)
+	if req.SchoolID != nil {
+		classroom.SchoolID = req.SchoolID
+	}
+	if req.TeacherID != nil {
+		classroom.TeacherID = req.TeacherID
+	}
+	if req.Name != nil {
+		classroom.Name = *req.Name
+	}
+	if req.Grade != nil {
+		classroom.Grade = *req.Grade
+	}
+	if moved || regrouped {
+		// A classroom moved to another school joins that school's current year unless one is given
+		if classroom.AcademicYearID, err = s.academicYearFor(ctx, *classroom.SchoolID, req.AcademicYearID); err != nil {
+			return nil, err
+		}
+	}
+	classroom.Version++
+	classroom.UpdatedAt = s.clock.Now().Unix()
+
+	// Check if the name is already used in the school year the classroom ends up in
+	if moved || renamed || regrouped {
+		if taken, err := s.classrooms.WithContext(ctx).NameTaken(*classroom.SchoolID, classroom.AcademicYearID, classroom.Name, id); err == nil && taken {
+			logger.LogWarning(ctx, "Classroom update failed - name already exists in school", logrus.Fields{
 				"classroom_id": fmt.Sprintf("%d", id),
-				"name":         req.Name,
-				"school_id":    fmt.Sprintf("%d", req.SchoolID),
+				"name":         classroom.Name,
+				"school_id":    fmt.Sprintf("%d", *classroom.SchoolID),
 			})
-			return nil, errors.New("classroom with this name already exists in this school")
+			return nil, ErrClassroomNameTaken
 		}
 	}
 
-	// Update fields
-	classroom.SchoolID = &req.SchoolID
-	classroom.TeacherID = &req.TeacherID
-	classroom.Name = req.Name
-	classroom.Grade = req.Grade
-	classroom.UpdatedAt = time.Now().Unix()
-
-	if err := configs.DB.Save(&classroom).Error; err != nil {
-		logger.LogError(err, "Failed to update classroom", logrus.Fields{
+	if err := s.classrooms.WithContext(ctx).WithTx(func(tx repositories.ClassroomRepository) error {
+		updated, err := tx.UpdateIfVersion(classroom, baseVersion)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// Another teacher saved the classroom after it was read
+			current, err := tx.FindByID(id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrClassroomNotFound
+			}
+			if err != nil {
+				return err
+			}
+			return ErrClassroomVersionConflict.WithCurrent(current)
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "classroom.updated",
+			AggregateType: "classroom",
+			AggregateID:   classroom.ID,
+			TeacherID:     *classroom.TeacherID,
+			SchoolID:      classroom.SchoolID,
+			Action:        models.LogActionUpdateClassroom,
+			Message:       i18n.Msg("log.classroom_updated", "name", classroom.Name),
+			Payload:       classroom,
+		})
+	}); err != nil {
+		if errors.Is(err, ErrClassroomVersionConflict) || errors.Is(err, ErrClassroomNotFound) {
+			logger.LogWarning(ctx, "Classroom update rejected - version conflict", logrus.Fields{
+				"classroom_id": fmt.Sprintf("%d", id),
+				"base_version": baseVersion,
+			})
+			return nil, err
+		}
+		logger.LogError(ctx, err, "Failed to update classroom", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", id),
 		})
 		return nil, errors.New("failed to update classroom")
 	}
 
-	logger.LogInfo("Classroom updated successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Classroom updated successfully", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", classroom.ID),
 		"name":         classroom.Name,
 	})
 
-	// Log activity automatically
-	logger.LogActivity(req.TeacherID, models.LogActionUpdateClassroom, fmt.Sprintf("อัพเดทห้องเรียน: %s", req.Name), &req.SchoolID)
-
-	return &classroom, nil
+	return classroom, nil
 }
 
-func (s *ClassroomService) DeleteClassroom(id uint) error {
-	logger.LogInfo("Deleting classroom", logrus.Fields{
+func (s *ClassroomService) DeleteClassroom(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "ClassroomService.DeleteClassroom")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Deleting classroom", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", id),
 	})
 
-	var classroom models.Classroom
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&classroom).Error; err != nil {
+	classroom, err := s.classrooms.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Classroom not found for deletion", logrus.Fields{
+			logger.LogWarning(ctx, "Classroom not found for deletion", logrus.Fields{
 				"classroom_id": fmt.Sprintf("%d", id),
 			})
-			return errors.New("classroom not found")
+			return ErrClassroomNotFound
 		}
-		logger.LogError(err, "Failed to find classroom for deletion", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find classroom for deletion", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", id),
 		})
 		return errors.New("failed to find classroom")
 	}
 
-	// Soft delete
-	deleteTime := time.Now().Unix()
-	classroom.DeletedAt = &deleteTime
+	// Soft delete; students and attendances share the timestamp so a restore brings back exactly this delete
+	deletedAt := models.NewDeletedAt(s.clock.Now())
 
-	if err := configs.DB.Save(&classroom).Error; err != nil {
-		logger.LogError(err, "Failed to delete classroom", logrus.Fields{
+	if err := s.classrooms.WithContext(ctx).WithTx(func(tx repositories.ClassroomRepository) error {
+		if err := tx.SoftDelete(classroom, deletedAt); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "classroom.deleted",
+			AggregateType: "classroom",
+			AggregateID:   classroom.ID,
+			TeacherID:     *classroom.TeacherID,
+			SchoolID:      classroom.SchoolID,
+			Action:        models.LogActionDeleteClassroom,
+			Message:       i18n.Msg("log.classroom_deleted", "name", classroom.Name),
+			Payload:       classroom,
+		})
+	}); err != nil {
+		logger.LogError(ctx, err, "Failed to delete classroom", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", id),
 		})
 		return errors.New("failed to delete classroom")
 	}
 
-	logger.LogInfo("Classroom deleted successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Classroom deleted successfully", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", id),
 	})
 
-	// Log activity automatically
-	logger.LogActivity(*classroom.TeacherID, models.LogActionDeleteClassroom, fmt.Sprintf("ลบห้องเรียน: %s", classroom.Name), classroom.SchoolID)
-
 	return nil
 }
 
-// GetClassroomsByTeacher gets all classrooms for a specific teacher
-func (s *ClassroomService) GetClassroomsByTeacher(teacherID uint) ([]models.Classroom, error) {
-	var classrooms []models.Classroom
+// RestoreClassroom brings a classroom back from the trash together with the students
+// and attendances that were deleted with it
+func (s *ClassroomService) RestoreClassroom(ctx context.Context, id uint) (*models.Classroom, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomService.RestoreClassroom")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Restoring classroom", logrus.Fields{
+		"classroom_id": fmt.Sprintf("%d", id),
+	})
 
-	if err := configs.DB.Where("teacher_id = ?", teacherID).Find(&classrooms).Error; err != nil {
-		return nil, errors.New("failed to get classrooms by teacher")
+	classroom, err := s.classrooms.WithContext(ctx).FindDeletedByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeletedClassroomNotFound
+		}
+		return nil, errors.New("failed to find classroom")
+	}
+
+	if classroom.SchoolID != nil {
+		if taken, err := s.classrooms.WithContext(ctx).NameTaken(*classroom.SchoolID, classroom.AcademicYearID, classroom.Name, classroom.ID); err == nil && taken {
+			return nil, ErrClassroomNameTaken
+		}
+	}
+
+	if err := s.classrooms.WithContext(ctx).WithTx(func(tx repositories.ClassroomRepository) error {
+		if err := tx.Restore(classroom); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "classroom.restored",
+			AggregateType: "classroom",
+			AggregateID:   classroom.ID,
+			TeacherID:     *classroom.TeacherID,
+			SchoolID:      classroom.SchoolID,
+			Action:        models.LogActionRestoreClassroom,
+			Message:       i18n.Msg("log.classroom_restored", "name", classroom.Name),
+			Payload:       classroom,
+		})
+	}); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrClassroomStudentNoTaken
+		}
+		logger.LogError(ctx, err, "Failed to restore classroom", logrus.Fields{
+			"classroom_id": fmt.Sprintf("%d", id),
+		})
+		return nil, errors.New("failed to restore classroom")
+	}
+
+	logger.LogInfo(ctx, "Classroom restored successfully", logrus.Fields{
+		"classroom_id": fmt.Sprintf("%d", id),
+	})
+
+	return classroom, nil
+}
+
+// GetClassroomsByTeacher gets one page of the classrooms of a specific teacher
+func (s *ClassroomService) GetClassroomsByTeacher(ctx context.Context, teacherID uint, query listquery.Query) ([]models.Classroom, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomService.GetClassroomsByTeacher")
+	defer span.End()
+
+	classrooms, err := s.classrooms.WithContext(ctx).ListByTeacher(teacherID, query)
+	if err != nil {
+		return nil, listquery.Pagination{}, errors.New("failed to get classrooms by teacher")
+	}
+
+	classrooms, meta := listquery.Page(query, classrooms)
+	return classrooms, meta, nil
+}
+
+// GetAccessibleClassroomIDs returns the classrooms a teacher owns or has joined as a member
+func (s *ClassroomService) GetAccessibleClassroomIDs(ctx context.Context, teacherID uint) ([]uint, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomService.GetAccessibleClassroomIDs")
+	defer span.End()
+
+	ids, err := s.classrooms.WithContext(ctx).AccessibleIDs(teacherID)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch accessible classrooms", logrus.Fields{
+			"teacher_id": fmt.Sprintf("%d", teacherID),
+		})
+		return nil, errors.New("failed to fetch accessible classrooms")
 	}
 
-	return classrooms, nil
+	return ids, nil
 }
@@ -1,10 +1,13 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
+	"easy-attend-service/utils/listquery"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
 
@@ -12,70 +15,82 @@ import (
 	"gorm.io/gorm"
 )
 
-type ClassroomMemberService struct{}
+type ClassroomMemberService struct {
+	members repositories.ClassroomMemberRepository
+}
 
-func NewClassroomMemberService() *ClassroomMemberService {
-	return &ClassroomMemberService{}
+func NewClassroomMemberService(members repositories.ClassroomMemberRepository) *ClassroomMemberService {
+	return &ClassroomMemberService{members: members}
 }
 
-func (s *ClassroomMemberService) GetAllClassroomMembers() ([]models.ClassroomMember, error) {
-	logger.LogInfo("Fetching all classroom members", logrus.Fields{})
+func (s *ClassroomMemberService) GetAllClassroomMembers(ctx context.Context, query listquery.Query) ([]models.ClassroomMember, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomMemberService.GetAllClassroomMembers")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching all classroom members", logrus.Fields{})
 
-	var members []models.ClassroomMember
-	if err := configs.DB.Find(&members).Error; err != nil {
-		logger.LogError(err, "Failed to fetch classroom members", logrus.Fields{})
-		return nil, errors.New("failed to fetch classroom members")
+	members, err := s.members.WithContext(ctx).List(query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch classroom members", logrus.Fields{})
+		return nil, listquery.Pagination{}, errors.New("failed to fetch classroom members")
 	}
 
-	logger.LogInfo("Successfully fetched classroom members", logrus.Fields{
+	members, meta := listquery.Page(query, members)
+	logger.LogInfo(ctx, "Successfully fetched classroom members", logrus.Fields{
 		"count": len(members),
 	})
 
-	return members, nil
+	return members, meta, nil
 }
 
-func (s *ClassroomMemberService) GetClassroomMembersByClassroomID(classroomID uint) ([]models.ClassroomMember, error) {
-	logger.LogInfo("Fetching classroom members by classroom ID", logrus.Fields{
+func (s *ClassroomMemberService) GetClassroomMembersByClassroomID(ctx context.Context, classroomID uint, query listquery.Query) ([]models.ClassroomMember, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomMemberService.GetClassroomMembersByClassroomID")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching classroom members by classroom ID", logrus.Fields{
 		"classroom_id": classroomID,
 	})
 
-	var members []models.ClassroomMember
-	if err := configs.DB.Where("classroom_id = ?", classroomID).Find(&members).Error; err != nil {
-		logger.LogError(err, "Failed to fetch classroom members", logrus.Fields{
+	members, err := s.members.WithContext(ctx).ListByClassroom(classroomID, query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch classroom members", logrus.Fields{
 			"classroom_id": classroomID,
 		})
-		return nil, errors.New("failed to fetch classroom members")
+		return nil, listquery.Pagination{}, errors.New("failed to fetch classroom members")
 	}
 
-	return members, nil
+	members, meta := listquery.Page(query, members)
+	return members, meta, nil
 }
 
-func (s *ClassroomMemberService) CreateClassroomMember(req *requests.ClassroomMemberCreateRequest) (*models.ClassroomMember, error) {
-	logger.LogInfo("Creating new classroom member", logrus.Fields{
+func (s *ClassroomMemberService) CreateClassroomMember(ctx context.Context, req *requests.ClassroomMemberCreateRequest) (*models.ClassroomMember, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomMemberService.CreateClassroomMember")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Creating new classroom member", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
 	})
 
 	// Validate that either teacher_id or student_id is provided, but not both
 	if (req.TeacherID == nil && req.StudentID == nil) || (req.TeacherID != nil && req.StudentID != nil) {
-		return nil, errors.New("either teacher_id or student_id must be provided, but not both")
+		return nil, ErrClassroomMemberAmbiguous
 	}
 
-	// Check if member already exists in classroom
-	var existingMember models.ClassroomMember
-	query := configs.DB.Where("classroom_id = ?", req.ClassroomID)
+	members := s.members.WithContext(ctx)
 
-	if req.TeacherID != nil {
-		query = query.Where("teacher_id = ?", *req.TeacherID)
-	}
-	if req.StudentID != nil {
-		query = query.Where("student_id = ?", *req.StudentID)
+	// Check if member already exists in classroom
+	exists, err := members.Exists(req.ClassroomID, req.TeacherID, req.StudentID)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to check classroom member", logrus.Fields{
+			"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
+		})
+		return nil, errors.New("failed to create classroom member")
 	}
-
-	if err := query.First(&existingMember).Error; err == nil {
-		logger.LogWarning("Classroom member already exists", logrus.Fields{
+	if exists {
+		logger.LogWarning(ctx, "Classroom member already exists", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
 		})
-		return nil, errors.New("member already exists in this classroom")
+		return nil, ErrClassroomMemberExists
 	}
 
 	// Create new classroom member
@@ -85,40 +100,42 @@ func (s *ClassroomMemberService) CreateClassroomMember(req *requests.ClassroomMe
 		StudentID:   req.StudentID,
 	}
 
-	if err := configs.DB.Create(&member).Error; err != nil {
-		logger.LogError(err, "Failed to create classroom member", logrus.Fields{
+	if err := members.Create(&member); err != nil {
+		logger.LogError(ctx, err, "Failed to create classroom member", logrus.Fields{
 			"classroom_id": fmt.Sprintf("%d", req.ClassroomID),
 		})
 		return nil, errors.New("failed to create classroom member")
 	}
 
-	logger.LogInfo("Classroom member created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Classroom member created successfully", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", member.ClassroomID),
 	})
 
 	return &member, nil
 }
 
-func (s *ClassroomMemberService) UpdateClassroomMember(classroomID uint, memberID uint, req *requests.ClassroomMemberUpdateRequest) (*models.ClassroomMember, error) {
-	logger.LogInfo("Updating classroom member", logrus.Fields{
+func (s *ClassroomMemberService) UpdateClassroomMember(ctx context.Context, classroomID uint, memberID uint, req *requests.ClassroomMemberUpdateRequest) (*models.ClassroomMember, error) {
+	ctx, span := tracing.Start(ctx, "ClassroomMemberService.UpdateClassroomMember")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Updating classroom member", logrus.Fields{
 		"classroom_id": classroomID,
 		"member_id":    memberID,
 	})
 
-	// Find existing member
-	var member models.ClassroomMember
-	query := configs.DB.Where("classroom_id = ?", classroomID)
+	members := s.members.WithContext(ctx)
 
 	// Find member by teacher_id or student_id
-	if err := query.Where("teacher_id = ? OR student_id = ?", memberID, memberID).First(&member).Error; err != nil {
+	member, err := members.FindByMember(classroomID, memberID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Classroom member not found for update", logrus.Fields{
+			logger.LogWarning(ctx, "Classroom member not found for update", logrus.Fields{
 				"classroom_id": classroomID,
 				"member_id":    memberID,
 			})
-			return nil, errors.New("classroom member not found")
+			return nil, ErrClassroomMemberNotFound
 		}
-		logger.LogError(err, "Failed to find classroom member for update", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find classroom member for update", logrus.Fields{
 			"classroom_id": classroomID,
 			"member_id":    memberID,
 		})
@@ -129,47 +146,49 @@ func (s *ClassroomMemberService) UpdateClassroomMember(classroomID uint, memberI
 	member.TeacherID = req.TeacherID
 	member.StudentID = req.StudentID
 
-	if err := configs.DB.Save(&member).Error; err != nil {
-		logger.LogError(err, "Failed to update classroom member", logrus.Fields{
+	if err := members.UpdateByMember(classroomID, memberID, member); err != nil {
+		logger.LogError(ctx, err, "Failed to update classroom member", logrus.Fields{
 			"classroom_id": classroomID,
 			"member_id":    memberID,
 		})
 		return nil, errors.New("failed to update classroom member")
 	}
 
-	logger.LogInfo("Classroom member updated successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Classroom member updated successfully", logrus.Fields{
 		"classroom_id": fmt.Sprintf("%d", member.ClassroomID),
 	})
 
-	return &member, nil
+	return member, nil
 }
 
-func (s *ClassroomMemberService) DeleteClassroomMember(classroomID uint, memberID uint) error {
-	logger.LogInfo("Deleting classroom member", logrus.Fields{
+func (s *ClassroomMemberService) DeleteClassroomMember(ctx context.Context, classroomID uint, memberID uint) error {
+	ctx, span := tracing.Start(ctx, "ClassroomMemberService.DeleteClassroomMember")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Deleting classroom member", logrus.Fields{
 		"classroom_id": classroomID,
 		"member_id":    memberID,
 	})
 
 	// Delete the member
-	result := configs.DB.Where("classroom_id = ? AND (teacher_id = ? OR student_id = ?)", classroomID, memberID, memberID).Delete(&models.ClassroomMember{})
-
-	if result.Error != nil {
-		logger.LogError(result.Error, "Failed to delete classroom member", logrus.Fields{
+	deleted, err := s.members.WithContext(ctx).DeleteByMember(classroomID, memberID)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to delete classroom member", logrus.Fields{
 			"classroom_id": classroomID,
 			"member_id":    memberID,
 		})
 		return errors.New("failed to delete classroom member")
 	}
 
-	if result.RowsAffected == 0 {
-		logger.LogWarning("Classroom member not found for deletion", logrus.Fields{
+	if deleted == 0 {
+		logger.LogWarning(ctx, "Classroom member not found for deletion", logrus.Fields{
 			"classroom_id": classroomID,
 			"member_id":    memberID,
 		})
-		return errors.New("classroom member not found")
+		return ErrClassroomMemberNotFound
 	}
 
-	logger.LogInfo("Classroom member deleted successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Classroom member deleted successfully", logrus.Fields{
 		"classroom_id": classroomID,
 		"member_id":    memberID,
 	})
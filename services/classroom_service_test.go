@@ -0,0 +1,108 @@
+package services
+
+import (
+	"easy-attend-service/models"
+	"easy-attend-service/requests"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCreateClassroomRejectsNameTakenInSchool(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, classroom, _ := env.seed(0)
+
+	_, err := env.classroom.CreateClassroom(t.Context(), &requests.ClassroomCreateRequest{
+		SchoolID: school.ID, TeacherID: teacher.ID, Name: classroom.Name, Grade: "ม.1",
+	})
+	if err == nil || err.Error() != "classroom with this name already exists in this school" {
+		t.Fatalf("error = %v", err)
+	}
+
+	other := &models.School{Name: "โรงเรียนอื่น"}
+	memSchoolRepo{env.store}.Create(other)
+	created, err := env.classroom.CreateClassroom(t.Context(), &requests.ClassroomCreateRequest{
+		SchoolID: other.ID, TeacherID: teacher.ID, Name: classroom.Name, Grade: "ม.1",
+	})
+	if err != nil {
+		t.Fatalf("same name in another school: %v", err)
+	}
+	if created.CreatedAt != env.clock.Now().Unix() {
+		t.Errorf("created_at = %d, want the clock time", created.CreatedAt)
+	}
+}
+
+func TestUpdateClassroomKeepsItsOwnName(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, classroom, _ := env.seed(0)
+	env.clock.Advance(time.Hour)
+
+	updated, err := env.classroom.UpdateClassroom(t.Context(), classroom.ID, &requests.ClassroomUpdateRequest{
+		SchoolID: &school.ID, TeacherID: &teacher.ID, Name: &classroom.Name, Grade: ptr("ม.2"),
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Grade != "ม.2" || updated.UpdatedAt != env.clock.Now().Unix() {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if _, err := env.classroom.UpdateClassroom(t.Context(), 999, &requests.ClassroomUpdateRequest{}); err == nil || err.Error() != "classroom not found" {
+		t.Errorf("unknown classroom error = %v", err)
+	}
+}
+
+func TestDeleteClassroomCascadesAndRestores(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(2)
+	attendance, _ := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+
+	// A student deleted earlier must stay in the trash after the classroom comes back
+	env.student.DeleteStudent(t.Context(), students[1].ID)
+	env.clock.Advance(time.Hour)
+
+	if err := env.classroom.DeleteClassroom(t.Context(), classroom.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if ids, _ := env.classroom.GetAccessibleClassroomIDs(t.Context(), teacher.ID); len(ids) != 0 {
+		t.Errorf("deleted classroom is still accessible: %v", ids)
+	}
+	if !env.store.students[students[0].ID].DeletedAt.Valid || !env.store.attendances[attendance.ID].DeletedAt.Valid {
+		t.Fatalf("delete did not cascade")
+	}
+
+	if _, err := env.classroom.RestoreClassroom(t.Context(), classroom.ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if env.store.students[students[0].ID].DeletedAt.Valid || env.store.attendances[attendance.ID].DeletedAt.Valid {
+		t.Errorf("restore did not bring the cascaded rows back")
+	}
+	if !env.store.students[students[1].ID].DeletedAt.Valid {
+		t.Errorf("student deleted earlier was restored with the classroom")
+	}
+
+	want := []string{"attendance.created", "student.deleted", "classroom.deleted", "classroom.restored"}
+	if got := env.store.eventTypes(); !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestRestoreClassroomRejectsReusedName(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, classroom, _ := env.seed(0)
+	env.classroom.DeleteClassroom(t.Context(), classroom.ID)
+
+	if _, err := env.classroom.CreateClassroom(t.Context(), &requests.ClassroomCreateRequest{
+		SchoolID: school.ID, TeacherID: teacher.ID, Name: classroom.Name, Grade: "ม.1",
+	}); err != nil {
+		t.Fatalf("reuse name: %v", err)
+	}
+	_, err := env.classroom.RestoreClassroom(t.Context(), classroom.ID)
+	if err == nil || err.Error() != "classroom with this name already exists in this school" {
+		t.Fatalf("error = %v", err)
+	}
+
+	if _, err := env.classroom.RestoreClassroom(t.Context(), 999); err == nil || err.Error() != "deleted classroom not found" {
+		t.Errorf("unknown classroom error = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import "easy-attend-service/utils/apperror"
+
+// Domain errors returned by the services. The codes are part of the API: clients
+// localize on them, so rename a message freely but never a code.
+var (
+	ErrInvalidCredentials = apperror.Unauthorized("auth.invalid_credentials", "invalid email or password")
+
+	ErrTeacherNotFound        = apperror.NotFound("teacher.not_found", "teacher not found")
+	ErrDeletedTeacherNotFound = apperror.NotFound("teacher.not_in_trash", "deleted teacher not found")
+	ErrTeacherEmailTaken      = apperror.Conflict("teacher.email_taken", "teacher with this email already exists")
+	ErrTeacherVersionConflict = apperror.Conflict("teacher.version_conflict", "teacher was changed by someone else")
+
+	ErrSchoolNotFound        = apperror.NotFound("school.not_found", "school not found")
+	ErrTeacherSchoolNotFound = apperror.NotFound("school.not_found", "school not found for this teacher")
+	ErrSchoolNameTaken       = apperror.Conflict("school.name_taken", "school with this name already exists")
+	ErrSchoolVersionConflict = apperror.Conflict("school.version_conflict", "school was changed by someone else")
+
+	ErrClassroomNotFound        = apperror.NotFound("classroom.not_found", "classroom not found")
+	ErrDeletedClassroomNotFound = apperror.NotFound("classroom.not_in_trash", "deleted classroom not found")
+	ErrClassroomNameTaken       = apperror.Conflict("classroom.name_taken", "classroom with this name already exists in this school")
+	ErrClassroomVersionConflict = apperror.Conflict("classroom.version_conflict", "classroom was changed by another teacher")
+
+	ErrStudentNotFound         = apperror.NotFound("student.not_found", "student not found")
+	ErrDeletedStudentNotFound  = apperror.NotFound("student.not_in_trash", "deleted student not found")
+	ErrStudentNumberTaken      = apperror.Conflict("student.number_taken", "student with this student number already exists")
+	ErrClassroomStudentNoTaken = apperror.Conflict("student.number_taken_in_classroom", "student with this student number already exists in this classroom")
+	ErrStudentClassroomInTrash = apperror.Conflict("student.classroom_in_trash", "restore the classroom of this student first")
+	ErrStudentVersionConflict  = apperror.Conflict("student.version_conflict", "student was changed by another teacher")
+
+	ErrAcademicYearNotFound    = apperror.NotFound("academic_year.not_found", "academic year not found")
+	ErrAcademicYearNameTaken   = apperror.Conflict("academic_year.name_taken", "academic year with this name already exists in this school")
+	ErrAcademicYearOverlaps    = apperror.Conflict("academic_year.overlaps", "academic year overlaps another year of this school")
+	ErrAcademicYearOtherSchool = apperror.Validation("academic_year.other_school", "academic year belongs to another school")
+	ErrAcademicYearForbidden   = apperror.Forbidden("academic_year.forbidden", "you can only manage the academic years of your own school")
+	ErrInvalidDateRange        = apperror.Validation("request.invalid_date_range", "start_date must not be after end_date")
+
+	ErrTermOutsideYear  = apperror.Validation("term.outside_year", "term dates must fall within the academic year")
+	ErrTermNumberTaken  = apperror.Conflict("term.number_taken", "academic year already has a term with this number")
+	ErrTermOverlaps     = apperror.Conflict("term.overlaps", "term overlaps another term of this school")
+	ErrRolloverBackward = apperror.Validation("rollover.not_forward", "the target year must start after the source year ends")
+	ErrRolloverTarget   = apperror.Conflict("rollover.target_not_empty", "the target year already has classrooms")
+	ErrRolloverStudent  = apperror.Validation("rollover.unknown_student", "student is not in a classroom of the source year")
+
+	ErrClassroomMemberNotFound  = apperror.NotFound("classroom_member.not_found", "classroom member not found")
+	ErrClassroomMemberExists    = apperror.Conflict("classroom_member.exists", "member already exists in this classroom")
+	ErrClassroomMemberAmbiguous = apperror.Validation("classroom_member.teacher_or_student", "either teacher_id or student_id must be provided, but not both")
+
+	ErrPrefixNotFound        = apperror.NotFound("prefix.not_found", "prefix not found")
+	ErrPrefixNameTaken       = apperror.Conflict("prefix.name_taken", "prefix with this name already exists")
+	ErrPrefixVersionConflict = apperror.Conflict("prefix.version_conflict", "prefix was changed by someone else")
+
+	ErrGenderNotFound        = apperror.NotFound("gender.not_found", "gender not found")
+	ErrGenderNameTaken       = apperror.Conflict("gender.name_taken", "gender with this name already exists")
+	ErrGenderVersionConflict = apperror.Conflict("gender.version_conflict", "gender was changed by someone else")
+
+	ErrLogNotFound = apperror.NotFound("log.not_found", "log not found")
+
+	ErrInvalidSyncCursor = apperror.Validation("sync.invalid_cursor", "invalid sync cursor")
+
+	ErrAttendanceNotFound        = apperror.NotFound("attendance.not_found", "attendance not found")
+	ErrDeletedAttendanceNotFound = apperror.NotFound("attendance.not_in_trash", "deleted attendance not found")
+	ErrAttendanceParentInTrash   = apperror.Conflict("attendance.parent_in_trash", "restore the classroom and student of this attendance first")
+	ErrAttendanceExists          = apperror.Conflict("attendance.exists", "attendance for this student on this date already exists")
+	ErrAttendanceReference       = apperror.Conflict("attendance.reference_missing", "classroom, teacher or student does not exist")
+	ErrAttendanceConstraint      = apperror.Conflict("attendance.constraint_violated", "attendance violates a data constraint")
+	ErrInvalidAttendanceStatus   = apperror.Validation("attendance.invalid_status", "invalid attendance status")
+	ErrAttendanceVersionConflict = apperror.Conflict("attendance.version_conflict", "attendance was changed by another teacher")
+)
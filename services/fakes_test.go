@@ -0,0 +1,1328 @@
+package services
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/outbox"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memStore is an in-memory stand-in for the database behind the repositories. It keeps
+// the constraints the services rely on: unique live attendance slots, student numbers
+// per classroom and teacher emails, reported with the same GORM errors as Postgres, and
+// the non-overlapping dates of academic years and terms.
+type memStore struct {
+	nextID      uint
+	nextTxID    int64
+	attendances map[uint]models.Attendance
+	students    map[uint]models.Student
+	classrooms  map[uint]models.Classroom
+	teachers    map[uint]models.Teacher
+	schools     map[uint]models.School
+	years       map[uint]models.AcademicYear
+	terms       map[uint]models.Term
+	logs        map[uint]models.Log
+	counters    map[string]int64
+	mutations   map[uint]models.SyncMutation
+	events      []outbox.Event
+
+	// enqueueErr makes Enqueue fail, to check that WithTx rolls the writes back
+	enqueueErr error
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		attendances: map[uint]models.Attendance{},
+		students:    map[uint]models.Student{},
+		classrooms:  map[uint]models.Classroom{},
+		teachers:    map[uint]models.Teacher{},
+		schools:     map[uint]models.School{},
+		years:       map[uint]models.AcademicYear{},
+		terms:       map[uint]models.Term{},
+		logs:        map[uint]models.Log{},
+		counters:    map[string]int64{},
+		mutations:   map[uint]models.SyncMutation{},
+	}
+}
+
+func (st *memStore) id() uint {
+	st.nextID++
+	return st.nextID
+}
+
+// txID stands in for the change_tx_id trigger: every attendance write moves the row
+// to the end of the change feed
+func (st *memStore) txID() int64 {
+	st.nextTxID++
+	return st.nextTxID
+}
+
+// withTx runs fn and puts every map back the way it was when fn fails
+func (st *memStore) withTx(fn func() error) error {
+	saved := *st
+	saved.attendances = maps.Clone(st.attendances)
+	saved.students = maps.Clone(st.students)
+	saved.classrooms = maps.Clone(st.classrooms)
+	saved.teachers = maps.Clone(st.teachers)
+	saved.schools = maps.Clone(st.schools)
+	saved.years = maps.Clone(st.years)
+	saved.terms = maps.Clone(st.terms)
+	saved.logs = maps.Clone(st.logs)
+	saved.counters = maps.Clone(st.counters)
+	saved.mutations = maps.Clone(st.mutations)
+	saved.events = slices.Clone(st.events)
+
+	if err := fn(); err != nil {
+		*st = saved
+		return err
+	}
+	return nil
+}
+
+func (st *memStore) enqueue(event outbox.Event) error {
+	if st.enqueueErr != nil {
+		return st.enqueueErr
+	}
+	st.events = append(st.events, event)
+	return nil
+}
+
+func (st *memStore) eventTypes() []string {
+	types := make([]string, 0, len(st.events))
+	for _, event := range st.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func sameUint(a, b *uint) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+func isUint(p *uint, v uint) bool {
+	return p != nil && *p == v
+}
+
+func sortedKeys[V any](m map[uint]V) []uint {
+	keys := slices.Collect(maps.Keys(m))
+	slices.Sort(keys)
+	return keys
+}
+
+// Attendance rows
+
+func (st *memStore) slotTaken(a models.Attendance) bool {
+	for id, other := range st.attendances {
+		if id != a.ID && !other.DeletedAt.Valid && other.SessionDate == a.SessionDate &&
+			sameUint(other.ClassroomID, a.ClassroomID) && sameUint(other.StudentID, a.StudentID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (st *memStore) softDeleteAttendances(deletedAt models.DeletedAt, match func(models.Attendance) bool) {
+	for id, a := range st.attendances {
+		if !a.DeletedAt.Valid && match(a) {
+			a.DeletedAt = deletedAt
+			a.Version++
+			a.ChangeTxID = st.txID()
+			st.attendances[id] = a
+		}
+	}
+}
+
+func (st *memStore) restoreAttendances(deletedAt models.DeletedAt, match func(models.Attendance) bool) error {
+	for _, id := range sortedKeys(st.attendances) {
+		a := st.attendances[id]
+		if a.DeletedAt != deletedAt || !match(a) {
+			continue
+		}
+		if st.slotTaken(a) {
+			return gorm.ErrDuplicatedKey
+		}
+		a.DeletedAt = models.DeletedAt{}
+		a.Version++
+		a.ChangeTxID = st.txID()
+		st.attendances[id] = a
+	}
+	return nil
+}
+
+type memAttendanceRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memAttendanceRepo) WithContext(ctx context.Context) repositories.AttendanceRepository {
+	return r
+}
+
+func (r memAttendanceRepo) WithTx(fn func(repo repositories.AttendanceRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r memAttendanceRepo) Enqueue(event outbox.Event) error { return r.st.enqueue(event) }
+
+// Rows are kept whole in memory; the controller trims what was not asked for
+func (r memAttendanceRepo) WithSelection(sel fieldset.Selection) repositories.AttendanceRepository {
+	return r
+}
+
+func (r memAttendanceRepo) LockSlot(classroomID, studentID uint, sessionDate string) error {
+	return nil
+}
+
+func (r memAttendanceRepo) FindByID(id uint) (*models.Attendance, error) {
+	a, ok := r.st.attendances[id]
+	if !ok || a.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &a, nil
+}
+
+func (r memAttendanceRepo) FindDeletedByID(id uint) (*models.Attendance, error) {
+	a, ok := r.st.attendances[id]
+	if !ok || !a.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &a, nil
+}
+
+func (r memAttendanceRepo) FindBySlot(classroomID, studentID uint, sessionDate string) (*models.Attendance, error) {
+	for _, a := range r.st.attendances {
+		if !a.DeletedAt.Valid && a.SessionDate == sessionDate && isUint(a.ClassroomID, classroomID) && isUint(a.StudentID, studentID) {
+			return &a, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memAttendanceRepo) FindDeletedBySlot(classroomID, studentID uint, sessionDate string) (*models.Attendance, error) {
+	var latest *models.Attendance
+	for _, id := range sortedKeys(r.st.attendances) {
+		a := r.st.attendances[id]
+		if a.DeletedAt.Valid && a.SessionDate == sessionDate && isUint(a.ClassroomID, classroomID) && isUint(a.StudentID, studentID) &&
+			(latest == nil || a.DeletedAt.Int64 >= latest.DeletedAt.Int64) {
+			latest = &a
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func (r memAttendanceRepo) live(match func(models.Attendance) bool) []models.Attendance {
+	result := []models.Attendance{}
+	for _, id := range sortedKeys(r.st.attendances) {
+		if a := r.st.attendances[id]; !a.DeletedAt.Valid && match(a) {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+func (r memAttendanceRepo) ListByClassroom(classroomID uint, query listquery.Query) ([]models.Attendance, error) {
+	return listquery.Apply(query, r.live(func(a models.Attendance) bool { return isUint(a.ClassroomID, classroomID) })), nil
+}
+
+func (r memAttendanceRepo) ListByStudent(studentID uint, query listquery.Query) ([]models.Attendance, error) {
+	return listquery.Apply(query, r.live(func(a models.Attendance) bool { return isUint(a.StudentID, studentID) })), nil
+}
+
+func (r memAttendanceRepo) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Attendance, error) {
+	return listquery.Apply(query, r.live(func(a models.Attendance) bool { return isUint(a.TeacherID, teacherID) })), nil
+}
+
+func (r memAttendanceRepo) ListBySession(classroomID uint, sessionDate string) ([]models.Attendance, error) {
+	all := r.live(func(a models.Attendance) bool {
+		return isUint(a.ClassroomID, classroomID) && a.SessionDate == sessionDate
+	})
+	sort.SliceStable(all, func(i, j int) bool { return *all[i].StudentID < *all[j].StudentID })
+	return all, nil
+}
+
+func (r memAttendanceRepo) HasLiveParents(classroomID, studentID uint) (bool, error) {
+	student, ok := r.st.students[studentID]
+	if !ok || student.DeletedAt.Valid || !isUint(student.ClassroomID, classroomID) {
+		return false, nil
+	}
+	classroom, ok := r.st.classrooms[classroomID]
+	return ok && !classroom.DeletedAt.Valid, nil
+}
+
+func (r memAttendanceRepo) Create(attendance *models.Attendance) error {
+	if r.st.slotTaken(*attendance) {
+		return gorm.ErrDuplicatedKey
+	}
+	attendance.ID = r.st.id()
+	attendance.ChangeTxID = r.st.txID()
+	r.st.attendances[attendance.ID] = *attendance
+	return nil
+}
+
+func (r memAttendanceRepo) UpdateIfVersion(attendance *models.Attendance, baseVersion uint) (bool, error) {
+	current, ok := r.st.attendances[attendance.ID]
+	if !ok || current.DeletedAt.Valid || current.Version != baseVersion {
+		return false, nil
+	}
+	attendance.ChangeTxID = r.st.txID()
+	r.st.attendances[attendance.ID] = *attendance
+	return true, nil
+}
+
+func (r memAttendanceRepo) SoftDelete(attendance *models.Attendance, deletedAt models.DeletedAt) error {
+	r.st.softDeleteAttendances(deletedAt, func(a models.Attendance) bool { return a.ID == attendance.ID })
+	attendance.DeletedAt = deletedAt
+	return nil
+}
+
+func (r memAttendanceRepo) Restore(attendance *models.Attendance) error {
+	return r.st.restoreAttendances(attendance.DeletedAt, func(a models.Attendance) bool { return a.ID == attendance.ID })
+}
+
+// Student rows
+
+func (st *memStore) studentNoTaken(s models.Student) bool {
+	for id, other := range st.students {
+		if id != s.ID && !other.DeletedAt.Valid && other.StudentNo == s.StudentNo && sameUint(other.ClassroomID, s.ClassroomID) {
+			return true
+		}
+	}
+	return false
+}
+
+type memStudentRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memStudentRepo) WithContext(ctx context.Context) repositories.StudentRepository { return r }
+
+func (r memStudentRepo) WithTx(fn func(repo repositories.StudentRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r memStudentRepo) Enqueue(event outbox.Event) error { return r.st.enqueue(event) }
+
+func (r memStudentRepo) WithSelection(sel fieldset.Selection) repositories.StudentRepository {
+	return r
+}
+
+func (r memStudentRepo) FindByID(id uint) (*models.Student, error) {
+	s, ok := r.st.students[id]
+	if !ok || s.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &s, nil
+}
+
+func (r memStudentRepo) FindDeletedByID(id uint) (*models.Student, error) {
+	s, ok := r.st.students[id]
+	if !ok || !s.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &s, nil
+}
+
+func (r memStudentRepo) FindByStudentNo(classroomID uint, studentNo string) (*models.Student, error) {
+	for _, s := range r.st.students {
+		if !s.DeletedAt.Valid && s.StudentNo == studentNo && isUint(s.ClassroomID, classroomID) {
+			return &s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memStudentRepo) StudentNoTaken(studentNo string, excludeID uint) (bool, error) {
+	for id, s := range r.st.students {
+		if id != excludeID && !s.DeletedAt.Valid && s.StudentNo == studentNo {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memStudentRepo) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Student, error) {
+	all := []models.Student{}
+	for _, id := range sortedKeys(r.st.students) {
+		s := r.st.students[id]
+		if s.DeletedAt.Valid || s.ClassroomID == nil {
+			continue
+		}
+		if classroom, ok := r.st.classrooms[*s.ClassroomID]; ok && isUint(classroom.TeacherID, teacherID) {
+			all = append(all, s)
+		}
+	}
+	return listquery.Apply(query, all), nil
+}
+
+func (r memStudentRepo) LoadRelations(student *models.Student) error {
+	if school, ok := r.st.schools[*student.SchoolID]; ok {
+		student.School = &school
+	}
+	if classroom, ok := r.st.classrooms[*student.ClassroomID]; ok {
+		student.Classroom = &classroom
+	}
+	return nil
+}
+
+func (r memStudentRepo) HighestStudentNo(classroomID uint, stem string) (int64, error) {
+	pattern := regexp.MustCompile("^" + regexp.QuoteMeta(stem) + "([0-9]{1,18})$")
+	var highest int64
+	for _, s := range r.st.students {
+		if !isUint(s.ClassroomID, classroomID) {
+			continue
+		}
+		if m := pattern.FindStringSubmatch(s.StudentNo); m != nil {
+			n, _ := strconv.ParseInt(m[1], 10, 64)
+			highest = max(highest, n)
+		}
+	}
+	return highest, nil
+}
+
+func (r memStudentRepo) NextStudentNo(classroomID uint, period string, floor int64, now int64) (int64, error) {
+	key := fmt.Sprintf("%d/%s", classroomID, period)
+	next := floor
+	if last, ok := r.st.counters[key]; ok {
+		next = max(last+1, floor)
+	}
+	r.st.counters[key] = next
+	return next, nil
+}
+
+func (r memStudentRepo) Create(student *models.Student) error {
+	if r.st.studentNoTaken(*student) {
+		return gorm.ErrDuplicatedKey
+	}
+	student.ID = r.st.id()
+	r.st.students[student.ID] = *student
+	return nil
+}
+
+func (r memStudentRepo) UpdateIfVersion(student *models.Student, baseVersion uint) (bool, error) {
+	current, ok := r.st.students[student.ID]
+	if !ok || current.DeletedAt.Valid || current.Version != baseVersion {
+		return false, nil
+	}
+	if r.st.studentNoTaken(*student) {
+		return false, gorm.ErrDuplicatedKey
+	}
+	r.st.students[student.ID] = *student
+	return true, nil
+}
+
+func (r memStudentRepo) SoftDelete(student *models.Student, deletedAt models.DeletedAt) error {
+	student.DeletedAt = deletedAt
+	r.st.students[student.ID] = *student
+	r.st.softDeleteAttendances(deletedAt, func(a models.Attendance) bool { return isUint(a.StudentID, student.ID) })
+	return nil
+}
+
+func (r memStudentRepo) Restore(student *models.Student) error {
+	deletedAt := student.DeletedAt
+	restored := *student
+	restored.DeletedAt = models.DeletedAt{}
+	if r.st.studentNoTaken(restored) {
+		return gorm.ErrDuplicatedKey
+	}
+	r.st.students[student.ID] = restored
+	*student = restored
+	return r.st.restoreAttendances(deletedAt, func(a models.Attendance) bool { return isUint(a.StudentID, student.ID) })
+}
+
+// Classroom rows
+
+type memClassroomRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memClassroomRepo) WithContext(ctx context.Context) repositories.ClassroomRepository { return r }
+
+func (r memClassroomRepo) WithTx(fn func(repo repositories.ClassroomRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r memClassroomRepo) Enqueue(event outbox.Event) error { return r.st.enqueue(event) }
+
+func (r memClassroomRepo) FindByID(id uint) (*models.Classroom, error) {
+	c, ok := r.st.classrooms[id]
+	if !ok || c.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &c, nil
+}
+
+func (r memClassroomRepo) FindDeletedByID(id uint) (*models.Classroom, error) {
+	c, ok := r.st.classrooms[id]
+	if !ok || !c.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &c, nil
+}
+
+func (r memClassroomRepo) FindByName(schoolID uint, name string) (*models.Classroom, error) {
+	for _, c := range r.st.classrooms {
+		if !c.DeletedAt.Valid && c.Name == name && isUint(c.SchoolID, schoolID) {
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memClassroomRepo) NameTaken(schoolID uint, academicYearID *uint, name string, excludeID uint) (bool, error) {
+	for _, c := range r.st.classrooms {
+		sameYear := c.AcademicYearID == nil && academicYearID == nil || sameUint(c.AcademicYearID, academicYearID)
+		if !c.DeletedAt.Valid && c.Name == name && isUint(c.SchoolID, schoolID) && sameYear && c.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memClassroomRepo) ownedBy(teacherID uint) []models.Classroom {
+	result := []models.Classroom{}
+	for _, id := range sortedKeys(r.st.classrooms) {
+		if c := r.st.classrooms[id]; !c.DeletedAt.Valid && isUint(c.TeacherID, teacherID) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func (r memClassroomRepo) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Classroom, error) {
+	return listquery.Apply(query, r.ownedBy(teacherID)), nil
+}
+
+func (r memClassroomRepo) ListWithStudentsByTeacher(teacherID uint) ([]models.Classroom, error) {
+	classrooms := r.ownedBy(teacherID)
+	for i := range classrooms {
+		for _, id := range sortedKeys(r.st.students) {
+			if s := r.st.students[id]; !s.DeletedAt.Valid && isUint(s.ClassroomID, classrooms[i].ID) {
+				classrooms[i].Students = append(classrooms[i].Students, s)
+			}
+		}
+	}
+	return classrooms, nil
+}
+
+func (r memClassroomRepo) AccessibleIDs(teacherID uint) ([]uint, error) {
+	ids := []uint{}
+	for _, c := range r.ownedBy(teacherID) {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+func (r memClassroomRepo) Create(classroom *models.Classroom) error {
+	classroom.ID = r.st.id()
+	r.st.classrooms[classroom.ID] = *classroom
+	return nil
+}
+
+func (r memClassroomRepo) UpdateIfVersion(classroom *models.Classroom, baseVersion uint) (bool, error) {
+	current, ok := r.st.classrooms[classroom.ID]
+	if !ok || current.DeletedAt.Valid || current.Version != baseVersion {
+		return false, nil
+	}
+	r.st.classrooms[classroom.ID] = *classroom
+	return true, nil
+}
+
+func (r memClassroomRepo) SoftDelete(classroom *models.Classroom, deletedAt models.DeletedAt) error {
+	classroom.DeletedAt = deletedAt
+	r.st.classrooms[classroom.ID] = *classroom
+	for id, s := range r.st.students {
+		if !s.DeletedAt.Valid && isUint(s.ClassroomID, classroom.ID) {
+			s.DeletedAt = deletedAt
+			r.st.students[id] = s
+		}
+	}
+	r.st.softDeleteAttendances(deletedAt, func(a models.Attendance) bool { return isUint(a.ClassroomID, classroom.ID) })
+	return nil
+}
+
+func (r memClassroomRepo) Restore(classroom *models.Classroom) error {
+	deletedAt := classroom.DeletedAt
+	classroom.DeletedAt = models.DeletedAt{}
+	r.st.classrooms[classroom.ID] = *classroom
+	for _, id := range sortedKeys(r.st.students) {
+		s := r.st.students[id]
+		if s.DeletedAt != deletedAt || !isUint(s.ClassroomID, classroom.ID) {
+			continue
+		}
+		s.DeletedAt = models.DeletedAt{}
+		if r.st.studentNoTaken(s) {
+			return gorm.ErrDuplicatedKey
+		}
+		r.st.students[id] = s
+	}
+	return r.st.restoreAttendances(deletedAt, func(a models.Attendance) bool { return isUint(a.ClassroomID, classroom.ID) })
+}
+
+// Teacher rows
+
+func (st *memStore) emailTaken(t models.Teacher) bool {
+	for id, other := range st.teachers {
+		if id != t.ID && !other.DeletedAt.Valid && other.Email == t.Email {
+			return true
+		}
+	}
+	return false
+}
+
+type memTeacherRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memTeacherRepo) WithContext(ctx context.Context) repositories.TeacherRepository { return r }
+
+func (r memTeacherRepo) WithTx(fn func(repo repositories.TeacherRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r memTeacherRepo) Enqueue(event outbox.Event) error { return r.st.enqueue(event) }
+
+func (r memTeacherRepo) FindByID(id uint) (*models.Teacher, error) {
+	t, ok := r.st.teachers[id]
+	if !ok || t.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &t, nil
+}
+
+func (r memTeacherRepo) FindDeletedByID(id uint) (*models.Teacher, error) {
+	t, ok := r.st.teachers[id]
+	if !ok || !t.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &t, nil
+}
+
+func (r memTeacherRepo) FindByEmail(email string) (*models.Teacher, error) {
+	for _, id := range sortedKeys(r.st.teachers) {
+		if t := r.st.teachers[id]; !t.DeletedAt.Valid && t.Email == email {
+			return &t, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memTeacherRepo) FindProfile(id uint) (*models.Teacher, error) {
+	t, err := r.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.SchoolID != nil {
+		if school, ok := r.st.schools[*t.SchoolID]; ok {
+			t.School = &school
+		}
+	}
+	return t, nil
+}
+
+func (r memTeacherRepo) FindFirstBySchool(schoolID uint) (*models.Teacher, error) {
+	for _, id := range sortedKeys(r.st.teachers) {
+		if t := r.st.teachers[id]; !t.DeletedAt.Valid && isUint(t.SchoolID, schoolID) {
+			return &t, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memTeacherRepo) EmailTaken(email string, excludeID uint) (bool, error) {
+	return r.st.emailTaken(models.Teacher{ID: excludeID, Email: email}), nil
+}
+
+func (r memTeacherRepo) List(query listquery.Query) ([]models.Teacher, error) {
+	all := []models.Teacher{}
+	for _, id := range sortedKeys(r.st.teachers) {
+		if t := r.st.teachers[id]; !t.DeletedAt.Valid {
+			all = append(all, t)
+		}
+	}
+	return listquery.Apply(query, all), nil
+}
+
+func (r memTeacherRepo) CountAttendancesByClassroom(teacherID uint) (map[uint]int64, error) {
+	counts := map[uint]int64{}
+	for _, a := range r.st.attendances {
+		if !a.DeletedAt.Valid && isUint(a.TeacherID, teacherID) {
+			counts[*a.ClassroomID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r memTeacherRepo) Create(teacher *models.Teacher) error {
+	if r.st.emailTaken(*teacher) {
+		return gorm.ErrDuplicatedKey
+	}
+	teacher.ID = r.st.id()
+	if teacher.Version == 0 {
+		teacher.Version = 1 // Column default
+	}
+	r.st.teachers[teacher.ID] = *teacher
+	return nil
+}
+
+func (r memTeacherRepo) UpdateIfVersion(teacher *models.Teacher, baseVersion uint) (bool, error) {
+	current, ok := r.st.teachers[teacher.ID]
+	if !ok || current.DeletedAt.Valid || current.Version != baseVersion {
+		return false, nil
+	}
+	if r.st.emailTaken(*teacher) {
+		return false, gorm.ErrDuplicatedKey
+	}
+	r.st.teachers[teacher.ID] = *teacher
+	return true, nil
+}
+
+func (r memTeacherRepo) SoftDelete(teacher *models.Teacher) error {
+	teacher.DeletedAt = models.NewDeletedAt(time.Now())
+	r.st.teachers[teacher.ID] = *teacher
+	return nil
+}
+
+func (r memTeacherRepo) Restore(teacher *models.Teacher) error {
+	restored := *teacher
+	restored.DeletedAt = models.DeletedAt{}
+	if r.st.emailTaken(restored) {
+		return gorm.ErrDuplicatedKey
+	}
+	r.st.teachers[teacher.ID] = restored
+	*teacher = restored
+	return nil
+}
+
+// School rows
+
+type memSchoolRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memSchoolRepo) WithContext(ctx context.Context) repositories.SchoolRepository { return r }
+
+func (r memSchoolRepo) FindByID(id uint) (*models.School, error) {
+	school, ok := r.st.schools[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &school, nil
+}
+
+func (r memSchoolRepo) FindByName(name string) (*models.School, error) {
+	for _, s := range r.st.schools {
+		if s.Name == name {
+			return &s, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memSchoolRepo) FindByClassroomID(classroomID uint) (*models.School, error) {
+	classroom, ok := r.st.classrooms[classroomID]
+	if !ok || classroom.SchoolID == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	school, ok := r.st.schools[*classroom.SchoolID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &school, nil
+}
+
+func (r memSchoolRepo) FindByTeacherID(teacherID uint) (*models.School, error) {
+	teacher, ok := r.st.teachers[teacherID]
+	if !ok || teacher.SchoolID == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.FindByID(*teacher.SchoolID)
+}
+
+func (r memSchoolRepo) NameTaken(name string, excludeID uint) (bool, error) {
+	school, err := r.FindByName(name)
+	return err == nil && school.ID != excludeID, nil
+}
+
+func (r memSchoolRepo) List(query listquery.Query) ([]models.School, error) {
+	all := []models.School{}
+	for _, id := range sortedKeys(r.st.schools) {
+		all = append(all, r.st.schools[id])
+	}
+	return listquery.Apply(query, all), nil
+}
+
+func (r memSchoolRepo) Create(school *models.School) error {
+	if _, err := r.FindByName(school.Name); err == nil {
+		return gorm.ErrDuplicatedKey
+	}
+	// Column defaults of the schools table
+	if school.SyncConflictPolicy == "" {
+		school.SyncConflictPolicy = models.SyncConflictServerWins
+	}
+	if school.StudentNoPrefix == "" {
+		school.StudentNoPrefix = "STD"
+	}
+	if school.StudentNoWidth == 0 {
+		school.StudentNoWidth = 3
+	}
+	if school.StudentNoYear == "" {
+		school.StudentNoYear = models.StudentNoYearNone
+	}
+	if school.Version == 0 {
+		school.Version = 1
+	}
+	school.ID = r.st.id()
+	r.st.schools[school.ID] = *school
+	return nil
+}
+
+func (r memSchoolRepo) UpdateIfVersion(school *models.School, baseVersion uint) (bool, error) {
+	current, ok := r.st.schools[school.ID]
+	if !ok || current.Version != baseVersion {
+		return false, nil
+	}
+	if taken, _ := r.NameTaken(school.Name, school.ID); taken {
+		return false, gorm.ErrDuplicatedKey
+	}
+	r.st.schools[school.ID] = *school
+	return true, nil
+}
+
+func (r memSchoolRepo) Delete(school *models.School) error {
+	delete(r.st.schools, school.ID)
+	return nil
+}
+
+// Sync mutations
+
+type memSyncRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memSyncRepo) WithContext(ctx context.Context) repositories.SyncRepository { return r }
+
+func (r memSyncRepo) WithTx(fn func(repo repositories.SyncRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r memSyncRepo) Enqueue(event outbox.Event) error { return r.st.enqueue(event) }
+
+func (r memSyncRepo) Attendances() repositories.AttendanceRepository { return memAttendanceRepo(r) }
+
+func (r memSyncRepo) SyncSettings(classroomIDs []uint) ([]repositories.ClassroomSyncSetting, error) {
+	settings := []repositories.ClassroomSyncSetting{}
+	for _, id := range classroomIDs {
+		classroom, ok := r.st.classrooms[id]
+		if !ok {
+			continue
+		}
+		setting := repositories.ClassroomSyncSetting{ClassroomID: id, SchoolID: classroom.SchoolID}
+		if classroom.SchoolID != nil {
+			setting.Policy = r.st.schools[*classroom.SchoolID].SyncConflictPolicy
+		}
+		settings = append(settings, setting)
+	}
+	return settings, nil
+}
+
+func (r memSyncRepo) FindMutation(teacherID uint, clientMutationID string) (*models.SyncMutation, error) {
+	for _, m := range r.st.mutations {
+		if m.TeacherID == teacherID && m.ClientMutationID == clientMutationID {
+			return &m, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memSyncRepo) CreateMutation(mutation *models.SyncMutation) error {
+	if _, err := r.FindMutation(mutation.TeacherID, mutation.ClientMutationID); err == nil {
+		return gorm.ErrDuplicatedKey
+	}
+	mutation.ID = r.st.id()
+	r.st.mutations[mutation.ID] = *mutation
+	return nil
+}
+
+// Every write in memory is visible at once, so there is no horizon to wait for
+func (r memSyncRepo) ChangesSince(classroomIDs []uint, txID int64, id uint, limit int) ([]models.Attendance, error) {
+	rows := []models.Attendance{}
+	for _, a := range r.st.attendances {
+		if slices.Contains(classroomIDs, *a.ClassroomID) && (a.ChangeTxID > txID || (a.ChangeTxID == txID && a.ID > id)) {
+			rows = append(rows, a)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].ChangeTxID != rows[j].ChangeTxID {
+			return rows[i].ChangeTxID < rows[j].ChangeTxID
+		}
+		return rows[i].ID < rows[j].ID
+	})
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+	return rows, nil
+}
+
+// Trash
+
+type memTrashRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memTrashRepo) WithContext(ctx context.Context) repositories.TrashRepository { return r }
+
+func (r memTrashRepo) WithTx(fn func(repo repositories.TrashRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+// teaches reports whether the teacher owns the classroom; the store keeps no members
+func (r memTrashRepo) teaches(teacherID uint, classroomID *uint) bool {
+	if classroomID == nil {
+		return false
+	}
+	classroom, ok := r.st.classrooms[*classroomID]
+	return ok && isUint(classroom.TeacherID, teacherID)
+}
+
+func deletedRows[T any](query listquery.Query, rows map[uint]T, keep func(T) bool) []T {
+	found := []T{}
+	for _, id := range sortedKeys(rows) {
+		if keep(rows[id]) {
+			found = append(found, rows[id])
+		}
+	}
+	return listquery.Apply(query, found)
+}
+
+func (r memTrashRepo) ListClassrooms(teacherID uint, query listquery.Query) ([]models.Classroom, error) {
+	return deletedRows(query, r.st.classrooms, func(c models.Classroom) bool {
+		return c.DeletedAt.Valid && r.teaches(teacherID, &c.ID)
+	}), nil
+}
+
+func (r memTrashRepo) ListStudents(teacherID uint, query listquery.Query) ([]models.Student, error) {
+	return deletedRows(query, r.st.students, func(s models.Student) bool {
+		return s.DeletedAt.Valid && r.teaches(teacherID, s.ClassroomID)
+	}), nil
+}
+
+func (r memTrashRepo) ListTeachers(schoolID *uint, query listquery.Query) ([]models.Teacher, error) {
+	return deletedRows(query, r.st.teachers, func(t models.Teacher) bool {
+		return t.DeletedAt.Valid && sameUint(t.SchoolID, schoolID)
+	}), nil
+}
+
+func (r memTrashRepo) ListAttendances(teacherID uint, query listquery.Query) ([]models.Attendance, error) {
+	return deletedRows(query, r.st.attendances, func(a models.Attendance) bool {
+		return a.DeletedAt.Valid && r.teaches(teacherID, a.ClassroomID)
+	}), nil
+}
+
+// purgeRows deletes the rows deleted before the cutoff unless another row still points at them
+func purgeRows[T any](rows map[uint]T, deletedAt func(T) models.DeletedAt, before int64, referenced func(uint) bool) (purged, kept int64) {
+	for id, row := range rows {
+		if at := deletedAt(row); !at.Valid || at.Int64 >= before {
+			continue
+		}
+		if referenced(id) {
+			kept++
+			continue
+		}
+		delete(rows, id)
+		purged++
+	}
+	return purged, kept
+}
+
+func (r memTrashRepo) PurgeAttendances(before int64) (int64, error) {
+	purged, _ := purgeRows(r.st.attendances, func(a models.Attendance) models.DeletedAt { return a.DeletedAt }, before,
+		func(uint) bool { return false })
+	return purged, nil
+}
+
+func (r memTrashRepo) PurgeStudents(before int64) (int64, int64, error) {
+	purged, kept := purgeRows(r.st.students, func(s models.Student) models.DeletedAt { return s.DeletedAt }, before,
+		func(id uint) bool {
+			return slices.ContainsFunc(slices.Collect(maps.Values(r.st.attendances)), func(a models.Attendance) bool { return isUint(a.StudentID, id) })
+		})
+	return purged, kept, nil
+}
+
+func (r memTrashRepo) PurgeClassrooms(before int64) (int64, int64, error) {
+	purged, kept := purgeRows(r.st.classrooms, func(c models.Classroom) models.DeletedAt { return c.DeletedAt }, before,
+		func(id uint) bool {
+			return slices.ContainsFunc(slices.Collect(maps.Values(r.st.students)), func(s models.Student) bool { return isUint(s.ClassroomID, id) }) ||
+				slices.ContainsFunc(slices.Collect(maps.Values(r.st.attendances)), func(a models.Attendance) bool { return isUint(a.ClassroomID, id) })
+		})
+	return purged, kept, nil
+}
+
+func (r memTrashRepo) PurgeTeachers(before int64) (int64, int64, error) {
+	purged, kept := purgeRows(r.st.teachers, func(t models.Teacher) models.DeletedAt { return t.DeletedAt }, before,
+		func(id uint) bool {
+			return slices.ContainsFunc(slices.Collect(maps.Values(r.st.classrooms)), func(c models.Classroom) bool { return isUint(c.TeacherID, id) }) ||
+				slices.ContainsFunc(slices.Collect(maps.Values(r.st.attendances)), func(a models.Attendance) bool { return isUint(a.TeacherID, id) })
+		})
+	return purged, kept, nil
+}
+
+// Log rows
+
+type memLogRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memLogRepo) WithContext(ctx context.Context) repositories.LogRepository { return r }
+
+func (r memLogRepo) matching(match func(models.Log) bool) []models.Log {
+	result := []models.Log{}
+	for _, id := range sortedKeys(r.st.logs) {
+		if l := r.st.logs[id]; match(l) {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+func (r memLogRepo) List(query listquery.Query) ([]models.Log, error) {
+	return listquery.Apply(query, r.matching(func(models.Log) bool { return true })), nil
+}
+
+func (r memLogRepo) FindByID(id uint) (*models.Log, error) {
+	l, ok := r.st.logs[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &l, nil
+}
+
+func (r memLogRepo) ListByTeacher(teacherID uint, query listquery.Query) ([]models.Log, error) {
+	return listquery.Apply(query, r.matching(func(l models.Log) bool { return l.TeacherID == teacherID })), nil
+}
+
+func (r memLogRepo) ListByAction(action models.LogAction, query listquery.Query) ([]models.Log, error) {
+	return listquery.Apply(query, r.matching(func(l models.Log) bool { return l.Action == action })), nil
+}
+
+func (r memLogRepo) Create(log *models.Log) error {
+	log.ID = r.st.id()
+	r.st.logs[log.ID] = *log
+	return nil
+}
+
+// Academic years and terms; dates are always YYYY-MM-DD here, so they compare as strings
+
+type memAcademicYearRepo struct{ st *memStore }
+
+// The in-memory store has nothing to cancel or trace
+func (r memAcademicYearRepo) WithContext(ctx context.Context) repositories.AcademicYearRepository {
+	return r
+}
+
+func (r memAcademicYearRepo) WithTx(fn func(repo repositories.AcademicYearRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r memAcademicYearRepo) Enqueue(event outbox.Event) error { return r.st.enqueue(event) }
+
+func (r memAcademicYearRepo) FindByID(id uint) (*models.AcademicYear, error) {
+	year, ok := r.st.years[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	year.Terms = r.terms(id)
+	return &year, nil
+}
+
+// There is only one writer, so holding the row is just a check that it exists
+func (r memAcademicYearRepo) Lock(id uint) error {
+	if _, ok := r.st.years[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r memAcademicYearRepo) FindCurrent(schoolID uint, day string) (*models.AcademicYear, error) {
+	for _, id := range sortedKeys(r.st.years) {
+		if year := r.st.years[id]; isUint(year.SchoolID, schoolID) && year.StartDate <= day && day <= year.EndDate {
+			return &year, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memAcademicYearRepo) List(query listquery.Query) ([]models.AcademicYear, error) {
+	years := []models.AcademicYear{}
+	for _, id := range sortedKeys(r.st.years) {
+		years = append(years, r.st.years[id])
+	}
+	return listquery.Apply(query, years), nil
+}
+
+func (r memAcademicYearRepo) NameTaken(schoolID uint, name string) (bool, error) {
+	for _, year := range r.st.years {
+		if isUint(year.SchoolID, schoolID) && year.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memAcademicYearRepo) Overlaps(schoolID uint, startDate, endDate string) (bool, error) {
+	for _, year := range r.st.years {
+		if isUint(year.SchoolID, schoolID) && year.StartDate <= endDate && year.EndDate >= startDate {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memAcademicYearRepo) Create(year *models.AcademicYear) error {
+	if taken, _ := r.NameTaken(*year.SchoolID, year.Name); taken {
+		return gorm.ErrDuplicatedKey
+	}
+	if overlaps, _ := r.Overlaps(*year.SchoolID, year.StartDate, year.EndDate); overlaps {
+		return repositories.ErrDatesOverlap
+	}
+	first := !r.schoolHasYears(*year.SchoolID)
+	year.ID = r.st.id()
+	r.st.years[year.ID] = *year
+	if first {
+		for id, c := range r.st.classrooms {
+			if c.AcademicYearID == nil && sameUint(c.SchoolID, year.SchoolID) {
+				c.AcademicYearID = &year.ID
+				r.st.classrooms[id] = c
+			}
+		}
+	}
+	return nil
+}
+
+func (r memAcademicYearRepo) schoolHasYears(schoolID uint) bool {
+	for _, year := range r.st.years {
+		if isUint(year.SchoolID, schoolID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r memAcademicYearRepo) ListTerms(academicYearID uint, query listquery.Query) ([]models.Term, error) {
+	return listquery.Apply(query, r.terms(academicYearID)), nil
+}
+
+// terms are the year's terms in order, the way FindByID preloads them
+func (r memAcademicYearRepo) terms(academicYearID uint) []models.Term {
+	terms := []models.Term{}
+	for _, id := range sortedKeys(r.st.terms) {
+		if term := r.st.terms[id]; isUint(term.AcademicYearID, academicYearID) {
+			terms = append(terms, term)
+		}
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return terms[i].Number < terms[j].Number })
+	return terms
+}
+
+func (r memAcademicYearRepo) TermNumberTaken(academicYearID uint, number int) (bool, error) {
+	return slices.ContainsFunc(r.terms(academicYearID), func(term models.Term) bool { return term.Number == number }), nil
+}
+
+func (r memAcademicYearRepo) TermOverlaps(schoolID uint, startDate, endDate string) (bool, error) {
+	for _, term := range r.st.terms {
+		if isUint(term.SchoolID, schoolID) && term.StartDate <= endDate && term.EndDate >= startDate {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r memAcademicYearRepo) CreateTerm(term *models.Term) error {
+	if taken, _ := r.TermNumberTaken(*term.AcademicYearID, term.Number); taken {
+		return gorm.ErrDuplicatedKey
+	}
+	if overlaps, _ := r.TermOverlaps(*term.SchoolID, term.StartDate, term.EndDate); overlaps {
+		return repositories.ErrDatesOverlap
+	}
+	term.ID = r.st.id()
+	r.st.terms[term.ID] = *term
+	for id, a := range r.st.attendances {
+		classroom := r.st.classrooms[*a.ClassroomID]
+		if a.TermID == nil && sameUint(classroom.SchoolID, term.SchoolID) &&
+			term.StartDate <= a.SessionDate && a.SessionDate <= term.EndDate {
+			a.TermID = &term.ID
+			r.st.attendances[id] = a
+		}
+	}
+	return nil
+}
+
+func (r memAcademicYearRepo) ListClassrooms(academicYearID uint) ([]models.Classroom, error) {
+	classrooms := []models.Classroom{}
+	for _, id := range sortedKeys(r.st.classrooms) {
+		c := r.st.classrooms[id]
+		if c.DeletedAt.Valid || !isUint(c.AcademicYearID, academicYearID) {
+			continue
+		}
+		for _, studentID := range sortedKeys(r.st.students) {
+			if s := r.st.students[studentID]; !s.DeletedAt.Valid && isUint(s.ClassroomID, c.ID) {
+				c.Students = append(c.Students, s)
+			}
+		}
+		classrooms = append(classrooms, c)
+	}
+	return classrooms, nil
+}
+
+func (r memAcademicYearRepo) CreateClassroom(classroom *models.Classroom) error {
+	return memClassroomRepo(r).Create(classroom)
+}
+
+func (r memAcademicYearRepo) MoveStudents(studentIDs []uint, classroomID uint) error {
+	for _, id := range studentIDs {
+		s := r.st.students[id]
+		s.ClassroomID = &classroomID
+		s.Version++
+		if r.st.studentNoTaken(s) {
+			return gorm.ErrDuplicatedKey
+		}
+		r.st.students[id] = s
+	}
+	return nil
+}
+
+func (r memAcademicYearRepo) RenumberStudent(studentID, classroomID uint, studentNo string) error {
+	s := r.st.students[studentID]
+	s.ClassroomID = &classroomID
+	s.StudentNo = studentNo
+	s.Version++
+	if r.st.studentNoTaken(s) {
+		return gorm.ErrDuplicatedKey
+	}
+	r.st.students[studentID] = s
+	return nil
+}
+
+func (r memAcademicYearRepo) HighestStudentNo(classroomID uint, stem string) (int64, error) {
+	return memStudentRepo(r).HighestStudentNo(classroomID, stem)
+}
+
+func (r memAcademicYearRepo) NextStudentNo(classroomID uint, period string, floor int64, now int64) (int64, error) {
+	return memStudentRepo(r).NextStudentNo(classroomID, period, floor, now)
+}
+
+// fixedClock is a clock.Clock that only moves when a test advances it
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fixedClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// testEnv wires the services to one in-memory store, the way setupRoutes wires them to Postgres
+type testEnv struct {
+	store      *memStore
+	clock      *fixedClock
+	attendance *AttendanceService
+	student    *StudentService
+	classroom  *ClassroomService
+	year       *AcademicYearService
+	teacher    *TeacherService
+	log        *LogService
+	sync       *SyncService
+	trash      *TrashService
+}
+
+func newTestEnv() *testEnv {
+	st := newMemStore()
+	clk := &fixedClock{now: time.Date(2025, time.June, 2, 8, 30, 0, 0, time.UTC)}
+	attendances := memAttendanceRepo{st}
+	students := memStudentRepo{st}
+	classrooms := memClassroomRepo{st}
+	teachers := memTeacherRepo{st}
+	schools := memSchoolRepo{st}
+	years := memAcademicYearRepo{st}
+
+	return &testEnv{
+		store:      st,
+		clock:      clk,
+		attendance: NewAttendanceService(attendances, classrooms, clk),
+		student:    NewStudentService(students, classrooms, teachers, schools, clk),
+		classroom:  NewClassroomService(classrooms, years, clk),
+		year:       NewAcademicYearService(years, schools, teachers, clk),
+		teacher:    NewTeacherService(teachers, classrooms, schools),
+		log:        NewLogService(memLogRepo{st}, clk),
+		sync:       NewSyncService(memSyncRepo{st}, clk),
+		trash:      NewTrashService(memTrashRepo{st}, teachers),
+	}
+}
+
+// list parses a raw query string such as "limit=2&sort=-created_at" the way the list handlers do
+func list(t *testing.T, resource listquery.Resource, rawQuery string) listquery.Query {
+	t.Helper()
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatalf("query %q: %v", rawQuery, err)
+	}
+	query, err := listquery.Parse(values, resource)
+	if err != nil {
+		t.Fatalf("query %q: %v", rawQuery, err)
+	}
+	return query
+}
+
+// seed adds a school, a teacher, a classroom and count students to the store
+func (e *testEnv) seed(count int) (*models.School, *models.Teacher, *models.Classroom, []models.Student) {
+	school := &models.School{Name: "โรงเรียนทดสอบ"}
+	memSchoolRepo{e.store}.Create(school)
+
+	teacher := &models.Teacher{SchoolID: &school.ID, Email: "teacher@example.com", FirstName: "สมชาย", LastName: "ใจดี"}
+	memTeacherRepo{e.store}.Create(teacher)
+
+	classroom := &models.Classroom{SchoolID: &school.ID, TeacherID: &teacher.ID, Name: "ม.1/1", Grade: "ม.1"}
+	memClassroomRepo{e.store}.Create(classroom)
+
+	students := make([]models.Student, 0, count)
+	for i := 1; i <= count; i++ {
+		student := models.Student{
+			SchoolID:    &school.ID,
+			ClassroomID: &classroom.ID,
+			StudentNo:   fmt.Sprintf("STD%03d", i),
+			FirstName:   fmt.Sprintf("นักเรียน%d", i),
+			LastName:    "ทดสอบ",
+		}
+		memStudentRepo{e.store}.Create(&student)
+		students = append(students, student)
+	}
+
+	return school, teacher, classroom, students
+}
+
+// fields parses a ?fields=&expand= query string for spec, failing the test on error
+func fields(t *testing.T, spec fieldset.Spec, rawQuery string) fieldset.Selection {
+	t.Helper()
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatalf("query %q: %v", rawQuery, err)
+	}
+	selection, err := fieldset.Parse(values, spec)
+	if err != nil {
+		t.Fatalf("query %q: %v", rawQuery, err)
+	}
+	return selection
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,57 @@
+package services
+
+import "easy-attend-service/utils/fieldset"
+
+// What each single-record and collection endpoint may narrow with ?fields= and
+// widen with ?expand=. Without expand no relation is loaded.
+
+var AttendanceFields = fieldset.Spec{
+	Fields: map[string]string{
+		"id":           "",
+		"classroom_id": "",
+		"teacher_id":   "",
+		"student_id":   "",
+		"term_id":      "",
+		"session_date": "",
+		"status":       "",
+		"checked_at":   "",
+		"remark":       "",
+		"version":      "",
+		"created_at":   "",
+		"updated_at":   "",
+		"deleted_at":   "",
+	},
+	Relations: map[string]fieldset.Relation{
+		"student":           {Preload: "Student", Column: "student_id"},
+		"student.school":    {Preload: "Student.School", Column: "student_id"},
+		"student.classroom": {Preload: "Student.Classroom", Column: "student_id"},
+		"student.gender":    {Preload: "Student.Gender", Column: "student_id"},
+		"student.prefix":    {Preload: "Student.Prefix", Column: "student_id"},
+		"classroom":         {Preload: "Classroom", Column: "classroom_id"},
+		"teacher":           {Preload: "Teacher", Column: "teacher_id"},
+		"term":              {Preload: "Term", Column: "term_id"},
+	},
+}
+
+// StudentFields qualifies its columns for the same join as StudentListing
+var StudentFields = fieldset.Spec{
+	Fields: map[string]string{
+		"id":           "students.id",
+		"school_id":    "students.school_id",
+		"classroom_id": "students.classroom_id",
+		"student_no":   "students.student_no",
+		"firstname":    "students.first_name",
+		"lastname":     "students.last_name",
+		"gender_id":    "students.gender_id",
+		"prefix_id":    "students.prefix_id",
+		"created_at":   "students.created_at",
+		"updated_at":   "students.updated_at",
+		"deleted_at":   "students.deleted_at",
+	},
+	Relations: map[string]fieldset.Relation{
+		"school":    {Preload: "School", Column: "students.school_id"},
+		"classroom": {Preload: "Classroom", Column: "students.classroom_id"},
+		"gender":    {Preload: "Gender", Column: "students.gender_id"},
+		"prefix":    {Preload: "Prefix", Column: "students.prefix_id"},
+	},
+}
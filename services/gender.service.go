@@ -1,85 +1,111 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/listquery"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-type GenderService struct{}
+type GenderService struct {
+	genders repositories.GenderRepository
+	clock   clock.Clock
+}
 
-func NewGenderService() *GenderService {
-	return &GenderService{}
+func NewGenderService(genders repositories.GenderRepository, clk clock.Clock) *GenderService {
+	return &GenderService{genders: genders, clock: clk}
 }
 
-func (s *GenderService) GetAllGenders() ([]models.Gender, error) {
-	logger.LogInfo("Fetching all genders", nil)
+func (s *GenderService) GetAllGenders(ctx context.Context, query listquery.Query) ([]models.Gender, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "GenderService.GetAllGenders")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching all genders", nil)
 
-	var genders []models.Gender
-	if err := configs.DB.Where("deleted_at IS NULL").Find(&genders).Error; err != nil {
-		logger.LogError(err, "Failed to fetch genders", nil)
-		return nil, errors.New("failed to fetch genders")
+	genders, err := s.genders.WithContext(ctx).List(query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch genders", nil)
+		return nil, listquery.Pagination{}, errors.New("failed to fetch genders")
 	}
 
-	logger.LogInfo("Genders fetched successfully", logrus.Fields{
+	genders, meta := listquery.Page(query, genders)
+	logger.LogInfo(ctx, "Genders fetched successfully", logrus.Fields{
 		"count": len(genders),
 	})
 
-	return genders, nil
+	return genders, meta, nil
 }
 
-func (s *GenderService) GetGenderByID(id uint) (*models.Gender, error) {
-	logger.LogInfo("Fetching gender by ID", logrus.Fields{
+func (s *GenderService) GetGenderByID(ctx context.Context, id uint) (*models.Gender, error) {
+	ctx, span := tracing.Start(ctx, "GenderService.GetGenderByID")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching gender by ID", logrus.Fields{
 		"gender_id": fmt.Sprintf("%d", id),
 	})
 
-	var gender models.Gender
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&gender).Error; err != nil {
-		logger.LogWarning("Gender not found", logrus.Fields{
+	gender, err := s.genders.WithContext(ctx).FindByID(id)
+	if err != nil {
+		logger.LogWarning(ctx, "Gender not found", logrus.Fields{
 			"gender_id": id,
 		})
-		return nil, errors.New("gender not found")
+		return nil, ErrGenderNotFound
 	}
 
-	return &gender, nil
+	return gender, nil
 }
 
-func (s *GenderService) CreateGender(req *requests.GenderCreateRequest) (*models.Gender, error) {
-	logger.LogInfo("Creating new gender", logrus.Fields{
+func (s *GenderService) CreateGender(ctx context.Context, req *requests.GenderCreateRequest) (*models.Gender, error) {
+	ctx, span := tracing.Start(ctx, "GenderService.CreateGender")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Creating new gender", logrus.Fields{
 		"name": req.Name,
 	})
 
+	genders := s.genders.WithContext(ctx)
+
 	// Check if gender already exists
-	var existingGender models.Gender
-	if err := configs.DB.Where("name = ? AND deleted_at IS NULL", req.Name).First(&existingGender).Error; err == nil {
-		logger.LogWarning("Gender creation failed - name already exists", logrus.Fields{
+	taken, err := genders.NameTaken(req.Name, 0)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to check gender name", logrus.Fields{
+			"name": req.Name,
+		})
+		return nil, errors.New("failed to create gender")
+	}
+	if taken {
+		logger.LogWarning(ctx, "Gender creation failed - name already exists", logrus.Fields{
 			"name": req.Name,
 		})
-		return nil, errors.New("gender with this name already exists")
+		return nil, ErrGenderNameTaken
 	}
 
 	// Create new gender
+	now := s.clock.Now().Unix()
 	gender := models.Gender{
 		Name:      req.Name,
-		CreatedAt: time.Now().Unix(),
-		UpdatedAt: time.Now().Unix(),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
-	if err := configs.DB.Create(&gender).Error; err != nil {
-		logger.LogError(err, "Failed to create gender", logrus.Fields{
+	if err := genders.Create(&gender); err != nil {
+		logger.LogError(ctx, err, "Failed to create gender", logrus.Fields{
 			"name": req.Name,
 		})
 		return nil, errors.New("failed to create gender")
 	}
 
-	logger.LogInfo("Gender created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Gender created successfully", logrus.Fields{
 		"gender_id": fmt.Sprintf("%d", gender.ID),
 		"name":      gender.Name,
 	})
@@ -87,90 +113,135 @@ func (s *GenderService) CreateGender(req *requests.GenderCreateRequest) (*models
 	return &gender, nil
 }
 
-func (s *GenderService) UpdateGender(id uint, req *requests.GenderUpdateRequest) (*models.Gender, error) {
-	logger.LogInfo("Updating gender", logrus.Fields{
+func (s *GenderService) UpdateGender(ctx context.Context, id uint, req *requests.GenderUpdateRequest) (*models.Gender, error) {
+	ctx, span := tracing.Start(ctx, "GenderService.UpdateGender")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Updating gender", logrus.Fields{
 		"gender_id": id,
 		"name":      req.Name,
 	})
 
-	var gender models.Gender
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&gender).Error; err != nil {
+	genders := s.genders.WithContext(ctx)
+
+	gender, err := genders.FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Gender not found for update", logrus.Fields{
+			logger.LogWarning(ctx, "Gender not found for update", logrus.Fields{
 				"gender_id": id,
 			})
-			return nil, errors.New("gender not found")
+			return nil, ErrGenderNotFound
 		}
-		logger.LogError(err, "Failed to find gender for update", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find gender for update", logrus.Fields{
 			"gender_id": id,
 		})
 		return nil, errors.New("failed to find gender")
 	}
 
+	if err := etag.CheckIfMatch(ctx, gender); err != nil {
+		return nil, err
+	}
+
 	// Check if name is being changed and if it already exists
 	if req.Name != gender.Name {
-		var existingGender models.Gender
-		if err := configs.DB.Where("name = ? AND id != ? AND deleted_at IS NULL", req.Name, id).First(&existingGender).Error; err == nil {
-			logger.LogWarning("Gender update failed - name already exists", logrus.Fields{
+		taken, err := genders.NameTaken(req.Name, id)
+		if err != nil {
+			logger.LogError(ctx, err, "Failed to check gender name", logrus.Fields{
+				"gender_id": id,
+			})
+			return nil, errors.New("failed to update gender")
+		}
+		if taken {
+			logger.LogWarning(ctx, "Gender update failed - name already exists", logrus.Fields{
 				"gender_id": id,
 				"name":      req.Name,
 			})
-			return nil, errors.New("gender with this name already exists")
+			return nil, ErrGenderNameTaken
 		}
 	}
 
 	// Update fields
 	gender.Name = req.Name
-	gender.UpdatedAt = time.Now().Unix()
+	gender.UpdatedAt = s.clock.Now().Unix()
 
-	if err := configs.DB.Save(&gender).Error; err != nil {
-		logger.LogError(err, "Failed to update gender", logrus.Fields{
+	if err := writeGender(genders, gender); err != nil {
+		if errors.Is(err, ErrGenderVersionConflict) || errors.Is(err, ErrGenderNotFound) {
+			return nil, err
+		}
+		logger.LogError(ctx, err, "Failed to update gender", logrus.Fields{
 			"gender_id": id,
 		})
 		return nil, errors.New("failed to update gender")
 	}
 
-	logger.LogInfo("Gender updated successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Gender updated successfully", logrus.Fields{
 		"gender_id": fmt.Sprintf("%d", gender.ID),
 		"name":      gender.Name,
 	})
 
-	return &gender, nil
+	return gender, nil
 }
 
-func (s *GenderService) DeleteGender(id uint) error {
-	logger.LogInfo("Deleting gender", logrus.Fields{
+func (s *GenderService) DeleteGender(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "GenderService.DeleteGender")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Deleting gender", logrus.Fields{
 		"gender_id": id,
 	})
 
-	var gender models.Gender
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&gender).Error; err != nil {
+	genders := s.genders.WithContext(ctx)
+
+	gender, err := genders.FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Gender not found for deletion", logrus.Fields{
+			logger.LogWarning(ctx, "Gender not found for deletion", logrus.Fields{
 				"gender_id": id,
 			})
-			return errors.New("gender not found")
+			return ErrGenderNotFound
 		}
-		logger.LogError(err, "Failed to find gender for deletion", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find gender for deletion", logrus.Fields{
 			"gender_id": id,
 		})
 		return errors.New("failed to find gender")
 	}
 
 	// Soft delete
-	deleteTime := time.Now().Unix()
+	deleteTime := s.clock.Now().Unix()
 	gender.DeletedAt = &deleteTime
 
-	if err := configs.DB.Save(&gender).Error; err != nil {
-		logger.LogError(err, "Failed to delete gender", logrus.Fields{
+	if err := writeGender(genders, gender); err != nil {
+		if errors.Is(err, ErrGenderVersionConflict) || errors.Is(err, ErrGenderNotFound) {
+			return err
+		}
+		logger.LogError(ctx, err, "Failed to delete gender", logrus.Fields{
 			"gender_id": id,
 		})
 		return errors.New("failed to delete gender")
 	}
 
-	logger.LogInfo("Gender deleted successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Gender deleted successfully", logrus.Fields{
 		"gender_id": id,
 	})
 
 	return nil
 }
+
+// writeGender saves gender over the version it was read at; when someone else saved it
+// first the conflict carries their copy
+func writeGender(genders repositories.GenderRepository, gender *models.Gender) error {
+	baseVersion := gender.Version
+	gender.Version++
+	updated, err := genders.UpdateIfVersion(gender, baseVersion)
+	if err != nil || updated {
+		return err
+	}
+	current, err := genders.FindByID(gender.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrGenderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrGenderVersionConflict.WithCurrent(current)
+}
@@ -0,0 +1,171 @@
+package services
+
+import "easy-attend-service/utils/listquery"
+
+// What each collection endpoint may be filtered and sorted by. Field names are the
+// JSON names clients see; filters match exactly, comma separated values match any.
+
+var TeacherListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":         {Kind: listquery.Int, Sort: true},
+		"school_id":  {Kind: listquery.Int, Filter: true},
+		"email":      {Filter: true, Sort: true},
+		"firstname":  {Column: "first_name", Filter: true, Sort: true},
+		"lastname":   {Column: "last_name", Filter: true, Sort: true},
+		"language":   {Filter: true},
+		"created_at": {Kind: listquery.Int, Sort: true},
+	},
+	Key:          []string{"id"},
+	DefaultSort:  "id",
+	DefaultLimit: 10,
+}
+
+// StudentListing qualifies its columns because the teacher's students are found
+// through a join with classrooms
+var StudentListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":           {Column: "students.id", Kind: listquery.Int, Sort: true},
+		"classroom_id": {Column: "students.classroom_id", Kind: listquery.Int, Filter: true, Sort: true},
+		"school_id":    {Column: "students.school_id", Kind: listquery.Int, Filter: true},
+		"gender_id":    {Column: "students.gender_id", Kind: listquery.Int, Filter: true},
+		"prefix_id":    {Column: "students.prefix_id", Kind: listquery.Int, Filter: true},
+		"student_no":   {Column: "students.student_no", Filter: true, Sort: true},
+		"firstname":    {Column: "students.first_name", Filter: true, Sort: true},
+		"lastname":     {Column: "students.last_name", Filter: true, Sort: true},
+		"created_at":   {Column: "students.created_at", Kind: listquery.Int, Sort: true},
+	},
+	Key:          []string{"id"},
+	DefaultSort:  "id",
+	DefaultLimit: 10,
+}
+
+var SchoolListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":         {Kind: listquery.Int, Sort: true},
+		"name":       {Filter: true, Sort: true},
+		"created_at": {Kind: listquery.Int, Sort: true},
+	},
+	Key:          []string{"id"},
+	DefaultSort:  "id",
+	DefaultLimit: 10,
+}
+
+// AcademicYearListing lists the latest year first
+var AcademicYearListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":         {Kind: listquery.Int, Sort: true},
+		"school_id":  {Kind: listquery.Int, Filter: true},
+		"name":       {Filter: true, Sort: true},
+		"start_date": {Sort: true},
+	},
+	Key:         []string{"id"},
+	DefaultSort: "-start_date",
+}
+
+// TermListing lists the terms of one academic year in order
+var TermListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":         {Kind: listquery.Int, Sort: true},
+		"number":     {Kind: listquery.Int, Filter: true, Sort: true},
+		"start_date": {Sort: true},
+	},
+	Key:         []string{"id"},
+	DefaultSort: "number",
+}
+
+var GenderListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":   {Kind: listquery.Int, Sort: true},
+		"name": {Filter: true, Sort: true},
+	},
+	Key:         []string{"id"},
+	DefaultSort: "id",
+}
+
+var PrefixListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":   {Kind: listquery.Int, Sort: true},
+		"name": {Filter: true, Sort: true},
+	},
+	Key:         []string{"id"},
+	DefaultSort: "id",
+}
+
+var ClassroomListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":               {Kind: listquery.Int, Sort: true},
+		"school_id":        {Kind: listquery.Int, Filter: true},
+		"academic_year_id": {Kind: listquery.Int, Filter: true},
+		"name":             {Filter: true, Sort: true},
+		"grade":            {Filter: true, Sort: true},
+		"created_at":       {Kind: listquery.Int, Sort: true},
+	},
+	Key:         []string{"id"},
+	DefaultSort: "id",
+}
+
+// ClassroomMemberListing has no id to break ties with; a member is identified by
+// its classroom and its teacher or student, one of which is NULL
+var ClassroomMemberListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"classroom_id": {Kind: listquery.Int, Filter: true, Sort: true},
+		"teacher_id":   {Column: "COALESCE(teacher_id, 0)", Kind: listquery.Int, Filter: true, Sort: true},
+		"student_id":   {Column: "COALESCE(student_id, 0)", Kind: listquery.Int, Filter: true, Sort: true},
+	},
+	Key:          []string{"classroom_id", "teacher_id", "student_id"},
+	DefaultSort:  "classroom_id",
+	DefaultLimit: 50,
+}
+
+var attendanceFields = map[string]listquery.Field{
+	"id":           {Kind: listquery.Int, Sort: true},
+	"classroom_id": {Kind: listquery.Int, Filter: true},
+	"student_id":   {Kind: listquery.Int, Filter: true, Sort: true},
+	"teacher_id":   {Kind: listquery.Int, Filter: true},
+	"term_id":      {Kind: listquery.Int, Filter: true},
+	"status":       {Filter: true, Sort: true},
+	"session_date": {Filter: true, Sort: true},
+	"checked_at":   {Kind: listquery.Int, Sort: true},
+	"created_at":   {Kind: listquery.Int, Sort: true},
+}
+
+// AttendanceListing lists one classroom's or one student's attendances, latest session first
+var AttendanceListing = listquery.Resource{
+	Fields:       attendanceFields,
+	Key:          []string{"id"},
+	DefaultSort:  "-session_date,-created_at",
+	DefaultLimit: 50,
+}
+
+// TeacherAttendanceListing lists the attendances a teacher recorded, latest check first
+var TeacherAttendanceListing = listquery.Resource{
+	Fields:       attendanceFields,
+	Key:          []string{"id"},
+	DefaultSort:  "-checked_at",
+	DefaultLimit: 50,
+}
+
+// TrashListing pages one kind of deleted rows, most recently deleted first
+var TrashListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":         {Kind: listquery.Int, Sort: true},
+		"deleted_at": {Kind: listquery.Int, Sort: true},
+	},
+	Key:          []string{"id"},
+	DefaultSort:  "-deleted_at",
+	DefaultLimit: 50,
+}
+
+var LogListing = listquery.Resource{
+	Fields: map[string]listquery.Field{
+		"id":         {Kind: listquery.Int, Sort: true},
+		"teacher_id": {Kind: listquery.Int, Filter: true},
+		"school_id":  {Kind: listquery.Int, Filter: true},
+		"action":     {Filter: true},
+		"request_id": {Filter: true},
+		"created_at": {Kind: listquery.Int, Sort: true},
+	},
+	Key:          []string{"id"},
+	DefaultSort:  "-created_at",
+	DefaultLimit: 50,
+}
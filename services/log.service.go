@@ -1,98 +1,140 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/requestid"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
-	"time"
+	"strconv"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-type LogService struct{}
+type LogService struct {
+	logs  repositories.LogRepository
+	clock clock.Clock
+}
 
-func NewLogService() *LogService {
-	return &LogService{}
+func NewLogService(logs repositories.LogRepository, clk clock.Clock) *LogService {
+	return &LogService{
+		logs:  logs,
+		clock: clk,
+	}
 }
 
-// GetAllLogs - อ่านข้อมูล log ทั้งหมด (เรียงตามเวลาล่าสุดก่อน)
-func (s *LogService) GetAllLogs() ([]models.Log, error) {
-	logger.LogInfo("Fetching all logs", logrus.Fields{})
+// GetAllLogs - อ่านข้อมูล log ทีละหน้า (ค่าเริ่มต้นเรียงตามเวลาล่าสุดก่อน)
+func (s *LogService) GetAllLogs(ctx context.Context, query listquery.Query) ([]models.Log, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "LogService.GetAllLogs")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching all logs", logrus.Fields{})
 
-	var logs []models.Log
-	if err := configs.DB.Order("created_at DESC").Find(&logs).Error; err != nil {
-		logger.LogError(err, "Failed to fetch logs", logrus.Fields{})
-		return nil, errors.New("failed to fetch logs")
+	logs, err := s.logs.WithContext(ctx).List(query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch logs", logrus.Fields{})
+		return nil, listquery.Pagination{}, errors.New("failed to fetch logs")
 	}
 
-	logger.LogInfo("Successfully fetched logs", logrus.Fields{
+	logs, meta := listquery.Page(query, logs)
+	logger.LogInfo(ctx, "Successfully fetched logs", logrus.Fields{
 		"count": len(logs),
 	})
 
-	return logs, nil
+	localizeLogs(ctx, logs)
+	return logs, meta, nil
 }
 
 // GetLogByID - อ่านข้อมูล log ตาม ID
-func (s *LogService) GetLogByID(id uint) (*models.Log, error) {
-	logger.LogInfo("Fetching log by ID", logrus.Fields{
+func (s *LogService) GetLogByID(ctx context.Context, id uint) (*models.Log, error) {
+	ctx, span := tracing.Start(ctx, "LogService.GetLogByID")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching log by ID", logrus.Fields{
 		"log_id": fmt.Sprintf("%d", id),
 	})
 
-	var log models.Log
-	if err := configs.DB.Where("id = ?", id).First(&log).Error; err != nil {
+	log, err := s.logs.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Log not found", logrus.Fields{
+			logger.LogWarning(ctx, "Log not found", logrus.Fields{
 				"log_id": fmt.Sprintf("%d", id),
 			})
-			return nil, errors.New("log not found")
+			return nil, ErrLogNotFound
 		}
-		logger.LogError(err, "Failed to fetch log", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to fetch log", logrus.Fields{
 			"log_id": fmt.Sprintf("%d", id),
 		})
 		return nil, errors.New("failed to fetch log")
 	}
 
-	return &log, nil
+	localizeLog(ctx, log)
+	return log, nil
 }
 
-func (s *LogService) GetLogsByTeacher(teacherID string) ([]models.Log, error) {
-	logger.LogInfo("Fetching logs by teacher", logrus.Fields{
+func (s *LogService) GetLogsByTeacher(ctx context.Context, teacherID string, query listquery.Query) ([]models.Log, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "LogService.GetLogsByTeacher")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching logs by teacher", logrus.Fields{
 		"teacher_id": teacherID,
 	})
 
-	var logs []models.Log
-	if err := configs.DB.Where("teacher_id = ?", teacherID).Order("created_at DESC").Find(&logs).Error; err != nil {
-		logger.LogError(err, "Failed to fetch logs by teacher", logrus.Fields{
+	id, err := strconv.ParseUint(teacherID, 10, 64)
+	if err != nil {
+		logger.LogWarning(ctx, "Invalid teacher ID for logs", logrus.Fields{
+			"teacher_id": teacherID,
+		})
+		return nil, listquery.Pagination{}, errors.New("failed to fetch logs")
+	}
+
+	logs, err := s.logs.WithContext(ctx).ListByTeacher(uint(id), query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch logs by teacher", logrus.Fields{
 			"teacher_id": teacherID,
 		})
-		return nil, errors.New("failed to fetch logs")
+		return nil, listquery.Pagination{}, errors.New("failed to fetch logs")
 	}
 
-	return logs, nil
+	logs, meta := listquery.Page(query, logs)
+	localizeLogs(ctx, logs)
+	return logs, meta, nil
 }
 
-func (s *LogService) GetLogsByAction(action models.LogAction) ([]models.Log, error) {
-	logger.LogInfo("Fetching logs by action", logrus.Fields{
+func (s *LogService) GetLogsByAction(ctx context.Context, action models.LogAction, query listquery.Query) ([]models.Log, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "LogService.GetLogsByAction")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching logs by action", logrus.Fields{
 		"action": string(action),
 	})
 
-	var logs []models.Log
-	if err := configs.DB.Where("action = ?", action).Order("created_at DESC").Find(&logs).Error; err != nil {
-		logger.LogError(err, "Failed to fetch logs by action", logrus.Fields{
+	logs, err := s.logs.WithContext(ctx).ListByAction(action, query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch logs by action", logrus.Fields{
 			"action": string(action),
 		})
-		return nil, errors.New("failed to fetch logs")
+		return nil, listquery.Pagination{}, errors.New("failed to fetch logs")
 	}
 
-	return logs, nil
+	logs, meta := listquery.Page(query, logs)
+	localizeLogs(ctx, logs)
+	return logs, meta, nil
 }
 
-func (s *LogService) CreateLog(req *requests.LogCreateRequest) (*models.Log, error) {
-	logger.LogInfo("Creating new log", logrus.Fields{
+func (s *LogService) CreateLog(ctx context.Context, req *requests.LogCreateRequest) (*models.Log, error) {
+	ctx, span := tracing.Start(ctx, "LogService.CreateLog")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Creating new log", logrus.Fields{
 		"teacher_id": fmt.Sprintf("%d", req.TeacherID),
 		"action":     string(req.Action),
 		"detail":     req.Detail,
@@ -103,18 +145,19 @@ func (s *LogService) CreateLog(req *requests.LogCreateRequest) (*models.Log, err
 		TeacherID: req.TeacherID,
 		Action:    req.Action,
 		Detail:    req.Detail,
-		CreatedAt: time.Now().Unix(),
+		CreatedAt: s.clock.Now().Unix(),
+		RequestID: requestid.Ptr(ctx),
 	}
 
-	if err := configs.DB.Create(&log).Error; err != nil {
-		logger.LogError(err, "Failed to create log", logrus.Fields{
+	if err := s.logs.WithContext(ctx).Create(&log); err != nil {
+		logger.LogError(ctx, err, "Failed to create log", logrus.Fields{
 			"teacher_id": fmt.Sprintf("%d", req.TeacherID),
 			"action":     string(req.Action),
 		})
 		return nil, errors.New("failed to create log")
 	}
 
-	logger.LogInfo("Log created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Log created successfully", logrus.Fields{
 		"log_id":     fmt.Sprintf("%d", log.ID),
 		"teacher_id": fmt.Sprintf("%d", log.TeacherID),
 		"action":     string(log.Action),
@@ -122,3 +165,17 @@ func (s *LogService) CreateLog(req *requests.LogCreateRequest) (*models.Log, err
 
 	return &log, nil
 }
+
+// localizeLog renders Detail in the request language from the stored message key.
+// Entries created through CreateLog have no key and keep their free-text detail.
+func localizeLog(ctx context.Context, log *models.Log) {
+	if log.MessageKey != "" {
+		log.Detail = i18n.T(ctx, log.MessageKey, log.MessageParams)
+	}
+}
+
+func localizeLogs(ctx context.Context, logs []models.Log) {
+	for i := range logs {
+		localizeLog(ctx, &logs[i])
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"easy-attend-service/models"
+	"easy-attend-service/requests"
+	"easy-attend-service/utils/apperror"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLogsAreListedNewestFirst(t *testing.T) {
+	env := newTestEnv()
+
+	first, err := env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 1, Action: models.LogActionLogin})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if first.CreatedAt != env.clock.Now().Unix() {
+		t.Errorf("created_at = %d, want the clock time", first.CreatedAt)
+	}
+	env.clock.Advance(time.Minute)
+	env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 1, Action: models.LogActionLogout})
+	env.clock.Advance(time.Minute)
+	env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 2, Action: models.LogActionLogin})
+
+	logs, _, err := env.log.GetLogsByTeacher(t.Context(), "1", list(t, LogListing, ""))
+	if err != nil {
+		t.Fatalf("by teacher: %v", err)
+	}
+	if len(logs) != 2 || logs[0].Action != models.LogActionLogout {
+		t.Errorf("logs = %+v", logs)
+	}
+
+	logins, _, _ := env.log.GetLogsByAction(t.Context(), models.LogActionLogin, list(t, LogListing, ""))
+	if len(logins) != 2 || logins[0].TeacherID != 2 {
+		t.Errorf("logins = %+v", logins)
+	}
+}
+
+func TestGetLogsByTeacherRejectsInvalidID(t *testing.T) {
+	env := newTestEnv()
+
+	if _, _, err := env.log.GetLogsByTeacher(t.Context(), "abc", list(t, LogListing, "")); err == nil || err.Error() != "failed to fetch logs" {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := env.log.GetLogByID(t.Context(), 42); err == nil || err.Error() != "log not found" {
+		t.Errorf("missing log error = %v", err)
+	}
+}
+
+func TestLogsAreRenderedInTheReadersLanguage(t *testing.T) {
+	env := newTestEnv()
+	id := env.store.id()
+	env.store.logs[id] = models.Log{
+		ID:            id,
+		TeacherID:     1,
+		Action:        models.LogActionCreateClassroom,
+		Detail:        "สร้างห้องเรียนใหม่: ม.1/1",
+		MessageKey:    "log.classroom_created",
+		MessageParams: models.MessageParams{"name": "ม.1/1"},
+	}
+	env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 1, Action: models.LogActionLogin, Detail: "free text"})
+
+	ctx := i18n.NewContext(t.Context(), i18n.English)
+	log, err := env.log.GetLogByID(ctx, id)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if log.Detail != "Created classroom: ม.1/1" {
+		t.Errorf("detail = %q", log.Detail)
+	}
+
+	logs, _, _ := env.log.GetLogsByTeacher(ctx, "1", list(t, LogListing, ""))
+	if len(logs) != 2 {
+		t.Fatalf("logs = %+v", logs)
+	}
+	for _, l := range logs {
+		if l.MessageKey == "" && l.Detail != "free text" {
+			t.Errorf("manual entry detail = %q, want it unchanged", l.Detail)
+		}
+	}
+}
+
+func TestLogsArePagedByCursor(t *testing.T) {
+	env := newTestEnv()
+	// Five entries in the same second: the id breaks the tie so no page repeats or skips one
+	for range 5 {
+		env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 1, Action: models.LogActionLogin})
+	}
+	env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 1, Action: models.LogActionLogout})
+
+	var seen []uint
+	raw := "limit=2&filter[action]=login"
+	for page := 1; ; page++ {
+		logs, meta, err := env.log.GetAllLogs(t.Context(), list(t, LogListing, raw))
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		for _, l := range logs {
+			seen = append(seen, l.ID)
+		}
+		if !meta.HasMore {
+			if meta.NextCursor != nil || page != 3 {
+				t.Errorf("last page %d has cursor %v", page, meta.NextCursor)
+			}
+			break
+		}
+		raw = "limit=2&filter[action]=login&cursor=" + *meta.NextCursor
+	}
+	if !slices.Equal(seen, []uint{5, 4, 3, 2, 1}) {
+		t.Errorf("ids = %v, want every login newest first", seen)
+	}
+
+	empty, meta, _ := env.log.GetLogsByTeacher(t.Context(), "9", list(t, LogListing, ""))
+	if empty == nil || len(empty) != 0 || meta.Limit != 50 || meta.HasMore {
+		t.Errorf("empty page = %v, meta = %+v", empty, meta)
+	}
+}
+
+func TestListQueryRejectsWhatIsNotWhitelisted(t *testing.T) {
+	env := newTestEnv()
+	env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 1, Action: models.LogActionLogin})
+	env.log.CreateLog(t.Context(), &requests.LogCreateRequest{TeacherID: 1, Action: models.LogActionLogin})
+	_, meta, _ := env.log.GetAllLogs(t.Context(), list(t, LogListing, "limit=1"))
+
+	for raw, code := range map[string]string{
+		"filter[detail]=x":                   "list.invalid_filter",
+		"filter[teacher_id]=abc":             "list.invalid_filter_value",
+		"sort=action":                        "list.invalid_sort",
+		"sort=id,-id":                        "list.invalid_sort",
+		"limit=0":                            "list.invalid_limit",
+		"limit=101":                          "list.invalid_limit",
+		"cursor=not-a-cursor":                "list.invalid_cursor",
+		"sort=id&cursor=" + *meta.NextCursor: "list.invalid_cursor", // issued for another sort
+	} {
+		values, _ := url.ParseQuery(raw)
+		_, err := listquery.Parse(values, LogListing)
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) || appErr.Code != code {
+			t.Errorf("%s: error = %v, want %s", raw, err, code)
+		}
+	}
+}
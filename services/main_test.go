@@ -0,0 +1,17 @@
+package services
+
+import (
+	"easy-attend-service/configs"
+	"easy-attend-service/utils/logger"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Services log through the global logger; keep test output readable
+	logger.InitLogger(configs.Default().Log)
+	logger.Log.SetOutput(io.Discard)
+
+	os.Exit(m.Run())
+}
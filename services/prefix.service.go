@@ -1,85 +1,111 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/listquery"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
-	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-type PrefixService struct{}
+type PrefixService struct {
+	prefixes repositories.PrefixRepository
+	clock    clock.Clock
+}
 
-func NewPrefixService() *PrefixService {
-	return &PrefixService{}
+func NewPrefixService(prefixes repositories.PrefixRepository, clk clock.Clock) *PrefixService {
+	return &PrefixService{prefixes: prefixes, clock: clk}
 }
 
-func (s *PrefixService) GetAllPrefixes() ([]models.Prefix, error) {
-	logger.LogInfo("Fetching all prefixes", nil)
+func (s *PrefixService) GetAllPrefixes(ctx context.Context, query listquery.Query) ([]models.Prefix, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "PrefixService.GetAllPrefixes")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching all prefixes", nil)
 
-	var prefixes []models.Prefix
-	if err := configs.DB.Where("deleted_at IS NULL").Find(&prefixes).Error; err != nil {
-		logger.LogError(err, "Failed to fetch prefixes", nil)
-		return nil, errors.New("failed to fetch prefixes")
+	prefixes, err := s.prefixes.WithContext(ctx).List(query)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch prefixes", nil)
+		return nil, listquery.Pagination{}, errors.New("failed to fetch prefixes")
 	}
 
-	logger.LogInfo("Prefixes fetched successfully", logrus.Fields{
+	prefixes, meta := listquery.Page(query, prefixes)
+	logger.LogInfo(ctx, "Prefixes fetched successfully", logrus.Fields{
 		"count": len(prefixes),
 	})
 
-	return prefixes, nil
+	return prefixes, meta, nil
 }
 
-func (s *PrefixService) GetPrefixByID(id uint) (*models.Prefix, error) {
-	logger.LogInfo("Fetching prefix by ID", logrus.Fields{
+func (s *PrefixService) GetPrefixByID(ctx context.Context, id uint) (*models.Prefix, error) {
+	ctx, span := tracing.Start(ctx, "PrefixService.GetPrefixByID")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Fetching prefix by ID", logrus.Fields{
 		"prefix_id": fmt.Sprintf("%d", id),
 	})
 
-	var prefix models.Prefix
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&prefix).Error; err != nil {
-		logger.LogWarning("Prefix not found", logrus.Fields{
+	prefix, err := s.prefixes.WithContext(ctx).FindByID(id)
+	if err != nil {
+		logger.LogWarning(ctx, "Prefix not found", logrus.Fields{
 			"prefix_id": id,
 		})
-		return nil, errors.New("prefix not found")
+		return nil, ErrPrefixNotFound
 	}
 
-	return &prefix, nil
+	return prefix, nil
 }
 
-func (s *PrefixService) CreatePrefix(req *requests.PrefixCreateRequest) (*models.Prefix, error) {
-	logger.LogInfo("Creating new prefix", logrus.Fields{
+func (s *PrefixService) CreatePrefix(ctx context.Context, req *requests.PrefixCreateRequest) (*models.Prefix, error) {
+	ctx, span := tracing.Start(ctx, "PrefixService.CreatePrefix")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Creating new prefix", logrus.Fields{
 		"name": req.Name,
 	})
 
+	prefixes := s.prefixes.WithContext(ctx)
+
 	// Check if prefix already exists
-	var existingPrefix models.Prefix
-	if err := configs.DB.Where("name = ? AND deleted_at IS NULL", req.Name).First(&existingPrefix).Error; err == nil {
-		logger.LogWarning("Prefix creation failed - name already exists", logrus.Fields{
+	taken, err := prefixes.NameTaken(req.Name, 0)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to check prefix name", logrus.Fields{
+			"name": req.Name,
+		})
+		return nil, errors.New("failed to create prefix")
+	}
+	if taken {
+		logger.LogWarning(ctx, "Prefix creation failed - name already exists", logrus.Fields{
 			"name": req.Name,
 		})
-		return nil, errors.New("prefix with this name already exists")
+		return nil, ErrPrefixNameTaken
 	}
 
 	// Create new prefix
+	now := s.clock.Now().Unix()
 	prefix := models.Prefix{
 		Name:      req.Name,
-		CreatedAt: time.Now().Unix(),
-		UpdatedAt: time.Now().Unix(),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
-	if err := configs.DB.Create(&prefix).Error; err != nil {
-		logger.LogError(err, "Failed to create prefix", logrus.Fields{
+	if err := prefixes.Create(&prefix); err != nil {
+		logger.LogError(ctx, err, "Failed to create prefix", logrus.Fields{
 			"name": req.Name,
 		})
 		return nil, errors.New("failed to create prefix")
 	}
 
-	logger.LogInfo("Prefix created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Prefix created successfully", logrus.Fields{
 		"prefix_id": fmt.Sprintf("%d", prefix.ID),
 		"name":      prefix.Name,
 	})
@@ -87,90 +113,135 @@ func (s *PrefixService) CreatePrefix(req *requests.PrefixCreateRequest) (*models
 	return &prefix, nil
 }
 
-func (s *PrefixService) UpdatePrefix(id uint, req *requests.PrefixUpdateRequest) (*models.Prefix, error) {
-	logger.LogInfo("Updating prefix", logrus.Fields{
+func (s *PrefixService) UpdatePrefix(ctx context.Context, id uint, req *requests.PrefixUpdateRequest) (*models.Prefix, error) {
+	ctx, span := tracing.Start(ctx, "PrefixService.UpdatePrefix")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Updating prefix", logrus.Fields{
 		"prefix_id": id,
 		"name":      req.Name,
 	})
 
-	var prefix models.Prefix
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&prefix).Error; err != nil {
+	prefixes := s.prefixes.WithContext(ctx)
+
+	prefix, err := prefixes.FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Prefix not found for update", logrus.Fields{
+			logger.LogWarning(ctx, "Prefix not found for update", logrus.Fields{
 				"prefix_id": id,
 			})
-			return nil, errors.New("prefix not found")
+			return nil, ErrPrefixNotFound
 		}
-		logger.LogError(err, "Failed to find prefix for update", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find prefix for update", logrus.Fields{
 			"prefix_id": id,
 		})
 		return nil, errors.New("failed to find prefix")
 	}
 
+	if err := etag.CheckIfMatch(ctx, prefix); err != nil {
+		return nil, err
+	}
+
 	// Check if name is being changed and if it already exists
 	if req.Name != prefix.Name {
-		var existingPrefix models.Prefix
-		if err := configs.DB.Where("name = ? AND id != ? AND deleted_at IS NULL", req.Name, id).First(&existingPrefix).Error; err == nil {
-			logger.LogWarning("Prefix update failed - name already exists", logrus.Fields{
+		taken, err := prefixes.NameTaken(req.Name, id)
+		if err != nil {
+			logger.LogError(ctx, err, "Failed to check prefix name", logrus.Fields{
+				"prefix_id": id,
+			})
+			return nil, errors.New("failed to update prefix")
+		}
+		if taken {
+			logger.LogWarning(ctx, "Prefix update failed - name already exists", logrus.Fields{
 				"prefix_id": id,
 				"name":      req.Name,
 			})
-			return nil, errors.New("prefix with this name already exists")
+			return nil, ErrPrefixNameTaken
 		}
 	}
 
 	// Update fields
 	prefix.Name = req.Name
-	prefix.UpdatedAt = time.Now().Unix()
+	prefix.UpdatedAt = s.clock.Now().Unix()
 
-	if err := configs.DB.Save(&prefix).Error; err != nil {
-		logger.LogError(err, "Failed to update prefix", logrus.Fields{
+	if err := writePrefix(prefixes, prefix); err != nil {
+		if errors.Is(err, ErrPrefixVersionConflict) || errors.Is(err, ErrPrefixNotFound) {
+			return nil, err
+		}
+		logger.LogError(ctx, err, "Failed to update prefix", logrus.Fields{
 			"prefix_id": id,
 		})
 		return nil, errors.New("failed to update prefix")
 	}
 
-	logger.LogInfo("Prefix updated successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Prefix updated successfully", logrus.Fields{
 		"prefix_id": fmt.Sprintf("%d", prefix.ID),
 		"name":      prefix.Name,
 	})
 
-	return &prefix, nil
+	return prefix, nil
 }
 
-func (s *PrefixService) DeletePrefix(id uint) error {
-	logger.LogInfo("Deleting prefix", logrus.Fields{
+func (s *PrefixService) DeletePrefix(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "PrefixService.DeletePrefix")
+	defer span.End()
+
+	logger.LogInfo(ctx, "Deleting prefix", logrus.Fields{
 		"prefix_id": id,
 	})
 
-	var prefix models.Prefix
-	if err := configs.DB.Where("id = ? AND deleted_at IS NULL", id).First(&prefix).Error; err != nil {
+	prefixes := s.prefixes.WithContext(ctx)
+
+	prefix, err := prefixes.FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogWarning("Prefix not found for deletion", logrus.Fields{
+			logger.LogWarning(ctx, "Prefix not found for deletion", logrus.Fields{
 				"prefix_id": id,
 			})
-			return errors.New("prefix not found")
+			return ErrPrefixNotFound
 		}
-		logger.LogError(err, "Failed to find prefix for deletion", logrus.Fields{
+		logger.LogError(ctx, err, "Failed to find prefix for deletion", logrus.Fields{
 			"prefix_id": id,
 		})
 		return errors.New("failed to find prefix")
 	}
 
 	// Soft delete
-	deleteTime := time.Now().Unix()
+	deleteTime := s.clock.Now().Unix()
 	prefix.DeletedAt = &deleteTime
 
-	if err := configs.DB.Save(&prefix).Error; err != nil {
-		logger.LogError(err, "Failed to delete prefix", logrus.Fields{
+	if err := writePrefix(prefixes, prefix); err != nil {
+		if errors.Is(err, ErrPrefixVersionConflict) || errors.Is(err, ErrPrefixNotFound) {
+			return err
+		}
+		logger.LogError(ctx, err, "Failed to delete prefix", logrus.Fields{
 			"prefix_id": id,
 		})
 		return errors.New("failed to delete prefix")
 	}
 
-	logger.LogInfo("Prefix deleted successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Prefix deleted successfully", logrus.Fields{
 		"prefix_id": id,
 	})
 
 	return nil
 }
+
+// writePrefix saves prefix over the version it was read at; when someone else saved it
+// first the conflict carries their copy
+func writePrefix(prefixes repositories.PrefixRepository, prefix *models.Prefix) error {
+	baseVersion := prefix.Version
+	prefix.Version++
+	updated, err := prefixes.UpdateIfVersion(prefix, baseVersion)
+	if err != nil || updated {
+		return err
+	}
+	current, err := prefixes.FindByID(prefix.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPrefixNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return ErrPrefixVersionConflict.WithCurrent(current)
+}
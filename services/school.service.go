@@ -1,106 +1,165 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/requests"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/tracing"
 	"errors"
 
 	"gorm.io/gorm"
 )
 
-type SchoolService struct{}
-
-func NewSchoolService() *SchoolService {
-	return &SchoolService{}
+type SchoolService struct {
+	schools repositories.SchoolRepository
 }
 
-func (s *SchoolService) GetAllSchools(page, limit int) ([]models.School, int64, error) {
-	var schools []models.School
-	var total int64
-
-	// Count total records
-	if err := configs.DB.Model(&models.School{}).Count(&total).Error; err != nil {
-		return nil, 0, errors.New("failed to count schools")
-	}
+func NewSchoolService(schools repositories.SchoolRepository) *SchoolService {
+	return &SchoolService{schools: schools}
+}
 
-	// Calculate offset
-	offset := (page - 1) * limit
+func (s *SchoolService) GetAllSchools(ctx context.Context, query listquery.Query) ([]models.School, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "SchoolService.GetAllSchools")
+	defer span.End()
 
-	// Get schools with pagination
-	if err := configs.DB.Offset(offset).Limit(limit).Find(&schools).Error; err != nil {
-		return nil, 0, errors.New("failed to get schools")
+	schools, err := s.schools.WithContext(ctx).List(query)
+	if err != nil {
+		return nil, listquery.Pagination{}, errors.New("failed to get schools")
 	}
 
-	return schools, total, nil
+	schools, meta := listquery.Page(query, schools)
+	return schools, meta, nil
 }
 
-func (s *SchoolService) GetSchoolByID(id uint) (*models.School, error) {
-	var school models.School
-	if err := configs.DB.Where("id = ?", id).First(&school).Error; err != nil {
+func (s *SchoolService) GetSchoolByID(ctx context.Context, id uint) (*models.School, error) {
+	ctx, span := tracing.Start(ctx, "SchoolService.GetSchoolByID")
+	defer span.End()
+
+	school, err := s.schools.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("school not found")
+			return nil, ErrSchoolNotFound
 		}
 		return nil, errors.New("failed to get school")
 	}
-	return &school, nil
+	return school, nil
 }
 
-func (s *SchoolService) CreateSchool(name string) (*models.School, error) {
+func (s *SchoolService) CreateSchool(ctx context.Context, name string) (*models.School, error) {
+	ctx, span := tracing.Start(ctx, "SchoolService.CreateSchool")
+	defer span.End()
+
+	schools := s.schools.WithContext(ctx)
+
 	// Check if school already exists
-	var existingSchool models.School
-	if err := configs.DB.Where("name = ?", name).First(&existingSchool).Error; err == nil {
-		return nil, errors.New("school with this name already exists")
+	taken, err := schools.NameTaken(name, 0)
+	if err != nil {
+		return nil, errors.New("failed to check school name")
+	}
+	if taken {
+		return nil, ErrSchoolNameTaken
 	}
 
 	// Create new school
 	school := models.School{
-		Name: name,
+		Name:               name,
+		SyncConflictPolicy: models.SyncConflictServerWins,
+		StudentNoPrefix:    "STD",
+		StudentNoWidth:     3,
+		StudentNoYear:      models.StudentNoYearNone,
 	}
 
-	if err := configs.DB.Create(&school).Error; err != nil {
+	if err := schools.Create(&school); err != nil {
 		return nil, errors.New("failed to create school")
 	}
 
 	return &school, nil
 }
 
-func (s *SchoolService) UpdateSchool(id uint, name string) (*models.School, error) {
-	var school models.School
-	if err := configs.DB.Where("id = ?", id).First(&school).Error; err != nil {
+func (s *SchoolService) UpdateSchool(ctx context.Context, id uint, req *requests.SchoolUpdateRequest) (*models.School, error) {
+	ctx, span := tracing.Start(ctx, "SchoolService.UpdateSchool")
+	defer span.End()
+
+	name := req.Name
+	schools := s.schools.WithContext(ctx)
+
+	school, err := schools.FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("school not found")
+			return nil, ErrSchoolNotFound
 		}
 		return nil, errors.New("failed to find school")
 	}
 
+	if err := etag.CheckIfMatch(ctx, school); err != nil {
+		return nil, err
+	}
+
 	// Check if name is being changed and if it already exists
 	if name != school.Name {
-		var existingSchool models.School
-		if err := configs.DB.Where("name = ? AND id != ?", name, id).First(&existingSchool).Error; err == nil {
-			return nil, errors.New("school with this name already exists")
+		taken, err := schools.NameTaken(name, id)
+		if err != nil {
+			return nil, errors.New("failed to check school name")
+		}
+		if taken {
+			return nil, ErrSchoolNameTaken
 		}
 	}
 
 	// Update fields
 	school.Name = name
+	if req.SyncConflictPolicy != "" {
+		school.SyncConflictPolicy = req.SyncConflictPolicy
+	}
+	if req.StudentNoPrefix != nil {
+		school.StudentNoPrefix = *req.StudentNoPrefix
+	}
+	if req.StudentNoWidth != nil {
+		school.StudentNoWidth = *req.StudentNoWidth
+	}
+	if req.StudentNoYear != "" {
+		school.StudentNoYear = req.StudentNoYear
+	}
+	baseVersion := school.Version
+	school.Version++
 
-	if err := configs.DB.Save(&school).Error; err != nil {
+	updated, err := schools.UpdateIfVersion(school, baseVersion)
+	if err != nil {
 		return nil, errors.New("failed to update school")
 	}
+	if !updated {
+		// Someone else saved the school after it was read
+		current, err := schools.FindByID(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSchoolNotFound
+		}
+		if err != nil {
+			return nil, errors.New("failed to update school")
+		}
+		return nil, ErrSchoolVersionConflict.WithCurrent(current)
+	}
 
-	return &school, nil
+	return school, nil
 }
 
-func (s *SchoolService) DeleteSchool(id uint) error {
-	var school models.School
-	if err := configs.DB.Where("id = ?", id).First(&school).Error; err != nil {
+func (s *SchoolService) DeleteSchool(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "SchoolService.DeleteSchool")
+	defer span.End()
+
+	schools := s.schools.WithContext(ctx)
+
+	school, err := schools.FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("school not found")
+			return ErrSchoolNotFound
 		}
 		return errors.New("failed to find school")
 	}
 
-	if err := configs.DB.Delete(&school).Error; err != nil {
+	if err := schools.Delete(school); err != nil {
 		return errors.New("failed to delete school")
 	}
 
@@ -108,19 +167,18 @@ func (s *SchoolService) DeleteSchool(id uint) error {
 }
 
 // GetSchoolByTeacher gets the school information for a specific teacher
-func (s *SchoolService) GetSchoolByTeacher(teacherID uint) (*models.School, error) {
-	var school models.School
+func (s *SchoolService) GetSchoolByTeacher(ctx context.Context, teacherID uint) (*models.School, error) {
+	ctx, span := tracing.Start(ctx, "SchoolService.GetSchoolByTeacher")
+	defer span.End()
 
 	// Get school through teacher relationship
-	if err := configs.DB.
-		Joins("JOIN teachers ON schools.id = teachers.school_id").
-		Where("teachers.id = ?", teacherID).
-		First(&school).Error; err != nil {
+	school, err := s.schools.WithContext(ctx).FindByTeacherID(teacherID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("school not found for this teacher")
+			return nil, ErrTeacherSchoolNotFound
 		}
 		return nil, errors.New("failed to get school by teacher")
 	}
 
-	return &school, nil
+	return school, nil
 }
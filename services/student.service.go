@@ -1,90 +1,176 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
 	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/tracing"
 	"errors"
 	"fmt"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
-type StudentService struct{}
+type StudentService struct {
+	students   repositories.StudentRepository
+	classrooms repositories.ClassroomRepository
+	teachers   repositories.TeacherRepository
+	schools    repositories.SchoolRepository
+	clock      clock.Clock
+}
 
-func NewStudentService() *StudentService {
-	return &StudentService{}
+func NewStudentService(students repositories.StudentRepository, classrooms repositories.ClassroomRepository,
+	teachers repositories.TeacherRepository, schools repositories.SchoolRepository, clk clock.Clock) *StudentService {
+	return &StudentService{
+		students:   students,
+		classrooms: classrooms,
+		teachers:   teachers,
+		schools:    schools,
+		clock:      clk,
+	}
+}
+
+// allocateStudentNo hands out the next student number of a classroom in its school's format.
+// The counter row stays locked until tx ends, so concurrent creates in the same classroom
+// queue up instead of computing the same number.
+func (s *StudentService) allocateStudentNo(ctx context.Context, tx repositories.StudentRepository, classroomID uint) (string, error) {
+	school, err := s.schools.WithContext(ctx).FindByClassroomID(classroomID)
+	if err != nil {
+		return "", err
+	}
+	return nextStudentNo(tx, school, classroomID, s.clock.Now())
 }
 
-// generateStudentNo creates a new student number per classroom in the format STD001, STD002, etc.
-func (s *StudentService) generateStudentNo(classroomID uint) (string, error) {
-	var lastStudent models.Student
+// studentNoCounter is the part of a repository that hands out student numbers
+type studentNoCounter interface {
+	HighestStudentNo(classroomID uint, stem string) (int64, error)
+	NextStudentNo(classroomID uint, period string, floor int64, now int64) (int64, error)
+}
 
-	// Find the student with the highest student_no in the specific classroom
-	err := configs.DB.Where("classroom_id = ? AND student_no LIKE 'STD%'", classroomID).
-		Order("CAST(SUBSTRING(student_no, 4) AS INTEGER) DESC").
-		First(&lastStudent).Error
+// nextStudentNo allocates the next number of a classroom from its counter, formatted for school
+func nextStudentNo(tx studentNoCounter, school *models.School, classroomID uint, now time.Time) (string, error) {
+	stem := school.StudentNoStem(now)
 
-	if err != nil && err != gorm.ErrRecordNotFound {
+	// Numbers typed in by hand or imported may already be ahead of the counter
+	highest, err := tx.HighestStudentNo(classroomID, stem)
+	if err != nil {
 		return "", err
 	}
 
-	// If no students exist in this classroom, start with STD001
-	if err == gorm.ErrRecordNotFound {
-		return "STD001", nil
+	next, err := tx.NextStudentNo(classroomID, school.StudentNoPeriod(now), highest+1, now.Unix())
+	if err != nil {
+		return "", err
+	}
+
+	return school.FormatStudentNo(next, now), nil
+}
+
+// findOrCreateSchool looks a school up by name and creates it when missing
+func (s *StudentService) findOrCreateSchool(ctx context.Context, name string) (*models.School, error) {
+	school, err := s.schools.WithContext(ctx).FindByName(name)
+	if err == nil {
+		return school, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.LogError(ctx, err, "Failed to find school", logrus.Fields{
+			"school_name": name,
+		})
+		return nil, errors.New("failed to find school")
+	}
+
+	logger.LogInfo(ctx, "Creating new school", logrus.Fields{
+		"school_name": name,
+	})
+	school = &models.School{Name: name}
+	if err := s.schools.WithContext(ctx).Create(school); err != nil {
+		logger.LogError(ctx, err, "Failed to create school", logrus.Fields{
+			"school_name": name,
+		})
+		return nil, errors.New("failed to create school")
 	}
+	logger.LogInfo(ctx, "School created successfully", logrus.Fields{
+		"school_id":   fmt.Sprintf("%d", school.ID),
+		"school_name": school.Name,
+	})
+	return school, nil
+}
 
-	// Extract the number part from the last student number
-	lastNo := lastStudent.StudentNo
-	if len(lastNo) < 4 || !strings.HasPrefix(lastNo, "STD") {
-		return "STD001", nil
+// findOrCreateTestClassroom returns the named classroom of a school, creating it and,
+// when the school has no teacher yet, a placeholder teacher
+func (s *StudentService) findOrCreateTestClassroom(ctx context.Context, school *models.School, name, grade string, placeholder models.Teacher) (*models.Classroom, error) {
+	classroom, err := s.classrooms.WithContext(ctx).FindByName(school.ID, name)
+	if err == nil {
+		return classroom, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("failed to find classroom")
 	}
 
-	numPart := lastNo[3:] // Remove "STD" prefix
-	lastNum, err := strconv.Atoi(numPart)
+	// Create default classroom (need a teacher first)
+	teacher, err := s.teachers.WithContext(ctx).FindFirstBySchool(school.ID)
 	if err != nil {
-		return "STD001", nil
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("failed to find teacher")
+		}
+		teacher = &placeholder
+		teacher.SchoolID = &school.ID
+		if err := s.teachers.WithContext(ctx).Create(teacher); err != nil {
+			return nil, errors.New("failed to create default teacher")
+		}
 	}
 
-	// Increment and format as STD001, STD002, etc. for this classroom
-	nextNum := lastNum + 1
-	return fmt.Sprintf("STD%03d", nextNum), nil
+	classroom = &models.Classroom{
+		SchoolID:  &school.ID,
+		TeacherID: &teacher.ID,
+		Name:      name,
+		Grade:     grade,
+		Version:   1,
+	}
+	if err := s.classrooms.WithContext(ctx).Create(classroom); err != nil {
+		return nil, errors.New("failed to create classroom")
+	}
+	return classroom, nil
 }
 
-func (s *StudentService) GetStudentByID(id uint) (*models.Student, error) {
-	var student models.Student
-	if err := configs.DB.Where("id = ?", id).First(&student).Error; err != nil {
+// GetStudentByID gets one student with the fields and relations of sel
+func (s *StudentService) GetStudentByID(ctx context.Context, id uint, sel fieldset.Selection) (*models.Student, error) {
+	ctx, span := tracing.Start(ctx, "StudentService.GetStudentByID")
+	defer span.End()
+
+	// updated_at makes the ETag, whichever fields the client asked for
+	student, err := s.students.WithContext(ctx).WithSelection(sel.Including("updated_at")).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("student not found")
+			return nil, ErrStudentNotFound
 		}
 		return nil, errors.New("failed to get student")
 	}
-	return &student, nil
+	return student, nil
 }
 
-func (s *StudentService) CreateStudent(req *requests.StudentCreateRequest) (*models.Student, error) {
+func (s *StudentService) CreateStudent(ctx context.Context, req *requests.StudentCreateRequest) (*models.Student, error) {
+	ctx, span := tracing.Start(ctx, "StudentService.CreateStudent")
+	defer span.End()
+
 	// Verify classroom exists
-	var classroom models.Classroom
-	if err := configs.DB.Where("id = ?", req.ClassroomID).First(&classroom).Error; err != nil {
-		return nil, errors.New("classroom not found")
+	if _, err := s.classrooms.WithContext(ctx).FindByID(req.ClassroomID); err != nil {
+		return nil, ErrClassroomNotFound
 	}
 
-	// Generate student number if not provided (per classroom)
+	// Student number is generated inside the transaction when not provided (per classroom)
 	studentNo := req.StudentNo
-	if studentNo == "" {
-		generatedNo, err := s.generateStudentNo(req.ClassroomID)
-		if err != nil {
-			logger.LogError(err, "Failed to generate student number", logrus.Fields{"classroom_id": req.ClassroomID})
-			return nil, errors.New("failed to generate student number")
-		}
-		studentNo = generatedNo
-	}
 
-	logger.LogInfo("Creating new student", logrus.Fields{
+	logger.LogInfo(ctx, "Creating new student", logrus.Fields{
 		"student_no":   studentNo,
 		"classroom_id": req.ClassroomID,
 		"school_name":  req.SchoolName,
@@ -93,42 +179,20 @@ func (s *StudentService) CreateStudent(req *requests.StudentCreateRequest) (*mod
 	})
 
 	// Check if student already exists by student number in the same classroom
-	var existingStudent models.Student
-	if err := configs.DB.Where("student_no = ? AND classroom_id = ?", studentNo, req.ClassroomID).First(&existingStudent).Error; err == nil {
-		logger.LogWarning("Student creation failed - student number already exists in classroom", logrus.Fields{
-			"student_no":   studentNo,
-			"classroom_id": req.ClassroomID,
-		})
-		return nil, errors.New("student with this student number already exists in this classroom")
+	if studentNo != "" {
+		if _, err := s.students.WithContext(ctx).FindByStudentNo(req.ClassroomID, studentNo); err == nil {
+			logger.LogWarning(ctx, "Student creation failed - student number already exists in classroom", logrus.Fields{
+				"student_no":   studentNo,
+				"classroom_id": req.ClassroomID,
+			})
+			return nil, ErrClassroomStudentNoTaken
+		}
 	}
 
 	// Find or create school by name
-	var school models.School
-	if err := configs.DB.Where("name = ?", req.SchoolName).First(&school).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logger.LogInfo("Creating new school", logrus.Fields{
-				"school_name": req.SchoolName,
-			})
-			// Create new school
-			school = models.School{
-				Name: req.SchoolName,
-			}
-			if err := configs.DB.Create(&school).Error; err != nil {
-				logger.LogError(err, "Failed to create school", logrus.Fields{
-					"school_name": req.SchoolName,
-				})
-				return nil, errors.New("failed to create school")
-			}
-			logger.LogInfo("School created successfully", logrus.Fields{
-				"school_id":   fmt.Sprintf("%d", school.ID),
-				"school_name": school.Name,
-			})
-		} else {
-			logger.LogError(err, "Failed to find school", logrus.Fields{
-				"school_name": req.SchoolName,
-			})
-			return nil, errors.New("failed to find school")
-		}
+	school, err := s.findOrCreateSchool(ctx, req.SchoolName)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create new student
@@ -140,184 +204,287 @@ func (s *StudentService) CreateStudent(req *requests.StudentCreateRequest) (*mod
 		ClassroomID: &req.ClassroomID,
 		GenderID:    req.GenderID,
 		PrefixID:    req.PrefixID,
+		Version:     1,
 	}
 
-	if err := configs.DB.Create(&student).Error; err != nil {
-		logger.LogError(err, "Failed to create student", logrus.Fields{
+	// Log activity automatically - use teacherID from service parameter if available
+	// Note: For now using a default system user ID. Should be passed from controller context.
+	var systemTeacherID uint = 1 // Default system user
+
+	if err := s.students.WithContext(ctx).WithTx(func(tx repositories.StudentRepository) error {
+		if student.StudentNo == "" {
+			generatedNo, err := s.allocateStudentNo(ctx, tx, req.ClassroomID)
+			if err != nil {
+				return fmt.Errorf("generate student number: %w", err)
+			}
+			student.StudentNo = generatedNo
+		}
+		if err := tx.Create(&student); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "student.created",
+			AggregateType: "student",
+			AggregateID:   student.ID,
+			TeacherID:     systemTeacherID,
+			SchoolID:      &school.ID,
+			Action:        models.LogActionCreateStudent,
+			Message:       i18n.Msg("log.student_created", "first_name", req.Firstname, "last_name", req.Lastname, "student_no", student.StudentNo),
+			Payload:       student,
+		})
+	}); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrClassroomStudentNoTaken
+		}
+		logger.LogError(ctx, err, "Failed to create student", logrus.Fields{
 			"student_no": req.StudentNo,
 			"school_id":  fmt.Sprintf("%d", school.ID),
 		})
 		return nil, errors.New("failed to create student")
 	}
 
-	logger.LogInfo("Student created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Student created successfully", logrus.Fields{
 		"student_id": fmt.Sprintf("%d", student.ID),
 		"student_no": student.StudentNo,
 		"school_id":  fmt.Sprintf("%d", school.ID),
 	})
 
-	// Log activity automatically - use teacherID from service parameter if available
-	// Note: For now using a default system user ID. Should be passed from controller context.
-	var systemTeacherID uint = 1 // Default system user
-	logger.LogActivity(systemTeacherID, models.LogActionCreateStudent, fmt.Sprintf("สร้างนักเรียนใหม่: %s %s (รหัส: %s)", req.Firstname, req.Lastname, studentNo), &school.ID)
-
 	return &student, nil
 }
 
-func (s *StudentService) UpdateStudent(id uint, req *requests.StudentUpdateRequest) (*models.Student, error) {
-	var student models.Student
-	if err := configs.DB.Where("id = ?", id).First(&student).Error; err != nil {
+func (s *StudentService) UpdateStudent(ctx context.Context, id uint, req *requests.StudentUpdateRequest) (*models.Student, error) {
+	ctx, span := tracing.Start(ctx, "StudentService.UpdateStudent")
+	defer span.End()
+
+	student, err := s.students.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("student not found")
+			return nil, ErrStudentNotFound
 		}
 		return nil, errors.New("failed to find student")
 	}
 
+	if err := etag.CheckIfMatch(ctx, student); err != nil {
+		return nil, err
+	}
+
+	if req.Version != nil && *req.Version != student.Version {
+		return nil, ErrStudentVersionConflict.WithCurrent(student)
+	}
+
 	// Check if student number is being changed and if it already exists
-	if req.StudentNo != student.StudentNo {
-		var existingStudent models.Student
-		if err := configs.DB.Where("student_no = ? AND id != ?", req.StudentNo, id).First(&existingStudent).Error; err == nil {
-			return nil, errors.New("student with this student number already exists")
+	if req.StudentNo != nil && *req.StudentNo != student.StudentNo {
+		taken, err := s.students.WithContext(ctx).StudentNoTaken(*req.StudentNo, id)
+		if err != nil {
+			logger.LogError(ctx, err, "Failed to check student number", logrus.Fields{
+				"student_id": fmt.Sprintf("%d", id),
+			})
+			return nil, errors.New("failed to check student number")
+		}
+		if taken {
+			return nil, ErrStudentNumberTaken
 		}
 	}
 
 	// Find or create school by name
-	var school models.School
-	if err := configs.DB.Where("name = ?", req.SchoolName).First(&school).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Create new school
-			school = models.School{
-				Name: req.SchoolName,
-			}
-			if err := configs.DB.Create(&school).Error; err != nil {
-				return nil, errors.New("failed to create school")
-			}
-		} else {
-			return nil, errors.New("failed to find school")
+	if req.SchoolName != nil {
+		school, err := s.findOrCreateSchool(ctx, *req.SchoolName)
+		if err != nil {
+			return nil, err
 		}
+		student.SchoolID = &school.ID
 	}
 
-	// Update fields
-	student.StudentNo = req.StudentNo
-	student.FirstName = req.Firstname // Note: field name difference
-	student.LastName = req.Lastname   // Note: field name difference
-	student.SchoolID = &school.ID
-
-	if err := configs.DB.Save(&student).Error; err != nil {
-		return nil, errors.New("failed to update student")
+	// Only the fields that were sent change
+	baseVersion := student.Version
+	if req.StudentNo != nil {
+		student.StudentNo = *req.StudentNo
+	}
+	if req.Firstname != nil {
+		student.FirstName = *req.Firstname // Note: field name difference
+	}
+	if req.Lastname != nil {
+		student.LastName = *req.Lastname // Note: field name difference
 	}
+	student.Version++
 
 	// Log activity automatically
 	var systemTeacherID uint = 1 // Default system user
-	logger.LogActivity(systemTeacherID, models.LogActionUpdateStudent,
-		fmt.Sprintf("อัพเดทข้อมูลนักเรียน: %s %s (รหัส: %s)", req.Firstname, req.Lastname, req.StudentNo),
-		&school.ID)
 
-	return &student, nil
+	if err := s.students.WithContext(ctx).WithTx(func(tx repositories.StudentRepository) error {
+		updated, err := tx.UpdateIfVersion(student, baseVersion)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// Someone else saved the student after it was read
+			current, err := tx.FindByID(id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrStudentNotFound
+			}
+			if err != nil {
+				return err
+			}
+			return ErrStudentVersionConflict.WithCurrent(current)
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "student.updated",
+			AggregateType: "student",
+			AggregateID:   student.ID,
+			TeacherID:     systemTeacherID,
+			SchoolID:      student.SchoolID,
+			Action:        models.LogActionUpdateStudent,
+			Message:       i18n.Msg("log.student_updated", "first_name", student.FirstName, "last_name", student.LastName, "student_no", student.StudentNo),
+			Payload:       student,
+		})
+	}); err != nil {
+		if errors.Is(err, ErrStudentVersionConflict) || errors.Is(err, ErrStudentNotFound) {
+			return nil, err
+		}
+		// Another request took the number between the check and the write
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrStudentNumberTaken
+		}
+		return nil, errors.New("failed to update student")
+	}
+
+	return student, nil
 }
 
-func (s *StudentService) DeleteStudent(id uint) error {
-	var student models.Student
-	if err := configs.DB.Where("id = ?", id).First(&student).Error; err != nil {
+func (s *StudentService) DeleteStudent(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "StudentService.DeleteStudent")
+	defer span.End()
+
+	student, err := s.students.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("student not found")
+			return ErrStudentNotFound
 		}
 		return errors.New("failed to find student")
 	}
 
-	// Log activity automatically before deletion
+	// Log activity automatically with the deletion
 	var systemTeacherID uint = 1 // Default system user
-	logger.LogActivity(systemTeacherID, models.LogActionDeleteStudent,
-		fmt.Sprintf("ลบข้อมูลนักเรียน: %s %s (รหัส: %s)", student.FirstName, student.LastName, student.StudentNo),
-		student.SchoolID)
 
-	if err := configs.DB.Delete(&student).Error; err != nil {
+	// The student's attendances go to the trash with the same timestamp so restoring brings them back together
+	deletedAt := models.NewDeletedAt(s.clock.Now())
+
+	if err := s.students.WithContext(ctx).WithTx(func(tx repositories.StudentRepository) error {
+		if err := tx.SoftDelete(student, deletedAt); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "student.deleted",
+			AggregateType: "student",
+			AggregateID:   student.ID,
+			TeacherID:     systemTeacherID,
+			SchoolID:      student.SchoolID,
+			Action:        models.LogActionDeleteStudent,
+			Message:       i18n.Msg("log.student_deleted", "first_name", student.FirstName, "last_name", student.LastName, "student_no", student.StudentNo),
+			Payload:       student,
+		})
+	}); err != nil {
 		return errors.New("failed to delete student")
 	}
 
 	return nil
 }
 
-// TestCreateStudentWithAutoClassroom creates a student with auto classroom creation (for testing)
-func (s *StudentService) TestCreateStudentWithAutoClassroom(schoolName, firstname, lastname string, genderID, prefixID *uint) (*models.Student, error) {
-	// Find or create school
-	var school models.School
-	if err := configs.DB.Where("name = ?", schoolName).First(&school).Error; err != nil {
+// RestoreStudent brings a student back from the trash together with the attendances
+// deleted with it. The classroom must be live and the student number still free.
+func (s *StudentService) RestoreStudent(ctx context.Context, id uint) (*models.Student, error) {
+	ctx, span := tracing.Start(ctx, "StudentService.RestoreStudent")
+	defer span.End()
+
+	student, err := s.students.WithContext(ctx).FindDeletedByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Create new school
-			school = models.School{
-				Name: schoolName,
-			}
-			if err := configs.DB.Create(&school).Error; err != nil {
-				return nil, errors.New("failed to create school")
-			}
-		} else {
-			return nil, errors.New("failed to find school")
+			return nil, ErrDeletedStudentNotFound
 		}
+		return nil, errors.New("failed to find student")
 	}
 
-	// Find or create a default classroom for this school
-	var classroom models.Classroom
-	classroomName := "ห้องเรียนทดสอบ " + schoolName
-	if err := configs.DB.Where("school_id = ? AND name = ?", school.ID, classroomName).First(&classroom).Error; err != nil {
+	if student.ClassroomID == nil {
+		return nil, ErrStudentClassroomInTrash
+	}
+	if _, err := s.classrooms.WithContext(ctx).FindByID(*student.ClassroomID); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Create default classroom (need a teacher first)
-			var teacher models.Teacher
-			if err := configs.DB.Where("school_id = ?", school.ID).First(&teacher).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					// Create default teacher
-					teacher = models.Teacher{
-						Email:     "test@" + schoolName + ".com",
-						FirstName: "ครูทดสอบ",
-						LastName:  "ระบบ",
-						Phone:     "081-000-0000",
-						SchoolID:  &school.ID,
-					}
-					if err := configs.DB.Create(&teacher).Error; err != nil {
-						return nil, errors.New("failed to create default teacher")
-					}
-				} else {
-					return nil, errors.New("failed to find teacher")
-				}
-			}
+			return nil, ErrStudentClassroomInTrash
+		}
+		return nil, errors.New("failed to restore student")
+	}
 
-			// Create classroom
-			classroom = models.Classroom{
-				SchoolID:  &school.ID,
-				TeacherID: &teacher.ID,
-				Name:      classroomName,
-				Grade:     "ม.1",
-			}
-			if err := configs.DB.Create(&classroom).Error; err != nil {
-				return nil, errors.New("failed to create classroom")
-			}
-		} else {
-			return nil, errors.New("failed to find classroom")
+	var systemTeacherID uint = 1 // Default system user
+
+	if err := s.students.WithContext(ctx).WithTx(func(tx repositories.StudentRepository) error {
+		if err := tx.Restore(student); err != nil {
+			return err
 		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "student.restored",
+			AggregateType: "student",
+			AggregateID:   student.ID,
+			TeacherID:     systemTeacherID,
+			SchoolID:      student.SchoolID,
+			Action:        models.LogActionRestoreStudent,
+			Message:       i18n.Msg("log.student_restored", "first_name", student.FirstName, "last_name", student.LastName, "student_no", student.StudentNo),
+			Payload:       student,
+		})
+	}); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrClassroomStudentNoTaken
+		}
+		return nil, errors.New("failed to restore student")
 	}
 
-	// Generate student number for this classroom
-	studentNo, err := s.generateStudentNo(classroom.ID)
+	return student, nil
+}
+
+// TestCreateStudentWithAutoClassroom creates a student with auto classroom creation (for testing)
+func (s *StudentService) TestCreateStudentWithAutoClassroom(ctx context.Context, schoolName, firstname, lastname string, genderID, prefixID *uint) (*models.Student, error) {
+	ctx, span := tracing.Start(ctx, "StudentService.TestCreateStudentWithAutoClassroom")
+	defer span.End()
+
+	// Find or create school
+	school, err := s.findOrCreateSchool(ctx, schoolName)
 	if err != nil {
-		return nil, errors.New("failed to generate student number")
+		return nil, err
 	}
 
-	// Create student
+	// Find or create a default classroom for this school
+	classroom, err := s.findOrCreateTestClassroom(ctx, school, "ห้องเรียนทดสอบ "+schoolName, "ม.1", models.Teacher{
+		Email:     "test@" + schoolName + ".com",
+		FirstName: "ครูทดสอบ",
+		LastName:  "ระบบ",
+		Phone:     "081-000-0000",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Create student with the next number of this classroom
 	student := models.Student{
-		StudentNo:   studentNo,
 		FirstName:   firstname,
 		LastName:    lastname,
 		SchoolID:    &school.ID,
 		ClassroomID: &classroom.ID,
 		GenderID:    genderID,
 		PrefixID:    prefixID,
+		Version:     1,
 	}
 
-	if err := configs.DB.Create(&student).Error; err != nil {
+	if err := s.students.WithContext(ctx).WithTx(func(tx repositories.StudentRepository) error {
+		studentNo, err := s.allocateStudentNo(ctx, tx, classroom.ID)
+		if err != nil {
+			return err
+		}
+		student.StudentNo = studentNo
+		return tx.Create(&student)
+	}); err != nil {
 		return nil, errors.New("failed to create student")
 	}
 
-	logger.LogInfo("Test student created successfully", logrus.Fields{
+	logger.LogInfo(ctx, "Test student created successfully", logrus.Fields{
 		"student_id":   student.ID,
 		"student_no":   student.StudentNo,
 		"classroom_id": classroom.ID,
@@ -328,126 +495,77 @@ func (s *StudentService) TestCreateStudentWithAutoClassroom(schoolName, firstnam
 }
 
 // TestCreateStudent creates a student with auto-generated classroom for testing purposes
-func (s *StudentService) TestCreateStudent(schoolName, firstname, lastname, studentNo *string, genderID, prefixID *uint) (*models.Student, error) {
-	// Find or create school
-	var school models.School
-	if err := configs.DB.Where("name = ?", *schoolName).First(&school).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Create new school
-			school = models.School{Name: *schoolName}
-			if err := configs.DB.Create(&school).Error; err != nil {
-				return nil, errors.New("failed to create school")
-			}
-		} else {
-			return nil, errors.New("failed to find school")
-		}
-	}
+func (s *StudentService) TestCreateStudent(ctx context.Context, schoolName, firstname, lastname, studentNo *string, genderID, prefixID *uint) (*models.Student, error) {
+	ctx, span := tracing.Start(ctx, "StudentService.TestCreateStudent")
+	defer span.End()
 
-	// Find or create a default classroom for this school
-	var classroom models.Classroom
-	classroomName := "ห้องทดสอบ - " + *schoolName
-	if err := configs.DB.Where("name = ? AND school_id = ?", classroomName, school.ID).First(&classroom).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Create new classroom (need a teacher first)
-			// Find first teacher in this school or create system teacher
-			var teacher models.Teacher
-			if err := configs.DB.Where("school_id = ?", school.ID).First(&teacher).Error; err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					// Create system teacher
-					teacher = models.Teacher{
-						Email:     fmt.Sprintf("system@%s.com", school.Name),
-						Password:  "system123", // This should be hashed in real implementation
-						FirstName: "ระบบ",
-						LastName:  "ทดสอบ",
-						Phone:     "000-000-0000",
-						SchoolID:  &school.ID,
-					}
-					if err := configs.DB.Create(&teacher).Error; err != nil {
-						return nil, errors.New("failed to create system teacher")
-					}
-				} else {
-					return nil, errors.New("failed to find teacher")
-				}
-			}
-
-			// Create classroom
-			classroom = models.Classroom{
-				SchoolID:  &school.ID,
-				TeacherID: &teacher.ID,
-				Name:      classroomName,
-				Grade:     "ทดสอบ",
-			}
-			if err := configs.DB.Create(&classroom).Error; err != nil {
-				return nil, errors.New("failed to create classroom")
-			}
-		} else {
-			return nil, errors.New("failed to find classroom")
-		}
+	// Find or create school
+	school, err := s.findOrCreateSchool(ctx, *schoolName)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate student number if not provided
-	finalStudentNo := ""
-	if studentNo != nil && *studentNo != "" {
-		finalStudentNo = *studentNo
-	} else {
-		generatedNo, err := s.generateStudentNo(classroom.ID)
-		if err != nil {
-			return nil, errors.New("failed to generate student number")
-		}
-		finalStudentNo = generatedNo
+	// Find or create a default classroom for this school, with a system teacher when it has none
+	classroom, err := s.findOrCreateTestClassroom(ctx, school, "ห้องทดสอบ - "+*schoolName, "ทดสอบ", models.Teacher{
+		Email:     fmt.Sprintf("system@%s.com", school.Name),
+		Password:  "system123", // This should be hashed in real implementation
+		FirstName: "ระบบ",
+		LastName:  "ทดสอบ",
+		Phone:     "000-000-0000",
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Create student
 	student := models.Student{
-		StudentNo:   finalStudentNo,
 		FirstName:   *firstname,
 		LastName:    *lastname,
 		SchoolID:    &school.ID,
 		ClassroomID: &classroom.ID,
 		GenderID:    genderID,
 		PrefixID:    prefixID,
+		Version:     1,
+	}
+	if studentNo != nil {
+		student.StudentNo = *studentNo
 	}
 
-	if err := configs.DB.Create(&student).Error; err != nil {
+	if err := s.students.WithContext(ctx).WithTx(func(tx repositories.StudentRepository) error {
+		// Generate student number if not provided
+		if student.StudentNo == "" {
+			generatedNo, err := s.allocateStudentNo(ctx, tx, classroom.ID)
+			if err != nil {
+				return err
+			}
+			student.StudentNo = generatedNo
+		}
+		return tx.Create(&student)
+	}); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrClassroomStudentNoTaken
+		}
 		return nil, errors.New("failed to create student")
 	}
 
 	// Load relationships for response
-	if err := configs.DB.Preload("School").Preload("Classroom").Preload("Gender").Preload("Prefix").First(&student, student.ID).Error; err != nil {
+	if err := s.students.WithContext(ctx).LoadRelations(&student); err != nil {
 		return &student, nil // Return even if preload fails
 	}
 
 	return &student, nil
 }
 
-// GetStudentsByTeacherPaginated gets all students taught by a specific teacher with pagination
-func (s *StudentService) GetStudentsByTeacherPaginated(teacherID uint, page, limit int) ([]models.Student, int64, error) {
-	var students []models.Student
-	var total int64
-
-	// Count total students for this teacher
-	if err := configs.DB.
-		Model(&models.Student{}).
-		Joins("JOIN classrooms ON students.classroom_id = classrooms.id").
-		Where("classrooms.teacher_id = ?", teacherID).
-		Count(&total).Error; err != nil {
-		return nil, 0, errors.New("failed to count students")
-	}
-
-	// Get paginated students
-	offset := (page - 1) * limit
-	if err := configs.DB.
-		Preload("School").
-		Preload("Classroom").
-		Preload("Gender").
-		Preload("Prefix").
-		Joins("JOIN classrooms ON students.classroom_id = classrooms.id").
-		Where("classrooms.teacher_id = ?", teacherID).
-		Offset(offset).
-		Limit(limit).
-		Find(&students).Error; err != nil {
-		return nil, 0, errors.New("failed to get students by teacher")
-	}
-
-	return students, total, nil
+// GetStudentsByTeacher gets one page of the students taught by a specific teacher
+func (s *StudentService) GetStudentsByTeacher(ctx context.Context, teacherID uint, query listquery.Query, sel fieldset.Selection) ([]models.Student, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "StudentService.GetStudentsByTeacher")
+	defer span.End()
+
+	students, err := s.students.WithContext(ctx).WithSelection(sel.Including(query.SortFields()...)).ListByTeacher(teacherID, query)
+	if err != nil {
+		return nil, listquery.Pagination{}, errors.New("failed to get students by teacher")
+	}
+
+	students, meta := listquery.Page(query, students)
+	return students, meta, nil
 }
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/requests"
+	"easy-attend-service/utils/apperror"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newStudentRequest(classroom *models.Classroom, studentNo string) *requests.StudentCreateRequest {
+	return &requests.StudentCreateRequest{
+		SchoolName:  "โรงเรียนทดสอบ",
+		ClassroomID: classroom.ID,
+		StudentNo:   studentNo,
+		Firstname:   "มานี",
+		Lastname:    "มีนา",
+	}
+}
+
+func TestCreateStudentAllocatesNumbersInSchoolFormat(t *testing.T) {
+	env := newTestEnv()
+	school, _, classroom, _ := env.seed(0)
+	school.StudentNoPrefix = "S"
+	school.StudentNoWidth = 4
+	school.StudentNoYear = models.StudentNoYearBE
+	env.store.schools[school.ID] = *school
+
+	first, err := env.student.CreateStudent(t.Context(), newStudentRequest(classroom, ""))
+	if err != nil {
+		t.Fatalf("first create: %v", err)
+	}
+	second, err := env.student.CreateStudent(t.Context(), newStudentRequest(classroom, ""))
+	if err != nil {
+		t.Fatalf("second create: %v", err)
+	}
+	if first.StudentNo != "S25680001" || second.StudentNo != "S25680002" {
+		t.Errorf("numbers = %s, %s", first.StudentNo, second.StudentNo)
+	}
+
+	// The Buddhist-era year restarts the running number
+	env.clock.Advance(365 * 24 * time.Hour)
+	next, err := env.student.CreateStudent(t.Context(), newStudentRequest(classroom, ""))
+	if err != nil {
+		t.Fatalf("create next year: %v", err)
+	}
+	if next.StudentNo != "S25690001" {
+		t.Errorf("next year number = %s", next.StudentNo)
+	}
+}
+
+func TestCreateStudentContinuesAfterManualAndDeletedNumbers(t *testing.T) {
+	env := newTestEnv()
+	_, _, classroom, students := env.seed(2)
+
+	if _, err := env.student.CreateStudent(t.Context(), newStudentRequest(classroom, "STD007")); err != nil {
+		t.Fatalf("manual create: %v", err)
+	}
+	if err := env.student.DeleteStudent(t.Context(), students[1].ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	student, err := env.student.CreateStudent(t.Context(), newStudentRequest(classroom, ""))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if student.StudentNo != "STD008" {
+		t.Errorf("number = %s, want STD008", student.StudentNo)
+	}
+}
+
+func TestCreateStudentRejectsTakenNumber(t *testing.T) {
+	env := newTestEnv()
+	_, _, classroom, _ := env.seed(1)
+
+	_, err := env.student.CreateStudent(t.Context(), newStudentRequest(classroom, "STD001"))
+	if err == nil || err.Error() != "student with this student number already exists in this classroom" {
+		t.Fatalf("error = %v", err)
+	}
+
+	if _, err := env.student.CreateStudent(t.Context(), &requests.StudentCreateRequest{ClassroomID: 999}); err == nil || err.Error() != "classroom not found" {
+		t.Errorf("unknown classroom error = %v", err)
+	}
+}
+
+func TestUpdateStudentRejectsNumberOfAnotherStudent(t *testing.T) {
+	env := newTestEnv()
+	_, _, _, students := env.seed(2)
+
+	_, err := env.student.UpdateStudent(t.Context(), students[0].ID, &requests.StudentUpdateRequest{
+		SchoolName: ptr("โรงเรียนทดสอบ"),
+		StudentNo:  ptr(students[1].StudentNo),
+		Firstname:  ptr("มานี"),
+		Lastname:   ptr("มีนา"),
+	})
+	if err == nil || err.Error() != "student with this student number already exists" {
+		t.Fatalf("error = %v", err)
+	}
+
+	updated, err := env.student.UpdateStudent(t.Context(), students[0].ID, &requests.StudentUpdateRequest{
+		SchoolName: ptr("โรงเรียนใหม่"),
+		StudentNo:  ptr("STD100"),
+		Firstname:  ptr("มานี"),
+		Lastname:   ptr("มีนา"),
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if school := env.store.schools[*updated.SchoolID]; school.Name != "โรงเรียนใหม่" {
+		t.Errorf("school = %q, want the new school to be created", school.Name)
+	}
+}
+
+// blindStudentRepo misses the number check: it fails, or a concurrent request has not
+// committed its student yet
+type blindStudentRepo struct {
+	memStudentRepo
+	checkErr error
+}
+
+func (r blindStudentRepo) WithContext(ctx context.Context) repositories.StudentRepository { return r }
+
+func (r blindStudentRepo) WithTx(fn func(repo repositories.StudentRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r blindStudentRepo) StudentNoTaken(studentNo string, excludeID uint) (bool, error) {
+	return false, r.checkErr
+}
+
+func TestUpdateStudentReportsNumberRacesAndFailedChecks(t *testing.T) {
+	env := newTestEnv()
+	_, _, _, students := env.seed(2)
+	blind := blindStudentRepo{memStudentRepo: memStudentRepo{env.store}}
+	service := NewStudentService(blind, memClassroomRepo{env.store}, memTeacherRepo{env.store}, memSchoolRepo{env.store}, env.clock)
+
+	// The unique index has the last word
+	_, err := service.UpdateStudent(t.Context(), students[0].ID, &requests.StudentUpdateRequest{StudentNo: ptr(students[1].StudentNo)})
+	if !errors.Is(err, ErrStudentNumberTaken) {
+		t.Errorf("race: error = %v, want %v", err, ErrStudentNumberTaken)
+	}
+
+	// A failing check is an error, not a free number
+	blind.checkErr = errors.New("connection reset")
+	service = NewStudentService(blind, memClassroomRepo{env.store}, memTeacherRepo{env.store}, memSchoolRepo{env.store}, env.clock)
+	_, err = service.UpdateStudent(t.Context(), students[0].ID, &requests.StudentUpdateRequest{StudentNo: ptr("STD100")})
+	if err == nil {
+		t.Errorf("failed check: update went through")
+	}
+	if got := env.store.students[students[0].ID].StudentNo; got != students[0].StudentNo {
+		t.Errorf("student_no = %q, want it unchanged", got)
+	}
+}
+
+func TestUpdateStudentChecksVersionAndKeepsUnsentFields(t *testing.T) {
+	env := newTestEnv()
+	_, _, _, students := env.seed(1)
+	original := students[0]
+
+	updated, err := env.student.UpdateStudent(t.Context(), original.ID, &requests.StudentUpdateRequest{
+		Firstname: ptr("มานี"),
+		Version:   ptr(original.Version),
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.FirstName != "มานี" || updated.LastName != original.LastName || updated.StudentNo != original.StudentNo {
+		t.Errorf("updated = %+v, want only the first name changed", updated)
+	}
+	if updated.Version != original.Version+1 {
+		t.Errorf("version = %d, want %d", updated.Version, original.Version+1)
+	}
+
+	// A second teacher still editing the first version gets the current row back
+	_, err = env.student.UpdateStudent(t.Context(), original.ID, &requests.StudentUpdateRequest{
+		Lastname: ptr("มีนา"),
+		Version:  ptr(original.Version),
+	})
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code != ErrStudentVersionConflict.Code {
+		t.Fatalf("stale update error = %v, want %v", err, ErrStudentVersionConflict)
+	}
+	if current, ok := appErr.Current.(*models.Student); !ok || current.FirstName != "มานี" {
+		t.Errorf("conflict current = %#v, want the updated student", appErr.Current)
+	}
+	if env.store.students[original.ID].LastName != original.LastName {
+		t.Errorf("stale update overwrote the last name")
+	}
+}
+
+func TestDeleteStudentRestoresOnlyAttendancesDeletedWithIt(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+
+	older, _ := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0]))
+	env.attendance.DeleteAttendance(t.Context(), older.ID)
+
+	env.clock.Advance(time.Hour)
+	req := newAttendanceRequest(classroom, teacher, students[0])
+	req.SessionDate = "2025-06-03"
+	kept, _ := env.attendance.CreateAttendance(t.Context(), req)
+
+	env.clock.Advance(time.Hour)
+	if err := env.student.DeleteStudent(t.Context(), students[0].ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if !env.store.attendances[kept.ID].DeletedAt.Valid {
+		t.Fatalf("attendance of the deleted student is still live")
+	}
+
+	if _, err := env.student.RestoreStudent(t.Context(), students[0].ID); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if env.store.attendances[kept.ID].DeletedAt.Valid {
+		t.Errorf("attendance deleted with the student was not restored")
+	}
+	if !env.store.attendances[older.ID].DeletedAt.Valid {
+		t.Errorf("attendance deleted earlier on its own was restored too")
+	}
+}
+
+func TestRestoreStudentChecksClassroomAndNumber(t *testing.T) {
+	env := newTestEnv()
+	_, _, classroom, students := env.seed(1)
+	env.student.DeleteStudent(t.Context(), students[0].ID)
+
+	// The number was handed to someone else meanwhile
+	other, err := env.student.CreateStudent(t.Context(), newStudentRequest(classroom, students[0].StudentNo))
+	if err != nil {
+		t.Fatalf("reuse number: %v", err)
+	}
+	_, err = env.student.RestoreStudent(t.Context(), students[0].ID)
+	if err == nil || err.Error() != "student with this student number already exists in this classroom" {
+		t.Fatalf("restore over a taken number error = %v", err)
+	}
+	env.student.DeleteStudent(t.Context(), other.ID)
+
+	env.clock.Advance(time.Hour)
+	env.classroom.DeleteClassroom(t.Context(), classroom.ID)
+	_, err = env.student.RestoreStudent(t.Context(), students[0].ID)
+	if err == nil || err.Error() != "restore the classroom of this student first" {
+		t.Fatalf("restore under a deleted classroom error = %v", err)
+	}
+}
+
+func TestTestCreateStudentBuildsPlaceholderClassroom(t *testing.T) {
+	env := newTestEnv()
+	schoolName, firstname, lastname := "โรงเรียนใหม่", "ปิติ", "พอใจ"
+
+	student, err := env.student.TestCreateStudent(t.Context(), &schoolName, &firstname, &lastname, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if student.StudentNo != "STD001" || student.Classroom == nil || student.Classroom.Name != "ห้องทดสอบ - "+schoolName {
+		t.Errorf("student = %s in %+v", student.StudentNo, student.Classroom)
+	}
+	if len(env.store.teachers) != 1 {
+		t.Errorf("teachers = %d, want one system teacher", len(env.store.teachers))
+	}
+}
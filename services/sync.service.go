@@ -0,0 +1,438 @@
+package services
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/requests"
+	"easy-attend-service/utils/clock"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/metrics"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/realtime"
+	"easy-attend-service/utils/tracing"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Outcomes of a single sync mutation
+const (
+	SyncResultApplied   = "applied"   // The mutation changed the server state
+	SyncResultDuplicate = "duplicate" // Already applied by an earlier sync, nothing changed
+	SyncResultConflict  = "conflict"  // The server row moved on, Attendance holds its current state
+	SyncResultRejected  = "rejected"  // Invalid or not permitted, Error explains why
+)
+
+const (
+	defaultSyncChangeLimit = 500
+	maxSyncChangeLimit     = 1000
+
+	// maxClientClockSkew bounds how far ahead a device clock may run, otherwise a
+	// wrong clock would win every last-writer-wins comparison
+	maxClientClockSkew = 5 * time.Minute
+)
+
+type SyncService struct {
+	sync  repositories.SyncRepository
+	clock clock.Clock
+}
+
+// SyncMutationResult reports what happened to one mutation of the batch
+type SyncMutationResult struct {
+	ClientMutationID string             `json:"client_mutation_id"`
+	Status           string             `json:"status"`
+	Attendance       *models.Attendance `json:"attendance,omitempty"`
+	Error            string             `json:"error,omitempty"`
+}
+
+// AttendanceChanges is one page of the server change feed
+type AttendanceChanges struct {
+	Changes []models.Attendance `json:"changes"`
+	Cursor  string              `json:"cursor"`   // Pass back on the next sync
+	HasMore bool                `json:"has_more"` // More changes are waiting after Cursor
+}
+
+// AttendanceSyncResponse is the outcome of pushing a batch plus the changes to pull
+type AttendanceSyncResponse struct {
+	Results []SyncMutationResult `json:"results"`
+	AttendanceChanges
+}
+
+// syncCursor is a position in the change feed, ordered by writing transaction then row ID
+type syncCursor struct {
+	TxID int64
+	ID   uint
+}
+
+func NewSyncService(sync repositories.SyncRepository, clk clock.Clock) *SyncService {
+	return &SyncService{sync: sync, clock: clk}
+}
+
+// SyncAttendances applies an offline queue in order and returns the changes since the cursor.
+// classroomIDs are the classrooms the teacher may write to and read from.
+func (s *SyncService) SyncAttendances(ctx context.Context, teacherID uint, classroomIDs []uint, req *requests.AttendanceSyncRequest) (*AttendanceSyncResponse, error) {
+	ctx, span := tracing.Start(ctx, "SyncService.SyncAttendances")
+	defer span.End()
+
+	cursor, err := parseSyncCursor(req.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogInfo(ctx, "Syncing offline attendance mutations", logrus.Fields{
+		"teacher_id": fmt.Sprintf("%d", teacherID),
+		"mutations":  len(req.Mutations),
+		"cursor":     req.Cursor,
+	})
+
+	allowed := make(map[uint]bool, len(classroomIDs))
+	for _, id := range classroomIDs {
+		allowed[id] = true
+	}
+
+	targets := make([]uint, 0, len(req.Mutations))
+	for _, m := range req.Mutations {
+		if allowed[m.ClassroomID] {
+			targets = append(targets, m.ClassroomID)
+		}
+	}
+	settings, err := s.classroomSyncSettings(ctx, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SyncMutationResult, 0, len(req.Mutations))
+	for i := range req.Mutations {
+		m := &req.Mutations[i]
+		if !allowed[m.ClassroomID] {
+			results = append(results, SyncMutationResult{
+				ClientMutationID: m.ClientMutationID,
+				Status:           SyncResultRejected,
+				Error:            "you do not have access to this classroom",
+			})
+			continue
+		}
+		results = append(results, s.applyMutation(ctx, teacherID, m, settings[m.ClassroomID]))
+	}
+
+	changes, err := s.changesSince(ctx, classroomIDs, cursor, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AttendanceSyncResponse{Results: results, AttendanceChanges: *changes}, nil
+}
+
+// GetAttendanceChanges returns the attendance rows of the given classrooms written after the cursor
+func (s *SyncService) GetAttendanceChanges(ctx context.Context, classroomIDs []uint, cursor string, limit int) (*AttendanceChanges, error) {
+	ctx, span := tracing.Start(ctx, "SyncService.GetAttendanceChanges")
+	defer span.End()
+
+	position, err := parseSyncCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return s.changesSince(ctx, classroomIDs, position, limit)
+}
+
+// classroomSync holds what applying a mutation needs to know about its classroom
+type classroomSync struct {
+	SchoolID *uint
+	Policy   models.SyncConflictPolicy
+}
+
+func (s *SyncService) classroomSyncSettings(ctx context.Context, classroomIDs []uint) (map[uint]classroomSync, error) {
+	settings := make(map[uint]classroomSync, len(classroomIDs))
+	if len(classroomIDs) == 0 {
+		return settings, nil
+	}
+
+	rows, err := s.sync.WithContext(ctx).SyncSettings(classroomIDs)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to load sync conflict policies", nil)
+		return nil, errors.New("failed to load sync settings")
+	}
+
+	for _, row := range rows {
+		setting := classroomSync{SchoolID: row.SchoolID, Policy: models.SyncConflictServerWins}
+		if row.Policy == models.SyncConflictLastWriterWins {
+			setting.Policy = models.SyncConflictLastWriterWins
+		}
+		settings[row.ClassroomID] = setting
+	}
+	return settings, nil
+}
+
+// applyMutation applies one mutation in its own transaction so a bad entry does not block the rest of the queue
+func (s *SyncService) applyMutation(ctx context.Context, teacherID uint, m *requests.AttendanceSyncMutation, setting classroomSync) SyncMutationResult {
+	result := SyncMutationResult{ClientMutationID: m.ClientMutationID}
+
+	if _, err := time.Parse("2006-01-02", m.SessionDate); err != nil {
+		result.Status = SyncResultRejected
+		result.Error = "session_date must be YYYY-MM-DD"
+		return result
+	}
+	if m.Op == requests.SyncOperationUpsert && !(&models.Attendance{Status: m.Status}).IsValidStatus() {
+		result.Status = SyncResultRejected
+		result.Error = "invalid attendance status"
+		return result
+	}
+	if time.Unix(m.ClientTimestamp, 0).After(s.clock.Now().Add(maxClientClockSkew)) {
+		result.Status = SyncResultRejected
+		result.Error = "client timestamp is in the future"
+		return result
+	}
+
+	var attendance models.Attendance
+	eventType := ""
+
+	err := s.sync.WithContext(ctx).WithTx(func(tx repositories.SyncRepository) error {
+		attendances := tx.Attendances()
+
+		// A replay targets the same slot, so holding the lock also orders it after the original
+		if err := attendances.LockSlot(m.ClassroomID, m.StudentID, m.SessionDate); err != nil {
+			return err
+		}
+
+		applied, err := tx.FindMutation(teacherID, m.ClientMutationID)
+		if err == nil {
+			result.Status = SyncResultDuplicate
+			if applied.AttendanceID != nil {
+				if current, err := attendances.FindByID(*applied.AttendanceID); err == nil {
+					result.Attendance = current
+				}
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		existing, err := attendances.FindBySlot(m.ClassroomID, m.StudentID, m.SessionDate)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		exists := err == nil
+		if exists {
+			attendance = *existing
+		}
+
+		if exists && attendance.Version != m.BaseVersion {
+			// Last-writer-wins lets a newer device change through, ties keep the server state
+			if setting.Policy != models.SyncConflictLastWriterWins || m.ClientTimestamp <= attendance.CheckedAt {
+				current := attendance
+				result.Status = SyncResultConflict
+				result.Attendance = &current
+				return nil
+			}
+		}
+		if !exists && m.BaseVersion != 0 && m.Op == requests.SyncOperationUpsert {
+			// The record was deleted on the server after the client saw it. Last-writer-wins
+			// recreates it when the change was made after the delete, otherwise the
+			// tombstone is returned so the client can decide.
+			tombstone, err := attendances.FindDeletedBySlot(m.ClassroomID, m.StudentID, m.SessionDate)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err != nil || setting.Policy != models.SyncConflictLastWriterWins || m.ClientTimestamp <= tombstone.DeletedAt.Int64 {
+				result.Status = SyncResultConflict
+				result.Attendance = tombstone
+				return nil
+			}
+		}
+
+		switch {
+		case m.Op == requests.SyncOperationDelete && exists:
+			if err := attendances.SoftDelete(&attendance, models.NewDeletedAt(s.clock.Now())); err != nil {
+				return err
+			}
+			eventType = "attendance.deleted"
+
+		case m.Op == requests.SyncOperationDelete:
+			// Already gone, deleting again is a no-op
+
+		case exists:
+			baseVersion := attendance.Version
+			attendance.TeacherID = &teacherID
+			attendance.Status = m.Status
+			attendance.Remark = m.Remark
+			attendance.CheckedAt = m.ClientTimestamp
+			attendance.Version = baseVersion + 1
+
+			updated, err := attendances.UpdateIfVersion(&attendance, baseVersion)
+			if err != nil {
+				return err
+			}
+			if !updated {
+				// Another writer moved the row on between the read and the write; return its copy
+				current, err := attendances.FindBySlot(m.ClassroomID, m.StudentID, m.SessionDate)
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					current, err = attendances.FindDeletedBySlot(m.ClassroomID, m.StudentID, m.SessionDate)
+				}
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+				result.Status = SyncResultConflict
+				result.Attendance = current
+				return nil
+			}
+			eventType = "attendance.updated"
+
+		default:
+			attendance = models.Attendance{
+				ClassroomID: &m.ClassroomID,
+				TeacherID:   &teacherID,
+				StudentID:   &m.StudentID,
+				SessionDate: m.SessionDate,
+				Status:      m.Status,
+				CheckedAt:   m.ClientTimestamp,
+				Remark:      m.Remark,
+				Version:     1,
+			}
+			if err := attendances.Create(&attendance); err != nil {
+				return err
+			}
+			eventType = "attendance.created"
+		}
+
+		record := models.SyncMutation{
+			TeacherID:        teacherID,
+			ClientMutationID: m.ClientMutationID,
+		}
+		if exists || eventType == "attendance.created" {
+			record.AttendanceID = &attendance.ID
+		}
+		if err := tx.CreateMutation(&record); err != nil {
+			return err
+		}
+
+		result.Status = SyncResultApplied
+		if eventType == "" {
+			return nil
+		}
+		if eventType != "attendance.deleted" {
+			result.Attendance = &attendance
+		}
+
+		event := outbox.Event{
+			Type:          eventType,
+			AggregateType: "attendance",
+			AggregateID:   attendance.ID,
+			TeacherID:     teacherID,
+			SchoolID:      setting.SchoolID,
+			Payload:       attendance,
+		}
+		if eventType != "attendance.deleted" {
+			event.Action = models.LogActionAttendance
+			event.Message = i18n.Msg("log.attendance_synced", "status", string(m.Status), "date", m.SessionDate)
+		}
+		return tx.Enqueue(event)
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// Another writer created the record without taking the slot lock; report it like a stale version
+		return SyncMutationResult{ClientMutationID: m.ClientMutationID, Status: SyncResultConflict}
+	}
+	if constraintErr := attendanceConstraintError(err); constraintErr != nil {
+		return SyncMutationResult{ClientMutationID: m.ClientMutationID, Status: SyncResultRejected, Error: constraintErr.Error()}
+	}
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to apply sync mutation", logrus.Fields{
+			"teacher_id":         fmt.Sprintf("%d", teacherID),
+			"client_mutation_id": m.ClientMutationID,
+		})
+		return SyncMutationResult{
+			ClientMutationID: m.ClientMutationID,
+			Status:           SyncResultRejected,
+			Error:            "failed to apply mutation",
+		}
+	}
+
+	if result.Status == SyncResultConflict {
+		logger.LogWarning(ctx, "Sync mutation rejected - version conflict", logrus.Fields{
+			"client_mutation_id": m.ClientMutationID,
+			"classroom_id":       fmt.Sprintf("%d", m.ClassroomID),
+			"student_id":         fmt.Sprintf("%d", m.StudentID),
+			"base_version":       m.BaseVersion,
+			"policy":             string(setting.Policy),
+		})
+	}
+
+	if eventType == "attendance.created" || eventType == "attendance.updated" {
+		realtime.Attendance.Publish(eventType, m.ClassroomID, attendance)
+		metrics.AttendanceRecorded.WithLabelValues(string(attendance.Status), "sync").Inc()
+	}
+
+	return result
+}
+
+// changesSince pages through the change feed. Rows are only returned once every
+// transaction that could still write before them has finished, so a slow
+// transaction committing late cannot slip behind a cursor the client already holds.
+// Deleted rows are included as tombstones with deleted_at set.
+func (s *SyncService) changesSince(ctx context.Context, classroomIDs []uint, cursor syncCursor, limit int) (*AttendanceChanges, error) {
+	if limit <= 0 {
+		limit = defaultSyncChangeLimit
+	}
+	if limit > maxSyncChangeLimit {
+		limit = maxSyncChangeLimit
+	}
+
+	changes := &AttendanceChanges{
+		Changes: []models.Attendance{},
+		Cursor:  cursor.String(),
+	}
+	if len(classroomIDs) == 0 {
+		return changes, nil
+	}
+
+	rows, err := s.sync.WithContext(ctx).ChangesSince(classroomIDs, cursor.TxID, cursor.ID, limit+1)
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch attendance changes", logrus.Fields{
+			"cursor": cursor.String(),
+		})
+		return nil, errors.New("failed to fetch changes")
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		changes.HasMore = true
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		changes.Cursor = syncCursor{TxID: last.ChangeTxID, ID: last.ID}.String()
+	}
+	changes.Changes = rows
+
+	return changes, nil
+}
+
+func (c syncCursor) String() string {
+	return fmt.Sprintf("%d-%d", c.TxID, c.ID)
+}
+
+func parseSyncCursor(value string) (syncCursor, error) {
+	if value == "" {
+		return syncCursor{}, nil
+	}
+
+	txPart, idPart, ok := strings.Cut(value, "-")
+	if !ok {
+		return syncCursor{}, ErrInvalidSyncCursor
+	}
+	txID, err := strconv.ParseInt(txPart, 10, 64)
+	if err != nil || txID < 0 {
+		return syncCursor{}, ErrInvalidSyncCursor
+	}
+	id, err := strconv.ParseUint(idPart, 10, 32)
+	if err != nil {
+		return syncCursor{}, ErrInvalidSyncCursor
+	}
+	return syncCursor{TxID: txID, ID: uint(id)}, nil
+}
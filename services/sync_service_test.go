@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/requests"
+	"slices"
+	"testing"
+)
+
+func newSyncMutation(id string, classroom *models.Classroom, student models.Student, baseVersion uint, status models.AttendanceStatus) requests.AttendanceSyncMutation {
+	return requests.AttendanceSyncMutation{
+		ClientMutationID: id,
+		Op:               requests.SyncOperationUpsert,
+		ClassroomID:      classroom.ID,
+		StudentID:        student.ID,
+		SessionDate:      "2025-06-02",
+		Status:           status,
+		ClientTimestamp:  1748853000,
+		BaseVersion:      baseVersion,
+	}
+}
+
+// racingAttendanceRepo lets another writer update the row just before the sync write lands
+type racingAttendanceRepo struct {
+	memAttendanceRepo
+}
+
+func (r racingAttendanceRepo) UpdateIfVersion(attendance *models.Attendance, baseVersion uint) (bool, error) {
+	other := r.st.attendances[attendance.ID]
+	other.Status = models.AttendanceStatusLate
+	other.Version++
+	r.st.attendances[attendance.ID] = other
+	return r.memAttendanceRepo.UpdateIfVersion(attendance, baseVersion)
+}
+
+type racingSyncRepo struct {
+	memSyncRepo
+}
+
+func (r racingSyncRepo) WithContext(ctx context.Context) repositories.SyncRepository { return r }
+
+func (r racingSyncRepo) WithTx(fn func(repo repositories.SyncRepository) error) error {
+	return r.st.withTx(func() error { return fn(r) })
+}
+
+func (r racingSyncRepo) Attendances() repositories.AttendanceRepository {
+	return racingAttendanceRepo{memAttendanceRepo{r.st}}
+}
+
+func TestSyncAttendancesReplaysAsDuplicate(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	req := &requests.AttendanceSyncRequest{Mutations: []requests.AttendanceSyncMutation{
+		newSyncMutation("7b0c3f8e-3c1a-4c55-9a57-1f1d7f0b2a01", classroom, students[0], 0, models.AttendanceStatusPresent),
+	}}
+
+	first, err := env.sync.SyncAttendances(t.Context(), teacher.ID, []uint{classroom.ID}, req)
+	if err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	if got := first.Results[0]; got.Status != SyncResultApplied || got.Attendance == nil || got.Attendance.Version != 1 {
+		t.Fatalf("first result = %+v", got)
+	}
+	if len(first.Changes) != 1 || first.Cursor == "" {
+		t.Errorf("changes = %d, cursor %q", len(first.Changes), first.Cursor)
+	}
+
+	req.Cursor = first.Cursor
+	second, err := env.sync.SyncAttendances(t.Context(), teacher.ID, []uint{classroom.ID}, req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if got := second.Results[0]; got.Status != SyncResultDuplicate || got.Attendance == nil || got.Attendance.ID != first.Results[0].Attendance.ID {
+		t.Errorf("replay result = %+v", got)
+	}
+	if len(second.Changes) != 0 {
+		t.Errorf("replay changed %d rows", len(second.Changes))
+	}
+	if got := env.store.eventTypes(); !slices.Equal(got, []string{"attendance.created"}) {
+		t.Errorf("events = %v, want only the first create", got)
+	}
+}
+
+func TestSyncAttendancesReportsLostUpdateAsConflict(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(1)
+	created, err := env.sync.SyncAttendances(t.Context(), teacher.ID, []uint{classroom.ID}, &requests.AttendanceSyncRequest{
+		Mutations: []requests.AttendanceSyncMutation{
+			newSyncMutation("7b0c3f8e-3c1a-4c55-9a57-1f1d7f0b2a02", classroom, students[0], 0, models.AttendanceStatusPresent),
+		},
+	})
+	if err != nil || created.Results[0].Status != SyncResultApplied {
+		t.Fatalf("create = %+v, %v", created, err)
+	}
+
+	racing := NewSyncService(racingSyncRepo{memSyncRepo{env.store}}, env.clock)
+	res, err := racing.SyncAttendances(t.Context(), teacher.ID, []uint{classroom.ID}, &requests.AttendanceSyncRequest{
+		Mutations: []requests.AttendanceSyncMutation{
+			newSyncMutation("7b0c3f8e-3c1a-4c55-9a57-1f1d7f0b2a03", classroom, students[0], 1, models.AttendanceStatusAbsent),
+		},
+	})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	// The write matched no row, so the client gets the other writer's copy instead of a false success
+	got := res.Results[0]
+	if got.Status != SyncResultConflict || got.Attendance == nil {
+		t.Fatalf("result = %+v, want a conflict with the server copy", got)
+	}
+	if got.Attendance.Version != 2 || got.Attendance.Status != models.AttendanceStatusLate {
+		t.Errorf("server copy = version %d status %s", got.Attendance.Version, got.Attendance.Status)
+	}
+	if _, err := (memSyncRepo{env.store}).FindMutation(teacher.ID, got.ClientMutationID); err == nil {
+		t.Errorf("mutation was recorded as applied, a retry would be reported as a duplicate")
+	}
+	if got := env.store.eventTypes(); !slices.Equal(got, []string{"attendance.created"}) {
+		t.Errorf("events = %v, want no update event", got)
+	}
+}
@@ -1,59 +1,79 @@
 package services
 
 import (
-	"easy-attend-service/configs"
+	"context"
 	"easy-attend-service/models"
+	"easy-attend-service/repositories"
 	"easy-attend-service/requests"
 	"easy-attend-service/utils"
-	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/etag"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/outbox"
+	"easy-attend-service/utils/tracing"
 	"errors"
-	"fmt"
+	"strconv"
 
 	"gorm.io/gorm"
 )
 
-type TeacherService struct{}
-
-func NewTeacherService() *TeacherService {
-	return &TeacherService{}
+type TeacherService struct {
+	teachers   repositories.TeacherRepository
+	classrooms repositories.ClassroomRepository
+	schools    repositories.SchoolRepository
 }
 
-func (s *TeacherService) GetAllTeachers(page, limit int) ([]models.Teacher, int64, error) {
-	var teachers []models.Teacher
-	var total int64
+func NewTeacherService(teachers repositories.TeacherRepository, classrooms repositories.ClassroomRepository, schools repositories.SchoolRepository) *TeacherService {
+	return &TeacherService{
+		teachers:   teachers,
+		classrooms: classrooms,
+		schools:    schools,
+	}
+}
 
-	// Count total records
-	if err := configs.DB.Model(&models.Teacher{}).Count(&total).Error; err != nil {
-		return nil, 0, errors.New("failed to count teachers")
+// parseTeacherID turns a path ID into a key; anything that is not a number cannot match a teacher
+func parseTeacherID(id string) (uint, bool) {
+	parsed, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, false
 	}
+	return uint(parsed), true
+}
 
-	// Calculate offset
-	offset := (page - 1) * limit
+func (s *TeacherService) GetAllTeachers(ctx context.Context, query listquery.Query) ([]models.Teacher, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "TeacherService.GetAllTeachers")
+	defer span.End()
 
-	// Get teachers with pagination
-	if err := configs.DB.Offset(offset).Limit(limit).Find(&teachers).Error; err != nil {
-		return nil, 0, errors.New("failed to get teachers")
+	teachers, err := s.teachers.WithContext(ctx).List(query)
+	if err != nil {
+		return nil, listquery.Pagination{}, errors.New("failed to get teachers")
 	}
 
-	return teachers, total, nil
+	teachers, meta := listquery.Page(query, teachers)
+	return teachers, meta, nil
 }
 
-func (s *TeacherService) GetTeacherByID(id uint) (*models.Teacher, error) {
-	var teacher models.Teacher
-	if err := configs.DB.Where("id = ?", id).First(&teacher).Error; err != nil {
+func (s *TeacherService) GetTeacherByID(ctx context.Context, id uint) (*models.Teacher, error) {
+	ctx, span := tracing.Start(ctx, "TeacherService.GetTeacherByID")
+	defer span.End()
+
+	teacher, err := s.teachers.WithContext(ctx).FindByID(id)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("teacher not found")
+			return nil, ErrTeacherNotFound
 		}
 		return nil, errors.New("failed to get teacher")
 	}
-	return &teacher, nil
+	return teacher, nil
 }
 
-func (s *TeacherService) CreateTeacher(req *requests.TeacherCreateRequest) (*models.Teacher, error) {
+func (s *TeacherService) CreateTeacher(ctx context.Context, req *requests.TeacherCreateRequest) (*models.Teacher, error) {
+	ctx, span := tracing.Start(ctx, "TeacherService.CreateTeacher")
+	defer span.End()
+
 	// Check if teacher already exists
-	var existingTeacher models.Teacher
-	if err := configs.DB.Where("email = ?", req.Email).First(&existingTeacher).Error; err == nil {
-		return nil, errors.New("teacher with this email already exists")
+	if taken, err := s.teachers.WithContext(ctx).EmailTaken(req.Email, 0); err == nil && taken {
+		return nil, ErrTeacherEmailTaken
 	}
 
 	// Hash password
@@ -72,43 +92,63 @@ func (s *TeacherService) CreateTeacher(req *requests.TeacherCreateRequest) (*mod
 		Phone:     req.Phone,
 	}
 
-	if err := configs.DB.Create(&teacher).Error; err != nil {
+	if err := s.teachers.WithContext(ctx).WithTx(func(tx repositories.TeacherRepository) error {
+		if err := tx.Create(&teacher); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "teacher.created",
+			AggregateType: "teacher",
+			AggregateID:   teacher.ID,
+			TeacherID:     teacher.ID,
+			SchoolID:      teacher.SchoolID,
+			Action:        models.LogActionCreateTeacher,
+			Message:       i18n.Msg("log.teacher_created", "first_name", req.FirstName, "last_name", req.LastName, "email", req.Email),
+			Payload:       teacher,
+		})
+	}); err != nil {
 		return nil, errors.New("failed to create teacher")
 	}
 
-	// Log activity automatically
-	logger.LogActivity(teacher.ID, models.LogActionCreateTeacher, fmt.Sprintf("สร้างครูใหม่: %s %s (%s)", req.FirstName, req.LastName, req.Email), teacher.SchoolID)
-
 	return &teacher, nil
 }
 
-func (s *TeacherService) UpdateTeacher(id string, req *requests.TeacherUpdateRequest) (*models.Teacher, error) {
-	var teacher models.Teacher
-	if err := configs.DB.Where("id = ?", id).First(&teacher).Error; err != nil {
+func (s *TeacherService) UpdateTeacher(ctx context.Context, id string, req *requests.TeacherUpdateRequest) (*models.Teacher, error) {
+	ctx, span := tracing.Start(ctx, "TeacherService.UpdateTeacher")
+	defer span.End()
+
+	teacherID, ok := parseTeacherID(id)
+	if !ok {
+		return nil, ErrTeacherNotFound
+	}
+	teacher, err := s.teachers.WithContext(ctx).FindByID(teacherID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("teacher not found")
+			return nil, ErrTeacherNotFound
 		}
 		return nil, errors.New("failed to find teacher")
 	}
 
+	if err := etag.CheckIfMatch(ctx, teacher); err != nil {
+		return nil, err
+	}
+
 	// Check if email is being changed and if it already exists
 	if req.Email != teacher.Email {
-		var existingTeacher models.Teacher
-		if err := configs.DB.Where("email = ? AND id != ?", req.Email, id).First(&existingTeacher).Error; err == nil {
-			return nil, errors.New("teacher with this email already exists")
+		if taken, err := s.teachers.WithContext(ctx).EmailTaken(req.Email, teacherID); err == nil && taken {
+			return nil, ErrTeacherEmailTaken
 		}
 	}
 
 	// Find or create school if school name is provided
 	if req.SchoolName != "" {
-		var school models.School
-		err := configs.DB.Where("name = ?", req.SchoolName).First(&school).Error
+		school, err := s.schools.WithContext(ctx).FindByName(req.SchoolName)
 		if err != nil {
 			// School doesn't exist, create new one
-			school = models.School{
+			school = &models.School{
 				Name: req.SchoolName,
 			}
-			if err := configs.DB.Create(&school).Error; err != nil {
+			if err := s.schools.WithContext(ctx).Create(school); err != nil {
 				return nil, errors.New("failed to create school")
 			}
 		}
@@ -118,6 +158,7 @@ func (s *TeacherService) UpdateTeacher(id string, req *requests.TeacherUpdateReq
 	teacher.FirstName = req.FirstName
 	teacher.LastName = req.LastName
 	teacher.Phone = req.Phone
+	teacher.Language = req.Language
 
 	// Update password if provided
 	if req.Password != "" {
@@ -127,36 +168,123 @@ func (s *TeacherService) UpdateTeacher(id string, req *requests.TeacherUpdateReq
 		}
 		teacher.Password = hashedPassword
 	}
+	baseVersion := teacher.Version
+	teacher.Version++
 
-	if err := configs.DB.Save(&teacher).Error; err != nil {
+	if err := s.teachers.WithContext(ctx).WithTx(func(tx repositories.TeacherRepository) error {
+		updated, err := tx.UpdateIfVersion(teacher, baseVersion)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// Someone else saved the teacher after it was read
+			current, err := tx.FindByID(teacherID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTeacherNotFound
+			}
+			if err != nil {
+				return err
+			}
+			return ErrTeacherVersionConflict.WithCurrent(current)
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "teacher.updated",
+			AggregateType: "teacher",
+			AggregateID:   teacher.ID,
+			TeacherID:     teacher.ID,
+			SchoolID:      teacher.SchoolID,
+			Action:        models.LogActionUpdateTeacher,
+			Message:       i18n.Msg("log.teacher_updated", "first_name", teacher.FirstName, "last_name", teacher.LastName, "email", teacher.Email),
+			Payload:       teacher,
+		})
+	}); err != nil {
+		if errors.Is(err, ErrTeacherVersionConflict) || errors.Is(err, ErrTeacherNotFound) {
+			return nil, err
+		}
 		return nil, errors.New("failed to update teacher")
 	}
 
-	// Log activity automatically
-	logger.LogActivity(teacher.ID, models.LogActionUpdateTeacher, fmt.Sprintf("อัพเดทข้อมูลครู: %s %s (%s)", teacher.FirstName, teacher.LastName, teacher.Email), teacher.SchoolID)
-
-	return &teacher, nil
+	return teacher, nil
 }
 
-func (s *TeacherService) DeleteTeacher(id string) error {
-	var teacher models.Teacher
-	if err := configs.DB.Where("id = ?", id).First(&teacher).Error; err != nil {
+func (s *TeacherService) DeleteTeacher(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "TeacherService.DeleteTeacher")
+	defer span.End()
+
+	teacherID, ok := parseTeacherID(id)
+	if !ok {
+		return ErrTeacherNotFound
+	}
+	teacher, err := s.teachers.WithContext(ctx).FindByID(teacherID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("teacher not found")
+			return ErrTeacherNotFound
 		}
 		return errors.New("failed to find teacher")
 	}
 
-	// Log activity automatically before deletion
-	logger.LogActivity(teacher.ID, models.LogActionDeleteTeacher, fmt.Sprintf("ลบข้อมูลครู: %s %s (%s)", teacher.FirstName, teacher.LastName, teacher.Email), teacher.SchoolID)
-
-	if err := configs.DB.Delete(&teacher).Error; err != nil {
+	if err := s.teachers.WithContext(ctx).WithTx(func(tx repositories.TeacherRepository) error {
+		if err := tx.SoftDelete(teacher); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "teacher.deleted",
+			AggregateType: "teacher",
+			AggregateID:   teacher.ID,
+			TeacherID:     teacher.ID,
+			SchoolID:      teacher.SchoolID,
+			Action:        models.LogActionDeleteTeacher,
+			Message:       i18n.Msg("log.teacher_deleted", "first_name", teacher.FirstName, "last_name", teacher.LastName, "email", teacher.Email),
+			Payload:       teacher,
+		})
+	}); err != nil {
 		return errors.New("failed to delete teacher")
 	}
 
 	return nil
 }
 
+// RestoreTeacher brings a teacher back from the trash, unless the email was taken again in the meantime
+func (s *TeacherService) RestoreTeacher(ctx context.Context, id string) (*models.Teacher, error) {
+	ctx, span := tracing.Start(ctx, "TeacherService.RestoreTeacher")
+	defer span.End()
+
+	teacherID, ok := parseTeacherID(id)
+	if !ok {
+		return nil, ErrDeletedTeacherNotFound
+	}
+	teacher, err := s.teachers.WithContext(ctx).FindDeletedByID(teacherID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDeletedTeacherNotFound
+		}
+		return nil, errors.New("failed to find teacher")
+	}
+
+	if err := s.teachers.WithContext(ctx).WithTx(func(tx repositories.TeacherRepository) error {
+		if err := tx.Restore(teacher); err != nil {
+			return err
+		}
+		return tx.Enqueue(outbox.Event{
+			Type:          "teacher.restored",
+			AggregateType: "teacher",
+			AggregateID:   teacher.ID,
+			TeacherID:     teacher.ID,
+			SchoolID:      teacher.SchoolID,
+			Action:        models.LogActionRestoreTeacher,
+			Message:       i18n.Msg("log.teacher_restored", "first_name", teacher.FirstName, "last_name", teacher.LastName, "email", teacher.Email),
+			Payload:       teacher,
+		})
+	}); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTeacherEmailTaken
+		}
+		return nil, errors.New("failed to restore teacher")
+	}
+
+	return teacher, nil
+}
+
 // TeacherInfo represents comprehensive teacher information
 type TeacherInfo struct {
 	Teacher    models.Teacher  `json:"teacher"`
@@ -178,43 +306,29 @@ type TeacherStats struct {
 }
 
 // GetTeacherInfo gets comprehensive information for a specific teacher
-func (s *TeacherService) GetTeacherInfo(teacherID uint) (*TeacherInfo, error) {
+func (s *TeacherService) GetTeacherInfo(ctx context.Context, teacherID uint) (*TeacherInfo, error) {
+	ctx, span := tracing.Start(ctx, "TeacherService.GetTeacherInfo")
+	defer span.End()
+
 	// Get teacher with relationships
-	var teacher models.Teacher
-	if err := configs.DB.Preload("School").Preload("Gender").Preload("Prefix").Where("id = ?", teacherID).First(&teacher).Error; err != nil {
+	teacher, err := s.teachers.WithContext(ctx).FindProfile(teacherID)
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("teacher not found")
+			return nil, ErrTeacherNotFound
 		}
 		return nil, errors.New("failed to get teacher")
 	}
 
 	// Get teacher's classrooms with preloaded students (optimized to prevent N+1 query)
-	var classrooms []models.Classroom
-	if err := configs.DB.
-		Preload("Students", func(db *gorm.DB) *gorm.DB {
-			return db.Preload("Gender").Preload("Prefix")
-		}).
-		Where("teacher_id = ?", teacherID).
-		Find(&classrooms).Error; err != nil {
+	classrooms, err := s.classrooms.WithContext(ctx).ListWithStudentsByTeacher(teacherID)
+	if err != nil {
 		return nil, errors.New("failed to get classrooms")
 	}
 
 	// Get attendance counts for all classrooms in a single query (optimized)
-	type AttendanceCountResult struct {
-		ClassroomID uint
-		Count       int64
-	}
-	var attendanceCounts []AttendanceCountResult
-	configs.DB.Model(&models.Attendance{}).
-		Select("classroom_id, COUNT(*) as count").
-		Where("teacher_id = ?", teacherID).
-		Group("classroom_id").
-		Scan(&attendanceCounts)
-
-	// Create a map for quick lookup
-	attendanceCountMap := make(map[uint]int64)
-	for _, ac := range attendanceCounts {
-		attendanceCountMap[ac.ClassroomID] = ac.Count
+	attendanceCountMap, err := s.teachers.WithContext(ctx).CountAttendancesByClassroom(teacherID)
+	if err != nil {
+		attendanceCountMap = map[uint]int64{}
 	}
 
 	// Get detailed classroom info with students
@@ -245,7 +359,7 @@ func (s *TeacherService) GetTeacherInfo(teacherID uint) (*TeacherInfo, error) {
 
 	// Prepare teacher info
 	teacherInfo := &TeacherInfo{
-		Teacher:    teacher,
+		Teacher:    *teacher,
 		School:     *teacher.School,
 		Classrooms: classroomInfos,
 		TotalStats: TeacherStats{
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"easy-attend-service/requests"
+	"easy-attend-service/utils/etag"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCreateTeacherRejectsTakenEmail(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, _, _ := env.seed(0)
+
+	_, err := env.teacher.CreateTeacher(t.Context(), &requests.TeacherCreateRequest{
+		SchoolID: school.ID, Email: teacher.Email, Password: "secret1", FirstName: "สมหญิง", LastName: "ใจงาม",
+	})
+	if err == nil || err.Error() != "teacher with this email already exists" {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+func TestTeacherPathIDMustBeNumeric(t *testing.T) {
+	env := newTestEnv()
+	env.seed(0)
+
+	if _, err := env.teacher.UpdateTeacher(t.Context(), "abc", &requests.TeacherUpdateRequest{}); err == nil || err.Error() != "teacher not found" {
+		t.Errorf("update error = %v", err)
+	}
+	if err := env.teacher.DeleteTeacher(t.Context(), "-1"); err == nil || err.Error() != "teacher not found" {
+		t.Errorf("delete error = %v", err)
+	}
+	if _, err := env.teacher.RestoreTeacher(t.Context(), "1x"); err == nil || err.Error() != "deleted teacher not found" {
+		t.Errorf("restore error = %v", err)
+	}
+}
+
+func TestRestoreTeacherRejectsReusedEmail(t *testing.T) {
+	env := newTestEnv()
+	school, teacher, _, _ := env.seed(0)
+	id := fmt.Sprintf("%d", teacher.ID)
+
+	if err := env.teacher.DeleteTeacher(t.Context(), id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := env.teacher.GetTeacherByID(t.Context(), teacher.ID); err == nil || err.Error() != "teacher not found" {
+		t.Fatalf("get after delete error = %v", err)
+	}
+
+	if _, err := env.teacher.CreateTeacher(t.Context(), &requests.TeacherCreateRequest{
+		SchoolID: school.ID, Email: teacher.Email, Password: "secret1", FirstName: "สมหญิง", LastName: "ใจงาม",
+	}); err != nil {
+		t.Fatalf("reuse email: %v", err)
+	}
+	_, err := env.teacher.RestoreTeacher(t.Context(), id)
+	if err == nil || err.Error() != "teacher with this email already exists" {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+func TestGetTeacherInfoTotals(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(3)
+	for _, student := range students[:2] {
+		env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, student))
+	}
+	env.student.DeleteStudent(t.Context(), students[2].ID)
+
+	info, err := env.teacher.GetTeacherInfo(t.Context(), teacher.ID)
+	if err != nil {
+		t.Fatalf("info: %v", err)
+	}
+	if info.School.ID != *teacher.SchoolID {
+		t.Errorf("school = %d", info.School.ID)
+	}
+	if info.TotalStats.TotalClassrooms != 1 || info.TotalStats.TotalStudents != 2 || info.TotalStats.TotalAttendance != 2 {
+		t.Errorf("stats = %+v", info.TotalStats)
+	}
+}
+
+func TestUpdateTeacherIfMatchCatchesSameSecondWrite(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, _, _ := env.seed(0)
+	id := fmt.Sprintf("%d", teacher.ID)
+
+	// Both clients read the teacher; the tag of a GET also hashes its body
+	read := etag.Representation(teacher.ETag(), []byte(`{"data":{"firstname":"สมชาย"}}`))
+	req := &requests.TeacherUpdateRequest{Email: teacher.Email, FirstName: "สมชาย", LastName: "ใจดีมาก"}
+
+	updated, err := env.teacher.UpdateTeacher(etag.WithIfMatch(t.Context(), read), id, req)
+	if err != nil {
+		t.Fatalf("update with the tag from GET: %v", err)
+	}
+	// The clock has not moved, only the version tells the writes apart
+	if updated.UpdatedAt != teacher.UpdatedAt || updated.ETag() == teacher.ETag() {
+		t.Fatalf("updated_at %d -> %d, ETag %s -> %s", teacher.UpdatedAt, updated.UpdatedAt, teacher.ETag(), updated.ETag())
+	}
+
+	req.LastName = "ใจงาม"
+	if _, err := env.teacher.UpdateTeacher(etag.WithIfMatch(t.Context(), read), id, req); !errors.Is(err, etag.ErrPreconditionFailed) {
+		t.Fatalf("update with a stale tag: error = %v, want %v", err, etag.ErrPreconditionFailed)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/repositories"
+	"easy-attend-service/utils/listquery"
+	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/tracing"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Resources that can be listed in the trash
+const (
+	TrashResourceClassrooms  = "classrooms"
+	TrashResourceStudents    = "students"
+	TrashResourceTeachers    = "teachers"
+	TrashResourceAttendances = "attendances"
+)
+
+type TrashService struct {
+	trash    repositories.TrashRepository
+	teachers repositories.TeacherRepository
+}
+
+// PurgeResult counts the rows removed for good by PurgeDeleted, and the expired rows
+// kept because something still references them
+type PurgeResult struct {
+	Attendances    int64
+	Students       int64
+	Classrooms     int64
+	Teachers       int64
+	KeptStudents   int64
+	KeptClassrooms int64
+	KeptTeachers   int64
+}
+
+func NewTrashService(trash repositories.TrashRepository, teachers repositories.TeacherRepository) *TrashService {
+	return &TrashService{trash: trash, teachers: teachers}
+}
+
+// GetTrash lists one page of one kind of deleted rows: those in the classrooms the
+// teacher owns or joined, or for teachers, those of the same school
+func (s *TrashService) GetTrash(ctx context.Context, teacherID uint, resource string, query listquery.Query) (any, listquery.Pagination, error) {
+	ctx, span := tracing.Start(ctx, "TrashService.GetTrash")
+	defer span.End()
+
+	teacher, err := s.teachers.WithContext(ctx).FindByID(teacherID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, listquery.Pagination{}, ErrTeacherNotFound
+		}
+		return nil, listquery.Pagination{}, errors.New("failed to fetch trash")
+	}
+
+	trash := s.trash.WithContext(ctx)
+	var items any
+	var meta listquery.Pagination
+	switch resource {
+	case TrashResourceClassrooms:
+		var classrooms []models.Classroom
+		if classrooms, err = trash.ListClassrooms(teacherID, query); err == nil {
+			items, meta = listquery.Page(query, classrooms)
+		}
+	case TrashResourceStudents:
+		var students []models.Student
+		if students, err = trash.ListStudents(teacherID, query); err == nil {
+			items, meta = listquery.Page(query, students)
+		}
+	case TrashResourceTeachers:
+		var teachers []models.Teacher
+		if teachers, err = trash.ListTeachers(teacher.SchoolID, query); err == nil {
+			items, meta = listquery.Page(query, teachers)
+		}
+	case TrashResourceAttendances:
+		var attendances []models.Attendance
+		if attendances, err = trash.ListAttendances(teacherID, query); err == nil {
+			items, meta = listquery.Page(query, attendances)
+		}
+	default:
+		err = fmt.Errorf("unknown trash resource %q", resource)
+	}
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to fetch trash", logrus.Fields{
+			"teacher_id": fmt.Sprintf("%d", teacherID),
+			"type":       resource,
+		})
+		return nil, listquery.Pagination{}, errors.New("failed to fetch trash")
+	}
+
+	return items, meta, nil
+}
+
+// PurgeDeleted permanently removes rows that were deleted before the cutoff. Children
+// go first so foreign key cascades never reach live history, and a teacher is kept
+// while any classroom or attendance still points at them.
+func (s *TrashService) PurgeDeleted(ctx context.Context, cutoff time.Time) (*PurgeResult, error) {
+	ctx, span := tracing.Start(ctx, "TrashService.PurgeDeleted")
+	defer span.End()
+
+	result := &PurgeResult{}
+	before := cutoff.Unix()
+
+	err := s.trash.WithContext(ctx).WithTx(func(tx repositories.TrashRepository) error {
+		var err error
+		if result.Attendances, err = tx.PurgeAttendances(before); err != nil {
+			return err
+		}
+		if result.Students, result.KeptStudents, err = tx.PurgeStudents(before); err != nil {
+			return err
+		}
+		if result.Classrooms, result.KeptClassrooms, err = tx.PurgeClassrooms(before); err != nil {
+			return err
+		}
+		result.Teachers, result.KeptTeachers, err = tx.PurgeTeachers(before)
+		return err
+	})
+	if err != nil {
+		logger.LogError(ctx, err, "Failed to purge deleted rows", logrus.Fields{
+			"cutoff": before,
+		})
+		return nil, errors.New("failed to purge deleted rows")
+	}
+
+	logger.LogInfo(ctx, "Purged deleted rows", logrus.Fields{
+		"cutoff":      before,
+		"attendances": result.Attendances,
+		"students":    result.Students,
+		"classrooms":  result.Classrooms,
+		"teachers":    result.Teachers,
+	})
+	if result.KeptStudents+result.KeptClassrooms+result.KeptTeachers > 0 {
+		// They go on a later run once nothing references them any more
+		logger.LogWarning(ctx, "Kept expired deleted rows that are still referenced", logrus.Fields{
+			"cutoff":     before,
+			"students":   result.KeptStudents,
+			"classrooms": result.KeptClassrooms,
+			"teachers":   result.KeptTeachers,
+		})
+	}
+
+	return result, nil
+}
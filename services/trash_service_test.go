@@ -0,0 +1,74 @@
+package services
+
+import (
+	"easy-attend-service/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGetTrashPagesMostRecentlyDeletedFirst(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, _, students := env.seed(3)
+	// Deleted in an order unrelated to the IDs: STD002 last, STD003 first
+	hoursAgo := []int{2, 1, 3}
+	for i, student := range students {
+		deletedAt := models.NewDeletedAt(env.clock.Now().Add(-time.Duration(hoursAgo[i]) * time.Hour))
+		if err := (memStudentRepo{env.store}).SoftDelete(&student, deletedAt); err != nil {
+			t.Fatalf("delete %s: %v", student.StudentNo, err)
+		}
+	}
+
+	var got []string
+	cursor := ""
+	for page := 1; ; page++ {
+		items, meta, err := env.trash.GetTrash(t.Context(), teacher.ID, TrashResourceStudents, list(t, TrashListing, "limit=2&cursor="+cursor))
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		rows, ok := items.([]models.Student)
+		if !ok {
+			t.Fatalf("page %d: items are %T", page, items)
+		}
+		for _, s := range rows {
+			got = append(got, s.StudentNo)
+		}
+		if !meta.HasMore {
+			break
+		}
+		cursor = *meta.NextCursor
+	}
+
+	if want := []string{"STD002", "STD001", "STD003"}; !slices.Equal(got, want) {
+		t.Errorf("trash = %v, want %v", got, want)
+	}
+}
+
+func TestPurgeDeletedReportsReferencedRowsAsKept(t *testing.T) {
+	env := newTestEnv()
+	_, teacher, classroom, students := env.seed(2)
+	if _, err := env.attendance.CreateAttendance(t.Context(), newAttendanceRequest(classroom, teacher, students[0])); err != nil {
+		t.Fatalf("create attendance: %v", err)
+	}
+
+	// Both students are long gone, but a live attendance still points at the first
+	deletedAt := models.NewDeletedAt(env.clock.Now().AddDate(0, -2, 0))
+	for _, student := range students {
+		student.DeletedAt = deletedAt
+		env.store.students[student.ID] = student
+	}
+
+	result, err := env.trash.PurgeDeleted(t.Context(), env.clock.Now().AddDate(0, -1, 0))
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if result.Students != 1 || result.KeptStudents != 1 {
+		t.Errorf("students purged %d kept %d, want 1 and 1", result.Students, result.KeptStudents)
+	}
+	if _, ok := env.store.students[students[0].ID]; !ok {
+		t.Errorf("referenced student was purged")
+	}
+	if _, ok := env.store.students[students[1].ID]; ok {
+		t.Errorf("unreferenced student was kept")
+	}
+}
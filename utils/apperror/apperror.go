@@ -0,0 +1,131 @@
+package apperror
+
+import "errors"
+
+// Kind จัดกลุ่มข้อผิดพลาดของโดเมน, the error middleware maps each kind to an HTTP status
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindUnprocessable
+	KindRateLimited
+	KindPreconditionFailed
+)
+
+// Error is a domain error with a stable code that clients can localize, e.g.
+// "attendance.not_found". Message is the English detail and stays what Error()
+// returns, so logs and callers comparing messages keep working.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Params fill the placeholders of the localized message, e.g. {name}
+	Params map[string]string
+	// Err is the underlying cause, logged but never sent to the client
+	Err error
+	// Current is the record as it is now, sent with a conflict so the client can merge
+	Current any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors by code, so a wrapped copy still matches its sentinel
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of e carrying cause; sentinels themselves are never modified
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Err = cause
+	return &wrapped
+}
+
+// With returns a copy of e with a message parameter set
+func (e *Error) With(name, value string) *Error {
+	copied := *e
+	copied.Params = make(map[string]string, len(e.Params)+1)
+	for k, v := range e.Params {
+		copied.Params[k] = v
+	}
+	copied.Params[name] = value
+	return &copied
+}
+
+// WithCurrent returns a copy of e carrying the current state of the record
+func (e *Error) WithCurrent(current any) *Error {
+	copied := *e
+	copied.Current = current
+	return &copied
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Validation: the request is malformed or breaks a business rule (400)
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+// Unauthorized: the caller is not authenticated (401)
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+// Forbidden: the caller may not touch this resource (403)
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+// NotFound: the resource does not exist or was deleted (404)
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+// Conflict: the request clashes with the current state, e.g. a duplicate (409)
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// Unprocessable: the request is well formed but cannot be applied (422)
+func Unprocessable(code, message string) *Error {
+	return New(KindUnprocessable, code, message)
+}
+
+// PreconditionFailed: a conditional header such as If-Match no longer holds (412)
+func PreconditionFailed(code, message string) *Error {
+	return New(KindPreconditionFailed, code, message)
+}
+
+// RateLimited: the caller sent too many requests (429)
+func RateLimited(code, message string) *Error {
+	return New(KindRateLimited, code, message)
+}
+
+// Internal: something failed on our side (500); the message is not shown to clients
+func Internal(code, message string) *Error {
+	return New(KindInternal, code, message)
+}
+
+// From returns the domain error in err's chain; anything else becomes an internal
+// error wrapping err
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal("internal_error", "an unexpected error occurred").Wrap(err)
+}
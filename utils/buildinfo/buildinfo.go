@@ -0,0 +1,53 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// Version and Commit are stamped at build time:
+//
+//	go build -ldflags "-X easy-attend-service/utils/buildinfo.Version=1.4.0 -X easy-attend-service/utils/buildinfo.Commit=$(git rev-parse --short HEAD)"
+//
+// Without ldflags the commit falls back to the VCS revision Go records in the binary
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+var startedAt = time.Now()
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	StartedAt string `json:"started_at"`
+	Uptime    string `json:"uptime"`
+}
+
+// Get returns the build info and how long this process has been running
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    commit(),
+		GoVersion: runtime.Version(),
+		StartedAt: startedAt.UTC().Format(time.RFC3339),
+		Uptime:    time.Since(startedAt).Round(time.Second).String(),
+	}
+}
+
+func commit() string {
+	if Commit != "" {
+		return Commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
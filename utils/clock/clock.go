@@ -0,0 +1,15 @@
+package clock
+
+import "time"
+
+// Clock บอกเวลาปัจจุบัน แยกออกมาเพื่อให้ test กำหนดเวลาเองได้
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock used in production
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
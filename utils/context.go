@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"easy-attend-service/utils/apperror"
 	"errors"
 	"strconv"
 
@@ -8,22 +9,25 @@ import (
 	"github.com/google/uuid"
 )
 
+// errUnauthenticated is returned when the JWT context has no usable user ID
+var errUnauthenticated = apperror.Unauthorized("auth.unauthenticated", "user ID not found in token")
+
 // GetTeacherIDFromContext ดึง teacher ID จาก JWT context (รูปแบบ uint)
 func GetTeacherIDFromContext(c *gin.Context) (uint, error) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		return 0, errors.New("user ID not found in context")
+		return 0, errUnauthenticated
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		return 0, errors.New("invalid user ID format in context")
+		return 0, errUnauthenticated
 	}
 
 	// Parse as uint
 	id, err := strconv.ParseUint(userIDStr, 10, 32)
 	if err != nil {
-		return 0, errors.New("failed to parse teacher ID")
+		return 0, errUnauthenticated
 	}
 
 	return uint(id), nil
@@ -33,17 +37,17 @@ func GetTeacherIDFromContext(c *gin.Context) (uint, error) {
 func GetTeacherIDUUIDFromContext(c *gin.Context) (uuid.UUID, error) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		return uuid.Nil, errors.New("user ID not found in context")
+		return uuid.Nil, errUnauthenticated
 	}
 
 	userIDStr, ok := userID.(string)
 	if !ok {
-		return uuid.Nil, errors.New("invalid user ID format in context")
+		return uuid.Nil, errUnauthenticated
 	}
 
 	teacherID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		return uuid.Nil, errors.New("failed to parse teacher ID")
+		return uuid.Nil, errUnauthenticated
 	}
 
 	return teacherID, nil
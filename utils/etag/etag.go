@@ -0,0 +1,89 @@
+// Package etag builds entity tags and evaluates the conditional request headers:
+// If-None-Match lets a client revalidate a cached GET and get 304 Not Modified,
+// If-Match lets it update a record only while it is still the version it read.
+package etag
+
+import (
+	"context"
+	"crypto/sha256"
+	"easy-attend-service/utils/apperror"
+	"encoding/base64"
+	"strings"
+)
+
+// ErrPreconditionFailed is returned when If-Match names a version the record no longer has
+var ErrPreconditionFailed = apperror.PreconditionFailed("precondition.failed", "the record was changed since it was read")
+
+// Tagged is a stored record that knows its own tag, see models
+type Tagged interface {
+	ETag() string
+}
+
+type ifMatchKey struct{}
+
+// WithIfMatch returns ctx carrying the request's If-Match header
+func WithIfMatch(ctx context.Context, header string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, header)
+}
+
+// CheckIfMatch compares the If-Match header in ctx with the record about to be
+// changed. It passes when the request has no If-Match.
+func CheckIfMatch(ctx context.Context, current Tagged) error {
+	header, _ := ctx.Value(ifMatchKey{}).(string)
+	if header == "" || Matches(header, current.ETag(), false) {
+		return nil
+	}
+	return ErrPreconditionFailed.With("etag", current.ETag())
+}
+
+// Weak tags a response that is not one record, such as a list page, by a hash of its body
+func Weak(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// Representation extends a record's strong tag with a hash of one response body,
+// so the full record, a ?fields= selection, an ?expand= and each language each
+// get their own tag. If-Match compares only the record part, so any of them can
+// be sent back to update the record.
+func Representation(tag string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strings.TrimSuffix(tag, `"`) + "." + base64.RawURLEncoding.EncodeToString(sum[:9]) + `"`
+}
+
+// record drops the part Representation added; base64url and the record tags never contain a dot
+func record(tag string) string {
+	if i := strings.IndexByte(tag, '.'); i >= 0 {
+		return tag[:i] + `"`
+	}
+	return tag
+}
+
+// Matches reports whether tag is listed in an If-Match or If-None-Match header.
+// If-None-Match compares weakly (W/ is ignored) and whole tags, so a cached
+// representation only matches itself. If-Match compares strongly (weak tags never
+// match) and only the record part, see Representation.
+func Matches(header, tag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if weak {
+		tag = strings.TrimPrefix(tag, "W/")
+	} else if strings.HasPrefix(tag, "W/") {
+		return false
+	} else {
+		tag = record(tag)
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		} else if !strings.HasPrefix(candidate, "W/") {
+			candidate = record(candidate)
+		}
+		if candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// Package fieldset reads ?fields= and ?expand= so clients fetch only what they
+// render:
+//
+//	GET /attendances?fields=id,status,session_date&expand=student,student.prefix
+//
+// fields picks the columns that are selected and sent, expand picks the relations
+// that are preloaded. Both are whitelisted per resource in a Spec; without them an
+// endpoint sends every column and no relations.
+package fieldset
+
+import (
+	"easy-attend-service/utils/apperror"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidField  = apperror.Validation("fields.invalid", "unknown field")
+	ErrInvalidExpand = apperror.Validation("expand.invalid", "unknown relation")
+)
+
+// Relation is one name clients may pass to expand
+type Relation struct {
+	Preload string // GORM association path, e.g. "Student.Prefix"
+	Column  string // Foreign key the preload reads; selected even when fields leaves it out
+}
+
+// Spec whitelists the fields and relations of one resource
+type Spec struct {
+	Fields    map[string]string   // JSON name to column, the JSON name when empty
+	Relations map[string]Relation // expand name, e.g. "student.prefix"
+}
+
+// Selection is a parsed fields and expand pair
+type Selection struct {
+	spec     Spec
+	columns  []string        // nil selects every column
+	render   map[string]bool // Top-level JSON keys to send, nil sends all
+	preloads []string
+}
+
+// Parse reads fields= and expand= from values; both are comma separated
+func Parse(values url.Values, spec Spec) (Selection, error) {
+	selection := Selection{spec: spec}
+
+	for _, name := range split(values.Get("expand")) {
+		relation, ok := spec.Relations[name]
+		if !ok {
+			return Selection{}, ErrInvalidExpand.With("relation", name)
+		}
+		if !slices.Contains(selection.preloads, relation.Preload) {
+			selection.preloads = append(selection.preloads, relation.Preload)
+		}
+	}
+
+	fields := split(values.Get("fields"))
+	if len(fields) == 0 {
+		return selection, nil
+	}
+	// id is always sent so clients can key what they render
+	selection.render = map[string]bool{"id": true}
+	selection.addField("id")
+	for _, name := range fields {
+		if _, ok := spec.Fields[name]; !ok {
+			return Selection{}, ErrInvalidField.With("field", name)
+		}
+		selection.render[name] = true
+		selection.addField(name)
+	}
+	for _, name := range split(values.Get("expand")) {
+		top, _, _ := strings.Cut(name, ".")
+		selection.render[top] = true
+		if column := spec.Relations[name].Column; column != "" {
+			selection.addColumn(column)
+		}
+	}
+	return selection, nil
+}
+
+// Including also selects fields without sending them, e.g. the sort fields a list
+// cursor is built from. It does nothing when every column is selected anyway.
+func (s Selection) Including(fields ...string) Selection {
+	if s.columns == nil {
+		return s
+	}
+	s.columns = slices.Clone(s.columns)
+	for _, name := range fields {
+		s.addField(name)
+	}
+	return s
+}
+
+func (s *Selection) addField(name string) {
+	column, ok := s.spec.Fields[name]
+	if !ok {
+		return
+	}
+	if column == "" {
+		column = name
+	}
+	s.addColumn(column)
+}
+
+func (s *Selection) addColumn(column string) {
+	if !slices.Contains(s.columns, column) {
+		s.columns = append(s.columns, column)
+	}
+}
+
+// Scope selects the columns and preloads the relations
+func (s Selection) Scope(db *gorm.DB) *gorm.DB {
+	if s.columns != nil {
+		db = db.Select(s.columns)
+	}
+	for _, preload := range s.preloads {
+		db = db.Preload(preload)
+	}
+	return db
+}
+
+// Render drops the fields the client did not ask for from v, a model or a slice
+// of models. v is returned as is when fields was not given.
+func (s Selection) Render(v any) any {
+	if s.render == nil {
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err == nil {
+		for _, row := range rows {
+			s.trim(row)
+		}
+		return rows
+	}
+	var row map[string]json.RawMessage
+	if err := json.Unmarshal(data, &row); err != nil {
+		return v
+	}
+	s.trim(row)
+	return row
+}
+
+func (s Selection) trim(row map[string]json.RawMessage) {
+	for key := range row {
+		if !s.render[key] {
+			delete(row, key)
+		}
+	}
+}
+
+func split(value string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,172 @@
+package i18n
+
+import (
+	"context"
+	"easy-attend-service/configs"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang is a supported language, named by its ISO 639-1 code
+type Lang string
+
+const (
+	Thai    Lang = "th"
+	English Lang = "en"
+)
+
+// Supported lists the languages that have a catalog
+var Supported = []Lang{Thai, English}
+
+//go:embed locales/*.json
+var locales embed.FS
+
+// catalogs maps language to message key to template, loaded once at start
+var catalogs = loadCatalogs()
+
+// defaultLang is used when neither the profile nor Accept-Language picks a language
+var defaultLang = Thai
+
+// Init sets the default language from the configuration
+func Init(cfg configs.I18nConfig) {
+	if lang, ok := Parse(cfg.DefaultLanguage); ok {
+		defaultLang = lang
+	}
+}
+
+// Default returns the configured default language
+func Default() Lang {
+	return defaultLang
+}
+
+// Message is a translatable text stored as key and parameters, so it can be
+// rendered in any language later, e.g. {Key: "log.attendance_recorded", Params: {"status": "late"}}
+type Message struct {
+	Key    string            `json:"key"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Msg builds a Message; params alternate name and value
+func Msg(key string, params ...string) Message {
+	msg := Message{Key: key}
+	if len(params) > 0 {
+		msg.Params = make(map[string]string, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			msg.Params[params[i]] = params[i+1]
+		}
+	}
+	return msg
+}
+
+// Parse accepts a language tag such as "th", "th-TH" or "en_US"
+func Parse(tag string) (Lang, bool) {
+	primary, _, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	lang := Lang(strings.ToLower(primary))
+	if _, ok := catalogs[lang]; ok {
+		return lang, true
+	}
+	return "", false
+}
+
+// Negotiate picks the supported language the client prefers most from an
+// Accept-Language header, honouring q-values
+func Negotiate(acceptLanguage string) (Lang, bool) {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag != "" && q > 0 {
+			candidates = append(candidates, candidate{tag, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if lang, ok := Parse(c.tag); ok {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the request language
+func NewContext(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext returns the request language, or the default outside a request
+func FromContext(ctx context.Context) Lang {
+	if ctx != nil {
+		if lang, ok := ctx.Value(contextKey{}).(Lang); ok {
+			return lang
+		}
+	}
+	return defaultLang
+}
+
+// Lookup renders key in lang, falling back to the default language. ok is false
+// when neither catalog knows the key.
+func Lookup(lang Lang, key string, params map[string]string) (string, bool) {
+	template, ok := catalogs[lang][key]
+	if !ok {
+		template, ok = catalogs[defaultLang][key]
+	}
+	if !ok {
+		return "", false
+	}
+	for name, value := range params {
+		template = strings.ReplaceAll(template, "{"+name+"}", value)
+	}
+	return template, true
+}
+
+// Render renders key in lang, or returns the key itself when it is unknown
+func Render(lang Lang, key string, params map[string]string) string {
+	if text, ok := Lookup(lang, key, params); ok {
+		return text
+	}
+	return key
+}
+
+// T renders key in the language of the request in ctx
+func T(ctx context.Context, key string, params map[string]string) string {
+	return Render(FromContext(ctx), key, params)
+}
+
+// Render renders the message in lang
+func (m Message) Render(lang Lang) string {
+	return Render(lang, m.Key, m.Params)
+}
+
+func loadCatalogs() map[Lang]map[string]string {
+	loaded := make(map[Lang]map[string]string, len(Supported))
+	for _, lang := range Supported {
+		data, err := locales.ReadFile("locales/" + string(lang) + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog for %s: %v", lang, err))
+		}
+		catalog := map[string]string{}
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog for %s: %v", lang, err))
+		}
+		loaded[lang] = catalog
+	}
+	return loaded
+}
@@ -2,32 +2,37 @@ package jwt
 
 import (
 	"context"
+	"easy-attend-service/configs"
 	"easy-attend-service/models"
 	"errors"
 	"log"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/joho/godotenv"
 )
 
+// settings holds the signing secret and token lifetime set by Init
+var settings = configs.Default().JWT
+
+// Init sets the secret and token lifetime used to sign and verify tokens
+func Init(cfg configs.JWTConfig) {
+	settings = cfg
+}
+
 type CustomClaims struct {
 	UserID   string `json:"user_id"`
 	Email    string `json:"email"`
 	UserType string `json:"user_type"`
+	Language string `json:"language,omitempty"` // Profile language, empty to follow Accept-Language
 }
 
 func VerifyToken(raw string) (map[string]any, error) {
-	godotenv.Load()
 	token, err := jwt.Parse(raw, func(token *jwt.Token) (
 		interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid token singing method")
 		}
-		secret := []byte(os.Getenv("JWT_SECRET"))
-		return secret, nil
+		return []byte(settings.Secret), nil
 	})
 	if err != nil {
 		if err == jwt.ErrSignatureInvalid {
@@ -43,15 +48,7 @@ func VerifyToken(raw string) (map[string]any, error) {
 }
 
 func GenerateTokenTeacher(ctx context.Context, teacher *models.Teacher) (string, error) {
-	godotenv.Load()
-
-	// Get JWT expiry hours from environment, default to 24 hours
-	expireHours := 24
-	if hours := os.Getenv("JWT_EXPIRE_HOURS"); hours != "" {
-		if h, err := strconv.Atoi(hours); err == nil {
-			expireHours = h
-		}
-	}
+	expireHours := settings.ExpireHours
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub": jwt.MapClaims{
@@ -65,8 +62,7 @@ func GenerateTokenTeacher(ctx context.Context, teacher *models.Teacher) (string,
 		"exp": time.Now().Add(time.Duration(expireHours) * time.Hour).Unix(),
 	})
 
-	secret := []byte(os.Getenv("JWT_SECRET"))
-	tokenString, err := token.SignedString(secret)
+	tokenString, err := token.SignedString([]byte(settings.Secret))
 	if err != nil {
 		log.Printf("[error]: %v", err)
 		return "", err
@@ -75,15 +71,7 @@ func GenerateTokenTeacher(ctx context.Context, teacher *models.Teacher) (string,
 }
 
 func GenerateToken(claims CustomClaims) (string, time.Time, error) {
-	godotenv.Load()
-
-	// Get JWT expiry hours from environment, default to 24 hours
-	expireHours := 24
-	if hours := os.Getenv("JWT_EXPIRE_HOURS"); hours != "" {
-		if h, err := strconv.Atoi(hours); err == nil {
-			expireHours = h
-		}
-	}
+	expireHours := settings.ExpireHours
 
 	expiresAt := time.Now().Add(time.Duration(expireHours) * time.Hour)
 
@@ -91,15 +79,44 @@ func GenerateToken(claims CustomClaims) (string, time.Time, error) {
 		"user_id":   claims.UserID,
 		"email":     claims.Email,
 		"user_type": claims.UserType,
+		"language":  claims.Language,
 		"nbf":       time.Now().Unix(),
 		"exp":       expiresAt.Unix(),
 	})
 
-	secret := []byte(os.Getenv("JWT_SECRET"))
-	tokenString, err := token.SignedString(secret)
+	tokenString, err := token.SignedString([]byte(settings.Secret))
 	if err != nil {
 		log.Printf("[error]: %v", err)
 		return "", time.Time{}, err
 	}
 	return tokenString, expiresAt, nil
 }
+
+// TicketScopeStream is the scope of tickets for the attendance stream and the
+// roll-call socket, which browsers open without an Authorization header
+const TicketScopeStream = "stream"
+
+// TicketTTL is how long a ticket can open a connection; an open connection stays open
+const TicketTTL = time.Minute
+
+// GenerateTicket signs a short-lived token limited to scope. Tickets travel in query
+// strings, so they expire quickly and are refused as bearer tokens.
+func GenerateTicket(claims CustomClaims, scope string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(TicketTTL)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":   claims.UserID,
+		"email":     claims.Email,
+		"user_type": claims.UserType,
+		"language":  claims.Language,
+		"scope":     scope,
+		"nbf":       time.Now().Unix(),
+		"exp":       expiresAt.Unix(),
+	})
+
+	tokenString, err := token.SignedString([]byte(settings.Secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
+}
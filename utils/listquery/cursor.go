@@ -0,0 +1,162 @@
+package listquery
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Pagination is the "pagination" block of a list response
+type Pagination struct {
+	Limit      int     `json:"limit"`
+	HasMore    bool    `json:"has_more"`
+	NextCursor *string `json:"next_cursor"` // null on the last page
+}
+
+// cursor is the opaque value clients pass back; S ties it to the list it came from
+type cursor struct {
+	S string `json:"s"`
+	V []any  `json:"v"`
+}
+
+// Page trims the look-ahead row that Scope and Apply fetch and returns the page
+// with its metadata. rows is never nil so an empty page encodes as [].
+func Page[T any](q Query, rows []T) ([]T, Pagination) {
+	meta := Pagination{Limit: q.Limit}
+	if rows == nil {
+		rows = []T{}
+	}
+	if len(rows) > q.Limit {
+		rows = rows[:q.Limit]
+		meta.HasMore = true
+		next := q.encodeCursor(q.values(rows[len(rows)-1]))
+		meta.NextCursor = &next
+	}
+	return rows, meta
+}
+
+func (q Query) encodeCursor(values []any) string {
+	data, _ := json.Marshal(cursor{S: q.hash(), V: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (q Query) decodeCursor(raw string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var c cursor
+	if err := decoder.Decode(&c); err != nil {
+		return nil, err
+	}
+	if c.S != q.hash() || len(c.V) != len(q.Sort) {
+		return nil, errors.New("cursor belongs to another list")
+	}
+
+	values := make([]any, len(c.V))
+	for i, order := range q.Sort {
+		switch v := c.V[i].(type) {
+		case json.Number:
+			if order.kind != Int {
+				return nil, errors.New("cursor value has the wrong type")
+			}
+			if values[i], err = v.Int64(); err != nil {
+				return nil, err
+			}
+		case string:
+			if order.kind != String {
+				return nil, errors.New("cursor value has the wrong type")
+			}
+			values[i] = v
+		default:
+			return nil, errors.New("cursor value has the wrong type")
+		}
+	}
+	return values, nil
+}
+
+func (q Query) hash() string {
+	h := fnv.New64a()
+	h.Write([]byte(q.signature()))
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// values reads the sort fields of row
+func (q Query) values(row any) []any {
+	values := make([]any, len(q.Sort))
+	for i, order := range q.Sort {
+		values[i] = fieldValue(row, order.Field, order.kind)
+	}
+	return values
+}
+
+// fieldValue reads the field tagged json:"name" as int64 or string. A nil pointer
+// reads as the zero value, which is why nullable key columns are sorted as
+// COALESCE(column, 0). Column types such as models.DeletedAt read as their stored value.
+func fieldValue(row any, name string, kind Kind) any {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	field, ok := findField(v, name)
+	for ok && field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			ok = false
+			break
+		}
+		field = field.Elem()
+	}
+	if ok {
+		if valuer, isValuer := field.Interface().(driver.Valuer); isValuer {
+			stored, _ := valuer.Value()
+			field = reflect.ValueOf(stored)
+			ok = field.IsValid()
+		}
+	}
+	if kind == Int {
+		if !ok {
+			return int64(0)
+		}
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return field.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(field.Uint())
+		}
+		return int64(0)
+	}
+	if !ok || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// findField looks name up among the JSON fields of struct v, embedded structs included
+func findField(v reflect.Value, name string) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && tag == "" {
+			if found, ok := findField(reflect.Indirect(v.Field(i)), name); ok {
+				return found, true
+			}
+			continue
+		}
+		if field.IsExported() && tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
@@ -0,0 +1,223 @@
+// Package listquery is the shared filter, sort and cursor pagination of the list
+// endpoints. Each collection whitelists its fields in a Resource; clients then send
+//
+//	?filter[status]=late,absent&sort=-session_date,student_id&limit=50&cursor=...
+//
+// and get back at most limit rows plus the cursor of the next page. Pages are keyset
+// based: the cursor holds the sort values of the last row, so rows inserted while a
+// client pages through never shift or repeat what it already has.
+package listquery
+
+import (
+	"easy-attend-service/utils/apperror"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by Parse; the field parameter names the offending field
+var (
+	ErrInvalidFilter      = apperror.Validation("list.invalid_filter", "field cannot be filtered on")
+	ErrInvalidFilterValue = apperror.Validation("list.invalid_filter_value", "invalid filter value")
+	ErrInvalidSort        = apperror.Validation("list.invalid_sort", "field cannot be sorted on")
+	ErrInvalidLimit       = apperror.Validation("list.invalid_limit", "invalid limit")
+	ErrInvalidCursor      = apperror.Validation("list.invalid_cursor", "invalid or expired cursor")
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Kind is the type of a field's values, used to parse filters and cursors
+type Kind int
+
+const (
+	String Kind = iota
+	Int
+)
+
+// Field is one field of a collection. Its name in Resource.Fields is the JSON name
+// of the model field, which is also how the value is read back from a row.
+type Field struct {
+	Column string // SQL column or expression, the field name when empty
+	Kind   Kind
+	Filter bool // Allowed in filter[name]=
+	Sort   bool // Allowed in sort=
+}
+
+// Resource whitelists what clients may filter and sort one collection by
+type Resource struct {
+	Fields map[string]Field
+	// Key names the fields that identify a row; they end every sort so the order is total
+	Key          []string
+	DefaultSort  string // e.g. "-created_at"
+	DefaultLimit int    // 20 when zero
+	MaxLimit     int    // 100 when zero
+}
+
+// Filter keeps the rows whose field equals one of Values
+type Filter struct {
+	Field  string
+	Column string
+	Values []any // int64 or string, by the field's kind
+	kind   Kind
+}
+
+// Order is one key of the sort
+type Order struct {
+	Field  string
+	Column string
+	Desc   bool
+	kind   Kind
+}
+
+// Query is a parsed list request
+type Query struct {
+	Filters []Filter
+	Sort    []Order // The requested sort followed by the resource key
+	Limit   int
+	After   []any // Sort values of the last row of the previous page, nil on the first page
+}
+
+// Parse reads filter[field]=, sort=, limit= and cursor= from values. Other
+// parameters are left to the handler.
+func Parse(values url.Values, resource Resource) (Query, error) {
+	var query Query
+
+	for name, raw := range values {
+		field, ok := strings.CutPrefix(name, "filter[")
+		if !ok {
+			continue
+		}
+		field, ok = strings.CutSuffix(field, "]")
+		spec, known := resource.Fields[field]
+		if !ok || !known || !spec.Filter {
+			return Query{}, ErrInvalidFilter.With("field", field)
+		}
+		filter := Filter{Field: field, Column: spec.column(field), kind: spec.Kind}
+		for _, value := range raw {
+			for _, part := range strings.Split(value, ",") {
+				parsed, err := spec.parse(part)
+				if err != nil {
+					return Query{}, ErrInvalidFilterValue.With("field", field)
+				}
+				filter.Values = append(filter.Values, parsed)
+			}
+		}
+		query.Filters = append(query.Filters, filter)
+	}
+	// Map order is random; the cursor signature needs a stable one
+	slices.SortFunc(query.Filters, func(a, b Filter) int { return strings.Compare(a.Field, b.Field) })
+
+	sort := values.Get("sort")
+	if sort == "" {
+		sort = resource.DefaultSort
+	}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, desc := strings.CutPrefix(part, "-")
+		spec, known := resource.Fields[field]
+		if !known || !spec.Sort || seen[field] {
+			return Query{}, ErrInvalidSort.With("field", field)
+		}
+		seen[field] = true
+		query.Sort = append(query.Sort, Order{Field: field, Column: spec.column(field), Desc: desc, kind: spec.Kind})
+	}
+	// The key follows the direction of the last requested field, so "-created_at"
+	// lists the newest row first also among rows created in the same second
+	keyDesc := len(query.Sort) > 0 && query.Sort[len(query.Sort)-1].Desc
+	for _, field := range resource.Key {
+		if seen[field] {
+			continue
+		}
+		spec := resource.Fields[field]
+		query.Sort = append(query.Sort, Order{Field: field, Column: spec.column(field), Desc: keyDesc, kind: spec.Kind})
+	}
+
+	var maxAllowed int
+	query.Limit, maxAllowed = resource.Limits()
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxAllowed {
+			return Query{}, ErrInvalidLimit.With("max", strconv.Itoa(maxAllowed))
+		}
+		query.Limit = limit
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		after, err := query.decodeCursor(raw)
+		if err != nil {
+			return Query{}, ErrInvalidCursor
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+// Limits returns the page size used when the client sends no limit and the largest one allowed
+func (r Resource) Limits() (def, max int) {
+	def, max = r.DefaultLimit, r.MaxLimit
+	if def == 0 {
+		def = defaultLimit
+	}
+	if max == 0 {
+		max = maxLimit
+	}
+	return def, max
+}
+
+func (f Field) column(name string) string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return name
+}
+
+func (f Field) parse(value string) (any, error) {
+	if f.Kind == Int {
+		return strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	}
+	return value, nil
+}
+
+// SortFields names the fields the rows are sorted by; a page's cursor is read from them
+func (q Query) SortFields() []string {
+	fields := make([]string, len(q.Sort))
+	for i, order := range q.Sort {
+		fields[i] = order.Field
+	}
+	return fields
+}
+
+// signature identifies the sort and filters a cursor was issued for; a cursor is
+// only valid for the same list
+func (q Query) signature() string {
+	var b strings.Builder
+	for _, order := range q.Sort {
+		if order.Desc {
+			b.WriteByte('-')
+		}
+		b.WriteString(order.Field)
+		b.WriteByte(',')
+	}
+	for _, filter := range q.Filters {
+		b.WriteString("|" + filter.Field + "=")
+		for _, value := range filter.Values {
+			b.WriteString(strconv.Quote(toString(value)) + ",")
+		}
+	}
+	return b.String()
+}
+
+func toString(value any) string {
+	if n, ok := value.(int64); ok {
+		return strconv.FormatInt(n, 10)
+	}
+	return value.(string)
+}
@@ -0,0 +1,96 @@
+package listquery
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Scope applies the filters, the cursor position, the order and a limit of one row
+// more than the page, which Page uses to tell whether another page follows
+func (q Query) Scope(db *gorm.DB) *gorm.DB {
+	for _, filter := range q.Filters {
+		if len(filter.Values) == 1 {
+			db = db.Where(filter.Column+" = ?", filter.Values[0])
+		} else {
+			db = db.Where(filter.Column+" IN ?", filter.Values)
+		}
+	}
+
+	if q.After != nil {
+		// (a, b) after (x, y) is a > x OR (a = x AND b > y), with < for descending keys
+		var ors []string
+		var args []any
+		for i, order := range q.Sort {
+			var ands []string
+			for _, prev := range q.Sort[:i] {
+				ands = append(ands, prev.Column+" = ?")
+			}
+			args = append(args, q.After[:i]...)
+			op := " > ?"
+			if order.Desc {
+				op = " < ?"
+			}
+			ands = append(ands, order.Column+op)
+			args = append(args, q.After[i])
+			ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		}
+		db = db.Where("("+strings.Join(ors, " OR ")+")", args...)
+	}
+
+	for _, order := range q.Sort {
+		if order.Desc {
+			db = db.Order(order.Column + " DESC")
+		} else {
+			db = db.Order(order.Column)
+		}
+	}
+	return db.Limit(q.Limit + 1)
+}
+
+// Apply is Scope for rows already in memory
+func Apply[T any](q Query, rows []T) []T {
+	var result []T
+	for _, row := range rows {
+		if q.matches(row) && (q.After == nil || q.compare(q.values(row), q.After) > 0) {
+			result = append(result, row)
+		}
+	}
+	slices.SortStableFunc(result, func(a, b T) int {
+		return q.compare(q.values(a), q.values(b))
+	})
+	if len(result) > q.Limit+1 {
+		result = result[:q.Limit+1]
+	}
+	return result
+}
+
+func (q Query) matches(row any) bool {
+	for _, filter := range q.Filters {
+		if !slices.Contains(filter.Values, fieldValue(row, filter.Field, filter.kind)) {
+			return false
+		}
+	}
+	return true
+}
+
+// compare orders two rows' sort values the way ORDER BY does
+func (q Query) compare(a, b []any) int {
+	for i, order := range q.Sort {
+		var c int
+		if x, ok := a[i].(int64); ok {
+			c = cmp.Compare(x, b[i].(int64))
+		} else {
+			c = strings.Compare(a[i].(string), b[i].(string))
+		}
+		if order.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
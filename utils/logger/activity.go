@@ -1,8 +1,11 @@
 package logger
 
 import (
+	"context"
 	"easy-attend-service/configs"
 	"easy-attend-service/models"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/requestid"
 	"fmt"
 	"time"
 
@@ -10,17 +13,22 @@ import (
 )
 
 // LogActivity สร้าง log record อัตโนมัติสำหรับ activity ต่างๆ
-func LogActivity(teacherID uint, action models.LogAction, detail string, schoolID *uint) error {
+// The message is kept as key and params; Detail holds it rendered in the default language
+func LogActivity(ctx context.Context, teacherID uint, action models.LogAction, message i18n.Message, schoolID *uint) error {
+	detail := message.Render(i18n.Default())
 	log := models.Log{
-		TeacherID: teacherID,
-		Action:    action,
-		Detail:    detail,
-		CreatedAt: time.Now().Unix(),
-		SchoolID:  schoolID,
+		TeacherID:     teacherID,
+		Action:        action,
+		Detail:        detail,
+		MessageKey:    message.Key,
+		MessageParams: message.Params,
+		CreatedAt:     time.Now().Unix(),
+		SchoolID:      schoolID,
+		RequestID:     requestid.Ptr(ctx),
 	}
 
-	if err := configs.DB.Create(&log).Error; err != nil {
-		LogError(err, "Failed to create activity log", logrus.Fields{
+	if err := configs.DB.WithContext(ctx).Create(&log).Error; err != nil {
+		LogError(ctx, err, "Failed to create activity log", logrus.Fields{
 			"teacher_id": fmt.Sprintf("%d", teacherID),
 			"action":     string(action),
 			"detail":     detail,
@@ -28,7 +36,7 @@ func LogActivity(teacherID uint, action models.LogAction, detail string, schoolI
 		return err
 	}
 
-	LogInfo("Activity logged successfully", logrus.Fields{
+	LogInfo(ctx, "Activity logged successfully", logrus.Fields{
 		"log_id":     fmt.Sprintf("%d", log.ID),
 		"teacher_id": fmt.Sprintf("%d", teacherID),
 		"action":     string(action),
@@ -38,17 +46,20 @@ func LogActivity(teacherID uint, action models.LogAction, detail string, schoolI
 }
 
 // LogActivityWithContext สร้าง log พร้อม context เพิ่มเติม
-func LogActivityWithContext(teacherID uint, action models.LogAction, detail string, schoolID *uint, context map[string]interface{}) error {
-	// เพิ่ม context เข้าไปใน detail
-	contextDetail := detail
-	if len(context) > 0 {
-		contextDetail += " | Context: "
-		for key, value := range context {
-			contextDetail += key + "=" + formatValue(value) + " "
+func LogActivityWithContext(ctx context.Context, teacherID uint, action models.LogAction, message i18n.Message, schoolID *uint, extra map[string]interface{}) error {
+	// เพิ่ม context เข้าไปใน params ของข้อความ
+	if len(extra) > 0 {
+		params := make(map[string]string, len(message.Params)+len(extra))
+		for key, value := range message.Params {
+			params[key] = value
 		}
+		for key, value := range extra {
+			params[key] = formatValue(value)
+		}
+		message.Params = params
 	}
 
-	return LogActivity(teacherID, action, contextDetail, schoolID)
+	return LogActivity(ctx, teacherID, action, message, schoolID)
 }
 
 // formatValue แปลงค่าต่างๆ เป็น string
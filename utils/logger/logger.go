@@ -1,19 +1,23 @@
 package logger
 
 import (
+	"context"
+	"easy-attend-service/configs"
+	"easy-attend-service/utils/requestid"
+	"easy-attend-service/utils/tracing"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
 var Log *logrus.Logger
 
-func InitLogger() {
+func InitLogger(cfg configs.LogConfig) {
 	Log = logrus.New()
 
 	// Set log level
-	level := os.Getenv("LOG_LEVEL")
-	switch level {
+	switch strings.ToUpper(cfg.Level) {
 	case "DEBUG":
 		Log.SetLevel(logrus.DebugLevel)
 	case "INFO":
@@ -27,7 +31,7 @@ func InitLogger() {
 	}
 
 	// Set log format
-	if os.Getenv("LOG_FORMAT") == "json" {
+	if cfg.Format == "json" {
 		Log.SetFormatter(&logrus.JSONFormatter{})
 	} else {
 		Log.SetFormatter(&logrus.TextFormatter{
@@ -37,20 +41,43 @@ func InitLogger() {
 
 	// Set output
 	Log.SetOutput(os.Stdout)
+
+	// Correlate log lines with requests and traces
+	Log.AddHook(contextHook{})
+}
+
+// contextHook adds request_id, trace_id and span_id to entries logged with a request context
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	if id := requestid.FromContext(entry.Context); id != "" {
+		entry.Data["request_id"] = id
+	}
+	if traceID, spanID := tracing.IDs(entry.Context); traceID != "" {
+		entry.Data["trace_id"] = traceID
+		entry.Data["span_id"] = spanID
+	}
+	return nil
 }
 
 // Helper functions for structured logging
-func LogAPIRequest(method, path string, userID string) {
-	Log.WithFields(logrus.Fields{
-		"type":    "api_request",
-		"method":  method,
-		"path":    path,
-		"user_id": userID,
+func LogAPIRequest(ctx context.Context, method, path string) {
+	Log.WithContext(ctx).WithFields(logrus.Fields{
+		"type":   "api_request",
+		"method": method,
+		"path":   path,
 	}).Info("API request received")
 }
 
-func LogAPIResponse(method, path string, statusCode int, userID string) {
-	Log.WithFields(logrus.Fields{
+func LogAPIResponse(ctx context.Context, method, path string, statusCode int, userID string) {
+	Log.WithContext(ctx).WithFields(logrus.Fields{
 		"type":        "api_response",
 		"method":      method,
 		"path":        path,
@@ -59,35 +86,35 @@ func LogAPIResponse(method, path string, statusCode int, userID string) {
 	}).Info("API response sent")
 }
 
-func LogError(err error, context string, fields logrus.Fields) {
+func LogError(ctx context.Context, err error, message string, fields logrus.Fields) {
 	if fields == nil {
 		fields = logrus.Fields{}
 	}
 	fields["type"] = "error"
-	fields["context"] = context
-	Log.WithFields(fields).Error(err.Error())
+	fields["context"] = message
+	Log.WithContext(ctx).WithFields(fields).Error(err.Error())
 }
 
-func LogInfo(message string, fields logrus.Fields) {
+func LogInfo(ctx context.Context, message string, fields logrus.Fields) {
 	if fields == nil {
 		fields = logrus.Fields{}
 	}
 	fields["type"] = "info"
-	Log.WithFields(fields).Info(message)
+	Log.WithContext(ctx).WithFields(fields).Info(message)
 }
 
-func LogDebug(message string, fields logrus.Fields) {
+func LogDebug(ctx context.Context, message string, fields logrus.Fields) {
 	if fields == nil {
 		fields = logrus.Fields{}
 	}
 	fields["type"] = "debug"
-	Log.WithFields(fields).Debug(message)
+	Log.WithContext(ctx).WithFields(fields).Debug(message)
 }
 
-func LogWarning(message string, fields logrus.Fields) {
+func LogWarning(ctx context.Context, message string, fields logrus.Fields) {
 	if fields == nil {
 		fields = logrus.Fields{}
 	}
 	fields["type"] = "warning"
-	Log.WithFields(fields).Warn(message)
+	Log.WithContext(ctx).WithFields(fields).Warn(message)
 }
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "easy_attend"
+
+// Registry holds every metric served on /metrics. A dedicated registry keeps
+// library defaults out and lets tests build the router more than once.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts finished requests by route template, e.g. /api/v1/students/:id
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration is the request latency by route template
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route template and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"method", "route", "status"})
+
+	// RateLimitRejections counts 429 responses per limiter (strict, normal, generous)
+	RateLimitRejections = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_rejections_total",
+		Help:      "Requests rejected by a rate limiter.",
+	}, []string{"limiter"})
+
+	// LoginFailures counts rejected logins by reason
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed login attempts, by reason.",
+	}, []string{"reason"})
+
+	// AttendanceRecorded counts attendance rows created or changed, by status and channel
+	AttendanceRecorded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "attendance_recorded_total",
+		Help:      "Attendance rows recorded, by status and source (api, rollcall, sync).",
+	}, []string{"status", "source"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		RateLimitRejections,
+		LoginFailures,
+		AttendanceRecorded,
+	)
+}
+
+// RegisterDB exposes the connection pool stats (open, in use, wait count...) of db
+func RegisterDB(db *sql.DB) error {
+	err := Registry.Register(collectors.NewDBStatsCollector(db, "easy_attend"))
+	if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return nil
+	}
+	return err
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,378 @@
+package openapi
+
+import (
+	"easy-attend-service/utils/fieldset"
+	"easy-attend-service/utils/listquery"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route documents one registered route. Path uses gin syntax, e.g. "/api/v1/students/:id".
+type Route struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Public      bool                // Reachable without a bearer token
+	Idempotent  bool                // Accepts an Idempotency-Key header
+	Query       any                 // Struct whose form tags are the query parameters
+	List        *listquery.Resource // Paginated list: adds filter, sort, limit and cursor and the pagination block
+	Fields      *fieldset.Spec      // Adds fields and expand
+	Body        any                 // JSON request body
+	Status      int                 // Success status, 200 when zero
+	Data        any                 // "data" of the success envelope, nil when the response has none
+	Result      any                 // Whole success body, for routes that do not use the envelope
+	ContentType string              // Success content type when it is not JSON, e.g. text/event-stream
+}
+
+// Spec is everything Build needs besides the registered routes
+type Spec struct {
+	Info     Info
+	Routes   []Route
+	Envelope any // Success body; its "data" property is replaced by each route's Data
+	Problem  any // Error body, sent as application/problem+json
+	// Overrides describe types whose JSON the reflection cannot see, e.g. custom MarshalJSON
+	Overrides map[reflect.Type]*Schema
+}
+
+// Document is an OpenAPI 3.0 document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	Responses       map[string]*Response       `json:"responses"`
+	Parameters      map[string]*Parameter      `json:"parameters"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Ref         string                `json:"$ref,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Undocumented is the summary given to registered routes that have no Route entry
+const Undocumented = "Undocumented route"
+
+// Build documents every registered route. Routes without a Route entry still
+// appear, summarised as Undocumented, so the document never hides an endpoint.
+func Build(spec Spec, registered gin.RoutesInfo) *Document {
+	s := newSchemas(spec.Overrides)
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    spec.Info,
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			Schemas: s.components,
+			Responses: map[string]*Response{
+				"Problem": {
+					Description: "RFC 7807 problem; code identifies the error",
+					Content:     map[string]*MediaType{"application/problem+json": {Schema: s.of(reflect.TypeOf(spec.Problem))}},
+				},
+			},
+			Parameters: map[string]*Parameter{
+				"AcceptLanguage": {
+					Name:        "Accept-Language",
+					In:          "header",
+					Description: "Language of messages when the profile sets none",
+					Schema:      &Schema{Type: "string", Example: "th"},
+				},
+				"IdempotencyKey": {
+					Name:        "Idempotency-Key",
+					In:          "header",
+					Description: "Replays the stored response when a POST is retried with the same key",
+					Schema:      &Schema{Type: "string", MaxLength: intPtr(255)},
+				},
+				"IfNoneMatch": {
+					Name:        "If-None-Match",
+					In:          "header",
+					Description: "ETag of the cached response; 304 Not Modified when it is still current",
+					Schema:      &Schema{Type: "string"},
+				},
+				"IfMatch": {
+					Name:        "If-Match",
+					In:          "header",
+					Description: "ETag of the record as last read; 412 when it has changed since",
+					Schema:      &Schema{Type: "string"},
+				},
+			},
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+
+	documented := map[string]Route{}
+	for _, route := range spec.Routes {
+		documented[routeKey(route.Method, route.Path)] = route
+	}
+
+	ids := map[string]int{}
+	for _, info := range sortedRoutes(registered) {
+		route, ok := documented[routeKey(info.Method, info.Path)]
+		if !ok {
+			route = Route{Method: info.Method, Path: info.Path, Summary: Undocumented}
+		}
+		op := s.operation(spec, route)
+		op.OperationID = operationID(info, ids)
+
+		path := toOpenAPIPath(info.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+		doc.Paths[path][strings.ToLower(info.Method)] = op
+	}
+	return doc
+}
+
+// Coverage compares the documented routes with the registered ones: undocumented
+// routes are registered without a Route entry, unknown entries match no route
+func Coverage(routes []Route, registered gin.RoutesInfo) (undocumented, unknown []string) {
+	documented := map[string]bool{}
+	for _, route := range routes {
+		documented[routeKey(route.Method, route.Path)] = true
+	}
+	seen := map[string]bool{}
+	for _, info := range registered {
+		key := routeKey(info.Method, info.Path)
+		seen[key] = true
+		if !documented[key] {
+			undocumented = append(undocumented, key)
+		}
+	}
+	for _, route := range routes {
+		if key := routeKey(route.Method, route.Path); !seen[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(undocumented)
+	slices.Sort(unknown)
+	return undocumented, unknown
+}
+
+func (s *schemas) operation(spec Spec, route Route) *Operation {
+	op := &Operation{
+		Summary:    route.Summary,
+		Parameters: []*Parameter{{Ref: "#/components/parameters/AcceptLanguage"}},
+		Responses:  map[string]*Response{"default": {Ref: "#/components/responses/Problem"}},
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if !route.Public {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+		op.Responses["401"] = &Response{Ref: "#/components/responses/Problem"}
+	}
+	if route.Idempotent {
+		op.Parameters = append(op.Parameters, &Parameter{Ref: "#/components/parameters/IdempotencyKey"})
+	}
+	// Protected routes evaluate conditional headers; streams and upgrades are never cached
+	if !route.Public && route.Method == http.MethodGet && route.Status == 0 && route.ContentType == "" {
+		op.Parameters = append(op.Parameters, &Parameter{Ref: "#/components/parameters/IfNoneMatch"})
+		op.Responses["304"] = &Response{Description: "Not Modified"}
+	}
+	if !route.Public && route.Method == http.MethodPut {
+		op.Parameters = append(op.Parameters, &Parameter{Ref: "#/components/parameters/IfMatch"})
+		op.Responses["412"] = &Response{Ref: "#/components/responses/Problem"}
+	}
+
+	for _, name := range pathParams(route.Path) {
+		op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}})
+	}
+	if route.Query != nil {
+		for field := range fields(reflect.TypeOf(route.Query), "form") {
+			schema := s.of(field.Type)
+			applyRules(schema, field)
+			op.Parameters = append(op.Parameters, &Parameter{Name: field.name, In: "query", Required: field.required, Schema: schema})
+		}
+	}
+	if route.List != nil {
+		op.Parameters = append(op.Parameters, listParameters(*route.List)...)
+	}
+	if route.Fields != nil {
+		op.Parameters = append(op.Parameters, fieldsetParameters(*route.Fields)...)
+	}
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: s.of(reflect.TypeOf(route.Body))}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case route.ContentType != "":
+		success.Content = map[string]*MediaType{route.ContentType: {Schema: &Schema{Type: "string"}}}
+	case route.Result != nil:
+		success.Content = map[string]*MediaType{"application/json": {Schema: s.of(reflect.TypeOf(route.Result))}}
+	case spec.Envelope != nil:
+		envelope := s.object(reflect.TypeOf(spec.Envelope))
+		delete(envelope.Properties, "data")
+		if route.Data != nil {
+			envelope.Properties["data"] = s.of(reflect.TypeOf(route.Data))
+		}
+		if route.List != nil {
+			envelope.Properties["pagination"] = s.of(reflect.TypeFor[listquery.Pagination]())
+		}
+		success.Content = map[string]*MediaType{"application/json": {Schema: envelope}}
+	}
+	op.Responses[fmt.Sprint(status)] = success
+	return op
+}
+
+// listParameters documents the query parameters listquery.Parse reads for resource
+func listParameters(resource listquery.Resource) []*Parameter {
+	def, max := resource.Limits()
+	var sortable, filterable []string
+	for name, field := range resource.Fields {
+		if field.Sort {
+			sortable = append(sortable, name)
+		}
+		if field.Filter {
+			filterable = append(filterable, name)
+		}
+	}
+	slices.Sort(sortable)
+	slices.Sort(filterable)
+
+	params := []*Parameter{
+		{Name: "limit", In: "query", Description: fmt.Sprintf("Page size, default %d", def), Schema: &Schema{Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(float64(max))}},
+		{Name: "cursor", In: "query", Description: "pagination.next_cursor of the previous page", Schema: &Schema{Type: "string"}},
+		{Name: "sort", In: "query", Description: fmt.Sprintf("Comma separated fields, - for descending: %s. Default %q", strings.Join(sortable, ", "), resource.DefaultSort), Schema: &Schema{Type: "string"}},
+	}
+	for _, name := range filterable {
+		description := "Exact match; comma separated values match any"
+		if resource.Fields[name].Kind == listquery.Int {
+			description = "Exact match on an integer; comma separated values match any"
+		}
+		params = append(params, &Parameter{Name: "filter[" + name + "]", In: "query", Description: description, Schema: &Schema{Type: "string"}})
+	}
+	return params
+}
+
+// fieldsetParameters documents the query parameters fieldset.Parse reads for spec
+func fieldsetParameters(spec fieldset.Spec) []*Parameter {
+	fields := slices.Sorted(maps.Keys(spec.Fields))
+	relations := slices.Sorted(maps.Keys(spec.Relations))
+	return []*Parameter{
+		{Name: "fields", In: "query", Description: "Comma separated fields to send, id is always sent: " + strings.Join(fields, ", "), Schema: &Schema{Type: "string"}},
+		{Name: "expand", In: "query", Description: "Comma separated relations to embed, none by default: " + strings.Join(relations, ", "), Schema: &Schema{Type: "string"}},
+	}
+}
+
+var ginParam = regexp.MustCompile(`[:*](\w+)`)
+
+// toOpenAPIPath turns "/students/:id" into "/students/{id}"
+func toOpenAPIPath(path string) string {
+	return ginParam.ReplaceAllString(path, "{$1}")
+}
+
+func pathParams(path string) []string {
+	var names []string
+	for _, match := range ginParam.FindAllStringSubmatch(path, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+func routeKey(method, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// operationID names the operation after its handler, e.g. "getAllStudents", which
+// is what generated clients call the function. Anonymous handlers fall back to the path.
+func operationID(info gin.RouteInfo, seen map[string]int) string {
+	name := info.Handler[strings.LastIndex(info.Handler, ".")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	if name == "" || strings.HasPrefix(name, "func") || !unicode.IsUpper(rune(name[0])) {
+		name = strings.ToLower(info.Method)
+		for _, part := range strings.FieldsFunc(info.Path, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	name = strings.ToLower(name[:1]) + name[1:]
+
+	seen[name]++
+	if seen[name] > 1 {
+		// The same handler on two routes, e.g. a create endpoint under /test
+		name = fmt.Sprintf("%s%d", name, seen[name])
+	}
+	return name
+}
+
+// sortedRoutes orders routes by path then method so the document is stable
+func sortedRoutes(routes gin.RoutesInfo) gin.RoutesInfo {
+	sorted := slices.Clone(routes)
+	slices.SortFunc(sorted, func(a, b gin.RouteInfo) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return sorted
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,244 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the generator emits
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Example              any                `json:"example,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	rawJSONType   = reflect.TypeFor[json.RawMessage]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// schemas turns Go types into schemas. Named structs become components so
+// recursive models (classroom -> teacher -> classrooms) end in a $ref.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	overrides  map[reflect.Type]*Schema
+}
+
+func newSchemas(overrides map[reflect.Type]*Schema) *schemas {
+	return &schemas{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+		overrides:  overrides,
+	}
+}
+
+// of returns the schema of t
+func (s *schemas) of(t reflect.Type) *Schema {
+	if override, ok := s.overrides[t]; ok {
+		copied := *override
+		return &copied
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawJSONType:
+		return &Schema{Description: "any JSON value"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.of(t.Elem())
+		if schema.Ref != "" {
+			// $ref ignores its siblings in OpenAPI 3.0
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+			// Custom JSON we cannot see through; callers describe it with an override
+			return &Schema{}
+		}
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	}
+	return &Schema{}
+}
+
+// component registers t under a unique name and returns the name
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := s.components[name]; taken {
+		// Same name in another package, e.g. services.LoginResponse and response.LoginResponse
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+	s.names[t] = name
+	s.components[name] = &Schema{} // placeholder while the fields refer back to t
+	*s.components[name] = *s.object(t)
+	return name
+}
+
+// object describes a struct by its JSON fields; embedded structs are flattened
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for field := range fields(t, "json") {
+		property := s.of(field.Type)
+		applyRules(property, field)
+		schema.Properties[field.name] = property
+		if field.required {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+	return schema
+}
+
+// jsonField is a struct field as it appears on the wire
+type jsonField struct {
+	reflect.StructField
+	name     string
+	required bool
+}
+
+// fields yields the exported fields of t named by tag ("json" or "form")
+func fields(t reflect.Type, tag string) func(yield func(jsonField) bool) {
+	return func(yield func(jsonField) bool) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				continue
+			}
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				for embedded := range fields(field.Type, tag) {
+					if !yield(embedded) {
+						return
+					}
+				}
+				continue
+			}
+			if name == "" {
+				if tag != "json" {
+					continue
+				}
+				name = field.Name
+			}
+			required := hasRule(field.Tag.Get("binding"), "required")
+			if !yield(jsonField{StructField: field, name: name, required: required}) {
+				return
+			}
+		}
+	}
+}
+
+// applyRules copies the gin binding rules and example tag of field onto schema
+func applyRules(schema *Schema, field jsonField) {
+	if example := field.Tag.Get("example"); example != "" {
+		schema.Example = example
+		if n, err := strconv.ParseInt(example, 10, 64); err == nil && schema.Type == "integer" {
+			schema.Example = n
+		}
+	}
+	if schema.Ref != "" {
+		return
+	}
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			// Later rules apply to the elements, which carry their own tags
+			return
+		case "email":
+			schema.Format = "email"
+		case "uuid":
+			schema.Format = "uuid"
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "max":
+			limit(schema, name, param)
+		}
+	}
+}
+
+// limit sets the min/max keyword that fits the schema's type
+func limit(schema *Schema, rule, param string) {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return
+	}
+	isMin := rule == "min"
+	switch schema.Type {
+	case "string":
+		if isMin {
+			schema.MinLength = &n
+		} else {
+			schema.MaxLength = &n
+		}
+	case "array":
+		if isMin {
+			schema.MinItems = &n
+		} else {
+			schema.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if isMin {
+			schema.Minimum = &f
+		} else {
+			schema.Maximum = &f
+		}
+	}
+}
+
+func hasRule(binding, rule string) bool {
+	for _, r := range strings.Split(binding, ",") {
+		if name, _, _ := strings.Cut(r, "="); name == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,9 @@
+package openapi
+
+import _ "embed"
+
+// SwaggerUI is the page that renders /openapi.json. The page is embedded in the
+// binary; the Swagger UI scripts and styles load from the swagger-ui-dist CDN.
+//
+//go:embed swagger.html
+var SwaggerUI []byte
@@ -0,0 +1,196 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/logger"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Consumer รับ event จาก dispatcher
+// Delivery is at least once, so Handle must tolerate seeing the same event twice.
+type Consumer interface {
+	Name() string
+	Handle(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// LogConsumer writes the activity log entry for events that carry an action.
+// The entry is keyed by the event ID so redelivery never duplicates it.
+type LogConsumer struct {
+	db *gorm.DB
+}
+
+func NewLogConsumer(db *gorm.DB) *LogConsumer {
+	return &LogConsumer{db: db}
+}
+
+func (c *LogConsumer) Name() string {
+	return "log"
+}
+
+func (c *LogConsumer) Handle(ctx context.Context, event *models.OutboxEvent) error {
+	if event.Action == "" {
+		return nil
+	}
+
+	eventID := event.ID
+	log := models.Log{
+		TeacherID:     event.TeacherID,
+		Action:        event.Action,
+		Detail:        event.Detail,
+		MessageKey:    event.MessageKey,
+		MessageParams: event.MessageParams,
+		CreatedAt:     event.CreatedAt,
+		SchoolID:      event.SchoolID,
+		EventID:       &eventID,
+		RequestID:     event.RequestID,
+	}
+
+	if err := c.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).
+		Create(&log).Error; err != nil {
+		return err
+	}
+
+	logger.LogInfo(ctx, "Activity logged successfully", logrus.Fields{
+		"event_id":   fmt.Sprintf("%d", event.ID),
+		"teacher_id": fmt.Sprintf("%d", event.TeacherID),
+		"action":     string(event.Action),
+	})
+
+	return nil
+}
+
+// WebhookConsumer POSTs every event as JSON to an external endpoint.
+// Receivers should deduplicate on the X-Outbox-Event-ID header.
+type WebhookConsumer struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookConsumer(url, secret string) *WebhookConsumer {
+	return &WebhookConsumer{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *WebhookConsumer) Name() string {
+	return "webhook"
+}
+
+type webhookBody struct {
+	ID            uint              `json:"id"`
+	Type          string            `json:"type"`
+	AggregateType string            `json:"aggregate_type"`
+	AggregateID   uint              `json:"aggregate_id"`
+	TeacherID     uint              `json:"teacher_id"`
+	SchoolID      *uint             `json:"school_id,omitempty"`
+	Action        models.LogAction  `json:"action,omitempty"`
+	Detail        string            `json:"detail,omitempty"`
+	MessageKey    string            `json:"message_key,omitempty"`
+	MessageParams map[string]string `json:"message_params,omitempty"`
+	Payload       json.RawMessage   `json:"payload"`
+	OccurredAt    int64             `json:"occurred_at"`
+}
+
+func (c *WebhookConsumer) Handle(ctx context.Context, event *models.OutboxEvent) error {
+	body, err := json.Marshal(webhookBody{
+		ID:            event.ID,
+		Type:          event.EventType,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		TeacherID:     event.TeacherID,
+		SchoolID:      event.SchoolID,
+		Action:        event.Action,
+		Detail:        event.Detail,
+		MessageKey:    event.MessageKey,
+		MessageParams: event.MessageParams,
+		Payload:       json.RawMessage(event.Payload),
+		OccurredAt:    event.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Outbox-Event-ID", fmt.Sprintf("%d", event.ID))
+	req.Header.Set("X-Outbox-Event-Type", event.EventType)
+	if c.secret != "" {
+		mac := hmac.New(sha256.New, []byte(c.secret))
+		mac.Write(body)
+		req.Header.Set("X-Outbox-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// NotificationHandler ประมวลผล event สำหรับการแจ้งเตือนภายในระบบ
+type NotificationHandler func(ctx context.Context, event *models.OutboxEvent) error
+
+// NotificationConsumer fans events out to in-process notification handlers
+// registered per event type.
+type NotificationConsumer struct {
+	mu       sync.RWMutex
+	handlers map[string][]NotificationHandler
+}
+
+// Notifications is the shared notification consumer that handlers subscribe to
+var Notifications = NewNotificationConsumer()
+
+func NewNotificationConsumer() *NotificationConsumer {
+	return &NotificationConsumer{
+		handlers: make(map[string][]NotificationHandler),
+	}
+}
+
+func (c *NotificationConsumer) Name() string {
+	return "notification"
+}
+
+// Subscribe registers a handler for an event type; "*" matches every event.
+func (c *NotificationConsumer) Subscribe(eventType string, handler NotificationHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[eventType] = append(c.handlers[eventType], handler)
+}
+
+func (c *NotificationConsumer) Handle(ctx context.Context, event *models.OutboxEvent) error {
+	c.mu.RLock()
+	handlers := append(append([]NotificationHandler{}, c.handlers[event.EventType]...), c.handlers["*"]...)
+	c.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package outbox
+
+import (
+	"context"
+	"easy-attend-service/models"
+	"easy-attend-service/utils/logger"
+	"easy-attend-service/utils/requestid"
+	"easy-attend-service/utils/tracing"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DispatcherConfig controls how often and how aggressively the outbox is drained
+type DispatcherConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	MaxBackoff   time.Duration
+	// Lease is how long a claimed batch stays reserved; it must outlast publishing
+	// the whole batch, or another replica publishes the rest a second time
+	Lease time.Duration
+}
+
+// DefaultDispatcherConfig returns the settings used when nothing is configured
+func DefaultDispatcherConfig() DispatcherConfig {
+	return DispatcherConfig{
+		PollInterval: 2 * time.Second,
+		BatchSize:    50,
+		MaxAttempts:  10,
+		MaxBackoff:   5 * time.Minute,
+		Lease:        5 * time.Minute,
+	}
+}
+
+// Stats is a snapshot of the dispatcher metrics
+type Stats struct {
+	Running    bool   `json:"running"`
+	Polls      uint64 `json:"polls"`
+	Published  uint64 `json:"published"`
+	Retried    uint64 `json:"retried"`
+	Failed     uint64 `json:"failed"`
+	Backlog    int64  `json:"backlog"`
+	LastPollAt int64  `json:"last_poll_at"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// Dispatcher publishes pending outbox rows to the registered consumers.
+// A batch is leased in a short SKIP LOCKED transaction so several replicas can run it
+// at once, and published outside of it: a slow webhook holds no connection or row lock.
+type Dispatcher struct {
+	db        *gorm.DB
+	config    DispatcherConfig
+	consumers []Consumer
+	workerID  string // Written to claimed_by of the leased rows
+
+	running    atomic.Bool
+	polls      atomic.Uint64
+	published  atomic.Uint64
+	retried    atomic.Uint64
+	failed     atomic.Uint64
+	backlog    atomic.Int64
+	lastPollAt atomic.Int64
+
+	mu        sync.Mutex
+	lastError string
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func NewDispatcher(db *gorm.DB, config DispatcherConfig, consumers ...Consumer) *Dispatcher {
+	host, _ := os.Hostname()
+	return &Dispatcher{
+		db:        db,
+		config:    config,
+		consumers: consumers,
+		workerID:  fmt.Sprintf("%s/%d/%s", host, os.Getpid(), uuid.NewString()[:8]),
+	}
+}
+
+// Start runs the dispatcher loop in a background goroutine
+func (d *Dispatcher) Start() {
+	if !d.running.CompareAndSwap(false, true) {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	d.done = make(chan struct{})
+
+	names := make([]string, 0, len(d.consumers))
+	for _, consumer := range d.consumers {
+		names = append(names, consumer.Name())
+	}
+	logger.LogInfo(ctx, "Outbox dispatcher started", logrus.Fields{
+		"consumers":     names,
+		"poll_interval": d.config.PollInterval.String(),
+	})
+
+	go d.loop(ctx)
+}
+
+// Stop signals the loop to exit and waits for the in-flight batch to finish
+func (d *Dispatcher) Stop() {
+	if !d.running.Load() {
+		return
+	}
+	d.cancel()
+	<-d.done
+	d.running.Store(false)
+	logger.LogInfo(context.Background(), "Outbox dispatcher stopped", nil)
+}
+
+// Running reports whether the background loop is alive
+func (d *Dispatcher) Running() bool {
+	return d.running.Load()
+}
+
+// Stats returns the current dispatcher metrics
+func (d *Dispatcher) Stats() Stats {
+	d.mu.Lock()
+	lastError := d.lastError
+	d.mu.Unlock()
+
+	return Stats{
+		Running:    d.running.Load(),
+		Polls:      d.polls.Load(),
+		Published:  d.published.Load(),
+		Retried:    d.retried.Load(),
+		Failed:     d.failed.Load(),
+		Backlog:    d.backlog.Load(),
+		LastPollAt: d.lastPollAt.Load(),
+		LastError:  lastError,
+	}
+}
+
+func (d *Dispatcher) loop(ctx context.Context) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep draining while full batches come back, then wait for the next tick
+		for {
+			n, err := d.processBatch(ctx)
+			if err != nil {
+				d.recordError(err)
+				logger.LogError(ctx, err, "Outbox dispatch failed", nil)
+				break
+			}
+			if n < d.config.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) processBatch(ctx context.Context) (int, error) {
+	d.polls.Add(1)
+	d.lastPollAt.Store(time.Now().Unix())
+
+	events, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range events {
+		// Every row is settled on its own, so one bad write does not undo the others
+		if err := d.publish(ctx, &events[i]); err != nil {
+			d.recordError(err)
+			logger.LogError(ctx, err, "Failed to record outbox delivery", logrus.Fields{
+				"event_id": fmt.Sprintf("%d", events[i].ID),
+			})
+		}
+	}
+
+	var backlog int64
+	if err := d.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+		Where("status = ?", models.OutboxStatusPending).
+		Count(&backlog).Error; err == nil {
+		d.backlog.Store(backlog)
+	}
+
+	return len(events), nil
+}
+
+// claim leases the next batch of due rows to this dispatcher. Rows whose lease ran out
+// belong to a dispatcher that died while publishing and are claimed again.
+func (d *Dispatcher) claim(ctx context.Context) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().Unix()
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxStatusPending, now).
+			Where("locked_until IS NULL OR locked_until <= ?", now).
+			Order("id").
+			Limit(d.config.BatchSize).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		lockedUntil := now + int64(d.config.Lease.Seconds())
+		for i := range events {
+			events[i].LockedUntil = &lockedUntil
+			events[i].ClaimedBy = &d.workerID
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).Updates(map[string]any{
+			"locked_until": lockedUntil,
+			"claimed_by":   d.workerID,
+		}).Error
+	})
+	return events, err
+}
+
+// publish hands one event to every consumer and records the outcome on the row
+func (d *Dispatcher) publish(ctx context.Context, event *models.OutboxEvent) error {
+	ctx, span := tracing.Start(ctx, "outbox.publish "+event.EventType)
+	defer span.End()
+	if event.RequestID != nil {
+		// Consumer logs carry the ID of the request that recorded the event
+		ctx = requestid.NewContext(ctx, *event.RequestID)
+	}
+
+	var deliveryErr error
+	for _, consumer := range d.consumers {
+		if err := consumer.Handle(ctx, event); err != nil {
+			deliveryErr = fmt.Errorf("%s: %w", consumer.Name(), err)
+			break
+		}
+	}
+
+	now := time.Now().Unix()
+	event.Attempts++
+
+	if deliveryErr == nil {
+		event.Status = models.OutboxStatusPublished
+		event.PublishedAt = &now
+		event.LastError = ""
+		d.published.Add(1)
+	} else {
+		d.recordError(deliveryErr)
+		event.LastError = deliveryErr.Error()
+		if event.Attempts >= d.config.MaxAttempts {
+			event.Status = models.OutboxStatusFailed
+			d.failed.Add(1)
+			logger.LogError(ctx, deliveryErr, "Outbox event gave up after max attempts", logrus.Fields{
+				"event_id":   fmt.Sprintf("%d", event.ID),
+				"event_type": event.EventType,
+				"attempts":   event.Attempts,
+			})
+		} else {
+			event.NextAttemptAt = now + int64(d.backoff(event.Attempts).Seconds())
+			d.retried.Add(1)
+			logger.LogWarning(ctx, "Outbox event delivery failed, will retry", logrus.Fields{
+				"event_id":   fmt.Sprintf("%d", event.ID),
+				"event_type": event.EventType,
+				"attempts":   event.Attempts,
+				"error":      deliveryErr.Error(),
+			})
+		}
+	}
+
+	// Release the lease even when Stop cancelled ctx during delivery; only while this
+	// dispatcher still holds it, another one may have taken over an expired lease
+	event.LockedUntil = nil
+	event.ClaimedBy = nil
+	return d.db.WithContext(context.WithoutCancel(ctx)).Model(event).
+		Where("claimed_by = ?", d.workerID).
+		Select("Status", "Attempts", "NextAttemptAt", "LastError", "PublishedAt", "LockedUntil", "ClaimedBy").
+		Updates(event).Error
+}
+
+// backoff doubles the wait for every failed attempt up to MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := time.Second << uint(attempts)
+	if wait <= 0 || wait > d.config.MaxBackoff {
+		return d.config.MaxBackoff
+	}
+	return wait
+}
+
+func (d *Dispatcher) recordError(err error) {
+	d.mu.Lock()
+	d.lastError = err.Error()
+	d.mu.Unlock()
+}
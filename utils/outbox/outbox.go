@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"easy-attend-service/models"
+	"easy-attend-service/utils/i18n"
+	"easy-attend-service/utils/requestid"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Event อธิบาย side effect ที่ต้องเกิดขึ้นหลังจากการเปลี่ยนแปลงข้อมูลสำเร็จ
+type Event struct {
+	Type          string // e.g. "attendance.created"
+	AggregateType string // e.g. "attendance"
+	AggregateID   uint
+	TeacherID     uint
+	SchoolID      *uint
+	Action        models.LogAction // Activity log action, leave empty to skip the activity log
+	Message       i18n.Message     // Activity log text, stored as key and params and rendered when read
+	Payload       any
+}
+
+// Enqueue บันทึก event ลง outbox โดยใช้ transaction เดียวกับการเปลี่ยนแปลงข้อมูลหลัก
+// The request ID is taken from the context tx was bound to with WithContext
+func Enqueue(tx *gorm.DB, event Event) error {
+	payload := "{}"
+	if event.Payload != nil {
+		data, err := json.Marshal(event.Payload)
+		if err != nil {
+			return err
+		}
+		payload = string(data)
+	}
+
+	row := models.OutboxEvent{
+		EventType:     event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		TeacherID:     event.TeacherID,
+		SchoolID:      event.SchoolID,
+		Action:        event.Action,
+		Detail:        event.Message.Render(i18n.Default()),
+		MessageKey:    event.Message.Key,
+		MessageParams: event.Message.Params,
+		Payload:       payload,
+		RequestID:     requestid.Ptr(tx.Statement.Context),
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now().Unix(),
+	}
+
+	return tx.Create(&row).Error
+}
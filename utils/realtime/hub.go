@@ -0,0 +1,135 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+// Event คือข้อความที่ถูกกระจายไปยังผู้ติดตามแบบ real-time
+type Event struct {
+	ID          uint64 `json:"id"`
+	Type        string `json:"type"` // e.g. "attendance.created"
+	ClassroomID uint   `json:"classroom_id"`
+	Data        any    `json:"data"`
+	OccurredAt  int64  `json:"occurred_at"`
+}
+
+// Subscription receives events accepted by its filter.
+// C is closed when the subscriber falls too far behind; the client should
+// reconnect with the last event ID it saw to resume from the replay buffer.
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	filter func(classroomID uint) bool
+}
+
+// Hub is an in-process pub/sub that keeps a bounded replay buffer of recent events
+type Hub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	buffer      []Event
+	bufferSize  int
+	subscribers map[*Subscription]struct{}
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Attendance is the hub fed by AttendanceService
+var Attendance = NewHub(1000)
+
+// Publish assigns the next event ID, stores the event for replay and fans it out
+func (h *Hub) Publish(eventType string, classroomID uint, data any) Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	event := Event{
+		ID:          h.nextID,
+		Type:        eventType,
+		ClassroomID: classroomID,
+		Data:        data,
+		OccurredAt:  time.Now().Unix(),
+	}
+
+	h.buffer = append(h.buffer, event)
+	if len(h.buffer) > h.bufferSize {
+		h.buffer = h.buffer[len(h.buffer)-h.bufferSize:]
+	}
+
+	for sub := range h.subscribers {
+		if !sub.filter(classroomID) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// Slow consumer: drop it rather than block every publisher
+			delete(h.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+
+	return event
+}
+
+// Subscribe registers a subscriber and returns the buffered events newer than
+// lastEventID that pass the filter, so no event is lost between replay and live delivery.
+// complete is false when events after lastEventID have already left the buffer
+// (or the server restarted) and the client has to refetch its state.
+func (h *Hub) Subscribe(filter func(classroomID uint) bool, lastEventID uint64) (sub *Subscription, replay []Event, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	complete = true
+	if lastEventID > 0 {
+		if lastEventID > h.nextID || (len(h.buffer) > 0 && h.buffer[0].ID > lastEventID+1) {
+			complete = false
+		}
+		for _, event := range h.buffer {
+			if event.ID > lastEventID && filter(event.ClassroomID) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	ch := make(chan Event, 64)
+	sub = &Subscription{C: ch, ch: ch, filter: filter}
+	h.subscribers[sub] = struct{}{}
+
+	return sub, replay, complete
+}
+
+// Unsubscribe removes the subscriber; it is safe to call more than once
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// Subscribers returns the number of connected subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+// CloseAll disconnects every subscriber, e.g. when the server shuts down; clients
+// reconnect with their last event ID
+func (h *Hub) CloseAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,161 @@
+package realtime
+
+import (
+	"sort"
+	"sync"
+)
+
+// Participant is a client connected to a live room
+type Participant struct {
+	ClientID         string `json:"client_id"`
+	TeacherID        uint   `json:"teacher_id"`
+	Name             string `json:"name"`
+	EditingStudentID *uint  `json:"editing_student_id,omitempty"`
+	JoinedAt         int64  `json:"joined_at"`
+
+	// Send is drained by the connection writer; it is closed when the
+	// participant leaves or is dropped for not keeping up.
+	Send chan []byte `json:"-"`
+}
+
+// Room groups the participants of one live session
+type Room struct {
+	Key string
+
+	mu           sync.Mutex
+	participants map[string]*Participant
+}
+
+// Broadcast queues a message for every participant except exceptClientID
+func (r *Room) Broadcast(message []byte, exceptClientID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, p := range r.participants {
+		if id == exceptClientID {
+			continue
+		}
+		select {
+		case p.Send <- message:
+		default:
+			// Slow client: drop it, its writer closes the connection
+			delete(r.participants, id)
+			close(p.Send)
+		}
+	}
+}
+
+// SendTo queues a message for a single participant if it is still in the room
+func (r *Room) SendTo(clientID string, message []byte) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.participants[clientID]
+	if !ok {
+		return false
+	}
+	select {
+	case p.Send <- message:
+		return true
+	default:
+		delete(r.participants, clientID)
+		close(p.Send)
+		return false
+	}
+}
+
+// SetEditing records which student a participant is currently editing (nil when idle)
+func (r *Room) SetEditing(clientID string, studentID *uint) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.participants[clientID]; ok {
+		p.EditingStudentID = studentID
+	}
+}
+
+// Participants returns a snapshot of the connected participants ordered by join time
+func (r *Room) Participants() []Participant {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Participant, 0, len(r.participants))
+	for _, p := range r.participants {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].JoinedAt == list[j].JoinedAt {
+			return list[i].ClientID < list[j].ClientID
+		}
+		return list[i].JoinedAt < list[j].JoinedAt
+	})
+	return list
+}
+
+// RoomRegistry keeps the open rooms of this process
+type RoomRegistry struct {
+	mu    sync.Mutex
+	rooms map[string]*Room
+}
+
+func NewRoomRegistry() *RoomRegistry {
+	return &RoomRegistry{
+		rooms: make(map[string]*Room),
+	}
+}
+
+// RollCall holds the live roll-call rooms, keyed by classroom and session date
+var RollCall = NewRoomRegistry()
+
+// Join adds a participant to the room, creating the room on first join
+func (rr *RoomRegistry) Join(key string, p *Participant) *Room {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	room, ok := rr.rooms[key]
+	if !ok {
+		room = &Room{Key: key, participants: make(map[string]*Participant)}
+		rr.rooms[key] = room
+	}
+
+	room.mu.Lock()
+	room.participants[p.ClientID] = p
+	room.mu.Unlock()
+
+	return room
+}
+
+// Leave removes a participant and closes the room once it is empty
+func (rr *RoomRegistry) Leave(room *Room, clientID string) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	room.mu.Lock()
+	if p, ok := room.participants[clientID]; ok {
+		delete(room.participants, clientID)
+		close(p.Send)
+	}
+	empty := len(room.participants) == 0
+	room.mu.Unlock()
+
+	if empty && rr.rooms[room.Key] == room {
+		delete(rr.rooms, room.Key)
+	}
+}
+
+// CloseAll removes every participant of every room so their writers close the
+// connections, e.g. when the server shuts down
+func (rr *RoomRegistry) CloseAll() {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	for key, room := range rr.rooms {
+		room.mu.Lock()
+		for id, p := range room.participants {
+			delete(room.participants, id)
+			close(p.Send)
+		}
+		room.mu.Unlock()
+		delete(rr.rooms, key)
+	}
+}
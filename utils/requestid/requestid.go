@@ -0,0 +1,49 @@
+package requestid
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// Header carries the request ID in both directions
+const Header = "X-Request-ID"
+
+// validID accepts IDs from proxies and clients (UUIDs, hex trace IDs, ULIDs...) but
+// nothing that could break a log line or a header
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type contextKey struct{}
+
+// New generates a request ID for requests that arrive without one
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether an incoming ID may be reused as is
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" outside a request
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Ptr returns the request ID of ctx for nullable columns, nil outside a request
+func Ptr(ctx context.Context) *string {
+	if id := FromContext(ctx); id != "" {
+		return &id
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores the open query span on the statement between the before and after callbacks
+const spanKey = "tracing:span"
+
+// GormPlugin opens a client span around every GORM query. Spans only join the
+// request trace when the query runs on a db bound with WithContext(ctx).
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", p.before("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", p.after),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", p.before("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", p.after),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", p.before("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", p.after),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", p.before("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", p.after),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", p.before("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", p.after),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", p.before("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", p.after),
+	)
+}
+
+func (GormPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		name := "gorm." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		ctx, span := Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemNamePostgreSQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(db.Statement.Table),
+			),
+		)
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func (GormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"easy-attend-service/configs"
+	"easy-attend-service/utils/buildinfo"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of this service's own spans
+const instrumentationName = "easy-attend-service"
+
+// Init installs the global tracer provider and W3C trace-context propagation.
+// With the "none" exporter spans are still created, so trace IDs reach the logs,
+// but nothing is exported. The returned func flushes pending spans on shutdown.
+func Init(cfg configs.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+		semconv.ServiceVersion(buildinfo.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}
+
+	switch cfg.Exporter {
+	case "otlp":
+		var clientOptions []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			clientOptions = append(clientOptions, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			clientOptions = append(clientOptions, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(context.Background(), clientOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("stdout exporter: %w", err)
+		}
+		// Synchronous so spans show up next to the log lines of the same request
+		options = append(options, sdktrace.WithSyncer(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start opens a span named after the operation, e.g. "AttendanceService.MarkAttendance"
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// IDs returns the trace and span IDs of the span in ctx, empty when there is none
+func IDs(ctx context.Context) (traceID, spanID string) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return "", ""
+	}
+	return spanContext.TraceID().String(), spanContext.SpanID().String()
+}